@@ -0,0 +1,147 @@
+package sticky
+
+import (
+	"context"
+)
+
+// defaultAsyncQueueSize is the size of the queue of WriteAsync, if
+// WithAsyncQueueSize is not used.
+const defaultAsyncQueueSize = 1024
+
+// asyncJob are the records of one call to WriteAsync.
+type asyncJob struct {
+	records [][]byte
+	result  chan<- error
+	after   func()
+}
+
+// WriteAsync is like Write, but does not wait until the event is appended to
+// the database. The event is validated and executed with the write lock, so
+// the next reader sees it. A background goroutine appends it afterwards.
+//
+// The returned channel gets the error of the write, when the event was
+// appended. Errors from Validate or Execute are returned immediately.
+//
+// If the queue is full, WriteAsync waits for the background goroutine. See
+// WithAsyncQueueSize. Close, Sync and all other writes wait until the queue
+// is empty.
+//
+// Events, that are executed but not appended, are lost on a crash. If an
+// append fails, the event is only in the model. Then all following writes
+// fail.
+func (s *Sticky[Model]) WriteAsync(f func(Model) Event[Model]) <-chan error {
+	result := make(chan error, 1)
+	if s.closed.Load() {
+		result <- ErrClosed
+		return result
+	}
+
+	err := s.withWriteLock(context.Background(), func() error {
+		event := f(s.model)
+		if event == nil {
+			result <- nil
+			return nil
+		}
+		return s.writeChain(s.write, writeOptions{async: result})(event)
+	})
+	if err != nil {
+		result <- err
+	}
+	return result
+}
+
+// enqueueAsync adds the records to the queue of the background goroutine. It
+// starts the goroutine, if it is not running. after is called after the
+// records where appended. It can be nil.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) enqueueAsync(records [][]byte, result chan<- error, after func()) {
+	if s.asyncQueue == nil {
+		s.asyncQueue = make(chan asyncJob, s.asyncQueueSize)
+		s.background.Add(1)
+		go s.runAsync(s.asyncQueue)
+	}
+
+	s.asyncPending.Add(1)
+	s.asyncQueue <- asyncJob{records: records, result: result, after: after}
+}
+
+// runAsync appends the records from the queue until it is closed. All jobs,
+// that are in the queue, are appended together.
+//
+// After an append failed, no other records are appended, so there are no
+// gaps in the database.
+func (s *Sticky[Model]) runAsync(queue <-chan asyncJob) {
+	defer s.background.Done()
+
+	for job := range queue {
+		jobs := []asyncJob{job}
+	collect:
+		for {
+			select {
+			case job, ok := <-queue:
+				if !ok {
+					break collect
+				}
+				jobs = append(jobs, job)
+			default:
+				break collect
+			}
+		}
+
+		if s.asyncErr == nil {
+			var records [][]byte
+			for _, job := range jobs {
+				records = append(records, job.records...)
+			}
+
+			if err := appendRecords(s.db, records); err != nil {
+				s.asyncErr = StorageError{Op: "writing async events to db", Err: err}
+				if s.logger != nil {
+					s.logger.Error("writing async events failed", "events", len(records), "error", err)
+				}
+			}
+		}
+
+		err := s.asyncErr
+		for _, job := range jobs {
+			job.result <- err
+			s.asyncPending.Done()
+		}
+
+		// The hooks are called without the write lock. They are called
+		// after the jobs are done, so they can write, while another write
+		// waits for the queue.
+		if err == nil {
+			for _, job := range jobs {
+				if job.after != nil {
+					job.after()
+				}
+			}
+		}
+	}
+}
+
+// flushAsync waits until the queue of WriteAsync is empty. It returns the
+// error, if an append failed.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) flushAsync() error {
+	s.asyncPending.Wait()
+	return s.asyncErr
+}
+
+// stopAsync waits until the queue of WriteAsync is empty and stops the
+// background goroutine.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) stopAsync() error {
+	if s.asyncQueue == nil {
+		return nil
+	}
+
+	err := s.flushAsync()
+	close(s.asyncQueue)
+	s.asyncQueue = nil
+	return err
+}
@@ -0,0 +1,165 @@
+package sticky
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// gateDB blocks each append, until a value is sent to gate.
+type gateDB struct {
+	*MemoryDB
+	gate chan error
+}
+
+func (db *gateDB) Append(bs []byte) error {
+	if err := <-db.gate; err != nil {
+		return err
+	}
+	return db.MemoryDB.Append(bs)
+}
+
+func (db *gateDB) AppendBatch(records [][]byte) error {
+	if err := <-db.gate; err != nil {
+		return err
+	}
+	return db.MemoryDB.AppendBatch(records)
+}
+
+func TestWriteAsync(t *testing.T) {
+	db := &gateDB{MemoryDB: NewMemoryDB(), gate: make(chan error)}
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	result := s.WriteAsync(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 1 {
+		t.Errorf("got sum %d, expected the event to be executed", model.Sum)
+	}
+
+	if got := len(db.Records()); got != 0 {
+		t.Errorf("got %d records before the append, expected 0", got)
+	}
+
+	db.gate <- nil
+	if err := <-result; err != nil {
+		t.Fatalf("async write: %v", err)
+	}
+
+	if got := len(db.Records()); got != 1 {
+		t.Errorf("got %d records after the append, expected 1", got)
+	}
+}
+
+func TestWriteAsync_order(t *testing.T) {
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getTestEvent, WithAsyncQueueSize[testModel](2))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	var results []<-chan error
+	for i := 0; i < 10; i++ {
+		results = append(results, s.WriteAsync(func(testModel) Event[testModel] { return addEvent{Value: 1} }))
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	for _, result := range results {
+		if err := <-result; err != nil {
+			t.Errorf("async write: %v", err)
+		}
+	}
+
+	records := db.Records()
+	if len(records) != 11 {
+		t.Fatalf("got %d records, expected 11", len(records))
+	}
+
+	for i, record := range records {
+		envelope, err := DecodeEnvelope(record)
+		if err != nil {
+			t.Fatalf("decoding record %d: %v", i, err)
+		}
+
+		if envelope.Version != uint64(i+1) {
+			t.Errorf("record %d has version %d, expected %d", i, envelope.Version, i+1)
+		}
+	}
+}
+
+func TestWriteAsync_validation_error(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	select {
+	case err := <-s.WriteAsync(func(testModel) Event[testModel] { return addEvent{Value: -1} }):
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("got error `%v`, expected a ValidationError", err)
+		}
+	default:
+		t.Errorf("validation error is not returned immediately")
+	}
+}
+
+func TestWriteAsync_failed_append(t *testing.T) {
+	db := &gateDB{MemoryDB: NewMemoryDB(), gate: make(chan error, 1)}
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	appendErr := errors.New("disk full")
+	db.gate <- appendErr
+	if err := <-s.WriteAsync(func(testModel) Event[testModel] { return addEvent{Value: 1} }); !errors.Is(err, appendErr) {
+		t.Errorf("async write returned `%v`, expected the append error", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); !errors.Is(err, appendErr) {
+		t.Errorf("write after failed async write returned `%v`, expected the append error", err)
+	}
+}
+
+func TestWriteAsync_close_drains_queue(t *testing.T) {
+	db := &gateDB{MemoryDB: NewMemoryDB(), gate: make(chan error)}
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	result := s.WriteAsync(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+
+	closed := make(chan error)
+	go func() {
+		closed <- s.Close()
+	}()
+
+	select {
+	case <-closed:
+		t.Fatalf("close returned before the queue was empty")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	db.gate <- nil
+	if err := <-closed; err != nil {
+		t.Errorf("close: %v", err)
+	}
+
+	if err := <-result; err != nil {
+		t.Errorf("async write: %v", err)
+	}
+
+	if got := len(db.Records()); got != 1 {
+		t.Errorf("got %d records, expected 1", got)
+	}
+}
@@ -0,0 +1,165 @@
+package sticky
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// pinnedReaderDB is a database, that can return its records as they are
+// stored, up to the size at the time of the call.
+type pinnedReaderDB interface {
+	pinnedReader() (io.ReadCloser, error)
+}
+
+// storedReaderDB is a database, that changes the records of an inner
+// database. storedReader returns the records as the inner database stores
+// them. pinned is like in backupReader. storedSize returns the maximum size
+// of a stored record for a record of the given size.
+type storedReaderDB interface {
+	storedReader() (r io.ReadCloser, pinned bool, err error)
+	storedSize(size int) int
+}
+
+// Backup writes a copy of the database to w. It contains the records, that
+// exist, when Backup is called. Writes can continue, while the copy is
+// written.
+//
+// The write lock is only held, while the database is opened. Afterwards, the
+// number of copied records is compared with the number of records of the
+// Sticky.
+//
+// For a FileDB, the records are copied as they are stored, including their
+// checksums. Compressed segments are decompressed. So w can be used as the
+// file of a FileDB. For other databases, the records of its reader are
+// copied.
+//
+// For an EncryptedDB, the records are copied as the inner database stores
+// them, so the backup is encrypted. It can be restored into an EncryptedDB
+// with the same key or an old key. VerifyLog and VerifyBackup can not check
+// it.
+func (s *Sticky[Model]) Backup(ctx context.Context, w io.Writer) error {
+	r, records, pinned, err := s.backupReader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// A stored record can have a checksum.
+	maxSize := s.loader.maxEventSize
+	if db, ok := s.db.(storedReaderDB); ok {
+		maxSize = db.storedSize(maxSize)
+	}
+	maxSize += checksumLen
+
+	bw := bufio.NewWriter(w)
+	var copied uint64
+	err = scanRecords(ctx, r, maxSize, func(line []byte) error {
+		if !pinned && copied == records {
+			return errCatchUpDone
+		}
+		copied++
+
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+	if err != nil && !errors.Is(err, errCatchUpDone) {
+		return fmt.Errorf("copying records: %w", err)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("copying records: %w", err)
+	}
+
+	if copied != records {
+		return fmt.Errorf("copied %d records, expected %d", copied, records)
+	}
+	return nil
+}
+
+// backupReader opens the database with the write lock. It returns the number
+// of records at this time. pinned is true, if the reader stops after them.
+func (s *Sticky[Model]) backupReader() (r io.ReadCloser, records uint64, pinned bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed.Load() {
+		return nil, 0, false, ErrClosed
+	}
+
+	if err := s.flushAsync(); err != nil {
+		return nil, 0, false, err
+	}
+
+	if db, ok := s.db.(storedReaderDB); ok {
+		r, pinned, err := db.storedReader()
+		if err != nil {
+			return nil, 0, false, StorageError{Op: "open database", Err: err}
+		}
+		return r, s.records, pinned, nil
+	}
+
+	if db, ok := s.db.(pinnedReaderDB); ok {
+		r, err := db.pinnedReader()
+		if err != nil {
+			return nil, 0, false, StorageError{Op: "open database", Err: err}
+		}
+		return r, s.records, true, nil
+	}
+
+	r, err = s.db.Reader()
+	if err != nil {
+		return nil, 0, false, StorageError{Op: "open database", Err: err}
+	}
+	return r, s.records, false, nil
+}
+
+// pinnedReader returns the content of all segments and the active file up to
+// its current size. The checksums are not removed.
+func (db *FileDB) pinnedReader() (io.ReadCloser, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.lock(); err != nil {
+		return nil, err
+	}
+
+	if err := db.finishReplace(); err != nil {
+		return nil, err
+	}
+
+	fr, err := db.openFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	readers := make([]io.Reader, 0, len(fr.segments)+1)
+	for _, segment := range fr.segments {
+		readers = append(readers, segment)
+	}
+
+	if fr.active != nil {
+		info, err := fr.active.Stat()
+		if err != nil {
+			fr.Close()
+			return nil, fmt.Errorf("checking database file: %w", err)
+		}
+		readers = append(readers, io.LimitReader(fr.active, info.Size()))
+	}
+
+	return &pinnedReadCloser{Reader: io.MultiReader(readers...), fr: fr}, nil
+}
+
+// pinnedReadCloser closes the files of a pinnedReader.
+type pinnedReadCloser struct {
+	io.Reader
+	fr *fileReader
+}
+
+func (r *pinnedReadCloser) Close() error {
+	return r.fr.Close()
+}
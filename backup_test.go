@@ -0,0 +1,182 @@
+package sticky
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestBackup_file_with_segments(t *testing.T) {
+	tmpdir := t.TempDir()
+	db := &FileDB{File: path.Join(tmpdir, "events.log"), MaxSegmentSize: 200, Compress: true, Checksum: true}
+	defer db.Close()
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 20; i++ {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.Backup(context.Background(), &buf); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), checksumSep) {
+		t.Errorf("backup does not contain the checksums")
+	}
+
+	backupFile := path.Join(tmpdir, "backup.log")
+	if err := os.WriteFile(backupFile, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("writing backup: %v", err)
+	}
+
+	backupDB := &FileDB{File: backupFile}
+	defer backupDB.Close()
+
+	restored, err := New(backupDB, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("loading backup: %v", err)
+	}
+
+	m, done := restored.ForReading()
+	done()
+	if m.Sum != 210 {
+		t.Errorf("got sum %d, expected 210", m.Sum)
+	}
+}
+
+// writeOnFirstWrite is a writer, that writes an event to the Sticky, before
+// the first bytes are written.
+type writeOnFirstWrite struct {
+	io.Writer
+	s       *Sticky[testModel]
+	written bool
+	err     error
+}
+
+func (w *writeOnFirstWrite) Write(p []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		w.err = w.s.Write(func(testModel) Event[testModel] { return addEvent{Value: 100} })
+	}
+	return w.Writer.Write(p)
+}
+
+func TestBackup_writes_continue(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		db   func(t *testing.T) database
+	}{
+		{"memory", func(t *testing.T) database { return NewMemoryDB() }},
+		{"file", func(t *testing.T) database {
+			db := &FileDB{File: path.Join(t.TempDir(), "events.log")}
+			t.Cleanup(func() { db.Close() })
+			return db
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.db(t), testModel{}, getTestEvent)
+			if err != nil {
+				t.Fatalf("creating sticky: %v", err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+			}
+
+			var buf bytes.Buffer
+			w := &writeOnFirstWrite{Writer: &buf, s: s}
+			if err := s.Backup(context.Background(), w); err != nil {
+				t.Fatalf("backup: %v", err)
+			}
+
+			if w.err != nil {
+				t.Fatalf("write during backup: %v", w.err)
+			}
+
+			if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+				t.Errorf("got %d records in the backup, expected the 2 records before the backup", lines)
+			}
+		})
+	}
+}
+
+func TestBackup_record_count_mismatch(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	// Remove the record behind the back of the Sticky.
+	if err := db.ReplaceWith(strings.NewReader("")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	if err := s.Backup(context.Background(), io.Discard); err == nil {
+		t.Errorf("backup of a database with missing records did not return an error")
+	}
+}
+
+func TestBackup_encrypted_db(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+	fileDB := &FileDB{File: path.Join(t.TempDir(), "events.log"), Checksum: true}
+	db, err := NewEncryptedDB(fileDB, key)
+	if err != nil {
+		t.Fatalf("creating encrypted db: %v", err)
+	}
+	defer db.Close()
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	var backup bytes.Buffer
+	if err := s.Backup(context.Background(), &backup); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+
+	if bytes.Contains(backup.Bytes(), []byte("add")) || bytes.Contains(backup.Bytes(), []byte("value")) {
+		t.Errorf("backup contains plaintext: %s", backup.Bytes())
+	}
+
+	restoredDB, err := NewEncryptedDB(NewMemoryDB(), key)
+	if err != nil {
+		t.Fatalf("creating encrypted db: %v", err)
+	}
+
+	if err := Restore(restoredDB, &backup); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	restored, err := New(restoredDB, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("loading restored database: %v", err)
+	}
+
+	m, done := restored.ForReading()
+	done()
+	if m.Sum != 6 {
+		t.Errorf("got sum %d, expected 6", m.Sum)
+	}
+}
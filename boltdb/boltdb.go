@@ -0,0 +1,271 @@
+// Package boltdb implements a sticky database that stores the events in a
+// bbolt file.
+package boltdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/ostcar/sticky/internal/records"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	defaultBucket = "events"
+
+	// readBatch is the number of events, the reader fetches in one read
+	// transaction.
+	readBatch = 1000
+)
+
+// Option is a option for boltdb.Open()
+type Option func(db *DB)
+
+// WithBucket uses a custom bucket name. Default is "events".
+func WithBucket(name string) Option {
+	return func(db *DB) {
+		db.bucket = []byte(name)
+	}
+}
+
+// DB is a event database based on a bbolt bucket.
+//
+// Each event is stored with a monotonically increasing key starting at 1.
+type DB struct {
+	bolt   *bolt.DB
+	bucket []byte
+
+	mu  sync.Mutex
+	len uint64
+}
+
+// Open opens the bbolt file and creates the bucket, if it does not exist.
+//
+// Open returns an error, if the keys in the bucket are not contiguous.
+func Open(file string, options ...Option) (*DB, error) {
+	db := DB{
+		bucket: []byte(defaultBucket),
+	}
+
+	for _, o := range options {
+		o(&db)
+	}
+
+	b, err := bolt.Open(file, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open bolt file: %w", err)
+	}
+
+	err = b.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(db.bucket)
+		if err != nil {
+			return fmt.Errorf("create bucket: %w", err)
+		}
+
+		var expect uint64 = 1
+		return bucket.ForEach(func(k, _ []byte) error {
+			if len(k) != 8 {
+				return fmt.Errorf("bucket %s looks corrupted: key %x after key %d has invalid length %d", db.bucket, k, expect-1, len(k))
+			}
+
+			if got := binary.BigEndian.Uint64(k); got != expect {
+				return fmt.Errorf("bucket %s looks corrupted: expected key %d, got %d", db.bucket, expect, got)
+			}
+			expect++
+			return nil
+		})
+	})
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	err = b.View(func(tx *bolt.Tx) error {
+		db.len = uint64(tx.Bucket(db.bucket).Stats().KeyN)
+		return nil
+	})
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("counting events: %w", err)
+	}
+
+	db.bolt = b
+	return &db, nil
+}
+
+// Len returns the number of stored events.
+func (db *DB) Len() uint64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.len
+}
+
+// Reader returns all events in key order separated by newlines.
+//
+// The reader does not hold a transaction between calls to Read, so it is
+// possible to append while reading.
+func (db *DB) Reader() (io.ReadCloser, error) {
+	return &bucketReader{db: db, next: 1}, nil
+}
+
+// ReaderAfter is like Reader, but skips the first n events.
+func (db *DB) ReaderAfter(n uint64) (io.ReadCloser, error) {
+	return &bucketReader{db: db, next: n + 1}, nil
+}
+
+// Append stores the event with the next key.
+func (db *DB) Append(bs []byte) error {
+	return db.AppendBatch([][]byte{bs})
+}
+
+// AppendBatch stores many events in one transaction.
+func (db *DB) AppendBatch(records [][]byte) error {
+	for _, bs := range records {
+		if bytes.Contains(bs, []byte("\n")) {
+			return errors.New("event contains a newline")
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key := db.len
+	err := db.bolt.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(db.bucket)
+		for _, bs := range records {
+			key++
+			if err := bucket.Put(encodeKey(key), bs); err != nil {
+				return fmt.Errorf("put event %d: %w", key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	db.len = key
+	return nil
+}
+
+// Size returns the size of all events including the newlines.
+func (db *DB) Size() (int64, error) {
+	var size int64
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(db.bucket).ForEach(func(_, v []byte) error {
+			size += int64(len(v)) + 1
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("reading size: %w", err)
+	}
+	return size, nil
+}
+
+// ReplaceWith recreates the bucket with the lines from r in one transaction.
+func (db *DB) ReplaceWith(r io.Reader) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var key uint64
+	err := db.bolt.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(db.bucket); err != nil {
+			return fmt.Errorf("delete bucket: %w", err)
+		}
+
+		bucket, err := tx.CreateBucket(db.bucket)
+		if err != nil {
+			return fmt.Errorf("create bucket: %w", err)
+		}
+
+		br := bufio.NewReader(r)
+		for {
+			record, err := records.Next(br)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading new content: %w", err)
+			}
+
+			key++
+			if err := bucket.Put(encodeKey(key), record); err != nil {
+				return fmt.Errorf("put event %d: %w", key, err)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	db.len = key
+	return nil
+}
+
+// Close closes the bbolt file.
+func (db *DB) Close() error {
+	return db.bolt.Close()
+}
+
+func encodeKey(key uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, key)
+	return k
+}
+
+// bucketReader fetches the events in batches.
+type bucketReader struct {
+	db   *DB
+	next uint64
+	buf  []byte
+	done bool
+}
+
+func (r *bucketReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.fetch(); err != nil {
+			return 0, err
+		}
+
+		if len(r.buf) == 0 {
+			return 0, io.EOF
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *bucketReader) fetch() error {
+	return r.db.bolt.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(r.db.bucket).Cursor()
+
+		count := 0
+		for k, v := c.Seek(encodeKey(r.next)); k != nil; k, v = c.Next() {
+			if count == readBatch {
+				return nil
+			}
+			r.buf = append(append(r.buf, v...), '\n')
+			r.next++
+			count++
+		}
+		r.done = true
+		return nil
+	})
+}
+
+func (r *bucketReader) Close() error {
+	return nil
+}
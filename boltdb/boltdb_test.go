@@ -0,0 +1,173 @@
+package boltdb_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ostcar/sticky/boltdb"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltDB_round_trip(t *testing.T) {
+	const count = 2500
+	file := path.Join(t.TempDir(), "events.bolt")
+
+	db, err := boltdb.Open(file)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	var expect strings.Builder
+	for i := 0; i < count; i++ {
+		event := fmt.Sprintf(`{"number":%d}`, i)
+		expect.WriteString(event + "\n")
+		if err := db.Append([]byte(event)); err != nil {
+			t.Fatalf("append event %d: %v", i, err)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("closing db: %v", err)
+	}
+
+	db, err = boltdb.Open(file)
+	if err != nil {
+		t.Fatalf("reopen db: %v", err)
+	}
+	defer db.Close()
+
+	if got := db.Len(); got != count {
+		t.Errorf("Len() returned %d, expected %d", got, count)
+	}
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if string(got) != expect.String() {
+		t.Errorf("read content differs from appended events")
+	}
+}
+
+func TestBoltDB_open_with_gap_returns_error(t *testing.T) {
+	file := path.Join(t.TempDir(), "events.bolt")
+
+	b, err := bolt.Open(file, 0600, nil)
+	if err != nil {
+		t.Fatalf("open bolt: %v", err)
+	}
+
+	err = b.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("events"))
+		if err != nil {
+			return err
+		}
+
+		for _, key := range []uint64{1, 2, 4} {
+			k := make([]byte, 8)
+			binary.BigEndian.PutUint64(k, key)
+			if err := bucket.Put(k, []byte("event")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("writing bucket: %v", err)
+	}
+	b.Close()
+
+	_, err = boltdb.Open(file)
+	if err == nil {
+		t.Fatalf("open with gap in keys did not return an error")
+	}
+
+	if !strings.Contains(err.Error(), "expected key 3, got 4") {
+		t.Errorf("got error `%v`, expected it to name the missing key", err)
+	}
+}
+
+func TestBoltDB_replace_with(t *testing.T) {
+	db, err := boltdb.Open(path.Join(t.TempDir(), "events.bolt"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.AppendBatch([][]byte{[]byte("one"), []byte("two")}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if err := db.ReplaceWith(strings.NewReader("three\n")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	if err := db.Append([]byte("four")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if got := db.Len(); got != 2 {
+		t.Errorf("Len() returned %d, expected 2", got)
+	}
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if expect := "three\nfour\n"; string(got) != expect {
+		t.Errorf("got `%s`, expected `%s`", got, expect)
+	}
+
+	size, err := db.Size()
+	if err != nil {
+		t.Fatalf("size: %v", err)
+	}
+	if size != int64(len(got)) {
+		t.Errorf("got size %d, expected %d", size, len(got))
+	}
+}
+
+func TestBoltDB_reader_after(t *testing.T) {
+	db, err := boltdb.Open(path.Join(t.TempDir(), "events.bolt"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.AppendBatch([][]byte{[]byte("one"), []byte("two"), []byte("three")}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	r, err := db.ReaderAfter(2)
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if string(got) != "three\n" {
+		t.Errorf("got `%s`, expected `three\n`", got)
+	}
+}
@@ -0,0 +1,15 @@
+module github.com/ostcar/sticky/boltdb
+
+go 1.21.1
+
+require (
+	github.com/ostcar/sticky v0.0.0-00010101000000-000000000000
+	go.etcd.io/bbolt v1.3.10
+)
+
+require (
+	golang.org/x/sync v0.6.0 // indirect
+	golang.org/x/sys v0.19.0 // indirect
+)
+
+replace github.com/ostcar/sticky => ..
@@ -0,0 +1,169 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// catchUpBatchSize is the maximum number of events of the database in one
+// value of SubscribeFrom.
+const catchUpBatchSize = 256
+
+var (
+	// errStopCatchUp stops reading the database, when the iteration of
+	// SubscribeFrom stopped.
+	errStopCatchUp = errors.New("catch up stopped")
+
+	// errCatchUpDone stops reading the database after the records, that
+	// existed, when it was opened. The later records are returned by the
+	// topic.
+	errCatchUpDone = errors.New("catch up done")
+)
+
+// SubscribeFrom is like Subscribe, but first returns the events of the
+// database with a sequence number of at least fromSeq. Afterwards, it returns
+// the written events. No event is missing or returned twice.
+//
+// The errors are like the errors of Subscribe. If an event of the database can
+// not be decoded, the error is returned and the iterator stops. If the database was compacted and the events at fromSeq
+// are not in the database anymore, an error wrapping ErrNotSupported is
+// returned.
+//
+// The write lock is held, while the database is opened.
+func (s *Sticky[Model]) SubscribeFrom(ctx context.Context, fromSeq uint64) func(yield func(val []Notification[Model], err error) bool) {
+	return func(yield func(val []Notification[Model], err error) bool) {
+		r, l, tid, cutSeq, err := s.catchUpReader()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		err = s.catchUpUntilClosed(ctx, r, l, fromSeq, yield)
+		r.Close()
+		if err != nil {
+			if !errors.Is(err, errStopCatchUp) {
+				if err := s.subscriptionErr(ctx, err); err != nil {
+					yield(nil, err)
+				}
+			}
+			return
+		}
+
+		// The events until cutSeq where returned from the database. With
+		// WithGroupCommit, some of them can be published afterwards.
+		live := s.subscribe(ctx, tid, func(n *Notification[Model]) bool {
+			return n.Seq > cutSeq && n.Seq >= fromSeq
+		})
+		live(yield)
+	}
+}
+
+// catchUpUntilClosed calls catchUp and stops, when the Sticky is closed.
+func (s *Sticky[Model]) catchUpUntilClosed(ctx context.Context, r io.Reader, l *loader[Model], fromSeq uint64, yield func([]Notification[Model], error) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(s.closeCtx, cancel)
+	defer stop()
+
+	return s.catchUp(ctx, r, l, fromSeq, yield)
+}
+
+// catchUpReader opens the database with the write lock. It returns a loader,
+// that only reads the records, that exist at this time, and the id of the
+// topic and the sequence number of the last record.
+func (s *Sticky[Model]) catchUpReader() (io.ReadCloser, *loader[Model], uint64, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed.Load() {
+		return nil, nil, 0, 0, ErrClosed
+	}
+
+	// The events of WriteAsync are published before they are in the
+	// database.
+	if err := s.flushAsync(); err != nil {
+		return nil, nil, 0, 0, err
+	}
+
+	r, err := s.db.Reader()
+	if err != nil {
+		return nil, nil, 0, 0, StorageError{Op: "open database", Err: err}
+	}
+
+	l := s.loader.replayLoader()
+	l.maxRecords = s.records
+	return r, l, s.topic.LastID(), s.seq, nil
+}
+
+// catchUp calls yield with the events of r with a sequence number of at least
+// fromSeq. It returns errStopCatchUp, if yield returned false.
+func (s *Sticky[Model]) catchUp(ctx context.Context, r io.Reader, l *loader[Model], fromSeq uint64, yield func([]Notification[Model], error) bool) error {
+	var batch []Notification[Model]
+	var records uint64
+	var seq uint64
+	err := scanRecords(ctx, r, l.maxEventSize, func(line []byte) error {
+		if records >= l.maxRecords {
+			return errCatchUpDone
+		}
+		records++
+
+		envelope, err := DecodeEnvelope(line)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", records, err)
+		}
+
+		seq++
+		if envelope.Seq != 0 {
+			seq = envelope.Seq
+		}
+
+		if envelope.Type == snapshotType {
+			if records == 1 && fromSeq <= seq {
+				return fmt.Errorf("database is compacted until seq %d: %w", seq, ErrNotSupported)
+			}
+			return nil
+		}
+
+		if seq < fromSeq {
+			return nil
+		}
+
+		event, eventTime, err := l.decodeEvent(envelope)
+		if err != nil {
+			if l.skipUnknown(envelope, err) {
+				return nil
+			}
+			return fmt.Errorf("record %d: %w", records, err)
+		}
+
+		batch = append(batch, Notification[Model]{
+			Name:  s.eventName(event),
+			Event: event,
+			Time:  eventTime,
+			Seq:   seq,
+			Meta:  envelope.Meta,
+
+			Correlation: envelope.Correlation,
+			Causation:   envelope.Causation,
+		})
+
+		if len(batch) == catchUpBatchSize {
+			if !yield(batch, nil) {
+				return errStopCatchUp
+			}
+			batch = nil
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errCatchUpDone) {
+		return err
+	}
+
+	if len(batch) > 0 && !yield(batch, nil) {
+		return errStopCatchUp
+	}
+	return nil
+}
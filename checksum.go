@@ -0,0 +1,154 @@
+package sticky
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// checksumSep is the separator between a record and its checksum. json.Marshal
+// never writes a literal tab, so it can not be part of a record.
+const checksumSep = "\tcrc32:"
+
+// checksumLen is the length of the checksum suffix.
+const checksumLen = len(checksumSep) + 8
+
+// ChecksumError is returned, when the checksum of a record does not match.
+type ChecksumError struct {
+	// Line is the line number of the record starting at 1.
+	Line int
+
+	// Offset is the byte offset of the start of the record.
+	Offset int64
+}
+
+func (err ChecksumError) Error() string {
+	return fmt.Sprintf("invalid checksum of record in line %d at offset %d", err.Line, err.Offset)
+}
+
+// appendChecksum appends the checksum of record to dst.
+func appendChecksum(dst, record []byte) []byte {
+	dst = append(dst, checksumSep...)
+	return fmt.Appendf(dst, "%08x", crc32.ChecksumIEEE(record))
+}
+
+// splitChecksum returns the record without the checksum suffix. ok is false,
+// if the record has a checksum, that does not match.
+func splitChecksum(line []byte) (record []byte, ok bool) {
+	if len(line) < checksumLen || !bytes.HasPrefix(line[len(line)-checksumLen:], []byte(checksumSep)) {
+		return line, true
+	}
+
+	record = line[:len(line)-checksumLen]
+	expect := appendChecksum(nil, record)
+	return record, bytes.Equal(line[len(line)-checksumLen:], expect)
+}
+
+// checksumReader removes the checksums from the records and validates them.
+//
+// Lines without a checksum are returned unchanged. io.EOF is not final, so the
+// reader can be read again after new data was appended to the underlying
+// reader.
+type checksumReader struct {
+	r      *bufio.Reader
+	buf    []byte
+	line   int
+	offset int64
+	err    error
+
+	// partial is an incomplete line. emitted is the number of bytes of it,
+	// that where already returned.
+	partial []byte
+	emitted int
+}
+
+func newChecksumReader(r io.Reader) *checksumReader {
+	return &checksumReader{r: bufio.NewReader(r)}
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		chunk, err := r.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			r.err = err
+		}
+		r.partial = append(r.partial, chunk...)
+
+		if !bytes.HasSuffix(r.partial, []byte("\n")) {
+			// The line is incomplete. An incomplete checksum is hold back, so
+			// it can be validated, when the rest of the line is read.
+			limit := len(r.partial)
+			if tab := bytes.IndexByte(r.partial, '\t'); tab >= 0 {
+				limit = tab
+			}
+
+			if limit > r.emitted {
+				r.buf = bytes.Clone(r.partial[r.emitted:limit])
+				r.emitted = limit
+				continue
+			}
+
+			if err == io.EOF {
+				return 0, io.EOF
+			}
+			continue
+		}
+
+		line := r.partial
+		emitted := r.emitted
+		r.partial = nil
+		r.emitted = 0
+
+		r.line++
+		offset := r.offset
+		r.offset += int64(len(line))
+
+		content := bytes.TrimRight(line, "\r\n")
+		newline := line[len(content):]
+
+		record, ok := splitChecksum(content)
+		if !ok {
+			r.err = ChecksumError{Line: r.line, Offset: offset}
+			continue
+		}
+
+		r.buf = append(record, newline...)[emitted:]
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Verify reads the whole database and validates all checksums.
+//
+// It returns a ChecksumError for the first corrupt record.
+func (db *FileDB) Verify(ctx context.Context) error {
+	dbReader, err := db.Reader()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer dbReader.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		_, err := dbReader.Read(buf)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
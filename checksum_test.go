@@ -0,0 +1,107 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestDBFile_checksum_is_written_and_removed(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	db := FileDB{File: path.Join(tmpdir, "file.db"), Checksum: true}
+	defer db.Close()
+
+	if err := db.Append([]byte(`{"type":"event"}`)); err != nil {
+		t.Fatalf("append to db: %v", err)
+	}
+
+	onDisk, err := os.ReadFile(db.File)
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+
+	if !strings.Contains(string(onDisk), "\tcrc32:") {
+		t.Errorf("file content `%s` has no checksum", onDisk)
+	}
+
+	got, err := readDB(t, &db)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if got != "{\"type\":\"event\"}\n" {
+		t.Errorf("got `%s`, expected the record without checksum", got)
+	}
+}
+
+func TestDBFile_checksum_mixed_with_old_records(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	if err := os.WriteFile(path.Join(tmpdir, "file.db"), []byte("old record\n"), 0666); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	db := FileDB{File: path.Join(tmpdir, "file.db"), Checksum: true}
+	defer db.Close()
+
+	if err := db.Append([]byte("new record")); err != nil {
+		t.Fatalf("append to db: %v", err)
+	}
+
+	got, err := readDB(t, &db)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if got != "old record\nnew record\n" {
+		t.Errorf("got `%s`, expected both records", got)
+	}
+
+	if err := db.Verify(context.Background()); err != nil {
+		t.Errorf("verify returned: %v", err)
+	}
+}
+
+func TestDBFile_verify_reports_corrupt_record(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	db := FileDB{File: path.Join(tmpdir, "file.db"), Checksum: true}
+	defer db.Close()
+
+	for _, record := range []string{"first", "second", "third"} {
+		if err := db.Append([]byte(record)); err != nil {
+			t.Fatalf("append to db: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(db.File)
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+
+	// Flip a byte in the second record.
+	corrupted := strings.Replace(string(content), "second", "secOnd", 1)
+	if err := os.WriteFile(db.File, []byte(corrupted), 0666); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	err = db.Verify(context.Background())
+
+	var errChecksum ChecksumError
+	if !errors.As(err, &errChecksum) {
+		t.Fatalf("got error `%v`, expected a ChecksumError", err)
+	}
+
+	if errChecksum.Line != 2 {
+		t.Errorf("got line %d, expected 2", errChecksum.Line)
+	}
+
+	expectOffset := int64(strings.Index(corrupted, "secOnd"))
+	if errChecksum.Offset != expectOffset {
+		t.Errorf("got offset %d, expected %d", errChecksum.Offset, expectOffset)
+	}
+}
@@ -0,0 +1,428 @@
+// Command sticky inspects and maintains the database files of sticky.
+//
+// Usage:
+//
+//	sticky inspect [filters] FILE   print the records as JSON lines
+//	sticky stats FILE               print the number of records per type
+//	sticky verify FILE              check the records and checksums
+//	sticky export [filters] FILE    write the records to stdout
+//	sticky import FILE              write the records from stdin to an empty file
+//	sticky compact [-checksum] FILE rewrite the records in the current format
+//	sticky tail [-f] [-n N] FILE    print the last records
+//
+// The filters are -type, that can be given more then once, -from and -to for
+// the sequence numbers and -since and -until as RFC 3339 times.
+//
+// The commands only use the file. The model is not loaded. So compact can
+// not fold the events into a snapshot. It rewrites the records with their
+// sequence numbers, removes empty lines and merges the segments. The records
+// get checksums, if the file already has some. Use -checksum=false or
+// -checksum to change this. Do not use compact or import, while the file is
+// used by a program.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ostcar/sticky"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "sticky: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+const usage = "usage: sticky inspect|stats|verify|export|import|compact|tail [flags] FILE"
+
+// followInterval is the time between two reads of tail -f.
+var followInterval = 500 * time.Millisecond
+
+// run runs the command of args.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	command, args := args[0], args[1:]
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+
+	var filters func() ([]sticky.EventFilter, error)
+	var follow *bool
+	var lines *int
+	var checksum *bool
+	switch command {
+	case "inspect", "export":
+		filters = filterFlags(fs)
+	case "tail":
+		follow = fs.Bool("f", false, "follow the file")
+		lines = fs.Int("n", 10, "number of records")
+	case "compact":
+		checksum = fs.Bool("checksum", false, "add checksums to the records, default is true, if the file has checksums")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New(usage)
+	}
+	file := fs.Arg(0)
+
+	var eventFilters []sticky.EventFilter
+	if filters != nil {
+		var err error
+		if eventFilters, err = filters(); err != nil {
+			return err
+		}
+	}
+
+	switch command {
+	case "inspect", "export":
+		return export(ctx, readOnly(file), stdout, eventFilters)
+	case "stats":
+		return stats(ctx, readOnly(file), stdout)
+	case "verify":
+		return verify(readOnly(file), stdout)
+	case "import":
+		return restore(&sticky.FileDB{File: file, Locking: true}, stdin)
+	case "compact":
+		db := &sticky.FileDB{File: file, Locking: true, Checksum: *checksum}
+		if !flagSet(fs, "checksum") {
+			var err error
+			if db.Checksum, err = hasChecksums(db); err != nil {
+				return err
+			}
+		}
+		return compact(ctx, db)
+	case "tail":
+		return tail(ctx, readOnly(file), stdout, *lines, *follow)
+	default:
+		return fmt.Errorf("unknown command %s\n%s", command, usage)
+	}
+}
+
+// flagSet returns true, if the flag was given.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// checksumSuffix matches the checksum, that a FileDB with Checksum appends to
+// each record.
+var checksumSuffix = regexp.MustCompile(`\tcrc32:[0-9a-f]{8}$`)
+
+// hasChecksums returns true, if a record of the database has a checksum.
+func hasChecksums(db *sticky.FileDB) (bool, error) {
+	segments, err := db.Segments()
+	if err != nil {
+		return false, fmt.Errorf("reading segments: %w", err)
+	}
+
+	for _, s := range segments {
+		found, err := segmentHasChecksums(s)
+		if err != nil {
+			return false, fmt.Errorf("reading segment %s: %w", s.Path, err)
+		}
+
+		if found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// segmentHasChecksums returns true, if a record of the segment has a
+// checksum.
+func segmentHasChecksums(s sticky.SegmentInfo) (bool, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if s.Compressed {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return false, err
+		}
+		r = zr
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if checksumSuffix.Match(bytes.TrimRight(line, "\r\n")) {
+			return true, nil
+		}
+
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// readOnly returns the database of the file for reading.
+func readOnly(file string) *sticky.FileDB {
+	return &sticky.FileDB{File: file, ReadOnly: true}
+}
+
+// stringList is a flag, that can be given more then once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// filterFlags adds the flags of the filters. The returned function returns
+// the filters after the flags are parsed.
+func filterFlags(fs *flag.FlagSet) func() ([]sticky.EventFilter, error) {
+	var types stringList
+	fs.Var(&types, "type", "only records of this type")
+	from := fs.Uint64("from", 0, "first sequence number")
+	to := fs.Uint64("to", 0, "last sequence number")
+	since := fs.String("since", "", "first time as RFC 3339")
+	until := fs.String("until", "", "time as RFC 3339, before which the records are")
+
+	return func() ([]sticky.EventFilter, error) {
+		filters := []sticky.EventFilter{sticky.FilterSnapshots(), sticky.FilterSeq(*from, *to)}
+		if len(types) > 0 {
+			filters = append(filters, sticky.FilterNames(types...))
+		}
+
+		var sinceTime, untilTime time.Time
+		for _, t := range []struct {
+			value  string
+			target *time.Time
+		}{{*since, &sinceTime}, {*until, &untilTime}} {
+			if t.value == "" {
+				continue
+			}
+
+			parsed, err := time.Parse(time.RFC3339Nano, t.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time %s: %w", t.value, err)
+			}
+			*t.target = parsed
+		}
+		return append(filters, sticky.FilterTime(sinceTime, untilTime)), nil
+	}
+}
+
+// events calls fn for each record of the database.
+func events(ctx context.Context, db *sticky.FileDB, filters []sticky.EventFilter, fn func(sticky.Envelope) error) error {
+	r, err := db.Reader()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer r.Close()
+
+	var fnErr error
+	sticky.ReadEvents(ctx, r, filters...)(func(e sticky.Envelope, err error) bool {
+		if err == nil {
+			err = fn(e)
+		}
+		fnErr = err
+		return err == nil
+	})
+	return fnErr
+}
+
+// export writes the records as JSON lines.
+func export(ctx context.Context, db *sticky.FileDB, w io.Writer, filters []sticky.EventFilter) error {
+	bw := bufio.NewWriter(w)
+	err := events(ctx, db, filters, func(e sticky.Envelope) error {
+		record, err := sticky.EncodeEnvelope(e)
+		if err != nil {
+			return err
+		}
+
+		bw.Write(record)
+		return bw.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// stats prints the number of records per type, the size and the time range.
+func stats(ctx context.Context, db *sticky.FileDB, w io.Writer) error {
+	counts := make(map[string]int)
+	var records int
+	var lastSeq uint64
+	var first, last string
+	err := events(ctx, db, []sticky.EventFilter{sticky.FilterSnapshots()}, func(e sticky.Envelope) error {
+		records++
+		counts[e.Type]++
+		lastSeq = e.Seq
+		if first == "" {
+			first = e.Time
+		}
+		last = e.Time
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	size, err := db.Size()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "records:  %d\n", records)
+	fmt.Fprintf(w, "last seq: %d\n", lastSeq)
+	fmt.Fprintf(w, "size:     %d bytes\n", size)
+	if records > 0 {
+		fmt.Fprintf(w, "first:    %s\n", first)
+		fmt.Fprintf(w, "last:     %s\n", last)
+	}
+
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		fmt.Fprintf(w, "%8d  %s\n", counts[t], t)
+	}
+	return nil
+}
+
+// verify checks all records.
+func verify(db *sticky.FileDB, w io.Writer) error {
+	r, err := db.Reader()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer r.Close()
+
+	if err := sticky.VerifyLog(r); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "ok")
+	return nil
+}
+
+// restore writes the records from r into the empty database.
+func restore(db *sticky.FileDB, r io.Reader) error {
+	defer db.Close()
+
+	if err := sticky.Restore(db, r); err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// compact rewrites all records.
+func compact(ctx context.Context, db *sticky.FileDB) error {
+	defer db.Close()
+
+	var buf bytes.Buffer
+	if err := export(ctx, db, &buf, []sticky.EventFilter{sticky.FilterSnapshots()}); err != nil {
+		return err
+	}
+
+	if err := db.ReplaceWith(&buf); err != nil {
+		return fmt.Errorf("replace database: %w", err)
+	}
+	return db.Close()
+}
+
+// tail prints the last n records. With follow, it prints new records until
+// ctx is done.
+func tail(ctx context.Context, db *sticky.FileDB, w io.Writer, n int, follow bool) error {
+	r, err := db.Reader()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer r.Close()
+
+	// The reader of a FileDB returns new records after io.EOF. So the lines
+	// are read by hand and not with ReadEvents.
+	br := bufio.NewReader(r)
+	var last []string
+	var partial []byte
+	started := false
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reading database: %w", err)
+		}
+
+		if err == io.EOF {
+			// The line is not complete yet.
+			partial = append(partial, line...)
+
+			if !started {
+				for _, l := range last {
+					fmt.Fprintln(w, l)
+				}
+				started = true
+			}
+
+			if !follow {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(followInterval):
+			}
+			br.Reset(r)
+			continue
+		}
+
+		line = bytes.TrimSpace(append(partial, line...))
+		partial = nil
+		if len(line) == 0 {
+			continue
+		}
+
+		if started {
+			fmt.Fprintln(w, string(line))
+			continue
+		}
+
+		last = append(last, string(line))
+		if len(last) > n {
+			last = last[1:]
+		}
+	}
+}
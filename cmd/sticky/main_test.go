@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky"
+)
+
+const testLog = `{"time":"2024-01-01 00:00:00","type":"add","payload":{"amount":1}}
+{"time":"2024-01-02 00:00:00","type":"add","payload":{"amount":2}}
+
+{"time":"2024-01-03 00:00:00","type":"remove","payload":{"amount":1}}
+`
+
+func writeTestLog(t *testing.T) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "db.jsonl")
+	if err := os.WriteFile(file, []byte(testLog), 0o600); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	return file
+}
+
+func runCommand(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := run(context.Background(), args, strings.NewReader(stdin), &out); err != nil {
+		t.Fatalf("run %v: %v", args, err)
+	}
+	return out.String()
+}
+
+func TestInspect(t *testing.T) {
+	file := writeTestLog(t)
+
+	got := runCommand(t, "", "inspect", "-type", "add", "-from", "2", file)
+
+	if lines := strings.Split(strings.TrimSpace(got), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"seq":2`) {
+		t.Errorf("got `%s`, expected only the record with seq 2", got)
+	}
+}
+
+func TestStats(t *testing.T) {
+	file := writeTestLog(t)
+
+	got := runCommand(t, "", "stats", file)
+
+	for _, expect := range []string{"records:  3", "last seq: 3", "       2  add", "       1  remove", "first:    2024-01-01"} {
+		if !strings.Contains(got, expect) {
+			t.Errorf("got `%s`, expected it to contain `%s`", got, expect)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	file := writeTestLog(t)
+
+	if got := runCommand(t, "", "verify", file); got != "ok\n" {
+		t.Errorf("got `%s`, expected ok", got)
+	}
+
+	broken := filepath.Join(t.TempDir(), "broken.jsonl")
+	if err := os.WriteFile(broken, []byte(testLog+"{\"time\":\"not a time\",\"type\":\"add\"}\n"), 0o600); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	if err := run(context.Background(), []string{"verify", broken}, nil, &bytes.Buffer{}); err == nil {
+		t.Errorf("verify did not return an error on an invalid time")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	file := writeTestLog(t)
+	exported := runCommand(t, "", "export", file)
+
+	target := filepath.Join(t.TempDir(), "target.jsonl")
+	runCommand(t, exported, "import", target)
+
+	if got := runCommand(t, "", "export", target); got != exported {
+		t.Errorf("got `%s`, expected `%s`", got, exported)
+	}
+
+	if err := run(context.Background(), []string{"import", target}, strings.NewReader(exported), &bytes.Buffer{}); err == nil {
+		t.Errorf("import into a not empty file did not return an error")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	file := writeTestLog(t)
+	before := runCommand(t, "", "export", file)
+
+	runCommand(t, "", "compact", file)
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+
+	if bytes.Contains(content, []byte("\n\n")) || !bytes.Contains(content, []byte(`"seq":3`)) {
+		t.Errorf("got `%s`, expected the records with seq and without empty lines", content)
+	}
+
+	if got := runCommand(t, "", "export", file); got != before {
+		t.Errorf("got `%s` after compact, expected `%s`", got, before)
+	}
+}
+
+func TestCompact_checksum(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.jsonl")
+	db := &sticky.FileDB{File: file, Checksum: true}
+	for _, record := range strings.Split(strings.TrimSpace(testLog), "\n") {
+		if record == "" {
+			continue
+		}
+		if err := db.Append([]byte(record)); err != nil {
+			t.Fatalf("writing record: %v", err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("closing database: %v", err)
+	}
+
+	checksums := func() int {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("reading file: %v", err)
+		}
+		return bytes.Count(content, []byte("\tcrc32:"))
+	}
+
+	runCommand(t, "", "compact", file)
+	if got := checksums(); got != 3 {
+		t.Errorf("got %d checksums after compact, expected 3", got)
+	}
+
+	runCommand(t, "", "compact", "-checksum=false", file)
+	if got := checksums(); got != 0 {
+		t.Errorf("got %d checksums after compact -checksum=false, expected 0", got)
+	}
+
+	runCommand(t, "", "compact", "-checksum", file)
+	if got := checksums(); got != 3 {
+		t.Errorf("got %d checksums after compact -checksum, expected 3", got)
+	}
+}
+
+func TestTailFlags_only_on_tail(t *testing.T) {
+	file := writeTestLog(t)
+
+	if err := run(context.Background(), []string{"stats", "-n", "1", file}, nil, &bytes.Buffer{}); err == nil {
+		t.Errorf("stats accepted the flag -n of tail")
+	}
+}
+
+func TestTail(t *testing.T) {
+	file := writeTestLog(t)
+
+	got := runCommand(t, "", "tail", "-n", "1", file)
+	if !strings.Contains(got, `"remove"`) || strings.Count(got, "\n") != 1 {
+		t.Errorf("got `%s`, expected the last record", got)
+	}
+}
+
+func TestTailFollow(t *testing.T) {
+	followInterval = time.Millisecond
+	file := writeTestLog(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := &lockedBuffer{}
+	done := make(chan error)
+	go func() {
+		done <- run(ctx, []string{"tail", "-f", "-n", "0", file}, nil, out)
+	}()
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open file: %v", err)
+	}
+	defer f.Close()
+
+	// Write the record in two parts to check, that partial lines are kept.
+	f.WriteString(`{"time":"2024-01-04 00:00:00",`)
+	time.Sleep(10 * time.Millisecond)
+	f.WriteString(`"type":"add","payload":{"amount":5}}` + "\n")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), `"amount":5`) {
+		if time.Now().After(deadline) {
+			t.Fatalf("tail did not print the new record, got `%s`", out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("tail returned: %v", err)
+	}
+
+	if got := out.String(); strings.Count(got, "\n") != 1 || !strings.HasPrefix(got, `{"time":"2024-01-04 00:00:00",`) {
+		t.Errorf("got `%s`, expected only the new record", got)
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
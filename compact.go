@@ -0,0 +1,321 @@
+package sticky
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CompactOption is an option for Compact.
+type CompactOption func(*compactConfig)
+
+type compactConfig struct {
+	keep   int
+	before time.Time
+}
+
+// CompactKeep keeps the last n records of the database after the snapshot.
+// This can be usefull for debugging.
+func CompactKeep(n int) CompactOption {
+	return func(c *compactConfig) {
+		c.keep = n
+	}
+}
+
+// CompactBefore only folds the events older then t into the snapshot. The
+// newer events are kept, so the recent history can still be replayed.
+//
+// The order of the events is never changed. Folding stops at the first event,
+// that is not older then t. All events after it are kept, even when their
+// timestamps are older then t. This can happen, when the clock of the system
+// was changed.
+//
+// CompactBefore can be combined with CompactKeep. Then the bigger window is
+// kept.
+func CompactBefore(t time.Time) CompactOption {
+	return func(c *compactConfig) {
+		c.before = t
+	}
+}
+
+// Compact replaces the database with a snapshot of the model.
+//
+// The model has to implement Snapshotter and the database has to support
+// replacing its content, otherwise an error wrapping ErrNotSupported is
+// returned. The FileDB, MemoryDB and the sqlite, bolt and postgres databases
+// support it. Compact can not be used with WithSnapshotStore, with
+// WithShredding or when unknown events where ignored. See WithUnknownEvents.
+//
+// Compact holds the write lock. An open group of WithGroupCommit is committed
+// first. The database contains either the old or the
+// new content, even when the process crashes. The kept events are held in
+// memory.
+//
+// The snapshot record gets the sequence number of the last folded record, so
+// the kept records keep their sequence numbers. If no record would be folded,
+// the database is not changed.
+func (s *Sticky[Model]) Compact(ctx context.Context, options ...CompactOption) (err error) {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	ctx, endTrace := s.startTrace(ctx, "compact")
+	defer func() { endTrace(TraceResult{Err: err}) }()
+
+	var cfg compactConfig
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	if s.snapshotStore != nil {
+		return fmt.Errorf("compact with a SnapshotStore: %w", ErrNotSupported)
+	}
+
+	// The snapshot of the folded events would keep the personal data after
+	// Forget.
+	if s.loader.shredder != nil {
+		return fmt.Errorf("compact with WithShredding: %w", ErrNotSupported)
+	}
+
+	if err := s.checkUnknownEvents(); err != nil {
+		return err
+	}
+
+	if err := s.flushAsync(); err != nil {
+		return err
+	}
+
+	// The events of an open group are in the model. If its sync fails, they
+	// are removed again and must not be in the snapshot.
+	if err := s.commitGroup(); err != nil {
+		return err
+	}
+
+	model := s.model
+	version := s.version
+	seq := s.seq
+	var kept [][]byte
+	if cfg.keep > 0 || !cfg.before.IsZero() {
+		var err error
+		model, version, seq, kept, err = s.replayKeep(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		if seq == 0 && len(kept) > 0 {
+			return nil
+		}
+	}
+
+	// An empty database gets a snapshot with the first sequence number.
+	seq = max(seq, 1)
+
+	data, err := marshalSnapshot(model)
+	if err != nil {
+		return err
+	}
+
+	record, err := s.snapshotRecord(data, version, seq)
+	if err != nil {
+		return err
+	}
+
+	var content bytes.Buffer
+	content.Write(record)
+	content.WriteByte('\n')
+	for _, line := range kept {
+		content.Write(line)
+		content.WriteByte('\n')
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := replaceDB(s.db, &content); err != nil {
+		return StorageError{Op: "replacing database", Err: err}
+	}
+
+	s.records = uint64(len(kept)) + 1
+	if len(kept) == 0 {
+		s.seq = seq
+	}
+	s.eventsSinceSnapshot = len(kept)
+	s.lastSnapshot = s.now()
+	s.stats.lastSnapshot = s.lastSnapshot
+
+	if s.logger != nil {
+		s.logger.Info("database compacted", "version", version, "kept", len(kept))
+	}
+	return nil
+}
+
+// replayKeep reads the database and returns the model and its version before
+// the records, that should be kept, the sequence number of the last folded
+// record and the raw kept records. The sequence number is 0, if no record is
+// folded.
+//
+// Has to be called with the lock.
+func (s *Sticky[Model]) replayKeep(ctx context.Context, cfg compactConfig) (Model, uint64, uint64, [][]byte, error) {
+	model := s.newModel()
+	var version uint64
+	var seq uint64
+
+	r, err := s.db.Reader()
+	if err != nil {
+		return model, 0, 0, nil, StorageError{Op: "open database", Err: err}
+	}
+	defer r.Close()
+
+	// kept holds the last cfg.keep records. After the cutoff is reached, all
+	// records are kept.
+	var kept [][]byte
+	cutoff := false
+	afterCutoff := 0
+
+	applyOldest := func() error {
+		envelope, err := DecodeEnvelope(kept[0])
+		if err != nil {
+			return err
+		}
+		kept = kept[1:]
+
+		version++
+		if envelope.Type == snapshotType {
+			version = envelope.Version
+		}
+
+		seq++
+		if envelope.Seq != 0 {
+			seq = envelope.Seq
+		}
+
+		model, err = s.loader.apply(model, envelope)
+		return err
+	}
+
+	err = scanRecords(ctx, r, s.loader.maxEventSize, func(line []byte) error {
+		if !cutoff && !cfg.before.IsZero() {
+			envelope, err := DecodeEnvelope(line)
+			if err != nil {
+				return err
+			}
+
+			eventTime, err := envelope.parseTime(s.loader.timeLayout)
+			if err != nil {
+				return err
+			}
+
+			cutoff = !eventTime.Before(cfg.before)
+		}
+
+		kept = append(kept, bytes.Clone(line))
+		if cutoff {
+			afterCutoff++
+			return nil
+		}
+
+		if len(kept) > cfg.keep {
+			return applyOldest()
+		}
+		return nil
+	})
+	if err != nil {
+		return model, 0, 0, nil, fmt.Errorf("replaying database: %w", err)
+	}
+
+	// Keep the bigger window of CompactKeep and CompactBefore.
+	for len(kept) > max(cfg.keep, afterCutoff) {
+		if err := applyOldest(); err != nil {
+			return model, 0, 0, nil, fmt.Errorf("replaying database: %w", err)
+		}
+	}
+
+	return model, version, seq, kept, nil
+}
+
+// scanRecords calls fn for each non empty line of r.
+func scanRecords(ctx context.Context, r io.Reader, maxEventSize int, fn func(line []byte) error) error {
+	scanner := newRecordScanner(r, maxEventSize, nil)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return scanError(err, maxEventSize)
+	}
+	return nil
+}
+
+// compactInBackground calls Compact, when the database gets to big. It stops,
+// when the Sticky is closed.
+//
+// Compact holds the write lock, so there are never two compactions at the
+// same time.
+func (s *Sticky[Model]) compactInBackground() {
+	defer s.background.Done()
+
+	ticker := time.NewTicker(s.compactInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.closeCtx.Done():
+			return
+
+		case <-ticker.C:
+			size, err := dbSize(s.db)
+			if err != nil {
+				s.compactionError(StorageError{Op: "reading size of database", Err: err})
+				continue
+			}
+
+			if size <= s.compactSize {
+				continue
+			}
+
+			if err := s.Compact(s.closeCtx); err != nil {
+				if errors.Is(err, ErrClosed) || s.closeCtx.Err() != nil {
+					return
+				}
+				s.compactionError(err)
+			}
+		}
+	}
+}
+
+func (s *Sticky[Model]) compactionError(err error) {
+	if s.logger != nil {
+		s.logger.Error("background compaction failed", "error", err)
+	}
+
+	if s.onCompactionError != nil {
+		s.onCompactionError(fmt.Errorf("background compaction: %w", err))
+	}
+}
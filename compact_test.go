@@ -0,0 +1,298 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompact_replaces_log_with_snapshot(t *testing.T) {
+	db := FileDB{File: path.Join(t.TempDir(), "events.log"), Checksum: true}
+	defer db.Close()
+
+	s, err := New(&db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 4; i++ {
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if err := s.Compact(context.Background()); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	content, err := readDB(t, &db)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], snapshotType) {
+		t.Fatalf("got content `%s`, expected one snapshot record", content)
+	}
+
+	if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: 5} }); err != nil {
+		t.Fatalf("write after compact: %v", err)
+	}
+
+	reloaded, err := New(&db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	model, done := reloaded.ForReading()
+	done()
+
+	if model.Sum != 15 || model.Replayed != 1 {
+		t.Errorf("got model %+v, expected sum 15 with one replayed event", model)
+	}
+}
+
+func TestCompact_keep_last_events(t *testing.T) {
+	db := NewMemoryDB()
+
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 5; i++ {
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if err := s.Compact(context.Background(), CompactKeep(2)); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	records := db.Records()
+	if len(records) != 3 {
+		t.Fatalf("got %d records, expected 3", len(records))
+	}
+
+	if !strings.Contains(string(records[0]), snapshotType) {
+		t.Errorf("first record is `%s`, expected a snapshot", records[0])
+	}
+
+	reloaded, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	model, done := reloaded.ForReading()
+	done()
+
+	if model.Sum != 15 || model.Replayed != 2 {
+		t.Errorf("got model %+v, expected sum 15 with two replayed events", model)
+	}
+}
+
+func TestCompact_not_supported(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Compact(context.Background()); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("compact with model without Snapshotter returned `%v`, expected ErrNotSupported", err)
+	}
+
+	db := struct{ database }{NewMemoryDB()}
+	snapshotSticky, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := snapshotSticky.Compact(context.Background()); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("compact on database without ReplaceWith returned `%v`, expected ErrNotSupported", err)
+	}
+}
+
+func TestCompactBefore_keeps_newer_events(t *testing.T) {
+	record := func(clock string, value int) string {
+		return fmt.Sprintf(`{"time":"2024-01-01 %s","type":"add","payload":{"value":%d}}`, clock, value)
+	}
+
+	newDB := func() *MemoryDB {
+		return NewMemoryDB(
+			record("00:00:00", 1),
+			record("01:00:00", 2),
+			record("03:00:00", 3),
+			// Out of order. It is kept, because it is after the cutoff.
+			record("02:00:00", 4),
+			record("04:00:00", 5),
+		)
+	}
+	cutoff := time.Date(2024, 1, 1, 2, 30, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name         string
+		options      []CompactOption
+		expectKept   int
+		expectRecord string
+	}{
+		{"before", []CompactOption{CompactBefore(cutoff)}, 3, record("03:00:00", 3)},
+		{"before all", []CompactOption{CompactBefore(cutoff.Add(24 * time.Hour))}, 0, ""},
+		{"bigger keep window", []CompactOption{CompactBefore(cutoff), CompactKeep(4)}, 4, record("01:00:00", 2)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newDB()
+			s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+			if err != nil {
+				t.Fatalf("creating sticky: %v", err)
+			}
+
+			if err := s.Compact(context.Background(), tt.options...); err != nil {
+				t.Fatalf("compact: %v", err)
+			}
+
+			records := db.Records()
+			if len(records) != tt.expectKept+1 {
+				t.Fatalf("got %d records, expected %d", len(records), tt.expectKept+1)
+			}
+
+			if tt.expectRecord != "" && string(records[1]) != tt.expectRecord {
+				t.Errorf("first kept record is `%s`, expected `%s`", records[1], tt.expectRecord)
+			}
+
+			reloaded, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+			if err != nil {
+				t.Fatalf("reloading sticky: %v", err)
+			}
+
+			model, done := reloaded.ForReading()
+			done()
+
+			if model.Sum != 15 || model.Replayed != tt.expectKept {
+				t.Errorf("got model %+v, expected sum 15 with %d replayed events", model, tt.expectKept)
+			}
+		})
+	}
+}
+
+func TestWithBackgroundCompaction(t *testing.T) {
+	db := FileDB{File: path.Join(t.TempDir(), "events.log")}
+
+	errs := make(chan error, 10)
+	s, err := New(
+		&db,
+		snapshotModel{},
+		getSnapshotTestEvent,
+		WithBackgroundCompaction[snapshotModel](200, 5*time.Millisecond),
+		WithCompactionError[snapshotModel](func(err error) { errs <- err }),
+	)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 10; i++ {
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		size, err := db.Size()
+		if err != nil {
+			t.Fatalf("size: %v", err)
+		}
+
+		if size <= 200 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("database was not compacted, size is %d", size)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		t.Errorf("got compaction error: %v", err)
+	default:
+	}
+
+	reloaded, err := New(&db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	model, done := reloaded.ForReading()
+	done()
+
+	if model.Sum != 55 {
+		t.Errorf("got sum %d, expected 55", model.Sum)
+	}
+}
+
+func TestWithBackgroundCompaction_needs_size(t *testing.T) {
+	db := struct{ database }{NewMemoryDB()}
+
+	_, err := New(db, snapshotModel{}, getSnapshotTestEvent, WithBackgroundCompaction[snapshotModel](100, time.Second))
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error `%v`, expected ErrNotSupported", err)
+	}
+}
+
+func TestCompact_commits_open_group(t *testing.T) {
+	fileDB := &FileDB{File: path.Join(t.TempDir(), "events.log")}
+	defer fileDB.Close()
+
+	db := &failSyncFileDB{FileDB: fileDB, err: errors.New("disk full")}
+	s := newGroupSticky(t, db, time.Hour, 0)
+
+	writeDone := make(chan error, 1)
+	go func() {
+		writeDone <- s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} })
+	}()
+
+	for {
+		s.mu.RLock()
+		open := s.group != nil
+		s.mu.RUnlock()
+		if open {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := s.Compact(context.Background()); !errors.Is(err, db.err) {
+		t.Errorf("compact returned `%v`, expected the sync error of the group", err)
+	}
+
+	select {
+	case err := <-writeDone:
+		if !errors.Is(err, db.err) {
+			t.Errorf("write returned `%v`, expected the sync error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("write is still waiting for its group")
+	}
+
+	getEvent := func(string) Event[cloneModel] { return &cloneAddEvent{} }
+	reloaded, err := New(fileDB, cloneModel{}, getEvent)
+	if err != nil {
+		t.Fatalf("reloading database: %v", err)
+	}
+
+	model, done := reloaded.ForReading()
+	done()
+	if model.Sum != 0 {
+		t.Errorf("got sum %d after reload, expected 0 without the failed group", model.Sum)
+	}
+}
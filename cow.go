@@ -0,0 +1,17 @@
+package sticky
+
+// published is the model, that readers get with WithCopyOnWrite. It is never
+// changed after it is published.
+type published[Model any] struct {
+	model   Model
+	version uint64
+}
+
+// publish makes the current model visible for readers. Has to be called
+// with the write lock.
+func (s *Sticky[Model]) publish() {
+	if !s.copyOnWrite {
+		return
+	}
+	s.published.Store(&published[Model]{model: s.model, version: s.version})
+}
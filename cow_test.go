@@ -0,0 +1,172 @@
+package sticky
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithCopyOnWrite(t *testing.T) {
+	getEvent := func(string) Event[mapModel] { return &setEvent{} }
+	s, err := New(NewMemoryDB(), mapModel{Values: map[string]int{}}, getEvent, WithCopyOnWrite[mapModel]())
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(mapModel) Event[mapModel] { return setEvent{Key: "a", Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	model, version, done := s.ForReadingVersioned()
+	defer done()
+
+	// The reader does not block the writer.
+	written := make(chan error)
+	go func() {
+		written <- s.Write(func(mapModel) Event[mapModel] { return setEvent{Key: "a", Value: 2} })
+	}()
+
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("write is blocked by the reader")
+	}
+
+	if got := model.Values["a"]; got != 1 || version != 1 {
+		t.Errorf("got value `%d` at version %d, expected the value 1 at version 1", got, version)
+	}
+
+	newModel, newVersion, newDone := s.ForReadingVersioned()
+	newDone()
+
+	if got := newModel.Values["a"]; got != 2 || newVersion != 2 {
+		t.Errorf("got value `%d` at version %d, expected the value 2 at version 2", got, newVersion)
+	}
+}
+
+func TestWithCopyOnWrite_no_cloner(t *testing.T) {
+	if _, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithCopyOnWrite[testModel]()); err == nil {
+		t.Errorf("got no error, expected an error about Cloner")
+	}
+}
+
+// readWork simulates a slow reader, like rendering a template.
+func readWork(m mapModel) int {
+	var sum int
+	for i := 0; i < 100; i++ {
+		for _, v := range m.Values {
+			sum += v
+		}
+	}
+	return sum
+}
+
+// benchmarkWriteWithReaders measures writes, while readers use the model all
+// the time.
+func benchmarkWriteWithReaders(b *testing.B, readers int, os ...Option[mapModel]) {
+	values := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		values[string(rune('a'+i))] = i
+	}
+
+	getEvent := func(string) Event[mapModel] { return &setEvent{} }
+	s, err := New(NewMemoryDB(), mapModel{Values: values}, getEvent, os...)
+	if err != nil {
+		b.Fatalf("creating sticky: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				m, done := s.ForReading()
+				readWork(m)
+				done()
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := s.Write(func(mapModel) Event[mapModel] { return setEvent{Key: "a", Value: i} }); err != nil {
+			b.Fatalf("write: %v", err)
+		}
+	}
+	b.StopTimer()
+
+	close(stop)
+	wg.Wait()
+}
+
+func BenchmarkWrite_with_readers(b *testing.B) {
+	b.Run("rwlock", func(b *testing.B) {
+		benchmarkWriteWithReaders(b, 8)
+	})
+
+	b.Run("copy_on_write", func(b *testing.B) {
+		benchmarkWriteWithReaders(b, 8, WithCopyOnWrite[mapModel]())
+	})
+}
+
+// benchmarkRead measures parallel reads, while one writer writes all the
+// time.
+func benchmarkRead(b *testing.B, os ...Option[mapModel]) {
+	getEvent := func(string) Event[mapModel] { return &setEvent{} }
+	s, err := New(NewMemoryDB(), mapModel{Values: map[string]int{"a": 1}}, getEvent, os...)
+	if err != nil {
+		b.Fatalf("creating sticky: %v", err)
+	}
+
+	stop := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			if err := s.Write(func(mapModel) Event[mapModel] { return setEvent{Key: "a", Value: i} }); err != nil {
+				b.Errorf("write: %v", err)
+				return
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m, done := s.ForReading()
+			readWork(m)
+			done()
+		}
+	})
+	b.StopTimer()
+
+	close(stop)
+	<-writerDone
+}
+
+func BenchmarkForReading_with_writer(b *testing.B) {
+	b.Run("rwlock", func(b *testing.B) {
+		benchmarkRead(b)
+	})
+
+	b.Run("copy_on_write", func(b *testing.B) {
+		benchmarkRead(b, WithCopyOnWrite[mapModel]())
+	})
+}
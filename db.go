@@ -7,68 +7,491 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
+// SyncPolicy decides, when the FileDB calls fsync on the database file.
+type SyncPolicy struct {
+	everyWrite bool
+	interval   time.Duration
+}
+
+var (
+	// SyncNever never calls fsync. The operating system decides, when the
+	// data is written to disk. This is the default.
+	SyncNever = SyncPolicy{}
+
+	// SyncEveryWrite calls fsync after each append.
+	SyncEveryWrite = SyncPolicy{everyWrite: true}
+)
+
+// SyncEveryDuration calls fsync at most once per duration. An appended event
+// is on stable storage at the latest after the duration.
+func SyncEveryDuration(d time.Duration) SyncPolicy {
+	return SyncPolicy{interval: d}
+}
+
 // FileDB is a evet database based of one file.
+//
+// If MaxSegmentSize is set, the file is rotated, when it gets bigger. For a
+// File "events.log", the completed segments are called "events-000001.log",
+// "events-000002.log" and so on. The active segment is always File.
+//
+// A FileDB keeps the open file and its lock, so it has to be used as a
+// pointer and must not be copied after the first use:
+//
+//	db := &sticky.FileDB{File: "events.log"}
+//	s, err := sticky.New(db, Model{}, getEvent)
+//
+// Earlier versions had value receivers. Code, that gives a FileDB by value
+// to New, has to give its address now.
 type FileDB struct {
-	File string
+	File       string
+	SyncPolicy SyncPolicy
+
+	// MaxSegmentSize is the size in bytes, after which the active file is
+	// rotated. Zero means, that the file is never rotated.
+	MaxSegmentSize int64
+
+	// Compress compresses completed segments with gzip in the background.
+	Compress bool
+
+	// Checksum adds a CRC32 checksum to each new record. The checksums are
+	// validated and removed by the reader. Records without a checksum can
+	// still be read.
+	Checksum bool
+
+	// Locking takes an advisory lock on the file File+".lock", when the
+	// database is used the first time. The lock is held until Close. If
+	// another process holds the lock, ErrLocked is returned.
+	Locking bool
+
+	// LockTimeout is the time to wait for the lock. Zero means, that
+	// ErrLocked is returned immediately.
+	LockTimeout time.Duration
+
+	// ReadOnly rejects all appends. With Locking, a shared lock is used, so
+	// many readers can use the database at the same time, but no writer.
+	ReadOnly bool
+
+	// timeLayout is the layout of WithTimeFormat of the Sticky, that uses
+	// the database. Segments uses it to parse the times.
+	timeLayout string
+
+	mu          sync.Mutex
+	f           *os.File
+	generation  int
+	size        int64
+	needNewline bool
+	syncTimer   *time.Timer
+	compressing sync.WaitGroup
+	compressErr error
+	lockFile    *os.File
 }
 
-// Reader opens the file and returns its reader.
-func (db FileDB) Reader() (io.ReadCloser, error) {
-	f, err := os.Open(db.File)
+// Reader returns the content of all segments and the active file.
+//
+// After the reader returned io.EOF, it can be read again to get the events,
+// that where appended in the meantime, even if the active file was rotated.
+func (db *FileDB) Reader() (io.ReadCloser, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.lock(); err != nil {
+		return nil, err
+	}
+
+	if err := db.finishReplace(); err != nil {
+		return nil, err
+	}
+
+	fr, err := db.openFiles()
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return io.NopCloser(strings.NewReader("")), nil
-		}
-		return nil, fmt.Errorf("open database file: %w", err)
+		return nil, err
 	}
-	return f, nil
+
+	return &fileReadCloser{Reader: newChecksumReader(fr), fr: fr}, nil
+}
+
+// fileReadCloser removes the checksums from a fileReader.
+type fileReadCloser struct {
+	io.Reader
+	fr *fileReader
+}
+
+func (r *fileReadCloser) Close() error {
+	return r.fr.Close()
 }
 
 // Append adds data to the file with a newline.
-func (db FileDB) Append(bs []byte) error {
-	if bytes.Contains(bs, []byte("\n")) {
-		return errors.New("event contains a newline")
+//
+// The event is written with one write call. If the write fails, the file is
+// truncated to its former size, so an event is either written completely or
+// not at all.
+func (db *FileDB) Append(bs []byte) error {
+	return db.AppendBatch([][]byte{bs})
+}
+
+// AppendBatch adds many events with one write call. Either all events are
+// written or none.
+func (db *FileDB) AppendBatch(records [][]byte) error {
+	size := 1
+	for _, bs := range records {
+		if bytes.Contains(bs, []byte("\n")) {
+			return errors.New("event contains a newline")
+		}
+		size += len(bs) + checksumLen + 1
+	}
+
+	if db.ReadOnly {
+		return errors.New("database is read only")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.lock(); err != nil {
+		return err
+	}
+
+	if err := db.finishReplace(); err != nil {
+		return err
+	}
+
+	if err := db.open(); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, size)
+	if db.needNewline {
+		// The last line of the file is incomplete, for example after a crash.
+		// Start the new event on its own line.
+		buf = append(buf, '\n')
+	}
+
+	for _, bs := range records {
+		buf = append(buf, bs...)
+		if db.Checksum {
+			buf = appendChecksum(buf, bs)
+		}
+		buf = append(buf, '\n')
+	}
+
+	if _, err := db.f.Write(buf); err != nil {
+		if tErr := db.f.Truncate(db.size); tErr != nil {
+			return fmt.Errorf("writing events to file: %w, truncating partial events: %v", err, tErr)
+		}
+		return fmt.Errorf("writing events to file: %w", err)
+	}
+	db.size += int64(len(buf))
+	db.needNewline = false
+
+	switch {
+	case db.SyncPolicy.everyWrite:
+		if err := db.f.Sync(); err != nil {
+			return fmt.Errorf("syncing db file: %w", err)
+		}
+
+	case db.SyncPolicy.interval > 0 && db.syncTimer == nil:
+		db.syncTimer = time.AfterFunc(db.SyncPolicy.interval, db.timedSync)
+	}
+
+	if db.MaxSegmentSize > 0 && db.size >= db.MaxSegmentSize {
+		if err := db.rotate(); err != nil {
+			return fmt.Errorf("rotating segment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// TruncateTail removes the last n bytes from the active file.
+func (db *FileDB) TruncateTail(n int64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.closeFile(); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(db.File)
+	if err != nil {
+		return fmt.Errorf("reading size of db file: %w", err)
+	}
+
+	if n > info.Size() {
+		return fmt.Errorf("can not remove %d bytes from file with %d bytes", n, info.Size())
+	}
+
+	if err := os.Truncate(db.File, info.Size()-n); err != nil {
+		return fmt.Errorf("truncating db file: %w", err)
+	}
+	return nil
+}
+
+// rotate closes the active file and renames it to the next segment.
+//
+// Has to be called with the lock.
+func (db *FileDB) rotate() error {
+	if err := db.closeFile(); err != nil {
+		return err
+	}
+
+	segments, err := listSegments(db.File)
+	if err != nil {
+		return fmt.Errorf("list segments: %w", err)
+	}
+
+	number := 1
+	if len(segments) > 0 {
+		number = segments[len(segments)-1].number + 1
+	}
+
+	segmentPath := segmentName(db.File, number)
+	if err := os.Rename(db.File, segmentPath); err != nil {
+		return fmt.Errorf("rename active file: %w", err)
+	}
+
+	if db.Compress {
+		db.compressing.Add(1)
+		go func() {
+			defer db.compressing.Done()
+			if err := db.compressSegment(segmentPath); err != nil {
+				db.mu.Lock()
+				db.compressErr = err
+				db.mu.Unlock()
+			}
+		}()
+	}
+
+	return nil
+}
+
+// open opens the database file for appending, if it is not already open.
+//
+// Has to be called with the lock.
+func (db *FileDB) open() error {
+	if db.f != nil {
+		return nil
 	}
 
-	f, err := os.OpenFile(db.File, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	f, err := os.OpenFile(db.File, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0600)
 	if err != nil {
 		return fmt.Errorf("open db file: %w", err)
 	}
-	defer func() {
-		wErr := f.Close()
-		if err != nil {
-			err = wErr
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("reading size of db file: %w", err)
+	}
+
+	db.needNewline = false
+	if size := info.Size(); size > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, size-1); err != nil {
+			f.Close()
+			return fmt.Errorf("reading end of db file: %w", err)
 		}
-	}()
+		db.needNewline = last[0] != '\n'
+	}
 
-	if _, err := fmt.Fprintf(f, "%s\n", bs); err != nil {
-		return fmt.Errorf("writing event to file: %q: %w", bs, err)
+	db.f = f
+	db.size = info.Size()
+	return nil
+}
+
+// Sync calls fsync on the active file.
+func (db *FileDB) Sync() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.f == nil {
+		return nil
 	}
 
+	if err := db.f.Sync(); err != nil {
+		return fmt.Errorf("syncing db file: %w", err)
+	}
+	return nil
+}
+
+func (db *FileDB) timedSync() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.syncTimer = nil
+	if db.f != nil {
+		// There is nobody to report the error to. The next timed sync tries
+		// again.
+		_ = db.f.Sync()
+	}
+}
+
+// Close syncs and closes the database file and releases the lock.
+//
+// Close waits for the background compression of segments. The FileDB can be
+// used after Close. The next Append opens the file again.
+func (db *FileDB) Close() error {
+	db.compressing.Wait()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	err := db.closeFile()
+	if uErr := db.unlock(); err == nil {
+		err = uErr
+	}
+
+	if cErr := db.compressErr; cErr != nil {
+		db.compressErr = nil
+		return fmt.Errorf("compressing segment: %w", cErr)
+	}
+	return err
+}
+
+// closeFile syncs and closes the active file.
+//
+// Has to be called with the lock.
+func (db *FileDB) closeFile() error {
+	if db.f == nil {
+		return nil
+	}
+
+	if db.syncTimer != nil {
+		db.syncTimer.Stop()
+		db.syncTimer = nil
+	}
+
+	var syncErr error
+	if db.SyncPolicy != SyncNever {
+		syncErr = db.f.Sync()
+	}
+
+	err := db.f.Close()
+	db.f = nil
+	db.generation++
+
+	if syncErr != nil {
+		return fmt.Errorf("syncing db file: %w", syncErr)
+	}
+	if err != nil {
+		return fmt.Errorf("closing db file: %w", err)
+	}
 	return nil
 }
 
 // MemoryDB stores Events in memory.
 //
-// Usefull for testing.
+// Usefull for testing. It is safe for concurrent use.
 type MemoryDB struct {
-	Content string
+	mu      sync.Mutex
+	records [][]byte
 }
 
 // NewMemoryDB initializes a MemoryDB
-func NewMemoryDB(content string) *MemoryDB {
-	return &MemoryDB{content}
+//
+// The db is seeded with the given lines. Each line can contain more then one
+// event separated by newlines.
+func NewMemoryDB(lines ...string) *MemoryDB {
+	var db MemoryDB
+	for _, line := range lines {
+		for _, record := range strings.Split(line, "\n") {
+			if record == "" {
+				continue
+			}
+			db.records = append(db.records, []byte(record))
+		}
+	}
+	return &db
 }
 
 // Reader reads the content.
 func (db *MemoryDB) Reader() (io.ReadCloser, error) {
-	return io.NopCloser(strings.NewReader(db.Content)), nil
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, record := range db.records {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+	return io.NopCloser(&buf), nil
 }
 
 // Append adds a new event.
 func (db *MemoryDB) Append(bs []byte) error {
-	db.Content += fmt.Sprintf("%s\n", bs)
+	if bytes.Contains(bs, []byte("\n")) {
+		return errors.New("event contains a newline")
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.records = append(db.records, bytes.Clone(bs))
 	return nil
 }
+
+// AppendBatch adds many events. Either all events are added or none.
+func (db *MemoryDB) AppendBatch(records [][]byte) error {
+	for _, bs := range records {
+		if bytes.Contains(bs, []byte("\n")) {
+			return errors.New("event contains a newline")
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, bs := range records {
+		db.records = append(db.records, bytes.Clone(bs))
+	}
+	return nil
+}
+
+// Size returns the size of all records including the newlines.
+func (db *MemoryDB) Size() (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var size int64
+	for _, record := range db.records {
+		size += int64(len(record)) + 1
+	}
+	return size, nil
+}
+
+// ReplaceWith replaces all records with the lines from r.
+func (db *MemoryDB) ReplaceWith(r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading new content: %w", err)
+	}
+
+	var records [][]byte
+	for _, record := range bytes.Split(content, []byte("\n")) {
+		if len(record) == 0 {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.records = records
+	return nil
+}
+
+// Records returns a copy of all persisted records.
+func (db *MemoryDB) Records() [][]byte {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	records := make([][]byte, len(db.records))
+	for i, record := range db.records {
+		records[i] = bytes.Clone(record)
+	}
+	return records
+}
@@ -4,13 +4,15 @@ import (
 	"io"
 	"os"
 	"path"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestDBFile_read_empty_does_nothing(t *testing.T) {
 	tmpdir := t.TempDir()
 
-	db := FileDB{path.Join(tmpdir, "file.db")}
+	db := FileDB{File: path.Join(tmpdir, "file.db")}
 
 	dbReader, err := db.Reader()
 	if err != nil {
@@ -44,7 +46,7 @@ func TestDBFile_read_existing_file_returns_its_content(t *testing.T) {
 		t.Fatalf("writing file: %v", err)
 	}
 
-	db := FileDB{path.Join(tmpdir, "file.db")}
+	db := FileDB{File: path.Join(tmpdir, "file.db")}
 
 	dbReader, err := db.Reader()
 	if err != nil {
@@ -65,7 +67,7 @@ func TestDBFile_read_existing_file_returns_its_content(t *testing.T) {
 func TestDBFile_append_on_empty_db_creates_file_with_newline_at_end(t *testing.T) {
 	tmpdir := t.TempDir()
 
-	db := FileDB{path.Join(tmpdir, "file.db")}
+	db := FileDB{File: path.Join(tmpdir, "file.db")}
 
 	if err := db.Append([]byte("some string without newline")); err != nil {
 		t.Fatalf("append to db: %v", err)
@@ -85,7 +87,7 @@ func TestDBFile_append_on_empty_db_creates_file_with_newline_at_end(t *testing.T
 func TestDBFile_append_new_line_creates_an_error(t *testing.T) {
 	tmpdir := t.TempDir()
 
-	db := FileDB{path.Join(tmpdir, "file.db")}
+	db := FileDB{File: path.Join(tmpdir, "file.db")}
 
 	if err := db.Append([]byte("some string\nwith newline")); err == nil {
 		t.Errorf("append an newline did not return an error")
@@ -99,7 +101,7 @@ func TestDBFile_append_on_existing_file_appends(t *testing.T) {
 		t.Fatalf("writing file: %v", err)
 	}
 
-	db := FileDB{path.Join(tmpdir, "file.db")}
+	db := FileDB{File: path.Join(tmpdir, "file.db")}
 
 	if err := db.Append([]byte("some string")); err != nil {
 		t.Fatalf("append to db: %v", err)
@@ -115,3 +117,154 @@ func TestDBFile_append_on_existing_file_appends(t *testing.T) {
 		t.Errorf("got `%s`, expected `%s`", got, expect)
 	}
 }
+
+func TestDBFile_append_after_partial_line_starts_new_line(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	// Simulate a crash in the middle of writing the last event.
+	if err := os.WriteFile(path.Join(tmpdir, "file.db"), []byte("complete\nincompl"), 0666); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	db := FileDB{File: path.Join(tmpdir, "file.db")}
+	defer db.Close()
+
+	if err := db.Append([]byte("some string")); err != nil {
+		t.Fatalf("append to db: %v", err)
+	}
+
+	if err := db.Append([]byte("other string")); err != nil {
+		t.Fatalf("append to db: %v", err)
+	}
+
+	got, err := os.ReadFile(path.Join(tmpdir, "file.db"))
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+
+	expect := "complete\nincompl\nsome string\nother string\n"
+	if string(got) != expect {
+		t.Errorf("got `%s`, expected `%s`", got, expect)
+	}
+}
+
+func TestDBFile_sync_policies(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		policy SyncPolicy
+	}{
+		{"never", SyncNever},
+		{"every write", SyncEveryWrite},
+		{"every duration", SyncEveryDuration(time.Millisecond)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpdir := t.TempDir()
+
+			db := FileDB{File: path.Join(tmpdir, "file.db"), SyncPolicy: tt.policy}
+
+			for i := 0; i < 3; i++ {
+				if err := db.Append([]byte("event")); err != nil {
+					t.Fatalf("append to db: %v", err)
+				}
+			}
+
+			if tt.policy.interval > 0 {
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			if err := db.Close(); err != nil {
+				t.Fatalf("closing db: %v", err)
+			}
+
+			got, err := os.ReadFile(path.Join(tmpdir, "file.db"))
+			if err != nil {
+				t.Fatalf("reading file: %v", err)
+			}
+
+			expect := "event\nevent\nevent\n"
+			if string(got) != expect {
+				t.Errorf("got `%s`, expected `%s`", got, expect)
+			}
+		})
+	}
+}
+
+func TestDBFile_append_after_close_reopens_file(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	db := FileDB{File: path.Join(tmpdir, "file.db")}
+
+	if err := db.Append([]byte("first")); err != nil {
+		t.Fatalf("append to db: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("closing db: %v", err)
+	}
+
+	if err := db.Append([]byte("second")); err != nil {
+		t.Fatalf("append to db: %v", err)
+	}
+	defer db.Close()
+
+	got, err := os.ReadFile(path.Join(tmpdir, "file.db"))
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+
+	expect := "first\nsecond\n"
+	if string(got) != expect {
+		t.Errorf("got `%s`, expected `%s`", got, expect)
+	}
+}
+
+func TestMemoryDB_seeded_lines_are_read(t *testing.T) {
+	db := NewMemoryDB("first", "second\nthird\n")
+
+	dbReader, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer dbReader.Close()
+
+	got, err := io.ReadAll(dbReader)
+	if err != nil {
+		t.Fatalf("Reading db: %v", err)
+	}
+
+	expect := "first\nsecond\nthird\n"
+	if string(got) != expect {
+		t.Errorf("got `%s`, expected `%s`", got, expect)
+	}
+}
+
+func TestMemoryDB_append_is_returned_by_records(t *testing.T) {
+	db := NewMemoryDB("first")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := db.Append([]byte("event")); err != nil {
+				t.Errorf("append to db: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	records := db.Records()
+	if len(records) != 11 {
+		t.Fatalf("got %d records, expected 11", len(records))
+	}
+
+	if string(records[0]) != "first" {
+		t.Errorf("first record is `%s`, expected `first`", records[0])
+	}
+
+	for _, record := range records[1:] {
+		if string(record) != "event" {
+			t.Errorf("got record `%s`, expected `event`", record)
+		}
+	}
+}
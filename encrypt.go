@@ -0,0 +1,285 @@
+package sticky
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// encryptedPrefix is the start of each encrypted record. It is followed by the
+// id of the key, a colon and the base64 encoded nonce and ciphertext.
+const encryptedPrefix = "enc1:"
+
+// EncryptedDB encrypts each event with AES-GCM before it is given to another
+// database.
+//
+// Each record is prefixed with an identifier of the used key. When reading,
+// the record is decrypted with the matching key.
+type EncryptedDB struct {
+	inner   database
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewEncryptedDB initializes an EncryptedDB.
+//
+// New events are encrypted with key. The oldKeys are only used for
+// decryption, so it is possible to rotate keys. Keys have to be 16, 24 or 32
+// bytes long.
+func NewEncryptedDB(inner database, key []byte, oldKeys ...[]byte) (*EncryptedDB, error) {
+	db := EncryptedDB{
+		inner: inner,
+		keys:  make(map[string]cipher.AEAD),
+	}
+
+	for i, k := range append([][]byte{key}, oldKeys...) {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+
+		id := keyID(k)
+		if i == 0 {
+			db.current = id
+		}
+		db.keys[id] = aead
+	}
+
+	return &db, nil
+}
+
+// keyID returns a short identifier of a key, that does not reveal the key.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// Reader decrypts the events of the inner database.
+func (db *EncryptedDB) Reader() (io.ReadCloser, error) {
+	r, err := db.inner.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{db: db, inner: r, r: bufio.NewReader(r)}, nil
+}
+
+// Append encrypts the event and appends it to the inner database.
+func (db *EncryptedDB) Append(bs []byte) error {
+	record, err := db.encrypt(bs)
+	if err != nil {
+		return err
+	}
+	return db.inner.Append(record)
+}
+
+// AppendBatch encrypts all events and appends them with one call, if the
+// inner database supports it.
+func (db *EncryptedDB) AppendBatch(records [][]byte) error {
+	encrypted := make([][]byte, len(records))
+	for i, bs := range records {
+		record, err := db.encrypt(bs)
+		if err != nil {
+			return err
+		}
+		encrypted[i] = record
+	}
+	return appendRecords(db.inner, encrypted)
+}
+
+func (db *EncryptedDB) encrypt(bs []byte) ([]byte, error) {
+	aead := db.keys[db.current]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("creating nonce: %w", err)
+	}
+
+	header := encryptedPrefix + db.current + ":"
+	sealed := aead.Seal(nonce, nonce, bs, []byte(header))
+
+	record := make([]byte, len(header)+base64.RawStdEncoding.EncodedLen(len(sealed)))
+	copy(record, header)
+	base64.RawStdEncoding.Encode(record[len(header):], sealed)
+	return record, nil
+}
+
+// Sync syncs the inner database, if it supports it.
+func (db *EncryptedDB) Sync() error {
+	if s, ok := db.inner.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Size returns the size of the inner database. It returns ErrNotSupported, if
+// the inner database can not report its size.
+func (db *EncryptedDB) Size() (int64, error) {
+	return dbSize(db.inner)
+}
+
+// ReplaceWith encrypts all records from r with the current key and replaces
+// the content of the inner database. It returns ErrNotSupported, if the inner
+// database can not replace its content.
+//
+// ReplaceWith can be used to encrypt all events with a new key.
+func (db *EncryptedDB) ReplaceWith(r io.Reader) error {
+	return replaceDB(db.inner, &encryptReader{db: db, r: bufio.NewReader(r)})
+}
+
+// storedReader returns the encrypted records of the inner database for a
+// backup.
+func (db *EncryptedDB) storedReader() (io.ReadCloser, bool, error) {
+	if inner, ok := db.inner.(pinnedReaderDB); ok {
+		r, err := inner.pinnedReader()
+		return r, true, err
+	}
+
+	r, err := db.inner.Reader()
+	return r, false, err
+}
+
+// storedSize returns the size of an encrypted record for a record of the
+// given size.
+func (db *EncryptedDB) storedSize(size int) int {
+	aead := db.keys[db.current]
+	header := len(encryptedPrefix) + len(db.current) + 1
+	return header + base64.RawStdEncoding.EncodedLen(aead.NonceSize()+size+aead.Overhead())
+}
+
+// Close closes the inner database, if it can be closed.
+func (db *EncryptedDB) Close() error {
+	if closer, ok := db.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// decrypt decodes one record.
+func (db *EncryptedDB) decrypt(record []byte) ([]byte, error) {
+	rest, ok := bytes.CutPrefix(record, []byte(encryptedPrefix))
+	if !ok {
+		return nil, errors.New("record is not encrypted")
+	}
+
+	id, encoded, ok := bytes.Cut(rest, []byte(":"))
+	if !ok {
+		return nil, errors.New("record has no key id")
+	}
+
+	aead, ok := db.keys[string(id)]
+	if !ok {
+		return nil, fmt.Errorf("record is encrypted with unknown key %s", id)
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decoding record: %w", err)
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("record is too short")
+	}
+
+	header := record[:len(encryptedPrefix)+len(id)+1]
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting record with key %s: %w", id, err)
+	}
+	return plain, nil
+}
+
+// decryptReader decrypts one line at a time.
+type decryptReader struct {
+	db    *EncryptedDB
+	inner io.Closer
+	r     *bufio.Reader
+	buf   []byte
+	line  int
+	err   error
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		line, err := r.r.ReadBytes('\n')
+		if err != nil {
+			r.err = err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		r.line++
+
+		plain, err := r.db.decrypt(line)
+		if err != nil {
+			r.err = fmt.Errorf("record %d: %w", r.line, err)
+			continue
+		}
+		r.buf = append(plain, '\n')
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.inner.Close()
+}
+
+// encryptReader encrypts one line at a time.
+type encryptReader struct {
+	db  *EncryptedDB
+	r   *bufio.Reader
+	buf []byte
+	err error
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		line, err := r.r.ReadBytes('\n')
+		if err != nil {
+			r.err = err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		record, err := r.db.encrypt(line)
+		if err != nil {
+			r.err = err
+			continue
+		}
+		r.buf = append(record, '\n')
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,165 @@
+package sticky
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedDB_round_trip(t *testing.T) {
+	inner := NewMemoryDB()
+	db, err := NewEncryptedDB(inner, bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("creating encrypted db: %v", err)
+	}
+
+	events := []string{`{"type":"first","payload":{"secret":"value"}}`, `{"type":"second"}`}
+	for _, event := range events {
+		if err := db.Append([]byte(event)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	for _, record := range inner.Records() {
+		if bytes.Contains(record, []byte("type")) || bytes.Contains(record, []byte("secret")) {
+			t.Errorf("stored record contains plaintext: %s", record)
+		}
+	}
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	expect := strings.Join(events, "\n") + "\n"
+	if string(got) != expect {
+		t.Errorf("got `%s`, expected `%s`", got, expect)
+	}
+}
+
+func TestEncryptedDB_key_rotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte("o"), 16)
+	newKey := bytes.Repeat([]byte("n"), 16)
+	inner := NewMemoryDB()
+
+	oldDB, err := NewEncryptedDB(inner, oldKey)
+	if err != nil {
+		t.Fatalf("creating encrypted db: %v", err)
+	}
+
+	if err := oldDB.Append([]byte("old event")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	newDB, err := NewEncryptedDB(inner, newKey, oldKey)
+	if err != nil {
+		t.Fatalf("creating encrypted db: %v", err)
+	}
+
+	if err := newDB.Append([]byte("new event")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	r, err := newDB.Reader()
+	if err != nil {
+		t.Fatalf("getting reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if string(got) != "old event\nnew event\n" {
+		t.Errorf("got `%s`, expected both events", got)
+	}
+}
+
+func TestEncryptedDB_wrong_key_returns_error(t *testing.T) {
+	inner := NewMemoryDB()
+
+	db, err := NewEncryptedDB(inner, bytes.Repeat([]byte("a"), 32))
+	if err != nil {
+		t.Fatalf("creating encrypted db: %v", err)
+	}
+
+	if err := db.Append([]byte("event")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	otherDB, err := NewEncryptedDB(inner, bytes.Repeat([]byte("b"), 32))
+	if err != nil {
+		t.Fatalf("creating encrypted db: %v", err)
+	}
+
+	r, err := otherDB.Reader()
+	if err != nil {
+		t.Fatalf("getting reader: %v", err)
+	}
+	defer r.Close()
+
+	_, err = io.ReadAll(r)
+	if err == nil {
+		t.Fatalf("reading with wrong key did not return an error")
+	}
+
+	if !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("got error `%v`, expected it to mention the unknown key", err)
+	}
+}
+
+func TestEncryptedDB_invalid_key_length(t *testing.T) {
+	if _, err := NewEncryptedDB(NewMemoryDB(), []byte("short")); err == nil {
+		t.Errorf("creating encrypted db with short key did not return an error")
+	}
+}
+
+func TestEncryptedDB_replace_with_encrypts_records(t *testing.T) {
+	inner := NewMemoryDB()
+	db, err := NewEncryptedDB(inner, bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("creating encrypted db: %v", err)
+	}
+
+	if err := db.Append([]byte("old")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if err := db.ReplaceWith(strings.NewReader("secret one\nsecret two\n")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	records := inner.Records()
+	if len(records) != 2 {
+		t.Fatalf("got %d records, expected 2", len(records))
+	}
+
+	for _, record := range records {
+		if bytes.Contains(record, []byte("secret")) {
+			t.Errorf("stored record contains plaintext: %s", record)
+		}
+	}
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if expect := "secret one\nsecret two\n"; string(got) != expect {
+		t.Errorf("got `%s`, expected `%s`", got, expect)
+	}
+}
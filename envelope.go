@@ -0,0 +1,346 @@
+package sticky
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// RecordFormat is the version of the format of the records, that are written
+// by this version of sticky. Records without a version have version 1.
+// Records with encrypted fields have version 2. See PersonalData.
+const RecordFormat = 1
+
+// Envelope is the format of one record in the database. The payload is the
+// encoded event.
+//
+// Use EncodeEnvelope and DecodeEnvelope to write or read records of a
+// database outside of a Sticky.
+type Envelope struct {
+	// Format is the version of the record format. See RecordFormat.
+	Format int    `json:"v,omitempty"`
+	Time   string `json:"time"`
+	Type   string `json:"type"`
+
+	// Schema is the schema version of the payload. See SchemaVersioner.
+	Schema int `json:"schema,omitempty"`
+
+	// Version is the version of the model after the event. Records of old
+	// databases have no version.
+	Version uint64 `json:"version,omitempty"`
+
+	// Seq is the sequence number of the record. It is incremented for each
+	// record. Records of old databases have no sequence number.
+	Seq  uint64            `json:"seq,omitempty"`
+	Meta map[string]string `json:"meta,omitempty"`
+
+	// Correlation is the id of the action, that the event belongs to.
+	// Causation is the id of the event or command, that triggered it. See
+	// WithCorrelation and WithCausation.
+	Correlation string `json:"correlation,omitempty"`
+	Causation   string `json:"causation,omitempty"`
+
+	// Subject is the id of the person, whose personal data is in the
+	// Encrypted fields of the payload. See PersonalData.
+	Subject   string   `json:"subject,omitempty"`
+	Encrypted []string `json:"encrypted,omitempty"`
+
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ParseTime returns the time of the event. It accepts the default format and
+// time.RFC3339Nano.
+func (e Envelope) ParseTime() (time.Time, error) {
+	return e.parseTime("")
+}
+
+// parseTime parses the time with the layout and then with the default
+// layouts.
+func (e Envelope) parseTime(layout string) (time.Time, error) {
+	var firstErr error
+	for _, l := range append([]string{layout}, timeFormat, time.RFC3339Nano) {
+		if l == "" {
+			continue
+		}
+
+		t, err := time.Parse(l, e.Time)
+		if err == nil {
+			return t, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return time.Time{}, fmt.Errorf("event `%s` has invalid time %s: %w", e.Type, e.Time, firstErr)
+}
+
+// FormatTime returns the time in the default format of the envelope.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// formatTime returns the time in the layout or in the default format, if the
+// layout is empty.
+func formatTime(t time.Time, layout string) string {
+	if layout == "" {
+		layout = timeFormat
+	}
+	return t.UTC().Format(layout)
+}
+
+// eventTime returns the time as it is written to the envelope with the
+// layout.
+func eventTime(t time.Time, layout string) time.Time {
+	if year := t.UTC().Year(); layout == "" && year >= 0 && year <= 9999 {
+		// The default format has seconds in UTC.
+		return t.UTC().Truncate(time.Second)
+	}
+
+	e := Envelope{Time: formatTime(t, layout)}
+	parsed, err := e.parseTime(layout)
+	if err != nil {
+		return t
+	}
+	return parsed
+}
+
+// timePrecision returns the smallest difference of two times, that can be
+// written with the layout.
+func timePrecision(layout string) time.Duration {
+	t := time.Date(2000, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	diff := t.Sub(eventTime(t, layout))
+
+	precision := time.Nanosecond
+	for precision <= diff {
+		precision *= 10
+	}
+	return precision
+}
+
+// errUnknownFormat is returned by DecodeEnvelope, when the record has a newer
+// format.
+var errUnknownFormat = errors.New("unknown record format")
+
+// EncodeEnvelope encodes the envelope as one record. If the envelope has no
+// format, RecordFormat is used. Envelopes with encrypted fields get format 2.
+func EncodeEnvelope(e Envelope) ([]byte, error) {
+	if e.Format == 0 {
+		e.Format = e.defaultFormat()
+	}
+
+	bs, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("encoding envelope: %w", err)
+	}
+	return bs, nil
+}
+
+// defaultFormat returns the format of a new record of the envelope.
+func (e Envelope) defaultFormat() int {
+	if len(e.Encrypted) > 0 {
+		return encryptedFormat
+	}
+	return RecordFormat
+}
+
+// appendRecord appends the envelope with the time t to buf. The result is the
+// same as from EncodeEnvelope, but without the intermediate allocations. The
+// time of the envelope is ignored.
+//
+// The payload has to be compact json, as it is returned by json.Marshal.
+func appendRecord(buf []byte, e *Envelope, t time.Time, layout string) ([]byte, error) {
+	if layout == "" {
+		layout = timeFormat
+	}
+
+	format := e.Format
+	if format == 0 {
+		format = e.defaultFormat()
+	}
+
+	buf = append(buf, `{"v":`...)
+	buf = strconv.AppendInt(buf, int64(format), 10)
+
+	buf = append(buf, `,"time":"`...)
+	start := len(buf)
+	buf = t.UTC().AppendFormat(buf, layout)
+	if !plainJSON(buf[start:]) {
+		formatted := string(buf[start:])
+		buf = appendJSONString(buf[:start-1], formatted)
+	} else {
+		buf = append(buf, '"')
+	}
+
+	buf = append(buf, `,"type":`...)
+	buf = appendJSONString(buf, e.Type)
+
+	if e.Schema != 0 {
+		buf = append(buf, `,"schema":`...)
+		buf = strconv.AppendInt(buf, int64(e.Schema), 10)
+	}
+
+	if e.Version != 0 {
+		buf = append(buf, `,"version":`...)
+		buf = strconv.AppendUint(buf, e.Version, 10)
+	}
+
+	if e.Seq != 0 {
+		buf = append(buf, `,"seq":`...)
+		buf = strconv.AppendUint(buf, e.Seq, 10)
+	}
+
+	if len(e.Meta) > 0 {
+		meta, err := json.Marshal(e.Meta)
+		if err != nil {
+			return nil, fmt.Errorf("encoding envelope: %w", err)
+		}
+		buf = append(buf, `,"meta":`...)
+		buf = append(buf, meta...)
+	}
+
+	if e.Correlation != "" {
+		buf = append(buf, `,"correlation":`...)
+		buf = appendJSONString(buf, e.Correlation)
+	}
+
+	if e.Causation != "" {
+		buf = append(buf, `,"causation":`...)
+		buf = appendJSONString(buf, e.Causation)
+	}
+
+	if e.Subject != "" {
+		buf = append(buf, `,"subject":`...)
+		buf = appendJSONString(buf, e.Subject)
+	}
+
+	if len(e.Encrypted) > 0 {
+		encrypted, err := json.Marshal(e.Encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("encoding envelope: %w", err)
+		}
+		buf = append(buf, `,"encrypted":`...)
+		buf = append(buf, encrypted...)
+	}
+
+	buf = append(buf, `,"payload":`...)
+	if e.Payload == nil {
+		buf = append(buf, "null"...)
+	} else {
+		buf = append(buf, e.Payload...)
+	}
+	return append(buf, '}'), nil
+}
+
+// appendJSONString appends the string as json string.
+func appendJSONString(buf []byte, s string) []byte {
+	if plainJSON(s) {
+		buf = append(buf, '"')
+		buf = append(buf, s...)
+		return append(buf, '"')
+	}
+
+	// json.Marshal does not fail for strings.
+	encoded, _ := json.Marshal(s)
+	return append(buf, encoded...)
+}
+
+// plainJSON returns true, if json.Marshal does not escape any of the bytes.
+func plainJSON[T string | []byte](bs T) bool {
+	for i := 0; i < len(bs); i++ {
+		if b := bs[i]; b < 0x20 || b >= utf8.RuneSelf || b == '"' || b == '\\' || b == '<' || b == '>' || b == '&' {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeEnvelope decodes one record.
+//
+// It returns an error, if the record was written by a newer version of sticky.
+func DecodeEnvelope(record []byte) (Envelope, error) {
+	var e Envelope
+	if err := json.Unmarshal(record, &e); err != nil {
+		return Envelope{}, fmt.Errorf("decoding event: %w", err)
+	}
+
+	if e.Format > encryptedFormat {
+		return Envelope{}, fmt.Errorf("log written by a newer sticky, record version %d: %w", e.Format, errUnknownFormat)
+	}
+
+	if e.Format == 0 {
+		e.Format = 1
+	}
+	return e, nil
+}
+
+// RecordFormatOf returns the format version of a record. It is 1 for records
+// without a version.
+func RecordFormatOf(record []byte) (int, error) {
+	var e struct {
+		Format int `json:"v"`
+	}
+	if err := json.Unmarshal(record, &e); err != nil {
+		return 0, fmt.Errorf("decoding record: %w", err)
+	}
+
+	if e.Format == 0 {
+		return 1, nil
+	}
+	return e.Format, nil
+}
+
+// NormalizeTimes reads records from r and writes them to w with the times in
+// the layout. Times in the layout, the default format and time.RFC3339Nano
+// are accepted. Empty lines are dropped.
+//
+// Use it with ReplaceWith of a database, to normalize a log after the time
+// format was changed with WithTimeFormat.
+func NormalizeTimes(r io.Reader, w io.Writer, layout string) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reading records: %w", err)
+		}
+
+		if record := bytes.TrimSpace(line); len(record) > 0 {
+			if err := normalizeTime(record, w, layout); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func normalizeTime(record []byte, w io.Writer, layout string) error {
+	e, err := DecodeEnvelope(record)
+	if err != nil {
+		return err
+	}
+
+	t, err := e.parseTime(layout)
+	if err != nil {
+		return err
+	}
+	e.Time = formatTime(t, layout)
+
+	bs, err := EncodeEnvelope(e)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(append(bs, '\n')); err != nil {
+		return fmt.Errorf("writing record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package sticky
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvelope_encode_and_decode(t *testing.T) {
+	when := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	record, err := EncodeEnvelope(Envelope{
+		Time:    FormatTime(when),
+		Type:    "add",
+		Version: 7,
+		Meta:    map[string]string{"user": "1"},
+		Payload: json.RawMessage(`{"value":1}`),
+	})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	expect := `{"v":1,"time":"2024-06-01 10:00:00","type":"add","version":7,"meta":{"user":"1"},"payload":{"value":1}}`
+	if string(record) != expect {
+		t.Errorf("got `%s`, expected `%s`", record, expect)
+	}
+
+	envelope, err := DecodeEnvelope(record)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	got, err := envelope.ParseTime()
+	if err != nil {
+		t.Fatalf("parse time: %v", err)
+	}
+	if !got.Equal(when) {
+		t.Errorf("got time %s, expected %s", got, when)
+	}
+
+	if envelope.Format != RecordFormat || envelope.Version != 7 || !reflect.DeepEqual(envelope.Meta, map[string]string{"user": "1"}) {
+		t.Errorf("got envelope %+v", envelope)
+	}
+}
+
+func TestDecodeEnvelope_old_record(t *testing.T) {
+	envelope, err := DecodeEnvelope([]byte(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if envelope.Format != 1 || envelope.Version != 0 || envelope.Meta != nil {
+		t.Errorf("got envelope %+v, expected format 1 without version and meta", envelope)
+	}
+}
+
+func TestWithTimeFormat(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)
+
+	now := func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 1500, time.UTC) }
+	s, err := New(db, testModel{}, getTestEvent, WithNow[testModel](now), WithTimeFormat[testModel](time.RFC3339Nano))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if s.timePrecision != time.Nanosecond {
+		t.Errorf("got precision %s, expected 1ns", s.timePrecision)
+	}
+
+	var times []string
+	for _, record := range db.Records()[1:] {
+		envelope, err := DecodeEnvelope(record)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		times = append(times, envelope.Time)
+	}
+
+	if times[0] != "2024-01-01T00:00:00.0000015Z" || times[1] != times[0] {
+		t.Errorf("got times %v", times)
+	}
+
+	if _, err := New(db, testModel{}, getTestEvent); err != nil {
+		t.Errorf("loading mixed times with the default format: %v", err)
+	}
+}
+
+func TestNormalizeTimes(t *testing.T) {
+	input := `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}` + "\n\n" +
+		`{"v":1,"time":"2024-01-01T00:00:01.5Z","type":"add","payload":{"value":1}}`
+
+	var out strings.Builder
+	if err := NormalizeTimes(strings.NewReader(input), &out, time.RFC3339Nano); err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+
+	expect := `{"v":1,"time":"2024-01-01T00:00:00Z","type":"add","payload":{"value":1}}` + "\n" +
+		`{"v":1,"time":"2024-01-01T00:00:01.5Z","type":"add","payload":{"value":1}}` + "\n"
+	if out.String() != expect {
+		t.Errorf("got `%s`, expected `%s`", out.String(), expect)
+	}
+}
+
+func TestAppendRecord_same_as_EncodeEnvelope(t *testing.T) {
+	when := time.Date(2024, 6, 1, 10, 0, 0, 123456789, time.FixedZone("CEST", 2*60*60))
+
+	for _, tt := range []struct {
+		name     string
+		envelope Envelope
+		layout   string
+	}{
+		{"minimal", Envelope{Type: "add", Payload: json.RawMessage(`{"value":1}`)}, ""},
+		{"all fields", Envelope{
+			Type:        "add",
+			Schema:      2,
+			Version:     7,
+			Seq:         9,
+			Meta:        map[string]string{"user": "1", "a": "<b>"},
+			Correlation: "c-1",
+			Causation:   "e-7",
+			Payload:     json.RawMessage(`{"value":1}`),
+		}, ""},
+		{"encrypted fields", Envelope{
+			Type:      "signup",
+			Subject:   "user-1",
+			Encrypted: []string{"email", "name"},
+			Payload:   json.RawMessage(`{"email":"k:abc"}`),
+		}, ""},
+		{"escaped strings", Envelope{Type: "a\"b\\c\n<&>äö ", Correlation: "ü", Payload: json.RawMessage(`{}`)}, ""},
+		{"nil payload", Envelope{Type: "add"}, ""},
+		{"layout", Envelope{Type: "add", Payload: json.RawMessage(`1`)}, time.RFC3339Nano},
+		{"layout with escaped chars", Envelope{Type: "add", Payload: json.RawMessage(`1`)}, `2006<01>02 "15"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.envelope
+			e.Time = formatTime(when, tt.layout)
+			expect, err := EncodeEnvelope(e)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			got, err := appendRecord([]byte("prefix"), &tt.envelope, when, tt.layout)
+			if err != nil {
+				t.Fatalf("append record: %v", err)
+			}
+
+			if string(got) != "prefix"+string(expect) {
+				t.Errorf("got `%s`, expected `prefix%s`", got, expect)
+			}
+		})
+	}
+}
+
+func TestEventTime_default_layout(t *testing.T) {
+	when := time.Date(2024, 6, 1, 10, 0, 0, 123456789, time.FixedZone("CEST", 2*60*60))
+
+	e := Envelope{Time: formatTime(when, "")}
+	expect, err := e.ParseTime()
+	if err != nil {
+		t.Fatalf("parse time: %v", err)
+	}
+
+	if got := eventTime(when, ""); got != expect {
+		t.Errorf("got `%v`, expected `%v`", got, expect)
+	}
+}
@@ -0,0 +1,168 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// EventFilter is a filter for Events.
+type EventFilter func(*eventFilter)
+
+type eventFilter struct {
+	fromSeq  uint64
+	toSeq    uint64
+	fromTime time.Time
+	toTime   time.Time
+	names    map[string]bool
+
+	snapshots bool
+}
+
+// FilterSeq only returns the events with a sequence number from from to to.
+// Both are inclusive. 0 means no limit.
+func FilterSeq(from, to uint64) EventFilter {
+	return func(f *eventFilter) {
+		f.fromSeq = from
+		f.toSeq = to
+	}
+}
+
+// FilterTime only returns the events with a time from from until before to.
+// The zero time means no limit.
+func FilterTime(from, to time.Time) EventFilter {
+	return func(f *eventFilter) {
+		f.fromTime = from
+		f.toTime = to
+	}
+}
+
+// FilterNames only returns the events with one of the names. The names of
+// WithEventAlias are resolved.
+func FilterNames(names ...string) EventFilter {
+	return func(f *eventFilter) {
+		if f.names == nil {
+			f.names = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			f.names[name] = true
+		}
+	}
+}
+
+// FilterSnapshots also returns snapshot records. Their type is
+// "$snapshot". FilterNames excludes them, unless it contains this name.
+func FilterSnapshots() EventFilter {
+	return func(f *eventFilter) {
+		f.snapshots = true
+	}
+}
+
+// match returns true, if the envelope passes the filter.
+func (f eventFilter) match(e Envelope, name string, t time.Time) bool {
+	switch {
+	case e.Seq < f.fromSeq:
+		return false
+	case !f.fromTime.IsZero() && t.Before(f.fromTime):
+		return false
+	case !f.toTime.IsZero() && !t.Before(f.toTime):
+		return false
+	case f.names != nil && !f.names[name]:
+		return false
+	}
+	return true
+}
+
+// Events returns the events of the database without decoding their payload
+// into events. Snapshot records are skipped, unless FilterSnapshots is used.
+// It only returns the records, that exist, when the iteration starts.
+//
+// The records are parsed like on load. Records of old databases without
+// sequence number get the sequence number of their position.
+//
+// The write lock is only held, while the database is opened. If a record can
+// not be parsed, the error is returned and the iterator stops.
+func (s *Sticky[Model]) Events(ctx context.Context, filters ...EventFilter) func(yield func(val Envelope, err error) bool) {
+	return func(yield func(val Envelope, err error) bool) {
+		r, l, _, _, err := s.catchUpReader()
+		if err != nil {
+			yield(Envelope{}, err)
+			return
+		}
+		defer r.Close()
+
+		readEvents(ctx, r, l, newEventFilter(filters), yield)
+	}
+}
+
+// ReadEvents is like Sticky.Events, but reads the records from r. r has the
+// format of the reader of a database. Use it for tools, that read a database
+// without its model.
+//
+// Records can have at most 1 MiB. See WithMaxEventSize.
+func ReadEvents(ctx context.Context, r io.Reader, filters ...EventFilter) func(yield func(val Envelope, err error) bool) {
+	return func(yield func(val Envelope, err error) bool) {
+		l := &loader[struct{}]{maxEventSize: defaultMaxEventSize, maxRecords: math.MaxUint64}
+		readEvents(ctx, r, l, newEventFilter(filters), yield)
+	}
+}
+
+// newEventFilter returns the filter of the options.
+func newEventFilter(filters []EventFilter) eventFilter {
+	var filter eventFilter
+	for _, f := range filters {
+		f(&filter)
+	}
+	return filter
+}
+
+// readEvents calls yield with the records of r, that pass the filter. The
+// sequence numbers are checked like on load. It stops after maxRecords of
+// the loader.
+func readEvents[Model any](ctx context.Context, r io.Reader, l *loader[Model], filter eventFilter, yield func(Envelope, error) bool) {
+	err := scanRecords(ctx, r, l.maxEventSize, func(line []byte) error {
+		if l.records >= l.maxRecords {
+			return errCatchUpDone
+		}
+		l.records++
+
+		envelope, err := DecodeEnvelope(line)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", l.records, err)
+		}
+
+		if err := l.checkSeq(envelope); err != nil {
+			return err
+		}
+		envelope.Seq = l.seq
+
+		if filter.toSeq > 0 && envelope.Seq > filter.toSeq {
+			return errCatchUpDone
+		}
+
+		if envelope.Type == snapshotType && !filter.snapshots {
+			return nil
+		}
+
+		eventTime, err := envelope.parseTime(l.timeLayout)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", l.records, err)
+		}
+
+		if !filter.match(envelope, l.currentName(envelope.Type), eventTime) {
+			return nil
+		}
+
+		if !yield(envelope, nil) {
+			return errStopCatchUp
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errCatchUpDone) && !errors.Is(err, errStopCatchUp) {
+		yield(Envelope{}, err)
+	}
+}
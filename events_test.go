@@ -0,0 +1,179 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// collectEvents returns the sequence numbers of the events.
+func collectEvents(t *testing.T, s *Sticky[testModel], filters ...EventFilter) []uint64 {
+	t.Helper()
+
+	var seqs []uint64
+	s.Events(context.Background(), filters...)(func(envelope Envelope, err error) bool {
+		if err != nil {
+			t.Fatalf("events: %v", err)
+		}
+		seqs = append(seqs, envelope.Seq)
+		return true
+	})
+	return seqs
+}
+
+func TestEvents(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-03 00:00:00","type":"sub","payload":{"value":2}}`,
+		`{"time":"2024-01-04 00:00:00","type":"add","payload":{"value":3}}`,
+	)
+
+	s, err := New(db, testModel{}, getTestEvent, WithUnknownEvents[testModel](UnknownEventsSkip))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	day := func(d int) time.Time {
+		return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		filters []EventFilter
+		expect  []uint64
+	}{
+		{"all", nil, []uint64{1, 2, 3}},
+		{"seq", []EventFilter{FilterSeq(2, 2)}, []uint64{2}},
+		{"seq from", []EventFilter{FilterSeq(2, 0)}, []uint64{2, 3}},
+		{"time", []EventFilter{FilterTime(day(1), day(4))}, []uint64{1, 2}},
+		{"names", []EventFilter{FilterNames("add")}, []uint64{1, 3}},
+		{"combined", []EventFilter{FilterNames("add"), FilterTime(day(2), time.Time{})}, []uint64{3}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collectEvents(t, s, tt.filters...)
+			if len(got) != len(tt.expect) {
+				t.Fatalf("got seqs %v, expected %v", got, tt.expect)
+			}
+			for i := range got {
+				if got[i] != tt.expect[i] {
+					t.Fatalf("got seqs %v, expected %v", got, tt.expect)
+				}
+			}
+		})
+	}
+}
+
+func TestEvents_does_not_hold_the_lock(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+	)
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	var seqs []uint64
+	s.Events(context.Background())(func(envelope Envelope, err error) bool {
+		if err != nil {
+			t.Fatalf("events: %v", err)
+		}
+		seqs = append(seqs, envelope.Seq)
+
+		if len(seqs) == 1 {
+			if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 3} }); err != nil {
+				t.Fatalf("write while iterating: %v", err)
+			}
+		}
+		return true
+	})
+
+	if len(seqs) != 2 {
+		t.Errorf("got seqs %v, expected only the records, that existed at the start", seqs)
+	}
+}
+
+func TestEvents_closed(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	s.Close()
+
+	var gotErr error
+	s.Events(context.Background())(func(_ Envelope, err error) bool {
+		gotErr = err
+		return false
+	})
+
+	if !errors.Is(gotErr, ErrClosed) {
+		t.Errorf("got error `%v`, expected ErrClosed", gotErr)
+	}
+}
+
+func TestEvents_skips_snapshots(t *testing.T) {
+	s, err := New(NewMemoryDB(), snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		if i == 0 {
+			if err := s.Snapshot(); err != nil {
+				t.Fatalf("snapshot: %v", err)
+			}
+		}
+	}
+
+	var types []string
+	s.Events(context.Background())(func(envelope Envelope, err error) bool {
+		if err != nil {
+			t.Fatalf("events: %v", err)
+		}
+		types = append(types, envelope.Type)
+		return true
+	})
+
+	if len(types) != 2 || types[0] == snapshotType || types[1] == snapshotType {
+		t.Errorf("got types %v, expected two events without the snapshot", types)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	log := `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}
+{"time":"2024-01-02 00:00:00","type":"$snapshot","payload":"1"}
+{"time":"2024-01-03 00:00:00","type":"add","payload":{"value":2}}
+`
+
+	for _, tt := range []struct {
+		name    string
+		filters []EventFilter
+		expect  []string
+	}{
+		{"events", nil, []string{"add", "add"}},
+		{"snapshots", []EventFilter{FilterSnapshots()}, []string{"add", snapshotType, "add"}},
+		{"names", []EventFilter{FilterSnapshots(), FilterNames("add")}, []string{"add", "add"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var types []string
+			ReadEvents(context.Background(), strings.NewReader(log), tt.filters...)(func(e Envelope, err error) bool {
+				if err != nil {
+					t.Fatalf("read events: %v", err)
+				}
+				types = append(types, e.Type)
+				return true
+			})
+
+			if strings.Join(types, ",") != strings.Join(tt.expect, ",") {
+				t.Errorf("got types %v, expected %v", types, tt.expect)
+			}
+		})
+	}
+}
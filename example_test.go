@@ -0,0 +1,107 @@
+package sticky_test
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ostcar/sticky"
+)
+
+type account struct {
+	Balance int
+}
+
+type depositEvent struct {
+	Amount int `json:"amount"`
+}
+
+func (e depositEvent) Name() string {
+	return "deposit"
+}
+
+func (e depositEvent) Validate(account) error {
+	if e.Amount <= 0 {
+		return errors.New("amount has to be positive")
+	}
+	return nil
+}
+
+func (e depositEvent) Execute(a account, _ time.Time) account {
+	a.Balance += e.Amount
+	return a
+}
+
+type withdrawEvent struct {
+	Amount int `json:"amount"`
+}
+
+func (e withdrawEvent) Name() string {
+	return "withdraw"
+}
+
+func (e withdrawEvent) Validate(account) error {
+	return nil
+}
+
+func (e withdrawEvent) Execute(a account, _ time.Time) account {
+	a.Balance -= e.Amount
+	return a
+}
+
+func (e withdrawEvent) ExecuteErr(a account, _ time.Time) (account, error) {
+	if a.Balance < e.Amount {
+		return a, errors.New("balance is too low")
+	}
+	a.Balance -= e.Amount
+	return a, nil
+}
+
+func getAccountEvent(name string) sticky.Event[account] {
+	switch name {
+	case "deposit":
+		return &depositEvent{}
+	case "withdraw":
+		return &withdrawEvent{}
+	default:
+		return nil
+	}
+}
+
+func ExampleValidationError() {
+	s, err := sticky.New(sticky.NewMemoryDB(), account{}, getAccountEvent)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer s.Close()
+
+	_, write, done := s.ForWriting()
+	err = write(depositEvent{Amount: 5}, depositEvent{Amount: -1})
+	done()
+
+	var validationErr sticky.ValidationError
+	if errors.As(err, &validationErr) {
+		fmt.Printf("event %d (%s): %v\n", validationErr.Index, validationErr.EventName, errors.Unwrap(validationErr))
+	}
+	// Output: event 1 (deposit): amount has to be positive
+}
+
+func ExampleExecutionError() {
+	s, err := sticky.New(sticky.NewMemoryDB(), account{}, getAccountEvent)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer s.Close()
+
+	_, write, done := s.ForWriting()
+	err = write(depositEvent{Amount: 5}, withdrawEvent{Amount: 3}, withdrawEvent{Amount: 3})
+	done()
+
+	var executionErr sticky.ExecutionError
+	if errors.As(err, &executionErr) {
+		fmt.Printf("event %d (%s): %v\n", executionErr.Index, executionErr.EventName, errors.Unwrap(executionErr))
+	}
+	// Output: event 2 (withdraw): balance is too low
+}
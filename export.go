@@ -0,0 +1,152 @@
+package sticky
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Export writes the records of the database to w. Each line of w is one
+// record in the format of EncodeEnvelope (NDJSON). Use Import to write them
+// into another database.
+//
+// Unlike Events, snapshot records are exported, so the export of a compacted
+// database contains the model. See FilterSnapshots. Records of old
+// databases get a sequence number.
+//
+// The write lock is only held, while the database is opened.
+func (s *Sticky[Model]) Export(ctx context.Context, w io.Writer, filters ...EventFilter) error {
+	var exportErr error
+	s.Events(ctx, append(filters, FilterSnapshots())...)(func(envelope Envelope, err error) bool {
+		if err != nil {
+			exportErr = err
+			return false
+		}
+
+		record, err := EncodeEnvelope(envelope)
+		if err != nil {
+			exportErr = err
+			return false
+		}
+
+		if _, err := w.Write(append(record, '\n')); err != nil {
+			exportErr = fmt.Errorf("writing record %d: %w", envelope.Seq, err)
+			return false
+		}
+		return true
+	})
+	return exportErr
+}
+
+// ImportOption is an option for Import.
+type ImportOption func(*importConfig)
+
+type importConfig struct {
+	force bool
+}
+
+// ImportForce imports the records into a database, that is not empty. The
+// records are appended after the existing ones. Their sequence numbers have
+// to be bigger then the existing ones, or the database can not be loaded.
+func ImportForce() ImportOption {
+	return func(c *importConfig) {
+		c.force = true
+	}
+}
+
+// Import appends the records of r to the database. r has the format of
+// Export.
+//
+// Each record is decoded with getEvent, before anything is written. If a
+// record can not be decoded, nothing is imported. Of records with encrypted
+// fields, only the name is checked. The events are not
+// executed. So a record, that can not be validated or executed, is not
+// detected. The records are held in memory until they are appended.
+//
+// Per default, Import returns ErrNotEmpty, if the database already has
+// records. See ImportForce.
+func Import[Model any](db database, r io.Reader, getEvent func(name string) Event[Model], options ...ImportOption) error {
+	var cfg importConfig
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	if !cfg.force {
+		empty, err := isEmpty(db)
+		if err != nil {
+			return err
+		}
+
+		if !empty {
+			return ErrNotEmpty
+		}
+	}
+
+	l := loader[Model]{
+		getEvent:     getEvent,
+		maxEventSize: defaultMaxEventSize,
+	}
+
+	var records [][]byte
+	err := scanRecords(context.Background(), r, l.maxEventSize, func(line []byte) error {
+		l.records++
+
+		envelope, err := DecodeEnvelope(line)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", l.records, err)
+		}
+
+		if err := l.checkSeq(envelope); err != nil {
+			return err
+		}
+
+		switch {
+		case envelope.Type == snapshotType:
+		case len(envelope.Encrypted) > 0:
+			// The keys of the encrypted fields are not known.
+			if getEvent(envelope.Type) == nil {
+				return fmt.Errorf("record %d: %w `%s`", l.records, errUnknownEvent, envelope.Type)
+			}
+		default:
+			if _, _, err := l.decodeEvent(envelope); err != nil {
+				return fmt.Errorf("record %d: %w", l.records, err)
+			}
+		}
+
+		records = append(records, bytes.Clone(line))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("reading records: %w", err)
+	}
+
+	if err := appendRecords(db, records); err != nil {
+		return fmt.Errorf("appending records: %w", err)
+	}
+	return nil
+}
+
+// isEmpty returns true, if the database has no records.
+func isEmpty(db database) (bool, error) {
+	r, err := db.Reader()
+	if err != nil {
+		return false, StorageError{Op: "open database", Err: err}
+	}
+	defer r.Close()
+
+	errNotEmpty := errors.New("not empty")
+	err = scanRecords(context.Background(), r, defaultMaxEventSize, func([]byte) error {
+		return errNotEmpty
+	})
+
+	switch {
+	case errors.Is(err, errNotEmpty):
+		return false, nil
+	case err != nil:
+		return false, StorageError{Op: "reading database", Err: err}
+	default:
+		return true, nil
+	}
+}
@@ -0,0 +1,119 @@
+package sticky
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExport_Import_round_trip(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		compact bool
+	}{
+		{"events", false},
+		{"compacted", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(NewMemoryDB(), snapshotModel{}, getSnapshotTestEvent)
+			if err != nil {
+				t.Fatalf("creating sticky: %v", err)
+			}
+
+			for i := 1; i <= 5; i++ {
+				if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: i} }); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+
+				if tt.compact && i == 3 {
+					if err := s.Compact(context.Background()); err != nil {
+						t.Fatalf("compact: %v", err)
+					}
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := s.Export(context.Background(), &buf); err != nil {
+				t.Fatalf("export: %v", err)
+			}
+
+			db := NewMemoryDB()
+			if err := Import(db, &buf, getSnapshotTestEvent); err != nil {
+				t.Fatalf("import: %v", err)
+			}
+
+			imported, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+			if err != nil {
+				t.Fatalf("loading imported database: %v", err)
+			}
+
+			expected, version, done := s.ForReadingVersioned()
+			done()
+			got, gotVersion, done := imported.ForReadingVersioned()
+			done()
+
+			if got.Sum != expected.Sum || gotVersion != version {
+				t.Errorf("got sum %d at version %d, expected %d at version %d", got.Sum, gotVersion, expected.Sum, version)
+			}
+
+			if imported.Stats().LastSeq != s.Stats().LastSeq {
+				t.Errorf("got last seq %d, expected %d", imported.Stats().LastSeq, s.Stats().LastSeq)
+			}
+		})
+	}
+}
+
+func TestExport_filter(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-02 00:00:00","type":"sub","payload":{"value":2}}`,
+	)
+
+	s, err := New(db, testModel{}, getTestEvent, WithUnknownEvents[testModel](UnknownEventsSkip))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Export(context.Background(), &buf, FilterNames("add")); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"seq":1`) {
+		t.Errorf("got export `%s`, expected only the first record with its seq", buf.String())
+	}
+}
+
+func TestImport_not_empty(t *testing.T) {
+	record := `{"time":"2024-01-01 00:00:00","type":"add","seq":2,"payload":{"value":2}}` + "\n"
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","seq":1,"payload":{"value":1}}`)
+
+	if err := Import(db, strings.NewReader(record), getTestEvent); !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("got error `%v`, expected ErrNotEmpty", err)
+	}
+
+	if err := Import(db, strings.NewReader(record), getTestEvent, ImportForce()); err != nil {
+		t.Fatalf("import with force: %v", err)
+	}
+
+	if got := len(db.Records()); got != 2 {
+		t.Errorf("got %d records, expected 2", got)
+	}
+}
+
+func TestImport_invalid_record(t *testing.T) {
+	records := `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}` + "\n" +
+		`{"time":"2024-01-01 00:00:00","type":"unknown","payload":{}}` + "\n"
+
+	db := NewMemoryDB()
+	if err := Import(db, strings.NewReader(records), getTestEvent); err == nil {
+		t.Fatalf("import of an unknown event did not return an error")
+	}
+
+	if got := len(db.Records()); got != 0 {
+		t.Errorf("got %d records, expected that nothing is imported", got)
+	}
+}
@@ -0,0 +1,246 @@
+package sticky
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"time"
+)
+
+// liveReader is a database, whose reader returns newly appended events after
+// it returned io.EOF. The FileDB is a liveReader.
+//
+// For other databases, the follower opens a new reader on each poll.
+type liveReader interface {
+	liveReader()
+}
+
+func (db *FileDB) liveReader() {}
+
+// NewFollower initializes a read only Sticky instance, that follows a database
+// written by another process.
+//
+// After the initial load, the database is polled in the given interval. New
+// events are applied to the model and published, so Listen works. An
+// incomplete last line is read again on the next poll.
+//
+// Following stops, when the context is done, the Sticky is closed or an event
+// can not be loaded.
+// Use WithFollowError to get notified about the error.
+func NewFollower[Model any](ctx context.Context, db database, emptyModel Model, getEvent func(name string) Event[Model], interval time.Duration, os ...Option[Model]) (*Sticky[Model], error) {
+	s := newSticky(db, getEvent, append(os, WithReadOnly[Model]())...)
+	s.writeQueue = nil // A follower does not write.
+	s.emptyModel = emptyModel
+	s.model = s.newModel()
+	s.initLogger()
+	s.publish()
+
+	if err := s.checkEvents(); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCloner(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	f := follower[Model]{s: s}
+	if err := f.poll(); err != nil {
+		f.close()
+		return nil, fmt.Errorf("loading database: %w", err)
+	}
+	s.loadStats = s.loader.stats
+	s.loadStats.EventTypes = maps.Clone(s.loadStats.EventTypes)
+	s.loadStats.Duration = time.Since(start)
+
+	// The later events are new.
+	s.loader.newEvents = true
+
+	s.background.Add(1)
+	go f.run(ctx, interval)
+	return s, nil
+}
+
+// follower reads new events from the database of a Sticky.
+type follower[Model any] struct {
+	s      *Sticky[Model]
+	reader io.ReadCloser
+	br     *bufio.Reader
+
+	// offset is the number of bytes of complete lines, that where applied.
+	offset int64
+
+	// partial is an incomplete line, that was read from the reader.
+	partial []byte
+}
+
+// run polls the database until the context is done or the Sticky is closed.
+// Close waits for it.
+func (f *follower[Model]) run(ctx context.Context, interval time.Duration) {
+	defer f.s.background.Done()
+	defer f.close()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-f.s.closeCtx.Done():
+			return
+
+		case <-ticker.C:
+			if err := f.poll(); err != nil {
+				if f.s.onFollowError != nil {
+					f.s.onFollowError(err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// open opens the reader of the database and skips the bytes, that where
+// already applied.
+func (f *follower[Model]) open() error {
+	f.close()
+
+	r, err := f.s.db.Reader()
+	if err != nil {
+		return StorageError{Op: "open database", Err: err}
+	}
+
+	if _, err := io.CopyN(io.Discard, r, f.offset); err != nil {
+		r.Close()
+		return fmt.Errorf("skipping applied events: %w", err)
+	}
+
+	f.reader = r
+	f.br = bufio.NewReader(r)
+	f.partial = nil
+	return nil
+}
+
+func (f *follower[Model]) close() {
+	if f.reader != nil {
+		f.reader.Close()
+		f.reader = nil
+	}
+}
+
+// poll reads all complete lines and applies them to the model.
+//
+// The lines are loaded like in New. They are first checked with a copy of the
+// loader, so the model is only changed, if all of them can be loaded.
+func (f *follower[Model]) poll() error {
+	if _, live := f.s.db.(liveReader); f.reader == nil || !live {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+
+	var data []byte
+	var offset int64
+	for {
+		chunk, err := f.br.ReadBytes('\n')
+		f.partial = append(f.partial, chunk...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return StorageError{Op: "reading database", Err: err}
+		}
+
+		offset += int64(len(f.partial))
+		data = append(data, f.partial...)
+		f.partial = nil
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		f.offset += offset
+		return nil
+	}
+
+	f.s.mu.Lock()
+	defer f.s.mu.Unlock()
+
+	if err := f.check(data); err != nil {
+		return err
+	}
+	f.offset += offset
+
+	model := f.s.model
+	if f.s.copyOnWrite {
+		// The published model is used by readers without a lock.
+		model = f.s.clone()
+	}
+
+	var notifications []*Notification[Model]
+	l := &f.s.loader
+	l.onExecuted = func(e Envelope, event Event[Model], t time.Time, seq uint64) {
+		notifications = append(notifications, &Notification[Model]{
+			Name:  f.s.eventName(event),
+			Event: event,
+			Time:  t,
+			Seq:   seq,
+			Meta:  e.Meta,
+
+			Correlation: e.Correlation,
+			Causation:   e.Causation,
+		})
+	}
+	defer func() { l.onExecuted = nil }()
+
+	model, err := l.load(bytes.NewReader(data), model, 0)
+	if err != nil {
+		// The check passed, so only a changing Execute can fail here.
+		return fmt.Errorf("loading checked events: %w", err)
+	}
+	l.stats.BytesRead += int64(len(data))
+
+	f.s.model = model
+	f.s.records = l.records
+	f.s.version = l.version
+	f.s.seq = l.seq
+	f.s.lastTime = l.lastTime
+	f.s.eventsSinceSnapshot = l.eventsSinceSnapshot
+	f.s.lastSnapshot = l.lastSnapshot
+
+	// Listeners have to see the new model.
+	f.s.publish()
+
+	f.s.publishNotifications(notifications)
+	if f.s.logger != nil {
+		f.s.logger.Debug("events followed", "events", len(notifications), "version", f.s.version)
+	}
+	return nil
+}
+
+// check loads the lines with a copy of the loader without changing the
+// model. If the model implements Cloner, the events are executed on a copy.
+// Otherwise, they are only decoded.
+//
+// Has to be called with the write lock.
+func (f *follower[Model]) check(data []byte) error {
+	l := f.s.loader.replayLoader()
+	l.recoverTail = false
+	l.records = f.s.loader.records
+	l.version = f.s.loader.version
+	l.seq = f.s.loader.seq
+	l.seqKnown = f.s.loader.seqKnown
+
+	model := f.s.model
+	if cloner, ok := any(model).(Cloner[Model]); ok {
+		model = cloner.Clone()
+	} else {
+		l.skipExecute = true
+	}
+
+	_, err := l.load(bytes.NewReader(data), model, 0)
+	return err
+}
@@ -0,0 +1,229 @@
+package sticky
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForSum waits until the model of s has the expected sum.
+func waitForSum(t *testing.T, s *Sticky[testModel], expect int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m, done := s.ForReading()
+		sum := m.Sum
+		done()
+
+		if sum == expect {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got sum %d, expected %d", sum, expect)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNewFollower_follows_writer_with_rotation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	file := path.Join(t.TempDir(), "events.log")
+	writerDB := FileDB{File: file, MaxSegmentSize: 200, Compress: true}
+	defer writerDB.Close()
+
+	writer, err := New(&writerDB, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating writer: %v", err)
+	}
+
+	if err := writer.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("writing event: %v", err)
+	}
+
+	followerDB := FileDB{File: file}
+	follower, err := NewFollower(ctx, &followerDB, testModel{}, getTestEvent, time.Millisecond)
+	if err != nil {
+		t.Fatalf("creating follower: %v", err)
+	}
+
+	waitForSum(t, follower, 1)
+
+	for i := 0; i < 20; i++ {
+		if err := writer.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+			t.Fatalf("writing event: %v", err)
+		}
+	}
+
+	waitForSum(t, follower, 21)
+
+	if err := follower.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != ErrReadOnly {
+		t.Errorf("write on follower returned `%v`, expected ErrReadOnly", err)
+	}
+}
+
+func TestNewFollower_waits_for_complete_line(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	file := path.Join(t.TempDir(), "events.log")
+	event := `{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}`
+
+	if err := os.WriteFile(file, []byte(event+"\n"+event[:20]), 0600); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	db := FileDB{File: file}
+	follower, err := NewFollower(ctx, &db, testModel{}, getTestEvent, time.Millisecond)
+	if err != nil {
+		t.Fatalf("creating follower: %v", err)
+	}
+
+	waitForSum(t, follower, 1)
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("open file: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(event[20:] + "\n"); err != nil {
+		t.Fatalf("completing line: %v", err)
+	}
+
+	waitForSum(t, follower, 2)
+}
+
+func TestNewFollower_on_memory_db(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := NewMemoryDB(`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}`)
+
+	follower, err := NewFollower(ctx, db, testModel{}, getTestEvent, time.Millisecond)
+	if err != nil {
+		t.Fatalf("creating follower: %v", err)
+	}
+
+	ch := make(chan []string, 1)
+	go func() {
+		follower.Listen(ctx)(func(names []string) bool {
+			ch <- names
+			return false
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := db.Append([]byte(`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":2}}`)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	waitForSum(t, follower, 3)
+
+	select {
+	case names := <-ch:
+		if len(names) != 1 || names[0] != "add" {
+			t.Errorf("listen got %v, expected [add]", names)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("listen did not get the followed event")
+	}
+}
+
+// openReaderDB counts the open readers of a MemoryDB.
+type openReaderDB struct {
+	*MemoryDB
+	open atomic.Int32
+}
+
+func (db *openReaderDB) Reader() (io.ReadCloser, error) {
+	r, err := db.MemoryDB.Reader()
+	if err != nil {
+		return nil, err
+	}
+	db.open.Add(1)
+	return openReader{ReadCloser: r, db: db}, nil
+}
+
+type openReader struct {
+	io.ReadCloser
+	db *openReaderDB
+}
+
+func (r openReader) Close() error {
+	r.db.open.Add(-1)
+	return r.ReadCloser.Close()
+}
+
+func TestNewFollower_close_waits_for_follower(t *testing.T) {
+	db := &openReaderDB{MemoryDB: NewMemoryDB(`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}`)}
+
+	follower, err := NewFollower(context.Background(), db, testModel{}, getTestEvent, time.Millisecond)
+	if err != nil {
+		t.Fatalf("creating follower: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := follower.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if open := db.open.Load(); open != 0 {
+		t.Errorf("got %d open readers after Close, expected 0", open)
+	}
+}
+
+func TestNewFollower_applies_poll_atomically(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := `{"time":"2024-01-01 12:00:00","type":"oldAdd","seq":1,"payload":{"value":1}}`
+	db := NewMemoryDB(first)
+
+	followErr := make(chan error, 1)
+	follower, err := NewFollower(ctx, db, testModel{}, getTestEvent, time.Millisecond,
+		WithEventAlias[testModel]("oldAdd", "add"),
+		WithFollowError[testModel](func(err error) { followErr <- err }),
+	)
+	if err != nil {
+		t.Fatalf("creating follower: %v", err)
+	}
+	waitForSum(t, follower, 1)
+
+	if got := follower.LoadStats().Events; got != 1 {
+		t.Errorf("got %d loaded events, expected 1", got)
+	}
+
+	// The second record is valid, the third has a smaller sequence number.
+	content := strings.Join([]string{
+		first,
+		`{"time":"2024-01-01 12:00:00","type":"add","seq":2,"payload":{"value":2}}`,
+		`{"time":"2024-01-01 12:00:00","type":"add","seq":1,"payload":{"value":4}}`,
+	}, "\n")
+	if err := db.ReplaceWith(strings.NewReader(content)); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	select {
+	case err := <-followErr:
+		if err == nil || !strings.Contains(err.Error(), "sequence number") {
+			t.Errorf("got error `%v`, expected an error about the sequence number", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("follower did not stop")
+	}
+
+	model, done := follower.ForReading()
+	defer done()
+	if model.Sum != 1 {
+		t.Errorf("got sum %d, expected the model before the poll with sum 1", model.Sum)
+	}
+}
@@ -0,0 +1,237 @@
+package sticky
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// tailMark is a position in a database. See tailMarker.
+type tailMark struct {
+	generation int
+	size       int64
+}
+
+// tailMarker is a database, that can remove the records, that where appended
+// after a mark.
+type tailMarker interface {
+	markTail() (tailMark, error)
+	truncateToMark(tailMark) error
+}
+
+// commitGroup are the writes, that are synced together. See WithGroupCommit.
+type commitGroup[Model any] struct {
+	done   chan struct{}
+	err    error
+	events int
+
+	// effects apply the projections and call the handlers of the writes.
+	// They and the notifications are only used after the sync.
+	effects       []func()
+	notifications []*Notification[Model]
+
+	// afterWrite are the functions of WithAfterWrite, that are called with
+	// the write lock after the sync.
+	afterWrite []func()
+
+	// mark is the end of the database before the first write of the group
+	// or nil, if it is unknown. The records after it are removed, if the
+	// sync fails.
+	mark *tailMark
+
+	// The state before the first write of the group. It is restored, if the
+	// sync fails.
+	model               Model
+	version             uint64
+	records             uint64
+	seq                 uint64
+	eventsWritten       uint64
+	eventsSinceSnapshot int
+	lastTime            time.Time
+}
+
+// wait blocks until the group is synced and returns the error of the sync.
+func (g *commitGroup[Model]) wait() error {
+	<-g.done
+	return g.err
+}
+
+// markGroupTail returns the end of the database, if there is no open group
+// and the database supports it. Otherwise, it returns nil.
+//
+// Has to be called with the write lock, before the events are appended.
+func (s *Sticky[Model]) markGroupTail() *tailMark {
+	marker, ok := s.db.(tailMarker)
+	if !ok || s.group != nil {
+		return nil
+	}
+
+	mark, err := marker.markTail()
+	if err != nil {
+		return nil
+	}
+	return &mark
+}
+
+// joinGroup adds the events to the open group. If there is no open group, a
+// new one is started, that is synced after the window. mark is the result of
+// markGroupTail.
+//
+// Has to be called with the write lock, before the state is changed.
+func (s *Sticky[Model]) joinGroup(events int, mark *tailMark) *commitGroup[Model] {
+	if s.group == nil {
+		g := &commitGroup[Model]{
+			done:                make(chan struct{}),
+			mark:                mark,
+			model:               s.model,
+			version:             s.version,
+			records:             s.records,
+			seq:                 s.seq,
+			eventsWritten:       s.eventsWritten,
+			eventsSinceSnapshot: s.eventsSinceSnapshot,
+			lastTime:            s.lastTime,
+		}
+		s.group = g
+
+		time.AfterFunc(s.groupWindow, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			if s.group == g {
+				s.commitGroup()
+			}
+		})
+	}
+
+	s.group.events += events
+	s.joined = s.group
+	return s.group
+}
+
+// commitGroup syncs the database and releases the writers of the open group.
+// If the sync fails, the model is restored to the state before the group and
+// all writers of the group get the error. The records of the group are
+// removed from the database. If this is not possible, all following writes
+// fail with the error. Otherwise, the projections and
+// handlers get the events, they are published and the functions of
+// WithAfterWrite are called.
+//
+// It returns the error of the group or nil, if there is no open group.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) commitGroup() error {
+	g := s.group
+	if g == nil {
+		return nil
+	}
+	s.group = nil
+
+	if err := s.syncDB(); err != nil {
+		g.err = err
+		if err := s.truncateGroup(g); err != nil {
+			if s.logger != nil {
+				s.logger.Error("removing the records of the failed group failed", "error", err)
+			}
+			s.failed = g.err
+		}
+		s.model = g.model
+		s.version = g.version
+		s.records = g.records
+		s.seq = g.seq
+		s.eventsWritten = g.eventsWritten
+		s.eventsSinceSnapshot = g.eventsSinceSnapshot
+		s.lastTime = g.lastTime
+		s.publish()
+	} else {
+		for _, effect := range g.effects {
+			effect()
+		}
+		s.publishNotifications(g.notifications)
+		if s.logger != nil {
+			s.logger.Debug("group committed", "events", g.events)
+		}
+	}
+	close(g.done)
+
+	if g.err == nil {
+		// The writers are released first, so they do not wait forever, if
+		// a function of WithAfterWrite panics.
+		for _, call := range g.afterWrite {
+			call()
+		}
+		s.autoSnapshot()
+	}
+	return g.err
+}
+
+// takeCommit returns the group, that the last write joined. The caller has
+// to wait for it after releasing the lock.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) takeCommit() *commitGroup[Model] {
+	g := s.joined
+	s.joined = nil
+	return g
+}
+
+// writeNow is like write, but for callers, that hold the lock until they are
+// done. With WithGroupCommit, the group is synced immediately.
+func (s *Sticky[Model]) writeNow(events []Event[Model], opts writeOptions) error {
+	if err := s.write(events, opts); err != nil {
+		return err
+	}
+
+	g := s.takeCommit()
+	if g == nil {
+		return nil
+	}
+
+	if s.group == g {
+		s.commitGroup()
+	}
+	return g.wait()
+}
+
+// truncateGroup removes the records of the group from the database.
+func (s *Sticky[Model]) truncateGroup(g *commitGroup[Model]) error {
+	if g.mark == nil {
+		return errors.New("database can not remove records")
+	}
+	return s.db.(tailMarker).truncateToMark(*g.mark)
+}
+
+// markTail returns the current end of the active file.
+func (db *FileDB) markTail() (tailMark, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.lock(); err != nil {
+		return tailMark{}, err
+	}
+
+	if err := db.finishReplace(); err != nil {
+		return tailMark{}, err
+	}
+
+	if err := db.open(); err != nil {
+		return tailMark{}, err
+	}
+	return tailMark{generation: db.generation, size: db.size}, nil
+}
+
+// truncateToMark removes everything after the mark from the active file. It
+// fails, if the file was closed in the meantime, for example by a rotation.
+func (db *FileDB) truncateToMark(mark tailMark) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.f == nil || db.generation != mark.generation {
+		return errors.New("database file was changed")
+	}
+
+	if err := db.f.Truncate(mark.size); err != nil {
+		return fmt.Errorf("truncating db file: %w", err)
+	}
+	db.size = mark.size
+	return nil
+}
@@ -0,0 +1,209 @@
+package sticky
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// groupSyncDB counts the calls to Sync and returns err.
+type groupSyncDB struct {
+	*MemoryDB
+	syncs int
+	err   error
+}
+
+func (db *groupSyncDB) Sync() error {
+	db.syncs++
+	return db.err
+}
+
+// newGroupSticky returns a Sticky with group commit on db.
+func newGroupSticky(t *testing.T, db database, window time.Duration, maxBatch int, options ...Option[cloneModel]) *Sticky[cloneModel] {
+	t.Helper()
+
+	getEvent := func(string) Event[cloneModel] { return &cloneAddEvent{} }
+	options = append(options, WithCopyOnWrite[cloneModel](), WithGroupCommit[cloneModel](window, maxBatch))
+	s, err := New(db, cloneModel{}, getEvent, options...)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	return s
+}
+
+func TestWithGroupCommit(t *testing.T) {
+	db := &groupSyncDB{MemoryDB: NewMemoryDB()}
+	s := newGroupSticky(t, db, 50*time.Millisecond, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} }); err != nil {
+				t.Errorf("write: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(db.Records()); got != 20 {
+		t.Errorf("got %d records, expected 20", got)
+	}
+
+	if db.syncs >= 20 {
+		t.Errorf("got %d syncs for 20 writes, expected less", db.syncs)
+	}
+}
+
+func TestWithGroupCommit_max_batch(t *testing.T) {
+	db := &groupSyncDB{MemoryDB: NewMemoryDB()}
+	s := newGroupSticky(t, db, time.Hour, 2)
+
+	err := s.WriteMany(func(cloneModel) ([]Event[cloneModel], error) {
+		return []Event[cloneModel]{cloneAddEvent{Value: 1}, cloneAddEvent{Value: 1}}, nil
+	})
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if db.syncs != 1 {
+		t.Errorf("got %d syncs, expected 1", db.syncs)
+	}
+}
+
+func TestWithGroupCommit_failed_sync(t *testing.T) {
+	db := &groupSyncDB{MemoryDB: NewMemoryDB(), err: errors.New("disk full")}
+	var handled int
+	s := newGroupSticky(t, db, 20*time.Millisecond, 0, WithHandler[cloneModel]("add", func(Event[cloneModel], time.Time, cloneModel) {
+		handled++
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} }); !errors.Is(err, db.err) {
+				t.Errorf("write returned `%v`, expected the sync error", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	model, version, done := s.ForReadingVersioned()
+	done()
+
+	if model.Sum != 0 || version != 0 {
+		t.Errorf("got sum %d at version %d, expected the model before the group", model.Sum, version)
+	}
+
+	if handled != 0 {
+		t.Errorf("handler was called %d times, expected no call for the failed group", handled)
+	}
+}
+
+func TestWithGroupCommit_needs_sync(t *testing.T) {
+	getEvent := func(string) Event[cloneModel] { return &cloneAddEvent{} }
+	_, err := New(NewMemoryDB(), cloneModel{}, getEvent, WithCopyOnWrite[cloneModel](), WithGroupCommit[cloneModel](time.Millisecond, 0))
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error `%v`, expected ErrNotSupported", err)
+	}
+}
+
+func TestWithGroupCommit_needs_copy_on_write(t *testing.T) {
+	db := &groupSyncDB{MemoryDB: NewMemoryDB()}
+	_, err := New(db, testModel{}, getTestEvent, WithGroupCommit[testModel](time.Millisecond, 0))
+	if err == nil || !strings.Contains(err.Error(), "WithCopyOnWrite") {
+		t.Errorf("got error `%v`, expected an error about WithCopyOnWrite", err)
+	}
+}
+
+func TestWithGroupCommit_handlers_after_sync(t *testing.T) {
+	db := &groupSyncDB{MemoryDB: NewMemoryDB()}
+
+	var syncsAtHandler []int
+	s := newGroupSticky(t, db, time.Hour, 1, WithHandler[cloneModel]("add", func(Event[cloneModel], time.Time, cloneModel) {
+		syncsAtHandler = append(syncsAtHandler, db.syncs)
+	}))
+
+	if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if len(syncsAtHandler) != 1 || syncsAtHandler[0] != 1 {
+		t.Errorf("handler was called after %v syncs, expected one call after the sync", syncsAtHandler)
+	}
+}
+
+// failSyncFileDB is a FileDB, whose Sync returns err.
+type failSyncFileDB struct {
+	*FileDB
+	err error
+}
+
+func (db *failSyncFileDB) Sync() error {
+	return db.err
+}
+
+func TestWithGroupCommit_failed_sync_reload(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		fileDB := &FileDB{File: path.Join(t.TempDir(), "events.log")}
+		defer fileDB.Close()
+
+		db := &failSyncFileDB{FileDB: fileDB}
+		s := newGroupSticky(t, db, time.Hour, 1)
+
+		if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		db.err = errors.New("disk full")
+		if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 2} }); !errors.Is(err, db.err) {
+			t.Fatalf("write returned `%v`, expected the sync error", err)
+		}
+
+		// The records of the failed group are removed, so the Sticky can be
+		// used further.
+		db.err = nil
+		if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 4} }); err != nil {
+			t.Fatalf("write after the failed group: %v", err)
+		}
+
+		getEvent := func(string) Event[cloneModel] { return &cloneAddEvent{} }
+		reloaded, err := New(fileDB, cloneModel{}, getEvent, WithStrictSeq[cloneModel]())
+		if err != nil {
+			t.Fatalf("reloading database: %v", err)
+		}
+
+		model, done := reloaded.ForReading()
+		done()
+		if model.Sum != 5 {
+			t.Errorf("got sum %d after reload, expected 5 without the failed group", model.Sum)
+		}
+	})
+
+	t.Run("no truncate", func(t *testing.T) {
+		db := &groupSyncDB{MemoryDB: NewMemoryDB(), err: errors.New("disk full")}
+		s := newGroupSticky(t, db, time.Hour, 1)
+
+		if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} }); !errors.Is(err, db.err) {
+			t.Fatalf("write returned `%v`, expected the sync error", err)
+		}
+
+		// The database keeps the records, so further writes would reuse
+		// their sequence numbers.
+		db.err = nil
+		if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} }); err == nil {
+			t.Errorf("write after the failed group returned no error")
+		}
+
+		getEvent := func(string) Event[cloneModel] { return &cloneAddEvent{} }
+		if _, err := New(db.MemoryDB, cloneModel{}, getEvent, WithStrictSeq[cloneModel]()); err != nil {
+			t.Errorf("reloading database: %v", err)
+		}
+	})
+}
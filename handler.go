@@ -0,0 +1,64 @@
+package sticky
+
+import "time"
+
+// HandlerOption is a option for WithHandler.
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	skipLoad bool
+}
+
+// HandlerSkipLoad does not call the handler on load. It is only called after
+// writes.
+func HandlerSkipLoad() HandlerOption {
+	return func(c *handlerConfig) {
+		c.skipLoad = true
+	}
+}
+
+// eventHandler is a function of WithHandler.
+type eventHandler[Model any] struct {
+	f        func(event Event[Model], t time.Time, model Model)
+	skipLoad bool
+}
+
+// handlersOnLoad returns true, if one of the handlers is called on load.
+func (l *loader[Model]) handlersOnLoad() bool {
+	for _, handlers := range l.handlers {
+		for _, h := range handlers {
+			if !h.skipLoad {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callHandlers calls the handlers of the event. load is true for events on
+// load.
+func (l *loader[Model]) callHandlers(name string, event Event[Model], t time.Time, model Model, load bool) {
+	for _, h := range l.handlers[name] {
+		if load && h.skipLoad {
+			continue
+		}
+		l.callHandler(h, name, event, t, model)
+	}
+}
+
+// callHandler calls the handler and recovers its panic.
+func (l *loader[Model]) callHandler(h eventHandler[Model], name string, event Event[Model], t time.Time, model Model) {
+	defer func() {
+		if r := recover(); r != nil {
+			if l.logger != nil {
+				l.logger.Error("handler panicked", "event", name, "panic", r)
+			}
+
+			if l.onHandlerPanic != nil {
+				l.onHandlerPanic(name, r)
+			}
+		}
+	}()
+
+	h.f(event, t, model)
+}
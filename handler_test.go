@@ -0,0 +1,81 @@
+package sticky
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithHandler(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+	)
+
+	var sums []int
+	handler := func(_ Event[testModel], _ time.Time, m testModel) {
+		sums = append(sums, m.Sum)
+	}
+
+	var writeOnly int
+	onlyWrites := func(Event[testModel], time.Time, testModel) {
+		writeOnly++
+	}
+
+	s, err := New(db, testModel{}, getWithdrawTestEvent,
+		WithHandler[testModel]("add", handler),
+		WithHandler[testModel]("add", onlyWrites, HandlerSkipLoad()),
+	)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.WriteMany(func(testModel) ([]Event[testModel], error) {
+		return []Event[testModel]{addEvent{Value: 3}, withdrawEvent{Value: 1}, addEvent{Value: 4}}, nil
+	}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	expect := []int{1, 3, 6, 9}
+	if len(sums) != len(expect) {
+		t.Fatalf("got sums %v, expected %v", sums, expect)
+	}
+	for i := range expect {
+		if sums[i] != expect[i] {
+			t.Errorf("got sums %v, expected %v", sums, expect)
+			break
+		}
+	}
+
+	if writeOnly != 2 {
+		t.Errorf("handler with HandlerSkipLoad was called %d times, expected 2", writeOnly)
+	}
+}
+
+func TestWithHandler_panic(t *testing.T) {
+	var panicked []string
+	onPanic := func(name string, recovered any) {
+		panicked = append(panicked, name+": "+recovered.(string))
+	}
+
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent,
+		WithHandler[testModel]("add", func(Event[testModel], time.Time, testModel) { panic("boom") }),
+		WithHandlerPanic[testModel](onPanic),
+	)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if len(panicked) != 1 || panicked[0] != "add: boom" {
+		t.Errorf("got panics %v, expected one from add", panicked)
+	}
+
+	model, done := s.ForReading()
+	done()
+	if model.Sum != 1 {
+		t.Errorf("got sum %d, expected the event to be written", model.Sum)
+	}
+}
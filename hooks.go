@@ -0,0 +1,125 @@
+package sticky
+
+import (
+	"context"
+	"time"
+)
+
+// beforeWrite calls the functions of WithBeforeWrite for each event. The
+// events are replaced by the results. Events, that become nil, are removed.
+func (s *Sticky[Model]) beforeWrite(ctx context.Context, events []Event[Model]) ([]Event[Model], error) {
+	if len(s.beforeWriteHooks) == 0 {
+		return events, nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for i := range events {
+		for _, hook := range s.beforeWriteHooks {
+			event, err := hook(ctx, events[i])
+			if err != nil {
+				return nil, err
+			}
+			events[i] = event
+			if event == nil {
+				break
+			}
+		}
+	}
+	return withoutNil(events), nil
+}
+
+// AppliedEvent is an event, that was written and applied to the model. See
+// WithAfterWrite.
+type AppliedEvent[Model any] struct {
+	Event   Event[Model]
+	Time    time.Time
+	Version uint64
+	Seq     uint64
+
+	// Correlation and Causation are the ids of the envelope. Give them to
+	// the events, that a hook writes, with WithCorrelation and
+	// WithCausation.
+	Correlation string
+	Causation   string
+}
+
+// afterWrite returns a function, that calls the functions of WithAfterWrite
+// with the written events, or nil if there are none. times are the times of
+// the events, model the model afterwards and opts the options of the write.
+//
+// Has to be called with the write lock, before the state is changed.
+func (s *Sticky[Model]) afterWrite(events []Event[Model], times []time.Time, model Model, opts writeOptions) func() {
+	if len(s.afterWriteHooks) == 0 {
+		return nil
+	}
+
+	applied := make([]AppliedEvent[Model], len(events))
+	for i, event := range events {
+		applied[i] = AppliedEvent[Model]{
+			Event:   event,
+			Time:    times[i],
+			Version: s.version + uint64(i) + 1,
+			Seq:     s.seq + uint64(i) + 1,
+
+			Correlation: opts.correlation,
+			Causation:   opts.causation,
+		}
+	}
+
+	return func() {
+		for _, hook := range s.afterWriteHooks {
+			hook(applied, model)
+		}
+	}
+}
+
+// runAfterWrite calls the function from afterWrite. With group commit, it is
+// called after the sync of the group and not at all, if the sync fails.
+//
+// Has to be called with the write lock, after the state is changed.
+func (s *Sticky[Model]) runAfterWrite(call func(), group *commitGroup[Model]) {
+	if call == nil {
+		return
+	}
+
+	switch {
+	case s.afterWriteUnlocked && group != nil:
+		// The writer waits for the group, before it calls the pending
+		// functions.
+		s.pendingAfterWrite = append(s.pendingAfterWrite, func() {
+			if group.wait() == nil {
+				call()
+			}
+		})
+	case s.afterWriteUnlocked:
+		s.pendingAfterWrite = append(s.pendingAfterWrite, call)
+	case group != nil:
+		group.afterWrite = append(group.afterWrite, call)
+	default:
+		call()
+	}
+}
+
+// takeAfterWrite returns the functions of WithAfterWrite, that have to be
+// called after the lock is released.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) takeAfterWrite() []func() {
+	pending := s.pendingAfterWrite
+	s.pendingAfterWrite = nil
+	return pending
+}
+
+// unlockWrite releases the write lock of ForWriting and calls the functions
+// of WithAfterWrite afterwards.
+func (s *Sticky[Model]) unlockWrite() {
+	pending := s.takeAfterWrite()
+	s.mu.Unlock()
+
+	for _, call := range pending {
+		call()
+	}
+}
@@ -0,0 +1,256 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type factorKey struct{}
+
+func TestWithBeforeWrite(t *testing.T) {
+	errRejected := errors.New("rejected")
+	db := NewMemoryDB()
+	s, err := New(
+		db,
+		testModel{},
+		getTestEvent,
+		WithBeforeWrite(func(ctx context.Context, e Event[testModel]) (Event[testModel], error) {
+			event := e.(addEvent)
+			if event.Value == 13 {
+				return nil, errRejected
+			}
+
+			if factor, ok := ctx.Value(factorKey{}).(int); ok {
+				event.Value *= factor
+			}
+			return event, nil
+		}),
+		WithBeforeWrite(func(_ context.Context, e Event[testModel]) (Event[testModel], error) {
+			event := e.(addEvent)
+			event.Value++
+			return event, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), factorKey{}, 10)
+	if err := s.WriteCtx(ctx, func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 13} }); !errors.Is(err, errRejected) {
+		t.Errorf("got error `%v`, expected the error of the hook", err)
+	}
+
+	records := db.Records()
+	if len(records) != 1 {
+		t.Fatalf("got %d records, expected 1", len(records))
+	}
+
+	if record := string(records[0]); !strings.Contains(record, `"value":11`) {
+		t.Errorf("record `%s` does not contain the value of the hooks", record)
+	}
+}
+
+func TestWithAfterWrite(t *testing.T) {
+	var got []AppliedEvent[testModel]
+	var gotModel testModel
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithAfterWrite(func(events []AppliedEvent[testModel], model testModel) {
+		got = append(got, events...)
+		gotModel = model
+	}))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	err = s.WriteMany(func(testModel) ([]Event[testModel], error) {
+		return []Event[testModel]{addEvent{Value: 2}, addEvent{Value: 3}}, nil
+	})
+	if err != nil {
+		t.Fatalf("write many: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("got %d applied events, expected 3", len(got))
+	}
+
+	for i, applied := range got {
+		if applied.Version != uint64(i+1) || applied.Seq != uint64(i+1) {
+			t.Errorf("event %d has version %d and seq %d, expected %d", i, applied.Version, applied.Seq, i+1)
+		}
+
+		if event := applied.Event.(addEvent); event.Value != i+1 {
+			t.Errorf("event %d has value %d, expected %d", i, event.Value, i+1)
+		}
+
+		if applied.Time.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+	}
+
+	if gotModel.Sum != 6 {
+		t.Errorf("got model with sum %d, expected 6", gotModel.Sum)
+	}
+}
+
+func TestWithAfterWrite_unlocked(t *testing.T) {
+	var s *Sticky[testModel]
+	called := make(chan struct{}, 1)
+	s, err := New(
+		NewMemoryDB(),
+		testModel{},
+		getTestEvent,
+		WithAfterWriteUnlocked[testModel](),
+		WithAfterWrite(func([]AppliedEvent[testModel], testModel) {
+			// The lock is released, so a write is possible.
+			if ok, err := s.TryWrite(func(testModel) Event[testModel] { return nil }); !ok || err != nil {
+				t.Errorf("try write returned %t, %v, expected the lock to be free", ok, err)
+			}
+			called <- struct{}{}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, write, done := s.ForWriting()
+	if err := write(addEvent{Value: 1}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	select {
+	case <-called:
+		t.Fatalf("hook was called before done")
+	default:
+	}
+
+	done()
+
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatalf("hook was not called after done")
+	}
+}
+
+func TestWithAfterWrite_panic(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithAfterWrite(func(events []AppliedEvent[testModel], _ testModel) {
+		if events[0].Event.(addEvent).Value == 13 {
+			panic("unlucky")
+		}
+	}))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p != "unlucky" {
+				t.Errorf("got panic `%v`, expected unlucky", p)
+			}
+		}()
+		s.Write(func(testModel) Event[testModel] { return addEvent{Value: 13} })
+	}()
+
+	if ok, err := s.TryWrite(func(testModel) Event[testModel] { return addEvent{Value: 1} }); !ok || err != nil {
+		t.Errorf("try write after panic returned %t, %v, expected the lock to be free", ok, err)
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 14 {
+		t.Errorf("got sum %d, expected 14", model.Sum)
+	}
+}
+
+func TestWithAfterWrite_group_commit(t *testing.T) {
+	for _, unlocked := range []bool{false, true} {
+		unlocked := unlocked
+		t.Run(fmt.Sprintf("unlocked %t", unlocked), func(t *testing.T) {
+			db := &groupSyncDB{MemoryDB: NewMemoryDB()}
+
+			var syncsAtHook []int
+			options := []Option[cloneModel]{WithAfterWrite(func([]AppliedEvent[cloneModel], cloneModel) {
+				syncsAtHook = append(syncsAtHook, db.syncs)
+			})}
+			if unlocked {
+				options = append(options, WithAfterWriteUnlocked[cloneModel]())
+			}
+			s := newGroupSticky(t, db, time.Hour, 1, options...)
+
+			if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} }); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			db.err = errors.New("disk full")
+			if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} }); !errors.Is(err, db.err) {
+				t.Fatalf("write returned `%v`, expected the sync error", err)
+			}
+
+			if len(syncsAtHook) != 1 || syncsAtHook[0] != 1 {
+				t.Errorf("hook was called after %v syncs, expected one call after the first sync", syncsAtHook)
+			}
+		})
+	}
+}
+
+func TestWithAfterWrite_async(t *testing.T) {
+	db := &gateDB{MemoryDB: NewMemoryDB(), gate: make(chan error)}
+	called := make(chan int, 2)
+	s, err := New(db, testModel{}, getTestEvent, WithAfterWrite(func(events []AppliedEvent[testModel], _ testModel) {
+		called <- len(db.Records())
+	}))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	result := s.WriteAsync(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+
+	select {
+	case <-called:
+		t.Fatalf("hook was called before the append")
+	default:
+	}
+
+	db.gate <- nil
+	if err := <-result; err != nil {
+		t.Fatalf("async write: %v", err)
+	}
+
+	select {
+	case records := <-called:
+		if records != 1 {
+			t.Errorf("hook was called with %d records in the database, expected 1", records)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("hook was not called after the append")
+	}
+
+	appendErr := errors.New("disk full")
+	result = s.WriteAsync(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+	db.gate <- appendErr
+	if err := <-result; !errors.Is(err, appendErr) {
+		t.Fatalf("async write returned `%v`, expected the append error", err)
+	}
+
+	if err := s.Close(); err == nil {
+		t.Errorf("close returned no error, expected the append error")
+	}
+
+	select {
+	case <-called:
+		t.Errorf("hook was called for the failed append")
+	default:
+	}
+}
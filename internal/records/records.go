@@ -0,0 +1,23 @@
+// Package records contains helpers for the databases of sticky, that read
+// records from an io.Reader.
+package records
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// Next returns the next non empty line from r without the newline. It
+// returns io.EOF, when there are no more lines.
+func Next(r *bufio.Reader) ([]byte, error) {
+	for {
+		line, err := r.ReadBytes('\n')
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
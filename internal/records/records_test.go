@@ -0,0 +1,28 @@
+package records
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNext(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader("first\n\n\nsecond\nlast"))
+
+	var got []string
+	for {
+		record, err := Next(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		got = append(got, string(record))
+	}
+
+	if strings.Join(got, ",") != "first,second,last" {
+		t.Errorf("got records %q, expected first, second and last", got)
+	}
+}
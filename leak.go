@@ -0,0 +1,26 @@
+package sticky
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// watchLock returns a done function, that calls done. With
+// WithLockLeakDetection, onLeak is called, if the returned function is not
+// called in the threshold.
+func (s *Sticky[Model]) watchLock(done func()) func() {
+	if s.leakThreshold <= 0 || s.onLeak == nil {
+		return done
+	}
+
+	stack := debug.Stack()
+	start := time.Now()
+	timer := time.AfterFunc(s.leakThreshold, func() {
+		s.onLeak(stack, time.Since(start))
+	})
+
+	return func() {
+		timer.Stop()
+		done()
+	}
+}
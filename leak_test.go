@@ -0,0 +1,46 @@
+package sticky
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithLockLeakDetection(t *testing.T) {
+	leaked := make(chan []byte, 1)
+	s, err := New(
+		NewMemoryDB(),
+		testModel{},
+		getTestEvent,
+		WithLockLeakDetection[testModel](10*time.Millisecond, func(stack []byte, heldFor time.Duration) {
+			if heldFor < 10*time.Millisecond {
+				t.Errorf("got heldFor `%s`, expected at least 10ms", heldFor)
+			}
+			leaked <- stack
+		}),
+	)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, done := s.ForReading()
+	done()
+
+	select {
+	case <-leaked:
+		t.Fatalf("onLeak was called for a released lock")
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	_, done = s.ForReading()
+	defer done()
+
+	select {
+	case stack := <-leaked:
+		if !strings.Contains(string(stack), "TestWithLockLeakDetection") {
+			t.Errorf("stack does not contain the caller:\n%s", stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("onLeak was not called")
+	}
+}
@@ -0,0 +1,610 @@
+package sticky
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// defaultMaxEventSize is the default for WithMaxEventSize.
+const defaultMaxEventSize = 1 << 20
+
+// defaultMaxPayloadDepth is the default for WithMaxPayloadDepth.
+const defaultMaxPayloadDepth = 100
+
+// loader builds a model from the events of a database.
+type loader[Model any] struct {
+	getEvent     func(name string) Event[Model]
+	recoverTail  bool
+	maxEventSize int
+
+	// maxPayloadDepth is set by WithMaxPayloadDepth. Zero means no limit.
+	maxPayloadDepth int
+
+	onLoad func(event Event[Model], meta map[string]string)
+
+	// onExecuted is called after an event was executed with the envelope
+	// and the sequence number of the record. With skipExecute, the events are
+	// only decoded. With newEvents, the handlers are called like for written
+	// events. They are used by the follower.
+	onExecuted  func(e Envelope, event Event[Model], t time.Time, seq uint64)
+	skipExecute bool
+	newEvents   bool
+
+	// aliases maps old event names to the current names.
+	aliases map[string]string
+
+	// onExecutionError is set by WithExecutionErrorSkip.
+	onExecutionError func(event Event[Model], err error)
+
+	// upcasters transform payloads of old schema versions.
+	upcasters map[upcastKey]func(json.RawMessage) (json.RawMessage, error)
+
+	// unknownEvents is set by WithUnknownEvents. unknownCount is the number
+	// of ignored records and unknown the preserved ones.
+	unknownEvents UnknownEvents
+	unknownCount  int
+	unknown       []Envelope
+
+	// droppedTail is set after load, when the last line could not be
+	// decoded and recoverTail is true. droppedBytes is the number of bytes
+	// of the dropped line, including newlines.
+	droppedTail  []byte
+	droppedBytes int64
+
+	// lastTime is the latest time of the loaded events.
+	lastTime time.Time
+
+	// timeLayout is set by WithTimeFormat. Empty means the default format.
+	timeLayout string
+
+	// eventsSinceSnapshot is the number of events after the last snapshot.
+	// lastSnapshot is the time of the last snapshot or zero, if there is no
+	// snapshot.
+	eventsSinceSnapshot int
+	lastSnapshot        time.Time
+
+	// records is the number of loaded records including the skipped ones.
+	records uint64
+
+	// version is the number of events, that are applied to the model.
+	version uint64
+
+	// seq is the sequence number of the last record. seqKnown is false at
+	// the beginning of the database, so the first record can have any
+	// sequence number. strictSeq and onSeqWarning are set by the options.
+	seq          uint64
+	seqKnown     bool
+	strictSeq    bool
+	onSeqWarning func(error)
+
+	// projections are applied after each event. onProjectionError is set by
+	// WithProjectionError.
+	projections       []namedProjection[Model]
+	onProjectionError func(name string, err error)
+
+	// handlers are set by WithHandler and onHandlerPanic by
+	// WithHandlerPanic.
+	handlers       map[string][]eventHandler[Model]
+	onHandlerPanic func(name string, recovered any)
+
+	// maxRecords stops the load after this number of records, if it is not
+	// 0. stop is called before a record is applied. If it returns true, the
+	// load stops. Both are used for replays of the past.
+	maxRecords uint64
+	stop       func(e Envelope) (bool, error)
+
+	// stats are the numbers of the load. See LoadStats.
+	stats LoadStats
+
+	// logger is set by WithLogger.
+	logger *slog.Logger
+
+	// shredder is set by WithShredding.
+	shredder *shredder
+
+	// progress is set during New with WithLoadProgress.
+	progress *loadProgress
+}
+
+// replayLoader returns a copy of the loader with its configuration but
+// without the state of the load and without the load hook.
+func (l *loader[Model]) replayLoader() *loader[Model] {
+	return &loader[Model]{
+		getEvent:         l.getEvent,
+		recoverTail:      l.recoverTail,
+		maxEventSize:     l.maxEventSize,
+		maxPayloadDepth:  l.maxPayloadDepth,
+		aliases:          l.aliases,
+		onExecutionError: l.onExecutionError,
+		upcasters:        l.upcasters,
+		unknownEvents:    l.unknownEvents,
+		timeLayout:       l.timeLayout,
+		strictSeq:        l.strictSeq,
+		onSeqWarning:     l.onSeqWarning,
+		shredder:         l.shredder,
+	}
+}
+
+// newRecordScanner returns a scanner for the lines of a database. If
+// lineBytes is not nil, it is set to the size of the last line including the
+// newline.
+func newRecordScanner(r io.Reader, maxEventSize int, lineBytes *int64) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+
+	// A line can have a \r\n at the end.
+	scanner.Buffer(nil, maxEventSize+2)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if lineBytes != nil {
+			*lineBytes = int64(advance)
+		}
+		return advance, token, err
+	})
+	return scanner
+}
+
+// scanError wraps an error of a record scanner.
+func scanError(err error, maxEventSize int) error {
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("scanning events: event is bigger then %d bytes, use WithMaxEventSize: %w", maxEventSize, err)
+	}
+	return StorageError{Op: "scanning events", Err: err}
+}
+
+// LoadError happens, when a record of the database can not be loaded. Line is
+// the line number, starting at 1, and Offset the byte offset of the start of
+// the line. Record is the number of the record without empty lines. It is 0,
+// if the record could not be decoded.
+//
+// The numbers count from the start of the reader. If the model is loaded from
+// a snapshot and the database supports reading after it, they count from the
+// snapshot.
+type LoadError struct {
+	Line   int
+	Offset int64
+	Record uint64
+	Err    error
+}
+
+func (err LoadError) Error() string {
+	if err.Record == 0 {
+		return fmt.Sprintf("line %d, byte %d: %v", err.Line, err.Offset, err.Err)
+	}
+	return fmt.Sprintf("record %d, line %d, byte %d: %v", err.Record, err.Line, err.Offset, err.Err)
+}
+
+func (err LoadError) Unwrap() error {
+	return err.Err
+}
+
+// loadBatchSize is the number of records, that the decoding stage of load
+// sends at once.
+const loadBatchSize = 256
+
+// loadRecord is a line of the database, that was decoded by the first stage
+// of load.
+type loadRecord[Model any] struct {
+	line      int
+	offset    int64
+	lineBytes int64
+	empty     bool
+	skipped   bool
+
+	// raw is only set, if the envelope could not be decoded.
+	raw         []byte
+	envelope    Envelope
+	envelopeErr error
+}
+
+// loadError returns a LoadError of the record with the number n.
+func (r loadRecord[Model]) loadError(n uint64, err error) error {
+	return LoadError{Line: r.line, Offset: r.offset, Record: n, Err: err}
+}
+
+// loadBatch are records from the first stage of load. err is the error of the
+// scanner and line and offset the position after the last record.
+type loadBatch[Model any] struct {
+	records []loadRecord[Model]
+	err     error
+	line    int
+	offset  int64
+}
+
+// decodeRecords is the first stage of load. It scans the lines from r and
+// decodes the envelopes. The first skip records are not decoded.
+//
+// It stops, when r is read or done is closed. Afterwards, batches is closed.
+func (l *loader[Model]) decodeRecords(r io.Reader, skip uint64, batches chan<- loadBatch[Model], done <-chan struct{}) {
+	defer close(batches)
+
+	var lineBytes int64
+	scanner := newRecordScanner(r, l.maxEventSize, &lineBytes)
+
+	send := func(batch loadBatch[Model]) bool {
+		select {
+		case batches <- batch:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	var lineNo int
+	var offset int64
+	var nonEmpty uint64
+	records := make([]loadRecord[Model], 0, loadBatchSize)
+	for scanner.Scan() {
+		lineNo++
+		record := loadRecord[Model]{line: lineNo, offset: offset, lineBytes: lineBytes}
+		offset += lineBytes
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		switch {
+		case len(line) == 0:
+			record.empty = true
+
+		case nonEmpty < skip:
+			nonEmpty++
+			record.skipped = true
+
+		default:
+			nonEmpty++
+			record.envelope, record.envelopeErr = DecodeEnvelope(line)
+			if record.envelopeErr != nil {
+				record.raw = bytes.Clone(line)
+			}
+		}
+
+		records = append(records, record)
+		if len(records) == loadBatchSize {
+			if !send(loadBatch[Model]{records: records}) {
+				return
+			}
+			records = make([]loadRecord[Model], 0, loadBatchSize)
+		}
+	}
+
+	send(loadBatch[Model]{records: records, err: scanner.Err(), line: lineNo + 1, offset: offset})
+}
+
+// load applies the events from r to the model.
+//
+// The first skip records are not decoded. Empty lines are not counted.
+//
+// The lines are scanned and the envelopes are decoded in a second goroutine.
+// The events are created with getEvent, decoded and executed in the order of
+// the database, so getEvent can return the same instance for each call.
+func (l *loader[Model]) load(r io.Reader, model Model, skip uint64) (Model, error) {
+	batches := make(chan loadBatch[Model], 4)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		l.decodeRecords(r, skip, batches, done)
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	return l.executeRecords(batches, model)
+}
+
+// executeRecords is the second stage of load. It creates the events of the
+// records from batches and executes them.
+func (l *loader[Model]) executeRecords(batches <-chan loadBatch[Model], model Model) (Model, error) {
+	var zero Model
+
+	// brokenLine is a line, that could not be decoded. It is only an error,
+	// if there is another line after it.
+	var brokenLine []byte
+	var brokenErr error
+	var brokenBytes int64
+
+	for batch := range batches {
+		for _, record := range batch.records {
+			if record.empty {
+				brokenBytes += record.lineBytes
+				continue
+			}
+
+			if brokenErr != nil {
+				return zero, brokenErr
+			}
+
+			if l.maxRecords > 0 && l.records >= l.maxRecords {
+				l.finishLoad(brokenLine, brokenBytes)
+				return model, nil
+			}
+
+			l.records++
+			if record.skipped {
+				l.seq = l.records
+				continue
+			}
+
+			envelope := record.envelope
+			if err := record.envelopeErr; err != nil {
+				brokenErr = LoadError{Line: record.line, Offset: record.offset, Err: err}
+				if !l.recoverTail || errors.Is(err, errUnknownFormat) {
+					return zero, brokenErr
+				}
+				brokenLine = record.raw
+				brokenBytes = record.lineBytes
+				l.records--
+				continue
+			}
+
+			if l.stop != nil {
+				stop, err := l.stop(envelope)
+				if err != nil {
+					return zero, err
+				}
+				if stop {
+					l.finishLoad(brokenLine, brokenBytes)
+					return model, nil
+				}
+			}
+
+			if err := l.checkSeq(envelope); err != nil {
+				return zero, record.loadError(l.records, err)
+			}
+
+			if envelope.Type == snapshotType {
+				var err error
+				if model, err = l.applySnapshot(model, envelope); err != nil {
+					return zero, record.loadError(l.records, err)
+				}
+
+				l.eventsSinceSnapshot = 0
+				l.lastSnapshot, _ = envelope.parseTime(l.timeLayout)
+				l.version = envelope.Version
+				continue
+			}
+
+			event, err := l.decodePayload(envelope)
+			var eventTime time.Time
+			if err == nil {
+				eventTime, err = envelope.parseTime(l.timeLayout)
+			}
+			if err != nil {
+				if l.skipUnknown(envelope, err) {
+					l.stats.count(envelope.Type, time.Time{})
+					l.eventsSinceSnapshot++
+					l.version++
+					continue
+				}
+				return zero, record.loadError(l.records, err)
+			}
+
+			if eventTime.After(l.lastTime) {
+				l.lastTime = eventTime
+			}
+			l.stats.count(envelope.Type, eventTime)
+
+			if l.onLoad != nil {
+				l.onLoad(event, envelope.Meta)
+			}
+
+			if l.skipExecute {
+				l.version++
+				continue
+			}
+
+			if model, err = l.execute(event, l.currentName(envelope.Type), model, eventTime); err != nil {
+				return zero, record.loadError(l.records, err)
+			}
+			if l.onExecuted != nil {
+				l.onExecuted(envelope, event, eventTime, l.seq)
+			}
+			l.applyProjections(l.currentName(envelope.Type), event, eventTime)
+			l.callHandlers(l.currentName(envelope.Type), event, eventTime, model, !l.newEvents)
+			l.eventsSinceSnapshot++
+			l.version++
+
+			if l.progress != nil {
+				l.progress.event()
+			}
+
+			if l.logger != nil && l.stats.Events%1000 == 0 {
+				l.logger.Debug("loading events", "events", l.stats.Events, "records", l.records)
+			}
+		}
+
+		if batch.err != nil {
+			return zero, LoadError{Line: batch.line, Offset: batch.offset, Err: scanError(batch.err, l.maxEventSize)}
+		}
+	}
+
+	l.finishLoad(brokenLine, brokenBytes)
+	return model, nil
+}
+
+// finishLoad remembers the broken last line, so it can be removed from the
+// database.
+func (l *loader[Model]) finishLoad(brokenLine []byte, brokenBytes int64) {
+	if brokenLine != nil {
+		l.droppedTail = brokenLine
+		l.droppedBytes = brokenBytes
+	}
+}
+
+// checkSeq checks the sequence number of a record and sets it as the last
+// one. A record without a sequence number gets the next number.
+//
+// Per default, it is an error, if the number is smaller then the previous
+// one. With strictSeq, it also is an error, if a number is skipped or used
+// twice.
+func (l *loader[Model]) checkSeq(e Envelope) error {
+	if e.Seq == 0 {
+		l.seq++
+		l.seqKnown = true
+		return nil
+	}
+
+	var err error
+	switch {
+	case !l.seqKnown:
+	case e.Seq < l.seq:
+		err = fmt.Errorf("record %d has sequence number %d after %d", l.records, e.Seq, l.seq)
+	case l.strictSeq && e.Seq != l.seq+1:
+		err = fmt.Errorf("record %d has sequence number %d, expected %d", l.records, e.Seq, l.seq+1)
+	}
+
+	if err != nil {
+		if l.onSeqWarning == nil {
+			return err
+		}
+		l.onSeqWarning(err)
+	}
+
+	l.seq = e.Seq
+	l.seqKnown = true
+	return nil
+}
+
+// apply executes the event of an envelope on the model. A snapshot record
+// replaces the model.
+func (l *loader[Model]) apply(model Model, e Envelope) (Model, error) {
+	if e.Type == snapshotType {
+		return l.applySnapshot(model, e)
+	}
+
+	event, eventTime, err := l.decodeEvent(e)
+	if err != nil {
+		return model, err
+	}
+	return l.execute(event, l.currentName(e.Type), model, eventTime)
+}
+
+// execute executes a loaded event with the given name. If it can not be
+// executed and onExecutionError is set, the event is skipped.
+func (l *loader[Model]) execute(event Event[Model], name string, model Model, t time.Time) (Model, error) {
+	newModel, err := executeEvent(event, name, model, t)
+	if err != nil {
+		if l.onExecutionError == nil {
+			return model, err
+		}
+		if l.logger != nil {
+			l.logger.Warn("event can not be executed and is skipped", "event", name, "error", err)
+		}
+		l.onExecutionError(event, err)
+		return model, nil
+	}
+	return newModel, nil
+}
+
+// checkAliases returns an error, if an alias points to an unknown event.
+func (l *loader[Model]) checkAliases() error {
+	for old, current := range l.aliases {
+		if l.getEvent(current) == nil {
+			return fmt.Errorf("event alias `%s` points to unknown event `%s`", old, current)
+		}
+	}
+	return nil
+}
+
+// currentName returns the name of an event. It is different from the name in
+// the database, if the event was renamed. See WithEventAlias.
+func (l *loader[Model]) currentName(name string) string {
+	if current, ok := l.aliases[name]; ok {
+		return current
+	}
+	return name
+}
+
+// decodeEvent creates the event of an envelope and parses its time.
+func (l *loader[Model]) decodeEvent(e Envelope) (Event[Model], time.Time, error) {
+	event, err := l.decodePayload(e)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	eventTime, err := e.parseTime(l.timeLayout)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return event, eventTime, nil
+}
+
+// decodePayload creates the event of an envelope.
+func (l *loader[Model]) decodePayload(e Envelope) (Event[Model], error) {
+	name := l.currentName(e.Type)
+	event := l.getEvent(name)
+	if event == nil {
+		return nil, fmt.Errorf("%w `%s`, payload `%s`", errUnknownEvent, e.Type, e.Payload)
+	}
+
+	payload := e.Payload
+	if len(e.Encrypted) > 0 {
+		if l.shredder == nil {
+			return nil, fmt.Errorf("event `%s` has encrypted fields, use WithShredding: %w", e.Type, ErrNotSupported)
+		}
+
+		var err error
+		if payload, err = l.shredder.decrypt(e); err != nil {
+			return nil, fmt.Errorf("decrypting event `%s`: %w", e.Type, err)
+		}
+	}
+
+	if current := schemaVersion(event); e.Schema != current {
+		var err error
+		if payload, err = l.upcast(name, payload, e.Schema, current); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.maxPayloadDepth > 0 {
+		if err := checkDepth(payload, l.maxPayloadDepth); err != nil {
+			return nil, fmt.Errorf("loading event `%s`: %w", e.Type, err)
+		}
+	}
+
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("loading event `%s`: %w", e.Type, err)
+	}
+
+	// A null payload sets the event to nil.
+	if event == nil {
+		return nil, fmt.Errorf("loading event `%s`: payload is null", e.Type)
+	}
+	return event, nil
+}
+
+// checkDepth returns an error, if objects and arrays in the JSON value are
+// nested deeper then max. It does not validate the JSON.
+func checkDepth(value []byte, max int) error {
+	depth := 0
+	inString := false
+	for i := 0; i < len(value); i++ {
+		if inString {
+			switch value[i] {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch value[i] {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > max {
+				return fmt.Errorf("payload is nested deeper then %d levels, use WithMaxPayloadDepth", max)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package sticky
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLoad_error_has_line(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		record string
+	}{
+		{"broken envelope", `not json`},
+		{"broken payload", `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":"x"}}`},
+		{"broken time", `{"time":"yesterday","type":"add","payload":{"value":1}}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			content := strings.Join([]string{
+				`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+				``,
+				tt.record,
+				`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+			}, "\n")
+
+			l := loader[testModel]{getEvent: getTestEvent, maxEventSize: defaultMaxEventSize}
+			_, err := l.load(strings.NewReader(content), testModel{}, 0)
+			if err == nil || !strings.Contains(err.Error(), "line 3") {
+				t.Errorf("got error `%v`, expected it to name line 3", err)
+			}
+
+			// The first line and the empty line with their newlines.
+			offset := int64(len(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`) + 2)
+
+			var loadErr LoadError
+			if !errors.As(err, &loadErr) {
+				t.Fatalf("got error `%v`, expected a LoadError", err)
+			}
+
+			if loadErr.Line != 3 || loadErr.Offset != offset {
+				t.Errorf("got line %d at byte %d, expected line 3 at byte %d", loadErr.Line, loadErr.Offset, offset)
+			}
+		})
+	}
+}
+
+func TestLoad_scanner_error_has_offset(t *testing.T) {
+	first := `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`
+	content := first + "\n" + strings.Repeat("x", 200) + "\n"
+
+	l := loader[testModel]{getEvent: getTestEvent, maxEventSize: 100}
+	_, err := l.load(strings.NewReader(content), testModel{}, 0)
+
+	var loadErr LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("got error `%v`, expected a LoadError", err)
+	}
+
+	if loadErr.Line != 2 || loadErr.Offset != int64(len(first)+1) || loadErr.Record != 0 {
+		t.Errorf("got %+v, expected line 2 at byte %d", loadErr, len(first)+1)
+	}
+}
+
+func TestLoad_many_batches(t *testing.T) {
+	db := NewMemoryDB()
+	for i := 0; i < 3*loadBatchSize+1; i++ {
+		if err := db.Append([]byte(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 3*loadBatchSize+1 {
+		t.Errorf("got sum %d, expected %d", model.Sum, 3*loadBatchSize+1)
+	}
+}
+
+func TestLoad_shared_event_instance(t *testing.T) {
+	records := make([]string, 2000)
+	for i := range records {
+		records[i] = fmt.Sprintf(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":%d}}`, i+1)
+	}
+
+	// getEvent returns the same instance for each call.
+	shared := &addEvent{}
+	getEvent := func(name string) Event[testModel] {
+		if name != "add" {
+			return nil
+		}
+		return shared
+	}
+
+	s, err := New(NewMemoryDB(records...), testModel{}, getEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 2001000 {
+		t.Errorf("got sum %d, expected 2001000", model.Sum)
+	}
+}
+
+// benchmarkDB returns a database with n events.
+func benchmarkDB(b *testing.B, n int) *MemoryDB {
+	b.Helper()
+
+	records := make([]string, n)
+	for i := range records {
+		records[i] = fmt.Sprintf(`{"time":"2024-01-01 00:00:00","type":"add","version":%d,"seq":%d,"payload":{"value":%d}}`, i+1, i+1, i%100)
+	}
+	return NewMemoryDB(records...)
+}
+
+func TestWithLoadProgress(t *testing.T) {
+	db := NewMemoryDB()
+	var size int64
+	for i := 0; i < 2*loadProgressEvery+1; i++ {
+		record := []byte(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)
+		if err := db.Append(record); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		size += int64(len(record)) + 1
+	}
+
+	type progress struct {
+		events int
+		bytes  int64
+	}
+	var calls []progress
+	_, err := New(db, testModel{}, getTestEvent, WithLoadProgress[testModel](func(events int, bytes int64) {
+		calls = append(calls, progress{events, bytes})
+	}))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if len(calls) < 3 {
+		t.Fatalf("got %d calls, expected at least 3", len(calls))
+	}
+
+	for i := 1; i < len(calls); i++ {
+		if calls[i].events < calls[i-1].events || calls[i].bytes < calls[i-1].bytes {
+			t.Errorf("call %d with %v is before call %d with %v", i, calls[i], i-1, calls[i-1])
+		}
+	}
+
+	if last := calls[len(calls)-1]; last.events != 2*loadProgressEvery+1 || last.bytes != size {
+		t.Errorf("got last call %v, expected %d events and %d bytes", last, 2*loadProgressEvery+1, size)
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	db := benchmarkDB(b, 100_000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := New(db, testModel{}, getTestEvent); err != nil {
+			b.Fatalf("creating sticky: %v", err)
+		}
+	}
+}
+
+// BenchmarkLoad compares load with running both stages of the pipeline one
+// after the other. The difference only shows with more then one CPU.
+func BenchmarkLoad(b *testing.B) {
+	db := benchmarkDB(b, 100_000)
+
+	b.Run("pipeline", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r, err := db.Reader()
+			if err != nil {
+				b.Fatalf("reader: %v", err)
+			}
+
+			l := loader[testModel]{getEvent: getTestEvent, maxEventSize: defaultMaxEventSize}
+			if _, err := l.load(r, testModel{}, 0); err != nil {
+				b.Fatalf("load: %v", err)
+			}
+			r.Close()
+		}
+	})
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r, err := db.Reader()
+			if err != nil {
+				b.Fatalf("reader: %v", err)
+			}
+
+			l := loader[testModel]{getEvent: getTestEvent, maxEventSize: defaultMaxEventSize}
+			batches := make(chan loadBatch[testModel], 100_000/loadBatchSize+1)
+			l.decodeRecords(r, 0, batches, nil)
+			if _, err := l.executeRecords(batches, testModel{}); err != nil {
+				b.Fatalf("load: %v", err)
+			}
+			r.Close()
+		}
+	})
+}
+
+func FuzzLoadModel(f *testing.F) {
+	for _, seed := range []string{
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"v":1,"time":"2024-01-01 00:00:00","type":"add","version":1,"seq":1,"payload":{"value":1}}` + "\n" +
+			`{"v":1,"time":"2024-01-01 00:00:01","type":"add","version":2,"seq":2,"payload":{"value":2}}`,
+		`{"time":"2024-01-01T00:00:00.123456789+02:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","type":"other","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1,"value":-1}}`,
+		`{"time":"9999-12-31 23:59:59","type":"add","payload":{"value":1}}`,
+		`{"time":"-0001-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"","type":"","payload":null}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":null}`,
+		`{"type":"add"}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":[[[[[[[[[[1]]]]]]]]]]}}`,
+		`{"value":1}`,
+		`[{"value":"]"}]`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":"\xff\xfe"}}`,
+		`{"time":"2024-01-01 00:00:00","type":"$snapshot","seq":1,"payload":{"Sum":1}}`,
+		`{"v":2,"time":"2024-01-01 00:00:00","type":"add","subject":"s","encrypted":["value"],"payload":{"value":"00000000:AAAA"}}`,
+		`{"v":99,"time":"2024-01-01 00:00:00","type":"add","payload":{}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","seq":18446744073709551615,"payload":{"value":1}}` + "\n" +
+			`{"time":"2024-01-01 00:00:00","type":"add","seq":1,"payload":{"value":1}}`,
+		"\n\n \t\n",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// The data is used as the database and as the payload of an event.
+		for _, db := range []*MemoryDB{
+			NewMemoryDB(strings.Split(string(data), "\n")...),
+			NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":` + string(data) + `}`),
+		} {
+			s, err := New(db, testModel{}, getTestEvent, WithMaxEventSize[testModel](1<<12), WithMaxPayloadDepth[testModel](8))
+			if err != nil {
+				continue
+			}
+			s.Close()
+		}
+	})
+}
+
+func TestLoad_malformed_records(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		record    string
+		expectSum int
+		expectErr string
+	}{
+		{"huge line", `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1,"x":"` + strings.Repeat("x", 5000) + `"}}`, 0, "use WithMaxEventSize"},
+		{"deep payload", `{"time":"2024-01-01 00:00:00","type":"add","payload":{"x":` + strings.Repeat("[", 20) + strings.Repeat("]", 20) + `}}`, 0, "use WithMaxPayloadDepth"},
+		{"brackets in strings", `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1,"x":"[[[[[[[[[[[[\"[[[[["}}`, 1, ""},
+		{"invalid utf-8", "{\"time\":\"2024-01-01 00:00:00\",\"type\":\"add\",\"payload\":{\"value\":1,\"x\":\"\xff\"}}", 1, ""},
+		{"duplicate keys", `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1,"value":2}}`, 2, ""},
+		{"missing time", `{"type":"add","payload":{"value":1}}`, 0, "invalid time"},
+		{"missing type", `{"time":"2024-01-01 00:00:00","payload":{"value":1}}`, 0, "unknown event"},
+		{"missing payload", `{"time":"2024-01-01 00:00:00","type":"add"}`, 0, "loading event"},
+		{"null payload", `{"time":"2024-01-01 00:00:00","type":"add","payload":null}`, 0, "loading event"},
+		{"absurd time", `{"time":"99999-01-01 00:00:00","type":"add","payload":{"value":1}}`, 0, "invalid time"},
+		{"negative time", `{"time":"-2024-01-01 00:00:00","type":"add","payload":{"value":1}}`, 0, "invalid time"},
+		{"max time", `{"time":"9999-12-31 23:59:59","type":"add","payload":{"value":1}}`, 1, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(NewMemoryDB(tt.record), testModel{}, getTestEvent, WithMaxEventSize[testModel](1<<12), WithMaxPayloadDepth[testModel](8))
+
+			if tt.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+					t.Fatalf("got error `%v`, expected `%s`", err, tt.expectErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("creating sticky: %v", err)
+			}
+			defer s.Close()
+
+			model, done := s.ForReading()
+			done()
+
+			if model.Sum != tt.expectSum {
+				t.Errorf("got sum %d, expected %d", model.Sum, tt.expectSum)
+			}
+		})
+	}
+}
+
+func TestWithMaxPayloadDepth_zero_disables_the_limit(t *testing.T) {
+	record := `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1,"x":` + strings.Repeat("[", 200) + strings.Repeat("]", 200) + `}}`
+
+	if _, err := New(NewMemoryDB(record), testModel{}, getTestEvent); err == nil {
+		t.Fatalf("loading a payload deeper then the default did not return an error")
+	}
+
+	if _, err := New(NewMemoryDB(record), testModel{}, getTestEvent, WithMaxPayloadDepth[testModel](0)); err != nil {
+		t.Errorf("creating sticky without a depth limit: %v", err)
+	}
+}
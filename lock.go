@@ -0,0 +1,82 @@
+package sticky
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLocked is returned by the FileDB, when another process holds the lock of
+// the database.
+var ErrLocked = errors.New("database is locked by another process")
+
+// errWouldBlock is returned by tryLockFile, when the lock is held by someone
+// else.
+var errWouldBlock = errors.New("lock is held")
+
+// lockRetry is the interval to retry getting the lock.
+const lockRetry = 10 * time.Millisecond
+
+// lock takes the lock of the database, if Locking is enabled and it is not
+// already taken.
+//
+// Has to be called with the lock of the mutex.
+func (db *FileDB) lock() error {
+	if !db.Locking || db.lockFile != nil {
+		return nil
+	}
+
+	path := db.File + ".lock"
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil && db.ReadOnly {
+		f, err = os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// On a read only filesystem, the lock file can not be created.
+			// There can not be a writer.
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(db.LockTimeout)
+	for {
+		err := tryLockFile(f, !db.ReadOnly)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, errWouldBlock) {
+			f.Close()
+			return fmt.Errorf("lock file %s: %w", path, err)
+		}
+
+		if !time.Now().Before(deadline) {
+			f.Close()
+			return fmt.Errorf("lock file %s: %w", path, ErrLocked)
+		}
+		time.Sleep(lockRetry)
+	}
+
+	db.lockFile = f
+	return nil
+}
+
+// unlock releases the lock.
+//
+// Has to be called with the lock of the mutex.
+func (db *FileDB) unlock() error {
+	if db.lockFile == nil {
+		return nil
+	}
+
+	// Closing the file releases the lock.
+	err := db.lockFile.Close()
+	db.lockFile = nil
+	if err != nil {
+		return fmt.Errorf("closing lock file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package sticky
+
+import (
+	"errors"
+	"os"
+)
+
+func tryLockFile(f *os.File, exclusive bool) error {
+	return errors.New("file locking is not supported on this platform")
+}
@@ -0,0 +1,69 @@
+package sticky
+
+import (
+	"errors"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestDBFile_second_writer_is_locked(t *testing.T) {
+	file := path.Join(t.TempDir(), "file.db")
+
+	first := FileDB{File: file, Locking: true}
+	if err := first.Append([]byte("event")); err != nil {
+		t.Fatalf("append to first db: %v", err)
+	}
+	defer first.Close()
+
+	second := FileDB{File: file, Locking: true}
+	if _, err := second.Reader(); !errors.Is(err, ErrLocked) {
+		t.Errorf("second db returned `%v`, expected ErrLocked", err)
+	}
+}
+
+func TestDBFile_lock_timeout_waits_for_release(t *testing.T) {
+	file := path.Join(t.TempDir(), "file.db")
+
+	first := FileDB{File: file, Locking: true}
+	if err := first.Append([]byte("event")); err != nil {
+		t.Fatalf("append to first db: %v", err)
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() { first.Close() })
+
+	second := FileDB{File: file, Locking: true, LockTimeout: 5 * time.Second}
+	defer second.Close()
+	if err := second.Append([]byte("event")); err != nil {
+		t.Errorf("append to second db: %v", err)
+	}
+}
+
+func TestDBFile_read_only_uses_shared_lock(t *testing.T) {
+	file := path.Join(t.TempDir(), "file.db")
+
+	reader1 := FileDB{File: file, Locking: true, ReadOnly: true}
+	r, err := reader1.Reader()
+	if err != nil {
+		t.Fatalf("first reader: %v", err)
+	}
+	r.Close()
+	defer reader1.Close()
+
+	reader2 := FileDB{File: file, Locking: true, ReadOnly: true}
+	r, err = reader2.Reader()
+	if err != nil {
+		t.Fatalf("second reader: %v", err)
+	}
+	r.Close()
+	defer reader2.Close()
+
+	writer := FileDB{File: file, Locking: true}
+	if err := writer.Append([]byte("event")); !errors.Is(err, ErrLocked) {
+		t.Errorf("writer returned `%v`, expected ErrLocked", err)
+	}
+
+	if err := reader1.Append([]byte("event")); err == nil {
+		t.Errorf("append to read only db did not return an error")
+	}
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package sticky
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// The constants of LockFileEx from the windows API.
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+
+	errorLockViolation syscall.Errno = 33
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+func tryLockFile(f *os.File, exclusive bool) error {
+	var flags uint32 = lockfileFailImmediately
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+
+	var overlapped syscall.Overlapped
+	ok, _, err := procLockFileEx.Call(f.Fd(), uintptr(flags), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if ok != 0 {
+		return nil
+	}
+
+	if errors.Is(err, errorLockViolation) {
+		return errWouldBlock
+	}
+	return err
+}
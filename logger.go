@@ -0,0 +1,17 @@
+package sticky
+
+import "fmt"
+
+// initLogger adds the name of the model to the logger. It has to be called
+// after the empty model is set.
+func (s *Sticky[Model]) initLogger() {
+	if s.logger == nil {
+		return
+	}
+
+	if s.name == "" {
+		s.name = fmt.Sprintf("%T", s.emptyModel)
+	}
+	s.logger = s.logger.With("sticky", s.name)
+	s.loader.logger = s.logger
+}
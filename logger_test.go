@@ -0,0 +1,56 @@
+package sticky
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"unknown","payload":{}}`,
+	)
+
+	s, err := New(db, testModel{}, getTestEvent, WithLogger[testModel](logger), WithName[testModel]("counter"), WithUnknownEvents[testModel](UnknownEventsSkip))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expect := []string{"loading model", "unknown event skipped", "model loaded", "events written"}
+	if len(lines) != len(expect) {
+		t.Fatalf("got %d log lines, expected %d:\n%s", len(lines), len(expect), buf.String())
+	}
+
+	for i, line := range lines {
+		if !strings.Contains(line, `msg="`+expect[i]+`"`) {
+			t.Errorf("line %d `%s` does not contain the message `%s`", i, line, expect[i])
+		}
+
+		if !strings.Contains(line, "sticky=counter") {
+			t.Errorf("line %d `%s` does not contain the name", i, line)
+		}
+	}
+}
+
+func TestWithLogger_default_name(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	if _, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithLogger[testModel](logger)); err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "sticky=sticky.testModel") {
+		t.Errorf("got log `%s`, expected the type of the model as name", buf.String())
+	}
+}
@@ -0,0 +1,232 @@
+package sticky
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MergeOption is an option for MergeLogs.
+type MergeOption func(*mergeConfig)
+
+type mergeConfig struct {
+	dedup      bool
+	onConflict func(records []Envelope) error
+}
+
+// MergeDedup writes records with the same time, type, metadata and payload
+// only once. Their sequence numbers and versions are ignored.
+func MergeDedup() MergeOption {
+	return func(c *mergeConfig) {
+		c.dedup = true
+	}
+}
+
+// MergeOnConflict sets a function, that is called with the records of more
+// then one source, that have the same time. With MergeDedup, identical
+// records are removed before. The records are in the order, in which they
+// are written. If f returns an error, MergeLogs stops and returns it.
+func MergeOnConflict(f func(records []Envelope) error) MergeOption {
+	return func(c *mergeConfig) {
+		c.onConflict = f
+	}
+}
+
+// mergeSource is a source of MergeLogs with its next record.
+type mergeSource struct {
+	index   int
+	scanner *bufio.Scanner
+	records int
+
+	next     Envelope
+	nextTime time.Time
+	done     bool
+}
+
+// advance reads the next record of the source.
+func (src *mergeSource) advance() error {
+	for src.scanner.Scan() {
+		line := bytes.TrimSpace(src.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		src.records++
+
+		e, err := DecodeEnvelope(line)
+		if err != nil {
+			return fmt.Errorf("source %d, record %d: %w", src.index, src.records, err)
+		}
+
+		if e.Type == snapshotType {
+			return fmt.Errorf("source %d, record %d: logs with snapshots can not be merged: %w", src.index, src.records, ErrNotSupported)
+		}
+
+		t, err := e.ParseTime()
+		if err != nil {
+			return fmt.Errorf("source %d, record %d: %w", src.index, src.records, err)
+		}
+
+		src.next, src.nextTime = e, t
+		return nil
+	}
+
+	if err := src.scanner.Err(); err != nil {
+		return fmt.Errorf("source %d: %w", src.index, scanError(err, defaultMaxEventSize))
+	}
+	src.done = true
+	return nil
+}
+
+// MergeLogs merges the records of the sources by their time and appends them
+// to dst, that has to be empty. Otherwise ErrNotEmpty is returned. The
+// sources have the format of the reader of a database.
+//
+// The records of each source have to be ordered by time, like in a database.
+// Records with the same time are written in the order of the sources. The
+// merged records get new sequence numbers and versions. Sources with
+// snapshot records can not be merged.
+//
+// The records are appended in batches, while the sources are read. If an
+// error happens, dst can contain a part of the records.
+func MergeLogs(dst database, srcs []io.Reader, options ...MergeOption) error {
+	var cfg mergeConfig
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	empty, err := isEmpty(dst)
+	if err != nil {
+		return err
+	}
+
+	if !empty {
+		return ErrNotEmpty
+	}
+
+	sources := make([]*mergeSource, len(srcs))
+	for i, r := range srcs {
+		sources[i] = &mergeSource{index: i, scanner: newRecordScanner(r, defaultMaxEventSize, nil)}
+		if err := sources[i].advance(); err != nil {
+			return err
+		}
+	}
+
+	var seq uint64
+	var batch [][]byte
+	for {
+		group, err := nextMergeGroup(sources)
+		if err != nil {
+			return err
+		}
+
+		if len(group) == 0 {
+			break
+		}
+
+		if cfg.dedup {
+			group = dedupRecords(group)
+		}
+
+		if cfg.onConflict != nil && multipleSources(group) {
+			records := make([]Envelope, len(group))
+			for i, r := range group {
+				records[i] = r.envelope
+			}
+
+			if err := cfg.onConflict(records); err != nil {
+				return err
+			}
+		}
+
+		for _, r := range group {
+			seq++
+			e := r.envelope
+			e.Seq = seq
+			e.Version = seq
+
+			record, err := EncodeEnvelope(e)
+			if err != nil {
+				return err
+			}
+			batch = append(batch, record)
+		}
+
+		if len(batch) >= loadBatchSize {
+			if err := appendRecords(dst, batch); err != nil {
+				return fmt.Errorf("appending records: %w", err)
+			}
+			batch = nil
+		}
+	}
+
+	if err := appendRecords(dst, batch); err != nil {
+		return fmt.Errorf("appending records: %w", err)
+	}
+	return nil
+}
+
+// mergeRecord is a record of a source.
+type mergeRecord struct {
+	source   int
+	envelope Envelope
+}
+
+// nextMergeGroup returns all next records with the earliest time. It returns
+// nil, if all sources are done.
+func nextMergeGroup(sources []*mergeSource) ([]mergeRecord, error) {
+	var earliest time.Time
+	found := false
+	for _, src := range sources {
+		if !src.done && (!found || src.nextTime.Before(earliest)) {
+			earliest = src.nextTime
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	var group []mergeRecord
+	for _, src := range sources {
+		for !src.done && src.nextTime.Equal(earliest) {
+			group = append(group, mergeRecord{source: src.index, envelope: src.next})
+			if err := src.advance(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return group, nil
+}
+
+// dedupRecords removes records, that are identical to a record before them.
+func dedupRecords(group []mergeRecord) []mergeRecord {
+	seen := make(map[string]bool, len(group))
+	result := group[:0]
+	for _, r := range group {
+		e := r.envelope
+		e.Format, e.Seq, e.Version = 0, 0, 0
+		key, err := EncodeEnvelope(e)
+		if err == nil {
+			if seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// multipleSources returns true, if the records are from more then one
+// source.
+func multipleSources(group []mergeRecord) bool {
+	for _, r := range group[1:] {
+		if r.source != group[0].source {
+			return true
+		}
+	}
+	return false
+}
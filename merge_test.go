@@ -0,0 +1,117 @@
+package sticky
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func mergeSources(logs ...string) []io.Reader {
+	srcs := make([]io.Reader, len(logs))
+	for i, log := range logs {
+		srcs[i] = strings.NewReader(log)
+	}
+	return srcs
+}
+
+func TestMergeLogs(t *testing.T) {
+	a := `{"time":"2024-01-01 00:00:00","type":"add","seq":1,"payload":{"value":1}}
+{"time":"2024-01-03 00:00:00","type":"add","seq":2,"payload":{"value":3}}
+`
+	b := `{"time":"2024-01-02 00:00:00","type":"add","seq":1,"payload":{"value":2}}
+{"time":"2024-01-03 00:00:00","type":"add","seq":2,"payload":{"value":4}}
+`
+
+	var conflicts [][]Envelope
+	db := NewMemoryDB()
+	err := MergeLogs(db, mergeSources(a, b), MergeOnConflict(func(records []Envelope) error {
+		conflicts = append(conflicts, records)
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	var values []string
+	for i, record := range db.Records() {
+		e, err := DecodeEnvelope(record)
+		if err != nil {
+			t.Fatalf("decoding record: %v", err)
+		}
+
+		if e.Seq != uint64(i+1) {
+			t.Errorf("record %d has seq %d", i, e.Seq)
+		}
+		values = append(values, string(e.Payload))
+	}
+
+	if got := strings.Join(values, ","); got != `{"value":1},{"value":2},{"value":3},{"value":4}` {
+		t.Errorf("got payloads %s", got)
+	}
+
+	if len(conflicts) != 1 || len(conflicts[0]) != 2 {
+		t.Errorf("got conflicts %v, expected one with two records", conflicts)
+	}
+
+	s, err := New(db, testModel{}, getTestEvent, WithStrictSeq[testModel]())
+	if err != nil {
+		t.Fatalf("loading merged database: %v", err)
+	}
+
+	m, done := s.ForReading()
+	done()
+	if m.Sum != 10 {
+		t.Errorf("got sum %d, expected 10", m.Sum)
+	}
+}
+
+func TestMergeLogs_dedup(t *testing.T) {
+	a := `{"time":"2024-01-01 00:00:00","type":"add","seq":1,"payload":{"value":1}}` + "\n"
+	b := `{"time":"2024-01-01 00:00:00","type":"add","seq":7,"payload":{"value":1}}` + "\n"
+
+	conflict := false
+	db := NewMemoryDB()
+	err := MergeLogs(db, mergeSources(a, b), MergeDedup(), MergeOnConflict(func([]Envelope) error {
+		conflict = true
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	if got := len(db.Records()); got != 1 {
+		t.Errorf("got %d records, expected the identical records once", got)
+	}
+
+	if conflict {
+		t.Errorf("identical records where reported as conflict")
+	}
+}
+
+func TestMergeLogs_errors(t *testing.T) {
+	record := `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}` + "\n"
+
+	t.Run("not empty", func(t *testing.T) {
+		if err := MergeLogs(NewMemoryDB(record), mergeSources(record)); !errors.Is(err, ErrNotEmpty) {
+			t.Errorf("got error `%v`, expected ErrNotEmpty", err)
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		snapshot := `{"time":"2024-01-01 00:00:00","type":"$snapshot","payload":"1"}` + "\n"
+		if err := MergeLogs(NewMemoryDB(), mergeSources(record, snapshot)); !errors.Is(err, ErrNotSupported) {
+			t.Errorf("got error `%v`, expected ErrNotSupported", err)
+		}
+	})
+
+	t.Run("conflict callback", func(t *testing.T) {
+		stop := errors.New("stop")
+		err := MergeLogs(NewMemoryDB(), mergeSources(record, strings.Replace(record, "1}", "2}", 1)), MergeOnConflict(func([]Envelope) error {
+			return stop
+		}))
+		if !errors.Is(err, stop) {
+			t.Errorf("got error `%v`, expected the error of the callback", err)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package sticky
+
+// WriteFunc writes events. It is the type of the write function of
+// ForWriting.
+type WriteFunc[Model any] func(events ...Event[Model]) error
+
+// writeChain returns a WriteFunc, that calls write with the options through
+// the middlewares of WithWriteMiddleware.
+func (s *Sticky[Model]) writeChain(write func([]Event[Model], writeOptions) error, opts writeOptions) WriteFunc[Model] {
+	f := WriteFunc[Model](func(events ...Event[Model]) error {
+		return write(events, opts)
+	})
+
+	if len(s.writeMiddlewares) == 0 {
+		return f
+	}
+
+	for i := len(s.writeMiddlewares) - 1; i >= 0; i-- {
+		f = s.writeMiddlewares[i](f)
+	}
+
+	// The middlewares do not see nil events.
+	next := f
+	return func(events ...Event[Model]) error {
+		events = withoutNil(events)
+		if len(events) == 0 {
+			return nil
+		}
+		return next(events...)
+	}
+}
@@ -0,0 +1,86 @@
+package sticky
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithWriteMiddleware(t *testing.T) {
+	var calls []string
+	middleware := func(name string) func(WriteFunc[testModel]) WriteFunc[testModel] {
+		return func(next WriteFunc[testModel]) WriteFunc[testModel] {
+			return func(events ...Event[testModel]) error {
+				calls = append(calls, name)
+				err := next(events...)
+				if err != nil {
+					calls = append(calls, name+" "+err.Error())
+				}
+				return err
+			}
+		}
+	}
+
+	db := NewMemoryDB()
+	s, err := New(
+		db,
+		testModel{},
+		getTestEvent,
+		WithWriteMiddleware(middleware("first")),
+		WithWriteMiddleware(middleware("second")),
+	)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	_, write, done := s.ForWriting()
+	err = write(addEvent{Value: -1})
+	done()
+
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got error `%v`, expected a ValidationError", err)
+	}
+
+	// A nil event does not reach the middlewares.
+	if err := s.Write(func(testModel) Event[testModel] { return nil }); err != nil {
+		t.Fatalf("write nil event: %v", err)
+	}
+
+	expect := []string{"first", "second", "first", "second", "second " + err.Error(), "first " + err.Error()}
+	if len(calls) != len(expect) {
+		t.Fatalf("got calls %v, expected %v", calls, expect)
+	}
+	for i := range expect {
+		if calls[i] != expect[i] {
+			t.Errorf("got call %d `%s`, expected `%s`", i, calls[i], expect[i])
+		}
+	}
+}
+
+func TestWithWriteMiddleware_veto(t *testing.T) {
+	errVeto := errors.New("not allowed")
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getTestEvent, WithWriteMiddleware(func(next WriteFunc[testModel]) WriteFunc[testModel] {
+		return func(events ...Event[testModel]) error {
+			return errVeto
+		}
+	}))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	err = s.WriteMany(func(testModel) ([]Event[testModel], error) {
+		return []Event[testModel]{addEvent{Value: 1}}, nil
+	})
+	if !errors.Is(err, errVeto) {
+		t.Errorf("got error `%v`, expected the error of the middleware", err)
+	}
+
+	if got := len(db.Records()); got != 0 {
+		t.Errorf("got %d records, expected 0", got)
+	}
+}
@@ -0,0 +1,312 @@
+// Package migrate rewrites the records of a sticky database.
+//
+// Use it to change the history permanently. For example to rename an event,
+// to remove a field from old payloads or to redact personal data. The source
+// is only read. The result is written to another database.
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ostcar/sticky"
+)
+
+// snapshotType is the type of snapshot records.
+const snapshotType = "$snapshot"
+
+// batchSize is the number of records, that are appended at once.
+const batchSize = 256
+
+// maxRecordSize is the maximum size of a record of the source.
+const maxRecordSize = 1 << 20
+
+// Database is the destination of a rewrite.
+type Database interface {
+	Reader() (io.ReadCloser, error)
+	Append([]byte) error
+}
+
+// batchAppender is a database, that can append many records at once.
+type batchAppender interface {
+	AppendBatch([][]byte) error
+}
+
+// ErrNotEmpty is returned, when the destination has records.
+var ErrNotEmpty = errors.New("destination is not empty")
+
+// Transform changes an event. It returns an empty slice, to drop the event,
+// or more then one envelope, to split it.
+//
+// The time of the envelope is kept, unless the transform changes it. The
+// sequence numbers and versions are set by the Rewriter.
+type Transform func(sticky.Envelope) ([]sticky.Envelope, error)
+
+// Rename changes the type of the events of type old.
+func Rename(old, current string) Transform {
+	return func(e sticky.Envelope) ([]sticky.Envelope, error) {
+		if e.Type == old {
+			e.Type = current
+		}
+		return []sticky.Envelope{e}, nil
+	}
+}
+
+// DropField removes a field from the payload of the events of type
+// eventType.
+func DropField(eventType, field string) Transform {
+	return func(e sticky.Envelope) ([]sticky.Envelope, error) {
+		if e.Type != eventType {
+			return []sticky.Envelope{e}, nil
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(e.Payload, &fields); err != nil {
+			return nil, fmt.Errorf("decoding payload: %w", err)
+		}
+
+		if _, ok := fields[field]; !ok {
+			return []sticky.Envelope{e}, nil
+		}
+		delete(fields, field)
+
+		payload, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("encoding payload: %w", err)
+		}
+		e.Payload = payload
+		return []sticky.Envelope{e}, nil
+	}
+}
+
+// Result is the result of a rewrite.
+type Result struct {
+	// Read is the number of records of the source and Written the number of
+	// records of the destination.
+	Read    int
+	Written int
+}
+
+// TransformError is returned, when a transform fails.
+type TransformError struct {
+	// Record is the number of the record in the source starting at 1.
+	Record int
+	Type   string
+	Err    error
+}
+
+func (err TransformError) Error() string {
+	return fmt.Sprintf("record %d of type `%s`: %v", err.Record, err.Type, err.Err)
+}
+
+func (err TransformError) Unwrap() error {
+	return err.Err
+}
+
+// Rewriter applies transforms to the records of a database.
+//
+// Snapshot records are copied without calling the transforms. Use Verify, to
+// make sure, that they match the rewritten events.
+type Rewriter struct {
+	// Transforms are called in order for each event. Each transform gets the
+	// envelopes of the transform before.
+	Transforms []Transform
+}
+
+// Rewrite reads the records from src, applies the transforms and appends the
+// result to dst. src has the format of the reader of a database. dst has to
+// be empty.
+//
+// When events are dropped or split, the sequence numbers and versions of the
+// following records are changed, so they stay contiguous. Records without a
+// sequence number or version keep it that way.
+func (rw Rewriter) Rewrite(ctx context.Context, src io.Reader, dst Database) (Result, error) {
+	var result Result
+
+	empty, err := isEmpty(dst)
+	if err != nil {
+		return result, err
+	}
+
+	if !empty {
+		return result, ErrNotEmpty
+	}
+
+	var seq, version uint64
+	var seqDelta, versionDelta int64
+	batch := make([][]byte, 0, batchSize)
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(nil, maxRecordSize+2)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		result.Read++
+
+		e, err := sticky.DecodeEnvelope(line)
+		if err != nil {
+			return result, fmt.Errorf("record %d: %w", result.Read, err)
+		}
+
+		// The sequence number and version like the loader of sticky.
+		seq++
+		if e.Seq != 0 {
+			seq = e.Seq
+		}
+		version++
+
+		var out []sticky.Envelope
+		if e.Type == snapshotType {
+			version = e.Version
+			e.Version = uint64(int64(e.Version) + versionDelta)
+			out = []sticky.Envelope{e}
+		} else {
+			out, err = rw.transform(e)
+			if err != nil {
+				return result, TransformError{Record: result.Read, Type: e.Type, Err: err}
+			}
+		}
+
+		for i := range out {
+			if e.Seq != 0 {
+				out[i].Seq = uint64(int64(seq) + seqDelta + int64(i))
+			}
+			if e.Version != 0 && out[i].Type != snapshotType {
+				out[i].Version = uint64(int64(version) + versionDelta + int64(i))
+			}
+
+			record, err := sticky.EncodeEnvelope(out[i])
+			if err != nil {
+				return result, fmt.Errorf("record %d: %w", result.Read, err)
+			}
+			batch = append(batch, record)
+		}
+
+		seqDelta += int64(len(out)) - 1
+		if e.Type != snapshotType {
+			versionDelta += int64(len(out)) - 1
+		}
+
+		if len(batch) >= batchSize {
+			if err := appendRecords(dst, batch); err != nil {
+				return result, err
+			}
+			result.Written += len(batch)
+			batch = batch[:0]
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("reading source: %w", err)
+	}
+
+	if err := appendRecords(dst, batch); err != nil {
+		return result, err
+	}
+	result.Written += len(batch)
+	return result, nil
+}
+
+// transform calls all transforms on the envelope.
+func (rw Rewriter) transform(e sticky.Envelope) ([]sticky.Envelope, error) {
+	envelopes := []sticky.Envelope{e}
+	for _, t := range rw.Transforms {
+		var next []sticky.Envelope
+		for _, envelope := range envelopes {
+			out, err := t(envelope)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		envelopes = next
+	}
+	return envelopes, nil
+}
+
+// Verify loads the model from src and from dst and calls compare with both.
+// getSrcEvent and getDstEvent return the events of the old and the new
+// records.
+//
+// The databases are loaded with sticky.NewReadOnly, so nothing is written.
+func Verify[Model any](src, dst Database, emptyModel Model, getSrcEvent, getDstEvent func(name string) sticky.Event[Model], compare func(src, dst Model) error) error {
+	srcModel, err := load(src, emptyModel, getSrcEvent)
+	if err != nil {
+		return fmt.Errorf("loading source: %w", err)
+	}
+
+	dstModel, err := load(dst, emptyModel, getDstEvent)
+	if err != nil {
+		return fmt.Errorf("loading destination: %w", err)
+	}
+
+	return compare(srcModel, dstModel)
+}
+
+// load returns the model of a database.
+func load[Model any](db Database, emptyModel Model, getEvent func(name string) sticky.Event[Model]) (Model, error) {
+	s, err := sticky.NewReadOnly(db, emptyModel, getEvent)
+	if err != nil {
+		return emptyModel, err
+	}
+	defer s.Close()
+
+	model, done := s.ForReading()
+	defer done()
+	return model, nil
+}
+
+// appendRecords appends the records with one call, if the database supports
+// it.
+func appendRecords(db Database, records [][]byte) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	if batcher, ok := db.(batchAppender); ok {
+		if err := batcher.AppendBatch(records); err != nil {
+			return fmt.Errorf("writing records: %w", err)
+		}
+		return nil
+	}
+
+	for _, record := range records {
+		if err := db.Append(record); err != nil {
+			return fmt.Errorf("writing records: %w", err)
+		}
+	}
+	return nil
+}
+
+// isEmpty returns true, if the database has no records.
+func isEmpty(db Database) (bool, error) {
+	r, err := db.Reader()
+	if err != nil {
+		return false, fmt.Errorf("open destination: %w", err)
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxRecordSize+2)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+			return false, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("reading destination: %w", err)
+	}
+	return true, nil
+}
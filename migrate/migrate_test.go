@@ -0,0 +1,227 @@
+package migrate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/migrate"
+)
+
+type model struct {
+	Users []string
+	Notes int
+}
+
+type userEvent struct {
+	name  string
+	Email string `json:"email"`
+	User  string `json:"user"`
+}
+
+func (e userEvent) Name() string {
+	return e.name
+}
+
+func (e userEvent) Validate(model) error {
+	return nil
+}
+
+func (e userEvent) Execute(m model, _ time.Time) model {
+	m.Users = append(m.Users, e.User)
+	return m
+}
+
+type noteEvent struct{}
+
+func (e noteEvent) Name() string {
+	return "note"
+}
+
+func (e noteEvent) Validate(model) error {
+	return nil
+}
+
+func (e noteEvent) Execute(m model, _ time.Time) model {
+	m.Notes++
+	return m
+}
+
+func getEvent(userType string) func(name string) sticky.Event[model] {
+	return func(name string) sticky.Event[model] {
+		switch name {
+		case userType:
+			return &userEvent{name: userType}
+		case "note":
+			return &noteEvent{}
+		}
+		return nil
+	}
+}
+
+func newSource() *sticky.MemoryDB {
+	return sticky.NewMemoryDB(
+		`{"v":1,"time":"2024-01-01 00:00:00","type":"user_created","version":1,"seq":1,"payload":{"user":"max","email":"max@example.com","age":30}}`,
+		`{"v":1,"time":"2024-01-02 00:00:00","type":"note","version":2,"seq":2,"payload":{}}`,
+		`{"v":1,"time":"2024-01-03 00:00:00","type":"user_created","version":3,"seq":3,"payload":{"user":"eva","email":"eva@example.com","age":25}}`,
+		`{"v":1,"time":"2024-01-04 00:00:00","type":"note","version":4,"seq":4,"payload":{}}`,
+	)
+}
+
+func redact(email string) migrate.Transform {
+	return func(e sticky.Envelope) ([]sticky.Envelope, error) {
+		e.Payload = json.RawMessage(strings.ReplaceAll(string(e.Payload), email, "redacted"))
+		return []sticky.Envelope{e}, nil
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	src := newSource()
+	dst := sticky.NewMemoryDB()
+
+	rw := migrate.Rewriter{
+		Transforms: []migrate.Transform{
+			migrate.Rename("user_created", "user_registered"),
+			migrate.DropField("user_registered", "age"),
+			redact("max@example.com"),
+		},
+	}
+
+	r, err := src.Reader()
+	if err != nil {
+		t.Fatalf("open source: %v", err)
+	}
+	defer r.Close()
+
+	result, err := rw.Rewrite(context.Background(), r, dst)
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+
+	if result.Read != 4 || result.Written != 4 {
+		t.Errorf("got %+v, expected 4 read and written records", result)
+	}
+
+	first, err := sticky.DecodeEnvelope(dst.Records()[0])
+	if err != nil {
+		t.Fatalf("decoding first record: %v", err)
+	}
+
+	if first.Type != "user_registered" || first.Time != "2024-01-01 00:00:00" {
+		t.Errorf("got type `%s` and time `%s`, expected the new type and the old time", first.Type, first.Time)
+	}
+
+	if payload := string(first.Payload); payload != `{"email":"redacted","user":"max"}` {
+		t.Errorf("got payload `%s`", payload)
+	}
+
+	if original := string(src.Records()[0]); !strings.Contains(original, "max@example.com") {
+		t.Errorf("source was changed: `%s`", original)
+	}
+
+	err = migrate.Verify(src, dst, model{}, getEvent("user_created"), getEvent("user_registered"), func(old, current model) error {
+		if fmt.Sprint(old) != fmt.Sprint(current) {
+			return fmt.Errorf("got model %v, expected %v", current, old)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("verify: %v", err)
+	}
+}
+
+func TestRewrite_drop_and_split(t *testing.T) {
+	src := newSource()
+	dst := sticky.NewMemoryDB()
+
+	rw := migrate.Rewriter{
+		Transforms: []migrate.Transform{
+			func(e sticky.Envelope) ([]sticky.Envelope, error) {
+				switch {
+				case e.Type == "note" && e.Seq == 2:
+					return nil, nil
+				case e.Type == "note":
+					return []sticky.Envelope{e, e}, nil
+				}
+				return []sticky.Envelope{e}, nil
+			},
+		},
+	}
+
+	r, err := src.Reader()
+	if err != nil {
+		t.Fatalf("open source: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := rw.Rewrite(context.Background(), r, dst); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+
+	var seqs []uint64
+	for _, record := range dst.Records() {
+		e, err := sticky.DecodeEnvelope(record)
+		if err != nil {
+			t.Fatalf("decoding record: %v", err)
+		}
+		seqs = append(seqs, e.Seq)
+	}
+
+	if fmt.Sprint(seqs) != "[1 2 3 4]" {
+		t.Errorf("got seqs %v, expected [1 2 3 4]", seqs)
+	}
+
+	s, err := sticky.NewReadOnly(dst, model{}, getEvent("user_created"), sticky.WithStrictSeq[model]())
+	if err != nil {
+		t.Fatalf("loading destination: %v", err)
+	}
+
+	m, done := s.ForReading()
+	done()
+	if m.Notes != 2 || len(m.Users) != 2 {
+		t.Errorf("got %v, expected 2 users and 2 notes", m)
+	}
+}
+
+func TestRewrite_transform_error(t *testing.T) {
+	rw := migrate.Rewriter{
+		Transforms: []migrate.Transform{
+			func(e sticky.Envelope) ([]sticky.Envelope, error) {
+				if e.Type == "note" {
+					return nil, errors.New("no notes")
+				}
+				return []sticky.Envelope{e}, nil
+			},
+		},
+	}
+
+	r, err := newSource().Reader()
+	if err != nil {
+		t.Fatalf("open source: %v", err)
+	}
+	defer r.Close()
+
+	_, err = rw.Rewrite(context.Background(), r, sticky.NewMemoryDB())
+
+	var transformErr migrate.TransformError
+	if !errors.As(err, &transformErr) || transformErr.Record != 2 {
+		t.Errorf("got error `%v`, expected a TransformError for record 2", err)
+	}
+}
+
+func TestRewrite_destination_not_empty(t *testing.T) {
+	r, err := newSource().Reader()
+	if err != nil {
+		t.Fatalf("open source: %v", err)
+	}
+	defer r.Close()
+
+	if _, err := (migrate.Rewriter{}).Rewrite(context.Background(), r, newSource()); !errors.Is(err, migrate.ErrNotEmpty) {
+		t.Errorf("got error `%v`, expected ErrNotEmpty", err)
+	}
+}
@@ -0,0 +1,108 @@
+package sticky
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ModelAt returns the model as it was at the time t. The events are replayed
+// from the database into a new model until the first event after t. The
+// model of the Sticky is not changed.
+//
+// The lock is only held, while the database is opened. Events, that are
+// written later, are not replayed.
+//
+// If the database was compacted after t, the time can not be rebuild and an
+// error wrapping ErrNotSupported is returned.
+//
+// A model with maps, slices or pointers has to implement Cloner. Otherwise,
+// the replay changes the empty model and the model of the Sticky.
+func (s *Sticky[Model]) ModelAt(ctx context.Context, t time.Time) (Model, error) {
+	model, _, err := s.replay(ctx, func(l *loader[Model], e Envelope) (bool, error) {
+		eventTime, err := e.parseTime(l.timeLayout)
+		if err != nil {
+			return false, err
+		}
+
+		if !eventTime.After(t) {
+			return false, nil
+		}
+
+		if e.Type == snapshotType && l.records == 1 {
+			return false, fmt.Errorf("database is compacted at %s: %w", e.Time, ErrNotSupported)
+		}
+		return true, nil
+	})
+	return model, err
+}
+
+// ModelAtVersion returns the model as it was at the version n. See ModelAt.
+func (s *Sticky[Model]) ModelAtVersion(ctx context.Context, n uint64) (Model, error) {
+	model, version, err := s.replay(ctx, func(l *loader[Model], e Envelope) (bool, error) {
+		if l.version == n {
+			return true, nil
+		}
+
+		if e.Type == snapshotType && e.Version > n {
+			return false, fmt.Errorf("database is compacted at version %d: %w", e.Version, ErrNotSupported)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return model, err
+	}
+
+	if version != n {
+		return s.emptyModel, fmt.Errorf("version %d does not exist, the database has version %d", n, version)
+	}
+	return model, nil
+}
+
+// replay loads the database into a new model and returns it with its
+// version. stop is called before each record.
+func (s *Sticky[Model]) replay(ctx context.Context, stop func(l *loader[Model], e Envelope) (bool, error)) (Model, uint64, error) {
+	r, l, err := s.replayReader()
+	if err != nil {
+		return s.emptyModel, 0, err
+	}
+	defer r.Close()
+
+	l.stop = func(e Envelope) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		return stop(l, e)
+	}
+
+	if l.maxRecords == 0 {
+		return s.emptyModel, 0, nil
+	}
+
+	model, err := l.load(r, s.newModel(), 0)
+	if err != nil {
+		return s.emptyModel, 0, fmt.Errorf("replaying database: %w", err)
+	}
+	return model, l.version, nil
+}
+
+// replayReader opens the database with the read lock and returns a loader,
+// that only reads the records, that exist at this time.
+func (s *Sticky[Model]) replayReader() (io.ReadCloser, *loader[Model], error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed.Load() {
+		return nil, nil, ErrClosed
+	}
+
+	r, err := s.db.Reader()
+	if err != nil {
+		return nil, nil, StorageError{Op: "open database", Err: err}
+	}
+
+	l := s.loader.replayLoader()
+	l.maxRecords = s.records
+	return r, l, nil
+}
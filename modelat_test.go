@@ -0,0 +1,133 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestModelAt(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 10:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 11:00:00","type":"add","payload":{"value":2}}`,
+		`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":4}}`,
+	)
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for _, tt := range []struct {
+		time   time.Time
+		expect int
+	}{
+		{time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), 0},
+		{time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), 3},
+		{time.Date(2024, 1, 1, 11, 30, 0, 0, time.UTC), 3},
+		{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 7},
+	} {
+		model, err := s.ModelAt(context.Background(), tt.time)
+		if err != nil {
+			t.Fatalf("model at %s: %v", tt.time, err)
+		}
+
+		if model.Sum != tt.expect {
+			t.Errorf("model at %s has sum %d, expected %d", tt.time, model.Sum, tt.expect)
+		}
+	}
+
+	live, done := s.ForReading()
+	done()
+	if live.Sum != 7 {
+		t.Errorf("live model was changed to sum %d", live.Sum)
+	}
+}
+
+func TestModelAtVersion(t *testing.T) {
+	db := NewMemoryDB()
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		if i == 2 {
+			if err := s.Snapshot(); err != nil {
+				t.Fatalf("snapshot: %v", err)
+			}
+		}
+	}
+
+	for version, expect := range []int{0, 1, 3, 6} {
+		model, err := s.ModelAtVersion(context.Background(), uint64(version))
+		if err != nil {
+			t.Fatalf("model at version %d: %v", version, err)
+		}
+
+		if model.Sum != expect {
+			t.Errorf("model at version %d has sum %d, expected %d", version, model.Sum, expect)
+		}
+	}
+
+	if _, err := s.ModelAtVersion(context.Background(), 4); err == nil {
+		t.Errorf("model at a future version returned no error")
+	}
+
+	if err := s.Compact(context.Background()); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	if _, err := s.ModelAtVersion(context.Background(), 1); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("model at a compacted version returned `%v`, expected ErrNotSupported", err)
+	}
+}
+
+func TestModelAt_map_model(t *testing.T) {
+	getEvent := func(string) Event[mapModel] { return &setEvent{} }
+	empty := mapModel{Values: map[string]int{}}
+	s, err := New(NewMemoryDB(), empty, getEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := s.Write(func(mapModel) Event[mapModel] { return setEvent{Key: "a", Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		go func() {
+			model, done := s.ForReading()
+			defer done()
+			_ = model.Values["a"]
+		}()
+
+		model, err := s.ModelAtVersion(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("model at version 1: %v", err)
+		}
+
+		if got := model.Values["a"]; got != 1 {
+			t.Errorf("model at version 1 has value %d, expected 1", got)
+		}
+	}
+
+	live, done := s.ForReading()
+	got := live.Values["a"]
+	done()
+
+	if got != 3 {
+		t.Errorf("live model has value %d, expected 3", got)
+	}
+
+	if len(empty.Values) != 0 {
+		t.Errorf("empty model was changed to %v", empty.Values)
+	}
+}
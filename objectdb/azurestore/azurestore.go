@@ -0,0 +1,74 @@
+// Package azurestore implements an objectdb.ObjectStore for Azure Blob
+// Storage.
+package azurestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/ostcar/sticky/objectdb"
+)
+
+// Store stores the objects as block blobs in a container.
+type Store struct {
+	container *container.Client
+}
+
+// New initializes a Store.
+func New(container *container.Client) *Store {
+	return &Store{container: container}
+}
+
+// List returns the names of all blobs with the prefix.
+func (s *Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	pager := s.container.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing blobs: %w", err)
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			if item.Name != nil {
+				names = append(names, *item.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// Get returns a reader for the blob.
+func (s *Store) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.container.NewBlobClient(name).DownloadStream(ctx, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, fmt.Errorf("blob %s: %w", name, objectdb.ErrNotFound)
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// PutIfNotExists uploads the blob with an If-None-Match: * condition.
+func (s *Store) PutIfNotExists(ctx context.Context, name string, data []byte) error {
+	etagAny := azcore.ETagAny
+	_, err := s.container.NewBlockBlobClient(name).UploadBuffer(ctx, data, &blockblob.UploadBufferOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: &etagAny},
+		},
+	})
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobAlreadyExists, bloberror.ConditionNotMet) {
+			return fmt.Errorf("blob %s: %w", name, objectdb.ErrExists)
+		}
+		return fmt.Errorf("uploading blob %s: %w", name, err)
+	}
+	return nil
+}
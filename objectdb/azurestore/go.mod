@@ -0,0 +1,17 @@
+module github.com/ostcar/sticky/objectdb/azurestore
+
+go 1.21.1
+
+require (
+	github.com/Azure/azure-sdk-for-go/sdk/azcore v1.11.1
+	github.com/Azure/azure-sdk-for-go/sdk/storage/azblob v1.3.2
+	github.com/ostcar/sticky v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/Azure/azure-sdk-for-go/sdk/internal v1.5.2 // indirect
+	golang.org/x/net v0.22.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/ostcar/sticky => ../..
@@ -0,0 +1,108 @@
+package objectdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirStore is an ObjectStore, that stores each object as a file in a
+// directory. It is usefull for testing.
+//
+// Object names can contain slashes. They are used as subdirectories.
+type DirStore struct {
+	Dir string
+}
+
+// List returns the names of all objects with the prefix.
+func (s DirStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(rel)
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", s.Dir, err)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Get opens the file of the object.
+func (s DirStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("object %s: %w", name, ErrNotFound)
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// PutIfNotExists writes the object to a temporary file and links it to its
+// name. The link fails, if the object already exists.
+func (s DirStore) PutIfNotExists(ctx context.Context, name string, data []byte) error {
+	path := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync temporary file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %w", err)
+	}
+
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("object %s: %w", name, ErrExists)
+		}
+		return fmt.Errorf("link object: %w", err)
+	}
+	return nil
+}
+
+func (s DirStore) path(name string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(name))
+}
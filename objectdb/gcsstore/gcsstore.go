@@ -0,0 +1,74 @@
+// Package gcsstore implements an objectdb.ObjectStore for Google Cloud
+// Storage.
+package gcsstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"cloud.google.com/go/storage"
+	"github.com/ostcar/sticky/objectdb"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+// Store stores the objects in a GCS bucket.
+type Store struct {
+	bucket *storage.BucketHandle
+}
+
+// New initializes a Store.
+func New(bucket *storage.BucketHandle) *Store {
+	return &Store{bucket: bucket}
+}
+
+// List returns the names of all objects with the prefix.
+func (s *Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	it := s.bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing objects: %w", err)
+		}
+		names = append(names, attrs.Name)
+	}
+}
+
+// Get returns a reader for the object.
+func (s *Store) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	r, err := s.bucket.Object(name).NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("object %s: %w", name, objectdb.ErrNotFound)
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// PutIfNotExists creates the object with the precondition, that it does not
+// exist.
+func (s *Store) PutIfNotExists(ctx context.Context, name string, data []byte) error {
+	w := s.bucket.Object(name).If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		w.Close()
+		return fmt.Errorf("writing object %s: %w", name, err)
+	}
+
+	if err := w.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return fmt.Errorf("object %s: %w", name, objectdb.ErrExists)
+		}
+		return fmt.Errorf("writing object %s: %w", name, err)
+	}
+	return nil
+}
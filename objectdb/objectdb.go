@@ -0,0 +1,322 @@
+// Package objectdb implements a sticky database on top of a generic object
+// store.
+//
+// Appended events are buffered in memory and written as numbered objects. The
+// objects are created with a conditional put, so two writers can not create
+// the same object. Adapters for Google Cloud Storage and Azure Blob Storage
+// are in the subpackages gcsstore and azurestore.
+package objectdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound has to be returned by ObjectStore.Get, when an object does
+	// not exist.
+	ErrNotFound = errors.New("object not found")
+
+	// ErrExists has to be returned by ObjectStore.PutIfNotExists, when the
+	// object already exists.
+	ErrExists = errors.New("object already exists")
+
+	// ErrConflict is returned by the DB, when another writer created the
+	// next object.
+	ErrConflict = errors.New("another writer appended to the log")
+)
+
+// ObjectStore is the part of an object storage, that is needed by the DB.
+type ObjectStore interface {
+	// List returns the names of all objects with the prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// Get returns the content of an object. It has to return an error
+	// wrapping ErrNotFound, if the object does not exist.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+
+	// PutIfNotExists creates an object. It has to return an error wrapping
+	// ErrExists, if the object already exists.
+	PutIfNotExists(ctx context.Context, name string, data []byte) error
+}
+
+const (
+	defaultFlushEvents   = 100
+	defaultFlushInterval = 5 * time.Second
+	objectExt            = ".log"
+)
+
+// Option is a option for objectdb.Open()
+type Option func(db *DB)
+
+// WithFlushEvents writes a new object after n buffered events. Default is
+// 100.
+func WithFlushEvents(n int) Option {
+	return func(db *DB) {
+		db.flushEvents = n
+	}
+}
+
+// WithFlushInterval writes a new object at the latest after the duration.
+// Default is 5 seconds.
+func WithFlushInterval(d time.Duration) Option {
+	return func(db *DB) {
+		db.flushInterval = d
+	}
+}
+
+// DB is a event database based on an object store.
+type DB struct {
+	store         ObjectStore
+	prefix        string
+	flushEvents   int
+	flushInterval time.Duration
+
+	mu         sync.Mutex
+	nextNumber uint64
+	buf        bytes.Buffer
+	buffered   int
+	flushTimer *time.Timer
+	flushErr   error
+}
+
+// Open lists the existing objects below the prefix.
+//
+// The prefix is put in front of all object names. For example "myapp/".
+func Open(ctx context.Context, store ObjectStore, prefix string, options ...Option) (*DB, error) {
+	db := DB{
+		store:         store,
+		prefix:        prefix,
+		flushEvents:   defaultFlushEvents,
+		flushInterval: defaultFlushInterval,
+	}
+
+	for _, o := range options {
+		o(&db)
+	}
+
+	numbers, err := db.objects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	db.nextNumber = 1
+	if len(numbers) > 0 {
+		db.nextNumber = numbers[len(numbers)-1] + 1
+	}
+	return &db, nil
+}
+
+// objects returns the numbers of all objects in order.
+func (db *DB) objects(ctx context.Context) ([]uint64, error) {
+	names, err := db.store.List(ctx, db.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("list objects: %w", err)
+	}
+
+	var numbers []uint64
+	for _, name := range names {
+		rest := strings.TrimPrefix(name, db.prefix)
+		if !strings.HasSuffix(rest, objectExt) {
+			continue
+		}
+
+		number, err := strconv.ParseUint(strings.TrimSuffix(rest, objectExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		numbers = append(numbers, number)
+	}
+
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers, nil
+}
+
+func (db *DB) objectName(number uint64) string {
+	return fmt.Sprintf("%s%012d%s", db.prefix, number, objectExt)
+}
+
+// Reader returns all events from all objects and the unflushed buffer.
+func (db *DB) Reader() (io.ReadCloser, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	ctx := context.Background()
+	numbers, err := db.objects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(numbers))
+	for i, number := range numbers {
+		names[i] = db.objectName(number)
+	}
+
+	return &objectReader{
+		ctx:      ctx,
+		store:    db.store,
+		names:    names,
+		buffered: bytes.Clone(db.buf.Bytes()),
+	}, nil
+}
+
+// Append adds the event to the buffer. The buffer is flushed, when it
+// contains enough events.
+//
+// If a background flush failed, the error is returned.
+func (db *DB) Append(bs []byte) error {
+	return db.AppendBatch([][]byte{bs})
+}
+
+// AppendBatch adds many events to the buffer. They are always written to the
+// same object.
+func (db *DB) AppendBatch(records [][]byte) error {
+	for _, bs := range records {
+		if bytes.Contains(bs, []byte("\n")) {
+			return errors.New("event contains a newline")
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.flushErr; err != nil {
+		db.flushErr = nil
+		return fmt.Errorf("previous flush: %w", err)
+	}
+
+	for _, bs := range records {
+		db.buf.Write(bs)
+		db.buf.WriteByte('\n')
+		db.buffered++
+	}
+
+	if db.buffered >= db.flushEvents {
+		return db.flush(context.Background())
+	}
+
+	if db.flushInterval > 0 && db.flushTimer == nil {
+		db.flushTimer = time.AfterFunc(db.flushInterval, db.timedFlush)
+	}
+	return nil
+}
+
+// Flush writes the buffered events as new object.
+func (db *DB) Flush(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.flush(ctx)
+}
+
+// Sync writes the buffered events as new object. It is the same as Flush
+// with a background context.
+func (db *DB) Sync() error {
+	return db.Flush(context.Background())
+}
+
+// flush writes the buffer as a new object.
+//
+// Has to be called with the lock. On error, the events stay in the buffer.
+func (db *DB) flush(ctx context.Context) error {
+	if db.flushTimer != nil {
+		db.flushTimer.Stop()
+		db.flushTimer = nil
+	}
+
+	if db.buffered == 0 {
+		return nil
+	}
+
+	name := db.objectName(db.nextNumber)
+	if err := db.store.PutIfNotExists(ctx, name, db.buf.Bytes()); err != nil {
+		if errors.Is(err, ErrExists) {
+			return fmt.Errorf("object %s: %w", name, ErrConflict)
+		}
+		return fmt.Errorf("put object %s: %w", name, err)
+	}
+
+	db.nextNumber++
+	db.buf.Reset()
+	db.buffered = 0
+	return nil
+}
+
+func (db *DB) timedFlush() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.flushTimer = nil
+	if err := db.flush(context.Background()); err != nil {
+		db.flushErr = err
+	}
+}
+
+// Close flushes the buffered events.
+//
+// Call Close on graceful shutdown. Otherwise buffered events get lost.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.flushErr = nil
+	return db.flush(context.Background())
+}
+
+// objectReader concatenates the objects and the buffered events.
+type objectReader struct {
+	ctx      context.Context
+	store    ObjectStore
+	names    []string
+	buffered []byte
+
+	current io.ReadCloser
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.names) == 0 {
+				if len(r.buffered) == 0 {
+					return 0, io.EOF
+				}
+				n := copy(p, r.buffered)
+				r.buffered = r.buffered[n:]
+				return n, nil
+			}
+
+			object, err := r.store.Get(r.ctx, r.names[0])
+			if err != nil {
+				return 0, fmt.Errorf("get object %s: %w", r.names[0], err)
+			}
+			r.current = object
+			r.names = r.names[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *objectReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
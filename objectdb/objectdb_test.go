@@ -0,0 +1,144 @@
+package objectdb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ostcar/sticky/objectdb"
+)
+
+func readAll(t *testing.T, db *objectdb.DB) string {
+	t.Helper()
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+	return string(got)
+}
+
+func TestObjectDB_flush_and_reopen(t *testing.T) {
+	ctx := context.Background()
+	store := objectdb.DirStore{Dir: t.TempDir()}
+
+	db, err := objectdb.Open(ctx, store, "app/", objectdb.WithFlushEvents(2), objectdb.WithFlushInterval(0))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	for _, event := range []string{"one", "two", "three"} {
+		if err := db.Append([]byte(event)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	names, err := store.List(ctx, "app/")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(names) != 1 || names[0] != "app/000000000001.log" {
+		t.Errorf("got objects %v, expected [app/000000000001.log]", names)
+	}
+
+	if got := readAll(t, db); got != "one\ntwo\nthree\n" {
+		t.Errorf("got `%s`, expected `one\ntwo\nthree\n`", got)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	db, err = objectdb.Open(ctx, store, "app/", objectdb.WithFlushInterval(0))
+	if err != nil {
+		t.Fatalf("reopen db: %v", err)
+	}
+
+	if err := db.Append([]byte("four")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if err := db.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	if got := readAll(t, db); got != "one\ntwo\nthree\nfour\n" {
+		t.Errorf("got `%s`, expected `one\ntwo\nthree\nfour\n`", got)
+	}
+
+	names, err = store.List(ctx, "app/")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(names) != 3 {
+		t.Errorf("got %d objects, expected 3", len(names))
+	}
+}
+
+func TestObjectDB_racing_writer(t *testing.T) {
+	ctx := context.Background()
+	store := objectdb.DirStore{Dir: t.TempDir()}
+
+	db1, err := objectdb.Open(ctx, store, "", objectdb.WithFlushInterval(0))
+	if err != nil {
+		t.Fatalf("open db1: %v", err)
+	}
+
+	db2, err := objectdb.Open(ctx, store, "", objectdb.WithFlushInterval(0))
+	if err != nil {
+		t.Fatalf("open db2: %v", err)
+	}
+
+	if err := db1.Append([]byte("first")); err != nil {
+		t.Fatalf("append db1: %v", err)
+	}
+	if err := db1.Sync(); err != nil {
+		t.Fatalf("sync db1: %v", err)
+	}
+
+	if err := db2.Append([]byte("second")); err != nil {
+		t.Fatalf("append db2: %v", err)
+	}
+
+	err = db2.Sync()
+	if !errors.Is(err, objectdb.ErrConflict) {
+		t.Fatalf("got error `%v`, expected ErrConflict", err)
+	}
+
+	if got := readAll(t, db1); got != "first\n" {
+		t.Errorf("got `%s`, expected `first\n`", got)
+	}
+}
+
+func TestDirStore_put_if_not_exists(t *testing.T) {
+	ctx := context.Background()
+	store := objectdb.DirStore{Dir: t.TempDir()}
+
+	if err := store.PutIfNotExists(ctx, "a/b.log", []byte("data")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	err := store.PutIfNotExists(ctx, "a/b.log", []byte("other"))
+	if !errors.Is(err, objectdb.ErrExists) {
+		t.Errorf("got error `%v`, expected ErrExists", err)
+	}
+
+	if _, err := store.Get(ctx, "missing.log"); !errors.Is(err, objectdb.ErrNotFound) {
+		t.Errorf("got error `%v`, expected ErrNotFound", err)
+	}
+
+	names, err := store.List(ctx, "")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(names) != 1 || names[0] != "a/b.log" {
+		t.Errorf("got %v, expected [a/b.log]", names)
+	}
+}
@@ -1,13 +1,593 @@
 package sticky
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
 
 // Option is a option for sticky.New()
 type Option[Model any] func(s *Sticky[Model])
 
 // WithNow uses a special now function. Default is time.Now()
+//
+// It is used for the time of new events and for the intervals of automatic
+// snapshots.
 func WithNow[Model any](now func() time.Time) Option[Model] {
 	return func(s *Sticky[Model]) {
 		s.now = now
 	}
 }
+
+// Clock returns the current time. See stickytest.Clock for a clock, that can
+// be changed in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock is like WithNow, but uses a Clock.
+func WithClock[Model any](c Clock) Option[Model] {
+	return WithNow[Model](c.Now)
+}
+
+// WithTruncatedTailRecovery drops the last line of the database, if it can
+// not be decoded. This happens, when the process crashed while writing an
+// event.
+//
+// If the database supports it, the broken line is removed, so the next event
+// starts on a clean line. The FileDB supports this.
+//
+// The callback is called with the dropped bytes and if they where removed from
+// the database. It can be nil. A broken line that is not the last line is
+// still an error.
+func WithTruncatedTailRecovery[Model any](onDropped func(dropped []byte, truncated bool)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.recoverTail = true
+		s.onTailDropped = onDropped
+	}
+}
+
+// WithMaxEventSize sets the maximum size of an encoded event in bytes. Default
+// is 1 MiB.
+//
+// Bigger events can not be written. A database containing a bigger event can
+// not be loaded.
+func WithMaxEventSize[Model any](n int) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.maxEventSize = n
+	}
+}
+
+// WithMaxPayloadDepth sets how deep objects and arrays in the payload of an
+// event can be nested. Default is 100. Zero means no limit.
+//
+// A database containing an event with a deeper payload can not be loaded.
+func WithMaxPayloadDepth[Model any](n int) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.maxPayloadDepth = n
+	}
+}
+
+// WithReadOnly rejects all writes with ErrReadOnly. See NewReadOnly.
+func WithReadOnly[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.readOnly = true
+	}
+}
+
+// WithFollowError sets a function, that is called, when a follower stops
+// because of an error. See NewFollower.
+func WithFollowError[Model any](f func(error)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.onFollowError = f
+	}
+}
+
+// WithAutoSnapshot writes a snapshot after every events or when maxInterval
+// passed since the last snapshot. Zero disables a threshold. The model has to
+// implement Snapshotter.
+//
+// The thresholds are checked at the end of each write. The write does not fail,
+// when the snapshot can not be written. Use WithSnapshotError to get notified.
+//
+// If the model implements Cloner, the snapshot is serialized from a copy
+// without holding the write lock.
+func WithAutoSnapshot[Model any](every int, maxInterval time.Duration) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.autoSnapshotEvery = every
+		s.autoSnapshotInterval = maxInterval
+	}
+}
+
+// WithSnapshotError sets a function, that is called, when an automatic
+// snapshot fails. See WithAutoSnapshot.
+func WithSnapshotError[Model any](f func(error)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.onSnapshotError = f
+	}
+}
+
+// WithBackgroundCompaction checks the size of the database every
+// checkInterval and calls Compact, when it is bigger then maxLogSize.
+//
+// The database has to support Size and ReplaceWith and the model has to
+// implement Snapshotter. The goroutine is stopped by Close. Use
+// WithCompactionError to get notified about errors.
+func WithBackgroundCompaction[Model any](maxLogSize int64, checkInterval time.Duration) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.compactSize = maxLogSize
+		s.compactInterval = checkInterval
+	}
+}
+
+// WithCompactionError sets a function, that is called, when a background
+// compaction fails. The compaction is tried again after the next interval.
+// See WithBackgroundCompaction.
+func WithCompactionError[Model any](f func(error)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.onCompactionError = f
+	}
+}
+
+// WithSnapshotStore stores snapshots in the store instead of the database.
+//
+// On load, the latest snapshot is read from the store and only the records
+// after its version are replayed. Compact can not be used with a
+// SnapshotStore.
+func WithSnapshotStore[Model any](store SnapshotStore) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.snapshotStore = store
+	}
+}
+
+// WithLoadHook sets a function, that is called for each event, that is loaded
+// from the database, with the metadata of the event. The metadata is nil for
+// events without metadata. See Sticky.WriteMeta.
+//
+// The hook is called before the event is executed. It is not called for the
+// events, that are contained in a snapshot.
+func WithLoadHook[Model any](f func(event Event[Model], meta map[string]string)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.onLoad = f
+	}
+}
+
+// WithStrictSeq returns an error on load, when a sequence number of a record
+// is skipped or used twice. Per default, it is only an error, when a sequence
+// number is smaller then the one before.
+//
+// Records without a sequence number, that are written by older versions of
+// sticky, get the next number.
+func WithStrictSeq[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.strictSeq = true
+	}
+}
+
+// WithSeqWarning calls f instead of returning an error, when the sequence
+// numbers of the records are not in order. See WithStrictSeq.
+func WithSeqWarning[Model any](f func(error)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.onSeqWarning = f
+	}
+}
+
+// WithEventAlias loads events with the name old as the event with the name
+// current. Use it, when an event was renamed. New events are always written
+// with the name of the event.
+//
+// New returns an error, if current is not a known event.
+func WithEventAlias[Model any](old, current string) Option[Model] {
+	return func(s *Sticky[Model]) {
+		if s.loader.aliases == nil {
+			s.loader.aliases = make(map[string]string)
+		}
+		s.loader.aliases[old] = current
+	}
+}
+
+// WithUpcaster transforms the payload of the event name from the schema
+// version fromVersion to fromVersion+1. It is called on load, before the
+// payload is decoded. See SchemaVersioner.
+//
+// The upcasters of an event are applied in order, until the current
+// version is reached. Loading fails with an UpcastError, if an upcaster is
+// missing. The name is the current name of the event. See WithEventAlias.
+func WithUpcaster[Model any](name string, fromVersion int, f func(json.RawMessage) (json.RawMessage, error)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		if s.loader.upcasters == nil {
+			s.loader.upcasters = make(map[upcastKey]func(json.RawMessage) (json.RawMessage, error))
+		}
+		s.loader.upcasters[upcastKey{name: name, from: fromVersion}] = f
+	}
+}
+
+// WithUnknownEvents sets, what happens with events, that getEvent does not
+// know. This happens for example, when an older version of the program reads
+// a database written by a newer version. Default is UnknownEventsFail.
+//
+// Ignored events still count for the version. The database can not be
+// compacted, when events where ignored, since they would be lost.
+func WithUnknownEvents[Model any](mode UnknownEvents) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.unknownEvents = mode
+	}
+}
+
+// WithRegistry loads the events with the registry. getEvent of New can be
+// nil. Written events get the name from Registry.NameOf, so derived names
+// work. New returns an error, if Registry.Check fails.
+func WithRegistry[Model any](r *Registry[Model]) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.registry = r
+		s.loader.getEvent = r.Get
+	}
+}
+
+// WithAllowUnregistered allows to write events, that getEvent does not know.
+// Per default, such writes fail with ErrUnregisteredEvent, since the database
+// could not be loaded afterwards. Use it with WithUnknownEvents, if another
+// program writes events, that this program does not know.
+func WithAllowUnregistered[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.allowUnregistered = true
+	}
+}
+
+// WithStrictEvents checks on the first write of each event type, that the
+// event can be loaded again. getEvent, or the registry, has to return an event
+// of the same type for the written name. Otherwise the write fails with an
+// EventDefinitionError.
+//
+// It finds events, whose Name method returns another name then they are
+// registered with.
+func WithStrictEvents[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.strictEvents = true
+	}
+}
+
+// WithRoundTripCheck is like WithStrictEvents but also decodes the payload of
+// the first written event of each type and compares it with the event. It
+// finds fields, that are not written, for example unexported fields.
+//
+// The events are compared with reflect.DeepEqual. Events, whose encoding
+// changes values on purpose, can not be used with this option.
+func WithRoundTripCheck[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.strictEvents = true
+		s.strictRoundTrip = true
+	}
+}
+
+// WithExecutionErrorSkip skips events on load, that return an error from
+// ExecuteErr. The function is called with the event and the ExecutionError.
+// Skipped events still count for the version. Per default, the load fails.
+// See ExecuterWithError.
+func WithExecutionErrorSkip[Model any](f func(event Event[Model], err error)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.onExecutionError = f
+	}
+}
+
+// WithBatchValidationBeforeExecute validates all events of a batch against
+// the model before the batch. This was the behavior of older versions.
+//
+// Per default, each event is validated against the model, after the events
+// before it in the batch where executed. This needs a copy of the model. If
+// the model has maps, slices or pointers and does not implement Cloner, the
+// events of a batch are always validated before they are executed.
+func WithBatchValidationBeforeExecute[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.validateBeforeBatch = true
+	}
+}
+
+// WithBatchTime uses the same time for all events, that are written
+// together. Per default, each event gets its own time.
+func WithBatchTime[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.oneTimePerBatch = true
+	}
+}
+
+// WithMaxClockSkew returns ErrClockSkew on write, when the clock went back
+// more then d since the last event. Per default, the time of the new event is
+// set to the time of the last event plus the precision of the time format,
+// so the events are always ordered by time. See WithTimeFormat.
+func WithMaxClockSkew[Model any](d time.Duration) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.maxClockSkew = d
+	}
+}
+
+// WithClockSkewHook sets a function, that is called, when the time of an
+// event was changed, because the clock went back. See WithMaxClockSkew.
+func WithClockSkewHook[Model any](f func(original, clamped time.Time)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.onClockSkew = f
+	}
+}
+
+// WithTimeFormat sets the layout of the time of new events. For example
+// time.RFC3339Nano for times with nanoseconds. Default is
+// "2006-01-02 15:04:05", that only has seconds.
+//
+// On load, the layout, the default format and time.RFC3339Nano are accepted.
+// Older versions of sticky can only read the default format. FileDB.Segments
+// also uses the layout. Use NormalizeTimes to rewrite the times of a
+// database.
+func WithTimeFormat[Model any](layout string) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.timeLayout = layout
+	}
+}
+
+// WithProjection adds a projection, that gets all events on load and after
+// each write. With projections, snapshots are not used on load, since the
+// projection needs all events. See Sticky.AddProjection.
+func WithProjection[Model any](name string, p Projection[Model]) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.projections = append(s.loader.projections, namedProjection[Model]{name: name, projection: p})
+	}
+}
+
+// WithProjectionError sets a function, that is called, when a projection
+// returns an error.
+func WithProjectionError[Model any](f func(name string, err error)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.onProjectionError = f
+	}
+}
+
+// WithWriteTimeout lets writes fail with ErrLockTimeout, when the write lock
+// can not be taken in the time d. This happens, when a reader holds the lock
+// for to long, for example because the done function was not called.
+func WithWriteTimeout[Model any](d time.Duration) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.writeTimeout = d
+	}
+}
+
+// WithLockWait calls f each time the write lock was taken with the time, the
+// write waited for it. f is called while the lock is held, so it has to be
+// fast.
+//
+// ForWriting and all Write methods take the lock this way, TryForWriting not.
+func WithLockWait[Model any](f func(wait time.Duration)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.onLockWait = f
+	}
+}
+
+// WithLockLeakDetection calls onLeak, when the done function of ForReading or
+// ForWriting is not called in the threshold. The stack is from the caller,
+// that got the lock.
+//
+// Without this option, there are no costs.
+func WithLockLeakDetection[Model any](threshold time.Duration, onLeak func(stack []byte, heldFor time.Duration)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.leakThreshold = threshold
+		s.onLeak = onLeak
+	}
+}
+
+// WithCloneOnRead lets ForReading, Read and ReadCtx return a copy of the
+// model. The lock is released before the reader gets the model, so the done
+// function does nothing and the model can be used after it.
+//
+// The model has to implement Cloner. Otherwise New returns an error.
+func WithCloneOnRead[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.cloneOnRead = true
+	}
+}
+
+// WithCopyOnWrite lets ForReading, Read and ReadCtx return the model without
+// taking a lock. Readers never block writers.
+//
+// Each write executes the events on a copy of the model and replaces the
+// model for the readers afterwards. So a writer pays for the copy. The model
+// has to implement Cloner. Otherwise New returns an error.
+//
+// The model returned by ForReading must not be changed.
+func WithCopyOnWrite[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.copyOnWrite = true
+	}
+}
+
+// WithWriteQueue lets one goroutine do all writes. Write and the other Write
+// methods send their function to the goroutine and wait for the result. So
+// the writers do not compete for the lock. Together with WithCopyOnWrite,
+// readers and writers do not wait for each other.
+//
+// The functions see the latest model and the events are written in the order,
+// the functions where submitted. ForWriting and TryWrite do not use the queue.
+func WithWriteQueue[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.writeQueue = make(chan writeJob[Model])
+	}
+}
+
+// WithGroupCommit syncs the database for many writes together. A write waits
+// up to the window for other writes. Then the database is synced once and
+// all writes return. If maxBatch events are collected, the database is synced
+// earlier. Zero means no limit.
+//
+// When a write returns without an error, its events are on stable storage.
+// Use a FileDB with SyncNever, so the database does not sync each write
+// itself.
+//
+// If the sync fails, all writes of the group return the error and the model
+// is restored to the state before the group. A FileDB removes the records of
+// the group, if it was not rotated in the meantime. For other databases or
+// after a rotation, the records stay in the database and all following writes
+// return the error. Readers can see the model of a group before it is synced. The
+// projections, handlers and Listen iterators get the events after the sync.
+//
+// The database has to implement Sync and WithCopyOnWrite has to be used, so
+// the model before the group is not changed. Otherwise New returns an error.
+func WithGroupCommit[Model any](window time.Duration, maxBatch int) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.groupWindow = window
+		s.groupMaxBatch = maxBatch
+	}
+}
+
+// WithAsyncQueueSize sets the number of events, that WriteAsync holds before
+// they are appended. If the queue is full, WriteAsync waits. The default is
+// 1024.
+func WithAsyncQueueSize[Model any](size int) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.asyncQueueSize = size
+	}
+}
+
+// WithNotificationHistory sets how many writes the topic of Subscribe holds.
+// A subscriber, that is further behind, gets an error wrapping
+// ErrMissedEvents. The default is 1024. Zero means no limit.
+func WithNotificationHistory[Model any](n int) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.notificationHistory = n
+	}
+}
+
+// WithLoadProgress calls f during New with the number of loaded events and
+// the bytes, that where read from the database. It is called every 10000
+// events or every second and a last time after the load.
+func WithLoadProgress[Model any](f func(eventsLoaded int, bytesRead int64)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.onLoadProgress = f
+	}
+}
+
+// WithWriteMiddleware adds a middleware around the writes. It is used for the
+// write function of ForWriting and for Write and the other Write methods.
+//
+// The middleware gets the next WriteFunc. It can look at the events, measure
+// the time of next or return an error without calling next, so nothing is
+// written. The first middleware is called first.
+func WithWriteMiddleware[Model any](middleware func(next WriteFunc[Model]) WriteFunc[Model]) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.writeMiddlewares = append(s.writeMiddlewares, middleware)
+	}
+}
+
+// WithAfterWrite calls f after events where written and applied to the
+// model. f gets the events of one write and the model afterwards.
+//
+// f is called with the write lock, so it sees each write in order. Use
+// WithAfterWriteUnlocked to call it after the lock is released.
+//
+// With WithGroupCommit, f is called after the sync of the group and not at
+// all, if the sync fails. With WriteAsync, f is called by the background
+// goroutine after the events where appended and without the write lock.
+func WithAfterWrite[Model any](f func(events []AppliedEvent[Model], model Model)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.afterWriteHooks = append(s.afterWriteHooks, f)
+	}
+}
+
+// WithAfterWriteUnlocked calls the functions of WithAfterWrite after the
+// write lock is released. With ForWriting, they are called by the done
+// function.
+func WithAfterWriteUnlocked[Model any]() Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.afterWriteUnlocked = true
+	}
+}
+
+// WithBeforeWrite calls f for each event before it is validated. f can return
+// another event, for example with the id of the user from the context. The
+// returned event is validated and written. If f returns nil, the event is not
+// written. If f returns an error, nothing is written.
+//
+// The context is the one from WriteCtx or ForWritingCtx. Other writes use
+// context.Background. The functions are called in the order of the options.
+func WithBeforeWrite[Model any](f func(ctx context.Context, event Event[Model]) (Event[Model], error)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.beforeWriteHooks = append(s.beforeWriteHooks, f)
+	}
+}
+
+// WithLogger logs the loading, writes, snapshots, compactions and storage
+// errors with l. The progress of the loading and each write are logged with
+// level debug. Without a
+// logger, sticky does not log anything.
+//
+// Each record has the attribute `sticky` with the name from WithName.
+func WithLogger[Model any](l *slog.Logger) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.logger = l
+	}
+}
+
+// WithName sets the name of the model for the logger. Default is the type of
+// the model.
+func WithName[Model any](name string) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.name = name
+	}
+}
+
+// WithTrace calls start at the beginning of an operation. operation is
+// "load", "write", "snapshot" or "compact". The returned function is called
+// with the result, when the operation is done. The returned context is given
+// to the functions of WithBeforeWrite.
+//
+// ctx is the context of WriteCtx, ForWritingCtx or Compact. Other operations
+// use context.Background. Writes and snapshots are traced with the write
+// lock held. The package stickyotel uses it for OpenTelemetry.
+func WithTrace[Model any](start func(ctx context.Context, operation string) (context.Context, func(TraceResult))) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.trace = start
+	}
+}
+
+// WithHandler calls f for each event with the name. f gets the event, its time
+// and the model after the event.
+//
+// f is called on load and after each write in the order of the events. Use
+// HandlerSkipLoad to only call it after writes. With handlers, that are called
+// on load, snapshots are not used on load. f is called with the write lock,
+// so it must not write. A panic in f is recovered. See WithHandlerPanic.
+//
+// Handlers can only be added with New, so they get all events on load.
+func WithHandler[Model any](name string, f func(event Event[Model], t time.Time, model Model), options ...HandlerOption) Option[Model] {
+	var cfg handlerConfig
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	return func(s *Sticky[Model]) {
+		if s.loader.handlers == nil {
+			s.loader.handlers = make(map[string][]eventHandler[Model])
+		}
+		s.loader.handlers[name] = append(s.loader.handlers[name], eventHandler[Model]{f: f, skipLoad: cfg.skipLoad})
+	}
+}
+
+// WithHandlerPanic sets a function, that is called, when a function of
+// WithHandler panics. It gets the name of the event and the recovered value.
+func WithHandlerPanic[Model any](f func(name string, recovered any)) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.onHandlerPanic = f
+	}
+}
+
+// WithShredding encrypts the personal fields of events, that implement
+// PersonalData, with a key of the subject from the store. When the database
+// is loaded, the fields are decrypted. If the key was deleted with
+// Sticky.Forget, the fields get their zero values.
+//
+// Without WithShredding, the fields are written unencrypted and a database
+// with encrypted fields can not be loaded. With WithShredding, the database
+// can not be compacted, since the snapshot would keep the data of the folded
+// events.
+func WithShredding[Model any](store KeyStore) Option[Model] {
+	return func(s *Sticky[Model]) {
+		s.loader.shredder = newShredder(store)
+	}
+}
@@ -0,0 +1,231 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// OffsetStore stores the sequence number of the last dispatched event of an
+// Outbox.
+type OffsetStore interface {
+	// Load returns the saved sequence number. It returns 0, if nothing was
+	// saved.
+	Load() (uint64, error)
+
+	// Save stores the sequence number.
+	Save(seq uint64) error
+}
+
+// FileOffsetStore stores the sequence number in the file Path.
+type FileOffsetStore struct {
+	Path string
+}
+
+// Load reads the sequence number from the file.
+func (fs FileOffsetStore) Load() (uint64, error) {
+	data, err := os.ReadFile(fs.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("reading offset: %w", err)
+	}
+
+	seq, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset in %s: %w", fs.Path, err)
+	}
+	return seq, nil
+}
+
+// Save writes the sequence number to a temporary file and renames it, so the
+// file is never incomplete.
+func (fs FileOffsetStore) Save(seq uint64) error {
+	tmp := fs.Path + ".tmp"
+	if err := writeFileSync(tmp, []byte(strconv.FormatUint(seq, 10))); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, fs.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename offset: %w", err)
+	}
+
+	return syncDir(filepath.Dir(fs.Path))
+}
+
+const (
+	defaultOutboxMinBackoff = 100 * time.Millisecond
+	defaultOutboxMaxBackoff = 30 * time.Second
+)
+
+// OutboxOption is a option for NewOutbox.
+type OutboxOption func(*outboxConfig)
+
+type outboxConfig struct {
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// OutboxBackoff sets the time between retries of a failed dispatch. It starts
+// with min and is doubled until max. Default is 100ms to 30s.
+func OutboxBackoff(min, max time.Duration) OutboxOption {
+	return func(c *outboxConfig) {
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+// OutboxStats are the numbers of an Outbox.
+type OutboxStats struct {
+	// Acknowledged is the sequence number of the last dispatched event.
+	Acknowledged uint64
+
+	// Lag is the difference between the sequence number of the last record
+	// and Acknowledged.
+	Lag uint64
+
+	// Failures is the number of failed dispatches.
+	Failures uint64
+}
+
+// Outbox dispatches the events of a Sticky to an external system. The
+// sequence number of the last dispatched event is saved in an OffsetStore, so
+// the dispatch continues after a restart.
+//
+// Each event is dispatched at least once. Events are dispatched in the order
+// of the database. After a crash, the event, that was dispatched but not
+// saved, is dispatched again. The external system has to handle duplicates,
+// for example with the sequence number.
+type Outbox[Model any] struct {
+	s        *Sticky[Model]
+	store    OffsetStore
+	dispatch func(ctx context.Context, n Notification[Model]) error
+	cfg      outboxConfig
+
+	acknowledged atomic.Uint64
+	failures     atomic.Uint64
+}
+
+// NewOutbox creates an outbox. Call Run to dispatch the events.
+//
+// dispatch is called with each event. If it returns an error, it is called
+// again with the same event after a backoff. See OutboxBackoff.
+func NewOutbox[Model any](s *Sticky[Model], store OffsetStore, dispatch func(ctx context.Context, n Notification[Model]) error, options ...OutboxOption) *Outbox[Model] {
+	cfg := outboxConfig{
+		minBackoff: defaultOutboxMinBackoff,
+		maxBackoff: defaultOutboxMaxBackoff,
+	}
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	return &Outbox[Model]{
+		s:        s,
+		store:    store,
+		dispatch: dispatch,
+		cfg:      cfg,
+	}
+}
+
+// Run dispatches the events after the saved sequence number and afterwards
+// each written event. It blocks until the context is done or the Sticky is
+// closed.
+//
+// It returns the error of the context, ErrClosed or the error of the
+// OffsetStore or of the subscription.
+func (o *Outbox[Model]) Run(ctx context.Context) error {
+	seq, err := o.store.Load()
+	if err != nil {
+		return fmt.Errorf("loading offset: %w", err)
+	}
+	o.acknowledged.Store(seq)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(o.s.closeCtx, cancel)
+	defer stop()
+
+	var runErr error
+	o.s.SubscribeFrom(ctx, seq+1)(func(notifications []Notification[Model], err error) bool {
+		if err != nil {
+			runErr = err
+			return false
+		}
+
+		for _, n := range notifications {
+			if err := o.dispatchWithRetry(ctx, n); err != nil {
+				return false
+			}
+
+			if err := o.store.Save(n.Seq); err != nil {
+				runErr = fmt.Errorf("saving offset: %w", err)
+				return false
+			}
+			o.acknowledged.Store(n.Seq)
+		}
+		return true
+	})
+
+	if runErr != nil {
+		return runErr
+	}
+
+	if o.s.closeCtx.Err() != nil {
+		return ErrClosed
+	}
+	return ctx.Err()
+}
+
+// dispatchWithRetry calls dispatch until it succeeds or the context is done.
+func (o *Outbox[Model]) dispatchWithRetry(ctx context.Context, n Notification[Model]) error {
+	backoff := o.cfg.minBackoff
+	for {
+		err := o.dispatch(ctx, n)
+		if err == nil {
+			return nil
+		}
+		o.failures.Add(1)
+
+		if o.s.logger != nil {
+			o.s.logger.Warn("outbox dispatch failed", "event", n.Name, "seq", n.Seq, "error", err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, o.cfg.maxBackoff)
+	}
+}
+
+// Stats returns the numbers of the outbox.
+func (o *Outbox[Model]) Stats() OutboxStats {
+	o.s.mu.RLock()
+	seq := o.s.seq
+	o.s.mu.RUnlock()
+
+	acknowledged := o.acknowledged.Load()
+	var lag uint64
+	if seq > acknowledged {
+		lag = seq - acknowledged
+	}
+
+	return OutboxStats{
+		Acknowledged: acknowledged,
+		Lag:          lag,
+		Failures:     o.failures.Load(),
+	}
+}
@@ -0,0 +1,91 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+	)
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	store := FileOffsetStore{Path: filepath.Join(t.TempDir(), "offset")}
+
+	var mu sync.Mutex
+	var dispatched []uint64
+	failed := false
+	dispatch := func(_ context.Context, n Notification[testModel]) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if n.Seq == 2 && !failed {
+			failed = true
+			return errors.New("queue is not available")
+		}
+		dispatched = append(dispatched, n.Seq)
+		return nil
+	}
+
+	run := func(expectSeq uint64) {
+		t.Helper()
+
+		outbox := NewOutbox(s, store, dispatch, OutboxBackoff(time.Millisecond, time.Millisecond))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- outbox.Run(ctx)
+		}()
+
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		timeout := time.After(time.Second)
+		for outbox.Stats().Acknowledged != expectSeq {
+			select {
+			case <-timeout:
+				t.Fatalf("outbox got to seq %d, expected %d", outbox.Stats().Acknowledged, expectSeq)
+			case <-time.After(time.Millisecond):
+			}
+		}
+
+		if lag := outbox.Stats().Lag; lag != 0 {
+			t.Errorf("got lag %d, expected 0", lag)
+		}
+
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("run returned `%v`, expected context.Canceled", err)
+		}
+	}
+
+	run(3)
+	run(4)
+
+	mu.Lock()
+	defer mu.Unlock()
+	expect := []uint64{1, 2, 3, 4}
+	if len(dispatched) != len(expect) {
+		t.Fatalf("dispatched %v, expected %v", dispatched, expect)
+	}
+	for i := range expect {
+		if dispatched[i] != expect[i] {
+			t.Fatalf("dispatched %v, expected %v", dispatched, expect)
+		}
+	}
+
+	if seq, err := store.Load(); err != nil || seq != 4 {
+		t.Errorf("store has seq %d with error %v, expected 4", seq, err)
+	}
+}
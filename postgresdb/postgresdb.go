@@ -0,0 +1,254 @@
+// Package postgresdb implements a sticky database that stores the events in a
+// postgres table.
+//
+// Many logs can be stored in the same table. Each log is identified by its
+// name.
+package postgresdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"regexp"
+
+	"github.com/ostcar/sticky/internal/records"
+)
+
+const defaultTable = "sticky_events"
+
+var validTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ErrLocked is returned from Open, when another session holds the lock for
+// the log.
+var ErrLocked = errors.New("log is locked by another session")
+
+// Option is a option for postgresdb.Open()
+type Option func(db *DB)
+
+// WithTable uses a custom table name. Default is "sticky_events".
+func WithTable(name string) Option {
+	return func(db *DB) {
+		db.table = name
+	}
+}
+
+// DB is a event database based on a postgres table.
+type DB struct {
+	pool  *sql.DB
+	conn  *sql.Conn
+	table string
+	log   string
+	key   int64
+}
+
+// Open creates the table, if it does not exist, and takes a session-level
+// advisory lock for the log name.
+//
+// The lock is hold on a dedicated connection from the pool until Close is
+// called. If another session holds the lock, Open returns ErrLocked.
+func Open(ctx context.Context, pool *sql.DB, log string, options ...Option) (*DB, error) {
+	db := DB{
+		pool:  pool,
+		table: defaultTable,
+		log:   log,
+		key:   lockKey(log),
+	}
+
+	for _, o := range options {
+		o(&db)
+	}
+
+	if !validTable.MatchString(db.table) {
+		return nil, fmt.Errorf("invalid table name `%s`", db.table)
+	}
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get connection: %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, db.key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("take advisory lock: %w", err)
+	}
+
+	if !locked {
+		conn.Close()
+		return nil, fmt.Errorf("log `%s`: %w", log, ErrLocked)
+	}
+
+	create := fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (id bigserial PRIMARY KEY, log text NOT NULL, event bytea NOT NULL)`,
+		db.table,
+	)
+	if _, err := conn.ExecContext(ctx, create); err != nil {
+		db.unlock(conn)
+		return nil, fmt.Errorf("create table %s: %w", db.table, err)
+	}
+
+	db.conn = conn
+	return &db, nil
+}
+
+// lockKey returns the key for the advisory lock of a log.
+func lockKey(log string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(log))
+	return int64(h.Sum64())
+}
+
+// Reader returns all events of the log in order separated by newlines.
+func (db *DB) Reader() (io.ReadCloser, error) {
+	rows, err := db.pool.Query(
+		fmt.Sprintf(`SELECT event FROM %s WHERE log = $1 ORDER BY id`, db.table),
+		db.log,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	return &rowReader{rows: rows}, nil
+}
+
+// Append inserts an event as new row.
+//
+// The insert happens in a transaction on the connection that holds the lock.
+func (db *DB) Append(bs []byte) error {
+	return db.AppendBatch([][]byte{bs})
+}
+
+// AppendBatch inserts many events in one transaction.
+func (db *DB) AppendBatch(records [][]byte) error {
+	for _, bs := range records {
+		if bytes.Contains(bs, []byte("\n")) {
+			return errors.New("event contains a newline")
+		}
+	}
+
+	ctx := context.Background()
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert := fmt.Sprintf(`INSERT INTO %s (log, event) VALUES ($1, $2)`, db.table)
+	for _, bs := range records {
+		if _, err := tx.ExecContext(ctx, insert, db.log, bs); err != nil {
+			return fmt.Errorf("insert event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit events: %w", err)
+	}
+	return nil
+}
+
+// Size returns the size of all events of the log including the newlines.
+func (db *DB) Size() (int64, error) {
+	var size int64
+	query := fmt.Sprintf(`SELECT COALESCE(SUM(octet_length(event) + 1), 0) FROM %s WHERE log = $1`, db.table)
+	if err := db.pool.QueryRow(query, db.log).Scan(&size); err != nil {
+		return 0, fmt.Errorf("query size: %w", err)
+	}
+	return size, nil
+}
+
+// ReplaceWith deletes all events of the log and inserts the lines from r in
+// one transaction.
+func (db *DB) ReplaceWith(r io.Reader) error {
+	ctx := context.Background()
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE log = $1`, db.table), db.log); err != nil {
+		return fmt.Errorf("delete events: %w", err)
+	}
+
+	insert := fmt.Sprintf(`INSERT INTO %s (log, event) VALUES ($1, $2)`, db.table)
+	br := bufio.NewReader(r)
+	for {
+		record, err := records.Next(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading new content: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, insert, db.log, record); err != nil {
+			return fmt.Errorf("insert event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit events: %w", err)
+	}
+	return nil
+}
+
+// Close releases the advisory lock and returns the connection to the pool.
+//
+// The pool itself is not closed.
+func (db *DB) Close() error {
+	return db.unlock(db.conn)
+}
+
+func (db *DB) unlock(conn *sql.Conn) error {
+	_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, db.key)
+	if cErr := conn.Close(); err == nil {
+		err = cErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("release advisory lock: %w", err)
+	}
+	return nil
+}
+
+// rowReader streams the rows of a query as newline separated bytes.
+type rowReader struct {
+	rows *sql.Rows
+	buf  []byte
+	err  error
+}
+
+func (r *rowReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		if !r.rows.Next() {
+			r.err = io.EOF
+			if err := r.rows.Err(); err != nil {
+				r.err = fmt.Errorf("reading events: %w", err)
+			}
+			continue
+		}
+
+		var event []byte
+		if err := r.rows.Scan(&event); err != nil {
+			r.err = fmt.Errorf("scanning event: %w", err)
+			continue
+		}
+		r.buf = append(event, '\n')
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *rowReader) Close() error {
+	return r.rows.Close()
+}
@@ -0,0 +1,98 @@
+package postgresdb_test
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/ostcar/sticky/postgresdb"
+)
+
+// openPool connects to the database in STICKY_POSTGRES_DSN. The test is
+// skipped, if the variable is not set.
+func openPool(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("STICKY_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("STICKY_POSTGRES_DSN is not set")
+	}
+
+	pool, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func TestPostgresDB_round_trip(t *testing.T) {
+	ctx := context.Background()
+	pool := openPool(t)
+	logName := fmt.Sprintf("%s-%d", t.Name(), os.Getpid())
+	t.Cleanup(func() { pool.Exec(`DELETE FROM sticky_events WHERE log = $1`, logName) })
+
+	db, err := postgresdb.Open(ctx, pool, logName)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 100; i++ {
+		if err := db.Append([]byte(fmt.Sprintf(`{"number":%d}`, i))); err != nil {
+			t.Fatalf("append event %d: %v", i, err)
+		}
+	}
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		expect := fmt.Sprintf(`{"number":%d}`, i)
+		if scanner.Text() != expect {
+			t.Fatalf("line %d is `%s`, expected `%s`", i, scanner.Text(), expect)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if i != 100 {
+		t.Errorf("got %d events, expected 100", i)
+	}
+}
+
+func TestPostgresDB_second_open_is_locked(t *testing.T) {
+	ctx := context.Background()
+	pool := openPool(t)
+	logName := fmt.Sprintf("%s-%d", t.Name(), os.Getpid())
+
+	db, err := postgresdb.Open(ctx, pool, logName)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	if _, err := postgresdb.Open(ctx, pool, logName); !errors.Is(err, postgresdb.ErrLocked) {
+		t.Errorf("second open returned `%v`, expected ErrLocked", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("closing db: %v", err)
+	}
+
+	db, err = postgresdb.Open(ctx, pool, logName)
+	if err != nil {
+		t.Fatalf("open db after close: %v", err)
+	}
+	db.Close()
+}
@@ -0,0 +1,60 @@
+package sticky
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// loadProgressEvery is the number of events, after which WithLoadProgress
+// reports the progress. It is also reported after each second.
+const loadProgressEvery = 10_000
+
+// countingReader counts the bytes, that are read.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// loadProgress calls the function of WithLoadProgress during the load.
+type loadProgress struct {
+	f          func(eventsLoaded int, bytesRead int64)
+	reader     *countingReader
+	events     int
+	lastEvents int
+	lastTime   time.Time
+}
+
+func newLoadProgress(r *countingReader, f func(eventsLoaded int, bytesRead int64)) *loadProgress {
+	return &loadProgress{
+		f:        f,
+		reader:   r,
+		lastTime: time.Now(),
+	}
+}
+
+// event counts a loaded event and reports the progress, if enough events
+// where loaded or enough time passed.
+func (p *loadProgress) event() {
+	p.events++
+
+	since := p.events - p.lastEvents
+	if since < loadProgressEvery && (since%100 != 0 || time.Since(p.lastTime) < time.Second) {
+		return
+	}
+
+	p.report()
+}
+
+// report calls the function with the current numbers.
+func (p *loadProgress) report() {
+	p.lastEvents = p.events
+	p.lastTime = time.Now()
+	p.f(p.events, p.reader.n.Load())
+}
@@ -0,0 +1,120 @@
+package sticky
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Projection is a secondary model, that is updated with each event. Use it
+// for indexes, that would be to expensive to compute from the model on each
+// read.
+//
+// Apply is called with the write lock after an event was written and for
+// each event on load. An error does not fail the write. See
+// WithProjectionError.
+type Projection[Model any] interface {
+	Apply(eventName string, event Event[Model], t time.Time) error
+}
+
+// namedProjection is a projection with its name.
+type namedProjection[Model any] struct {
+	name       string
+	projection Projection[Model]
+}
+
+// applyProjections calls all projections with the event.
+func (l *loader[Model]) applyProjections(name string, event Event[Model], t time.Time) {
+	for _, p := range l.projections {
+		if err := p.projection.Apply(name, event, t); err != nil && l.onProjectionError != nil {
+			l.onProjectionError(p.name, fmt.Errorf("projection %s: %w", p.name, err))
+		}
+	}
+}
+
+// checkProjections returns an error, if two projections have the same name.
+func (l *loader[Model]) checkProjections() error {
+	names := make(map[string]bool, len(l.projections))
+	for _, p := range l.projections {
+		if names[p.name] {
+			return fmt.Errorf("projection %s already exists", p.name)
+		}
+		names[p.name] = true
+	}
+	return nil
+}
+
+// AddProjection adds a projection after New. All events of the database are
+// applied to it, before AddProjection returns. It holds the write lock during
+// this time. Use WithProjection to add it on load.
+//
+// If the database was compacted, only the events after the compaction are
+// applied.
+func (s *Sticky[Model]) AddProjection(ctx context.Context, name string, p Projection[Model]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	if s.projection(name) != nil {
+		return fmt.Errorf("projection %s already exists", name)
+	}
+
+	if s.records > 0 {
+		r, err := s.db.Reader()
+		if err != nil {
+			return StorageError{Op: "open database", Err: err}
+		}
+		defer r.Close()
+
+		l := s.loader.replayLoader()
+		l.maxRecords = s.records
+		l.projections = []namedProjection[Model]{{name: name, projection: p}}
+		l.onProjectionError = s.loader.onProjectionError
+		l.stop = func(Envelope) (bool, error) {
+			return false, ctx.Err()
+		}
+
+		if _, err := l.load(r, s.newModel(), 0); err != nil {
+			return fmt.Errorf("replaying database: %w", err)
+		}
+	}
+
+	s.loader.projections = append(s.loader.projections, namedProjection[Model]{name: name, projection: p})
+	return nil
+}
+
+// Projection returns the projection with the name or nil, if it does not
+// exist.
+//
+// The projection is changed with the write lock. Use ReadProjection to read
+// it, while events are written.
+func (s *Sticky[Model]) Projection(name string) Projection[Model] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.projection(name)
+}
+
+// ReadProjection calls f with the projection, while holding the read lock.
+func (s *Sticky[Model]) ReadProjection(name string, f func(Projection[Model]) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p := s.projection(name)
+	if p == nil {
+		return fmt.Errorf("projection %s does not exist", name)
+	}
+	return f(p)
+}
+
+func (s *Sticky[Model]) projection(name string) Projection[Model] {
+	for _, p := range s.loader.projections {
+		if p.name == name {
+			return p.projection
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countProjection counts the events by value.
+type countProjection struct {
+	byValue map[int]int
+}
+
+func (p *countProjection) Apply(_ string, event Event[snapshotModel], _ time.Time) error {
+	// Loaded events are pointers, written events are values.
+	var value int
+	switch e := event.(type) {
+	case snapshotAddEvent:
+		value = e.Value
+	case *snapshotAddEvent:
+		value = e.Value
+	}
+
+	if value < 0 {
+		return errors.New("negative value")
+	}
+	p.byValue[value]++
+	return nil
+}
+
+func TestWithProjection(t *testing.T) {
+	db := NewMemoryDB()
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for _, value := range []int{1, 2, 2} {
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: value} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	// The snapshot is not used, since the projection needs all events.
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	var projectionErrors []string
+	onError := func(name string, _ error) {
+		projectionErrors = append(projectionErrors, name)
+	}
+
+	projection := &countProjection{byValue: make(map[int]int)}
+	reloaded, err := New(db, snapshotModel{}, getSnapshotTestEvent, WithProjection[snapshotModel]("count", projection), WithProjectionError[snapshotModel](onError))
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	if err := reloaded.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := reloaded.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: -1} }); err != nil {
+		t.Errorf("write with failing projection returned: %v", err)
+	}
+
+	err = reloaded.ReadProjection("count", func(p Projection[snapshotModel]) error {
+		counts := p.(*countProjection).byValue
+		if counts[1] != 2 || counts[2] != 2 {
+			t.Errorf("got counts %v, expected two of 1 and 2", counts)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("read projection: %v", err)
+	}
+
+	if len(projectionErrors) != 1 || projectionErrors[0] != "count" {
+		t.Errorf("got projection errors %v, expected one of `count`", projectionErrors)
+	}
+}
+
+func TestAddProjection(t *testing.T) {
+	db := NewMemoryDB()
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	projection := &countProjection{byValue: make(map[int]int)}
+	if err := s.AddProjection(context.Background(), "count", projection); err != nil {
+		t.Fatalf("add projection: %v", err)
+	}
+
+	if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := s.Projection("count").(*countProjection).byValue[1]; got != 2 {
+		t.Errorf("projection got %d events, expected 2", got)
+	}
+
+	if err := s.AddProjection(context.Background(), "count", projection); err == nil {
+		t.Errorf("adding a projection twice returned no error")
+	}
+}
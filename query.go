@@ -0,0 +1,99 @@
+package sticky
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Query selects events for Sticky.Query.
+type Query struct {
+	// Types are the names of the events. Empty means all events.
+	Types []string
+
+	// Where is called with the payload of each event, that passes the other
+	// conditions. Nil means all payloads. See PathEquals.
+	Where func(payload json.RawMessage) bool
+
+	// Since and Until limit the time of the events. Since is inclusive,
+	// Until exclusive. The zero time means no limit.
+	Since time.Time
+	Until time.Time
+
+	// Limit is the maximum number of returned events. 0 means no limit.
+	Limit int
+}
+
+// Query returns the envelopes of the events, that match the query.
+//
+// Query reads the whole database like Events. There is no index, so it takes
+// as long as loading the database. Use it for debugging and not in the hot
+// path. The reading stops, when ctx is done or Limit is reached.
+func (s *Sticky[Model]) Query(ctx context.Context, q Query) ([]Envelope, error) {
+	filters := []EventFilter{FilterTime(q.Since, q.Until)}
+	if len(q.Types) > 0 {
+		filters = append(filters, FilterNames(q.Types...))
+	}
+
+	var result []Envelope
+	var queryErr error
+	s.Events(ctx, filters...)(func(e Envelope, err error) bool {
+		if err != nil {
+			queryErr = err
+			return false
+		}
+
+		if q.Where != nil && !q.Where(e.Payload) {
+			return true
+		}
+
+		result = append(result, e)
+		return q.Limit <= 0 || len(result) < q.Limit
+	})
+
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	return result, nil
+}
+
+// PathEquals returns a function for Query.Where, that checks, that the value
+// at a dotted path of the payload is equal to value. For example
+// PathEquals("customer.id", 42) matches the payload
+// {"customer":{"id":42}}.
+//
+// The payload is decoded into map[string]any for each event. value is
+// compared after encoding it to JSON and decoding it, so 42 and 42.0 are
+// equal.
+func PathEquals(path string, value any) func(payload json.RawMessage) bool {
+	var expect any
+	encoded, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(encoded, &expect)
+	}
+	if err != nil {
+		return func(json.RawMessage) bool { return false }
+	}
+
+	keys := strings.Split(path, ".")
+	return func(payload json.RawMessage) bool {
+		var current any
+		if err := json.Unmarshal(payload, &current); err != nil {
+			return false
+		}
+
+		for _, key := range keys {
+			object, ok := current.(map[string]any)
+			if !ok {
+				return false
+			}
+
+			if current, ok = object[key]; !ok {
+				return false
+			}
+		}
+		return reflect.DeepEqual(current, expect)
+	}
+}
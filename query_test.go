@@ -0,0 +1,91 @@
+package sticky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"order","payload":{"customer":{"id":42},"total":10}}`,
+		`{"time":"2024-01-02 00:00:00","type":"order","payload":{"customer":{"id":7},"total":20}}`,
+		`{"time":"2024-01-03 00:00:00","type":"add","payload":{"value":42}}`,
+		`{"time":"2024-01-04 00:00:00","type":"order","payload":{"customer":{"id":42},"total":30}}`,
+	)
+
+	s, err := New(db, testModel{}, getTestEvent, WithUnknownEvents[testModel](UnknownEventsSkip))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		query  Query
+		expect []uint64
+	}{
+		{"all", Query{}, []uint64{1, 2, 3, 4}},
+		{"path", Query{Types: []string{"order"}, Where: PathEquals("customer.id", 42)}, []uint64{1, 4}},
+		{"limit", Query{Where: PathEquals("customer.id", 42), Limit: 1}, []uint64{1}},
+		{"time", Query{Types: []string{"order"}, Since: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}, []uint64{2, 4}},
+		{"missing path", Query{Where: PathEquals("value.id", 42)}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			envelopes, err := s.Query(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+
+			var got []uint64
+			for _, e := range envelopes {
+				got = append(got, e.Seq)
+			}
+
+			if len(got) != len(tt.expect) {
+				t.Fatalf("got seqs %v, expected %v", got, tt.expect)
+			}
+			for i := range got {
+				if got[i] != tt.expect[i] {
+					t.Fatalf("got seqs %v, expected %v", got, tt.expect)
+				}
+			}
+		})
+	}
+}
+
+func TestQuery_canceled(t *testing.T) {
+	s, err := New(NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.Query(ctx, Query{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error `%v`, expected context.Canceled", err)
+	}
+}
+
+func TestPathEquals(t *testing.T) {
+	payload := json.RawMessage(`{"a":{"b":"x","n":1.0},"list":[1]}`)
+
+	for _, tt := range []struct {
+		path   string
+		value  any
+		expect bool
+	}{
+		{"a.b", "x", true},
+		{"a.b", "y", false},
+		{"a.n", 1, true},
+		{"a.c", nil, false},
+		{"list", []int{1}, true},
+		{"list.0", 1, false},
+	} {
+		if got := PathEquals(tt.path, tt.value)(payload); got != tt.expect {
+			t.Errorf("PathEquals(%s, %v) = %t, expected %t", tt.path, tt.value, got, tt.expect)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package sticky
+
+import "context"
+
+// writeJob is a function, that the write goroutine calls with the write lock.
+type writeJob[Model any] struct {
+	ctx    context.Context
+	f      func() error
+	result chan writeResult[Model]
+}
+
+// writeResult is the result of a writeJob. If f panics, the value is given
+// to the caller. With WithGroupCommit, the caller waits for the commit.
+// Afterwards, it calls the functions of WithAfterWrite, if they are called
+// without the lock.
+type writeResult[Model any] struct {
+	err      error
+	panicked any
+	commit   *commitGroup[Model]
+	after    []func()
+}
+
+// withWriteLock calls f with the write lock.
+//
+// With WithWriteQueue, f is called by the write goroutine. The jobs are
+// called in the order, they where submitted.
+func (s *Sticky[Model]) withWriteLock(ctx context.Context, f func() error) error {
+	var result writeResult[Model]
+	if s.writeQueue == nil {
+		if err := s.lockWrite(ctx); err != nil {
+			return err
+		}
+
+		result = func() writeResult[Model] {
+			defer s.mu.Unlock()
+			err := f()
+			return writeResult[Model]{err: err, commit: s.takeCommit(), after: s.takeAfterWrite()}
+		}()
+	} else {
+		job := writeJob[Model]{ctx: ctx, f: f, result: make(chan writeResult[Model], 1)}
+
+		// The queue is unbuffered, so each job, that is sent, is also called.
+		select {
+		case s.writeQueue <- job:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.closeCtx.Done():
+			return ErrClosed
+		}
+
+		result = <-job.result
+		if result.panicked != nil {
+			panic(result.panicked)
+		}
+	}
+
+	if result.commit != nil {
+		if err := result.commit.wait(); err != nil {
+			return err
+		}
+	}
+
+	for _, call := range result.after {
+		call()
+	}
+	return result.err
+}
+
+// runWriteQueue calls the jobs of the write queue until the Sticky is closed.
+func (s *Sticky[Model]) runWriteQueue() {
+	defer s.background.Done()
+
+	for {
+		select {
+		case job := <-s.writeQueue:
+			job.result <- s.runWriteJob(job)
+		case <-s.closeCtx.Done():
+			return
+		}
+	}
+}
+
+func (s *Sticky[Model]) runWriteJob(job writeJob[Model]) (result writeResult[Model]) {
+	defer func() {
+		if p := recover(); p != nil {
+			result = writeResult[Model]{panicked: p}
+		}
+	}()
+
+	if err := s.lockWrite(job.ctx); err != nil {
+		return writeResult[Model]{err: err}
+	}
+	defer s.mu.Unlock()
+
+	err := job.f()
+	return writeResult[Model]{err: err, commit: s.takeCommit(), after: s.takeAfterWrite()}
+}
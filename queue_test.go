@@ -0,0 +1,100 @@
+package sticky
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithWriteQueue(t *testing.T) {
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getTestEvent, WithWriteQueue[testModel]())
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+				t.Errorf("write: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each function sees the model of the function before.
+	for i := 0; i < 10; i++ {
+		err := s.WriteMany(func(m testModel) ([]Event[testModel], error) {
+			if m.Sum != 50+i {
+				return nil, errors.New("function does not see the latest model")
+			}
+			return []Event[testModel]{addEvent{Value: 1}}, nil
+		})
+		if err != nil {
+			t.Fatalf("write many: %v", err)
+		}
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 60 {
+		t.Errorf("got sum %d, expected 60", model.Sum)
+	}
+
+	for i, record := range db.Records() {
+		envelope, err := DecodeEnvelope(record)
+		if err != nil {
+			t.Fatalf("decoding record %d: %v", i, err)
+		}
+
+		if envelope.Version != uint64(i+1) {
+			t.Errorf("record %d has version %d, expected %d", i, envelope.Version, i+1)
+		}
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); !errors.Is(err, ErrClosed) {
+		t.Errorf("write after close returned `%v`, expected ErrClosed", err)
+	}
+}
+
+func TestWithWriteQueue_panic(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithWriteQueue[testModel]())
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("got panic `%v`, expected boom", p)
+			}
+		}()
+
+		s.Write(func(testModel) Event[testModel] { panic("boom") })
+	}()
+
+	// The write goroutine still works.
+	written := make(chan error)
+	go func() {
+		written <- s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+	}()
+
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Errorf("write after panic: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("write after panic is blocked")
+	}
+}
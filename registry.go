@@ -0,0 +1,160 @@
+package sticky
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Naming is the style of event names, that are derived from the type name.
+type Naming int
+
+const (
+	// NamingLowerCamel derives `userCreated` from the type UserCreated.
+	NamingLowerCamel Naming = iota
+
+	// NamingSnakeCase derives `user_created` from the type UserCreated.
+	NamingSnakeCase
+)
+
+// Registry creates events by their name. Use its Get method as getEvent for
+// New:
+//
+//	var registry sticky.Registry[Model]
+//	registry.Register("add", func() sticky.Event[Model] { return &addEvent{} })
+//	s, err := sticky.New(db, Model{}, registry.Get)
+//
+// The zero value is an empty registry. Register is not safe for concurrent
+// use. Register all events before calling New.
+//
+// Events, whose Name method returns an empty string, get a name derived from
+// their type name. Use WithRegistry, so written events get the same name.
+type Registry[Model any] struct {
+	// Naming is the style of derived names.
+	Naming Naming
+
+	events map[string]func() Event[Model]
+}
+
+// Register adds an event. newEvent is called for each decoded record, so
+// each record gets its own instance. It has to return a pointer, so the
+// payload can be decoded into it.
+//
+// If name is empty, the name of the event is used. It returns an error, if
+// the name is already registered, or if the event returned by newEvent has
+// another name.
+func (r *Registry[Model]) Register(name string, newEvent func() Event[Model]) error {
+	event := newEvent()
+	if event == nil {
+		return fmt.Errorf("event `%s`: constructor returned nil", name)
+	}
+
+	if name == "" {
+		name = r.NameOf(event)
+	}
+
+	if name == "" {
+		return errors.New("event name is empty")
+	}
+
+	if name == snapshotType {
+		return fmt.Errorf("event name %s is reserved for snapshots", snapshotType)
+	}
+
+	if _, ok := r.events[name]; ok {
+		return fmt.Errorf("event `%s` is already registered", name)
+	}
+
+	if reflect.ValueOf(event).Kind() != reflect.Pointer {
+		return fmt.Errorf("event `%s`: constructor has to return a pointer, got %T", name, event)
+	}
+
+	if got := r.NameOf(event); got != name {
+		return fmt.Errorf("event `%s`: constructor returned event with name `%s`", name, got)
+	}
+
+	if r.events == nil {
+		r.events = make(map[string]func() Event[Model])
+	}
+	r.events[name] = newEvent
+	return nil
+}
+
+// Get returns a new instance of the event or nil, if the name is not
+// registered.
+func (r *Registry[Model]) Get(name string) Event[Model] {
+	newEvent, ok := r.events[name]
+	if !ok {
+		return nil
+	}
+	return newEvent()
+}
+
+// NameOf returns the name of the event. If its Name method returns an empty
+// string, the name is derived from the type name.
+func (r *Registry[Model]) NameOf(event Event[Model]) string {
+	if name := event.Name(); name != "" {
+		return name
+	}
+	return deriveName(event, r.Naming)
+}
+
+// Names returns the sorted names of the registered events.
+func (r *Registry[Model]) Names() []string {
+	names := make([]string, 0, len(r.events))
+	for name := range r.events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check returns an error, if an event has another name then it was
+// registered with. This can happen, when the Naming was changed after
+// registration. Then two events could have the same name.
+func (r *Registry[Model]) Check() error {
+	for _, name := range r.Names() {
+		if got := r.NameOf(r.events[name]()); got != name {
+			return fmt.Errorf("event `%s` has the name `%s`", name, got)
+		}
+	}
+	return nil
+}
+
+// deriveName returns a name from the type name of the event. Type
+// parameters are ignored.
+func deriveName(event any, naming Naming) string {
+	t := reflect.TypeOf(event)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	name, _, _ := strings.Cut(t.Name(), "[")
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			b.WriteRune(r)
+			continue
+		}
+
+		// An upper case letter starts a new word, if it follows a lower case
+		// letter or if it is the last letter of an abbreviation.
+		startsWord := i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+
+		switch {
+		case naming == NamingSnakeCase && startsWord:
+			b.WriteRune('_')
+			b.WriteRune(unicode.ToLower(r))
+		case naming == NamingLowerCamel && startsWord:
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
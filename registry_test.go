@@ -0,0 +1,212 @@
+package sticky
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	var registry Registry[testModel]
+	if err := registry.Register("add", func() Event[testModel] { return &addEvent{} }); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+	)
+
+	var loaded []*addEvent
+	hook := func(event Event[testModel], _ map[string]string) {
+		loaded = append(loaded, event.(*addEvent))
+	}
+
+	s, err := New(db, testModel{}, registry.Get, WithLoadHook(hook))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 3 {
+		t.Errorf("got sum %d, expected 3", model.Sum)
+	}
+
+	if len(loaded) != 2 || loaded[0] == loaded[1] || loaded[0].Value != 1 {
+		t.Errorf("events share one instance")
+	}
+
+	if event := registry.Get("unknown"); event != nil {
+		t.Errorf("got event %v for unknown name, expected nil", event)
+	}
+}
+
+func TestRegistry_Register_errors(t *testing.T) {
+	var registry Registry[testModel]
+	if err := registry.Register("add", func() Event[testModel] { return &addEvent{} }); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		newEvent func() Event[testModel]
+	}{
+		{"", func() Event[testModel] { return &addEvent{} }},
+		{"add", func() Event[testModel] { return &addEvent{} }},
+		{"other", func() Event[testModel] { return &addEvent{} }},
+		{"nil", func() Event[testModel] { return nil }},
+		{snapshotType, func() Event[testModel] { return &addEvent{} }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := registry.Register(tt.name, tt.newEvent); err == nil {
+				t.Errorf("register returned no error")
+			}
+		})
+	}
+}
+
+func TestRegistry_Register_value(t *testing.T) {
+	var registry Registry[testModel]
+	if err := registry.Register("add", func() Event[testModel] { return addEvent{} }); err == nil {
+		t.Errorf("register of a non pointer event returned no error")
+	}
+}
+
+// UserCreated has no name, so it is derived from the type.
+type UserCreated struct {
+	Value int `json:"value"`
+}
+
+func (e UserCreated) Name() string {
+	return ""
+}
+
+func (e UserCreated) Validate(testModel) error {
+	return nil
+}
+
+func (e UserCreated) Execute(m testModel, _ time.Time) testModel {
+	m.Sum += e.Value
+	return m
+}
+
+func TestRegistry_derived_names(t *testing.T) {
+	for _, tt := range []struct {
+		naming Naming
+		expect string
+	}{
+		{NamingLowerCamel, "userCreated"},
+		{NamingSnakeCase, "user_created"},
+	} {
+		t.Run(tt.expect, func(t *testing.T) {
+			registry := Registry[testModel]{Naming: tt.naming}
+			if err := registry.Register("", func() Event[testModel] { return &UserCreated{} }); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+
+			if names := registry.Names(); len(names) != 1 || names[0] != tt.expect {
+				t.Fatalf("got names %v, expected [%s]", names, tt.expect)
+			}
+
+			db := NewMemoryDB()
+			s, err := New(db, testModel{}, nil, WithRegistry(&registry))
+			if err != nil {
+				t.Fatalf("creating sticky: %v", err)
+			}
+
+			if err := s.Write(func(testModel) Event[testModel] { return UserCreated{Value: 1} }); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			envelope, err := DecodeEnvelope(db.Records()[0])
+			if err != nil {
+				t.Fatalf("decoding record: %v", err)
+			}
+
+			if envelope.Type != tt.expect {
+				t.Errorf("got type `%s`, expected `%s`", envelope.Type, tt.expect)
+			}
+		})
+	}
+}
+
+// AccountDebited has no name and fails, if the sum gets negative.
+type AccountDebited struct {
+	Value int `json:"value"`
+}
+
+func (e AccountDebited) Name() string {
+	return ""
+}
+
+func (e AccountDebited) Validate(testModel) error {
+	return nil
+}
+
+func (e AccountDebited) Execute(m testModel, _ time.Time) testModel {
+	m.Sum -= e.Value
+	return m
+}
+
+func (e AccountDebited) ExecuteErr(m testModel, _ time.Time) (testModel, error) {
+	if m.Sum < e.Value {
+		return m, errors.New("not enough money")
+	}
+	m.Sum -= e.Value
+	return m, nil
+}
+
+func TestRegistry_derived_name_in_execution_error(t *testing.T) {
+	var registry Registry[testModel]
+	if err := registry.Register("", func() Event[testModel] { return &AccountDebited{} }); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"accountDebited","payload":{"value":2}}`)
+	_, err := New(db, testModel{}, nil, WithRegistry(&registry))
+
+	var executionErr ExecutionError
+	if !errors.As(err, &executionErr) {
+		t.Fatalf("got error `%v`, expected an ExecutionError", err)
+	}
+
+	if executionErr.EventName != "accountDebited" {
+		t.Errorf("got event name `%s` on load, expected accountDebited", executionErr.EventName)
+	}
+}
+
+func TestRegistry_Check(t *testing.T) {
+	var registry Registry[testModel]
+	if err := registry.Register("", func() Event[testModel] { return &UserCreated{} }); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	registry.Naming = NamingSnakeCase
+	if _, err := New(NewMemoryDB(), testModel{}, nil, WithRegistry(&registry)); err == nil {
+		t.Errorf("New with changed naming returned no error")
+	}
+}
+
+func TestDeriveName(t *testing.T) {
+	for _, tt := range []struct {
+		event      any
+		lowerCamel string
+		snakeCase  string
+	}{
+		{UserCreated{}, "userCreated", "user_created"},
+		{&HTTPRequestDone{}, "httpRequestDone", "http_request_done"},
+		{&typedEvent[testModel, int]{}, "typedEvent", "typed_event"},
+	} {
+		if got := deriveName(tt.event, NamingLowerCamel); got != tt.lowerCamel {
+			t.Errorf("got `%s`, expected `%s`", got, tt.lowerCamel)
+		}
+
+		if got := deriveName(tt.event, NamingSnakeCase); got != tt.snakeCase {
+			t.Errorf("got `%s`, expected `%s`", got, tt.snakeCase)
+		}
+	}
+}
+
+type HTTPRequestDone struct{}
@@ -0,0 +1,216 @@
+package sticky
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// replaceExt is appended to File for the new content of a FileDB, that is
+// written by ReplaceWith.
+const replaceExt = ".replace"
+
+// Size returns the size of all segments and the active file on disk.
+func (db *FileDB) Size() (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.finishReplace(); err != nil {
+		return 0, err
+	}
+
+	segments, err := listSegments(db.File)
+	if err != nil {
+		return 0, fmt.Errorf("list segments: %w", err)
+	}
+	segments = append(segments, segment{path: db.File})
+
+	var size int64
+	for _, s := range segments {
+		info, err := os.Stat(s.path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return 0, fmt.Errorf("reading size of %s: %w", s.path, err)
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// ReplaceWith replaces all segments and the active file with the records
+// from r. Each line of r is one record.
+//
+// The new content is written to the file File+".replace". Afterwards, all
+// segments are removed and the file is renamed to File. If the process stops
+// in between, the replacement is finished, when the database is used the next
+// time. So after a crash, there is either the old or the new content.
+//
+// The caller has to make sure, that there are no concurrent appends. A Sticky
+// calls ReplaceWith with its write lock.
+func (db *FileDB) ReplaceWith(r io.Reader) error {
+	if db.ReadOnly {
+		return errors.New("database is read only")
+	}
+
+	db.compressing.Wait()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.lock(); err != nil {
+		return err
+	}
+
+	if err := db.closeFile(); err != nil {
+		return err
+	}
+
+	if cErr := db.compressErr; cErr != nil {
+		db.compressErr = nil
+		return fmt.Errorf("compressing segment: %w", cErr)
+	}
+
+	tmp := db.File + replaceExt + ".tmp"
+	if err := db.writeReplacement(tmp, r); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, db.File+replaceExt); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename replacement: %w", err)
+	}
+
+	if err := syncDir(filepath.Dir(db.File)); err != nil {
+		return err
+	}
+
+	return db.finishReplace()
+}
+
+// writeReplacement writes the records from r to the file path.
+func (db *FileDB) writeReplacement(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("create replacement: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	br := bufio.NewReader(r)
+	var buf []byte
+	for {
+		line, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			buf = append(buf, line...)
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reading new content: %w", err)
+		}
+
+		buf = append(buf, line...)
+		if len(buf) > 0 && buf[len(buf)-1] == '\n' {
+			buf = buf[:len(buf)-1]
+		}
+
+		if len(buf) > 0 {
+			record := buf
+			if db.Checksum {
+				record = appendChecksum(record, buf)
+			}
+			record = append(record, '\n')
+
+			if _, wErr := w.Write(record); wErr != nil {
+				return fmt.Errorf("writing replacement: %w", wErr)
+			}
+		}
+		buf = buf[:0]
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing replacement: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing replacement: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing replacement: %w", err)
+	}
+	return nil
+}
+
+// finishReplace removes all segments and renames the replacement file to
+// File, if a replacement exists.
+//
+// Has to be called with the lock.
+func (db *FileDB) finishReplace() error {
+	replacement := db.File + replaceExt
+	if _, err := os.Stat(replacement); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("checking for replacement: %w", err)
+	}
+
+	if db.ReadOnly {
+		return errors.New("replacement of the database is unfinished, open it writable to finish it")
+	}
+
+	segments, err := listSegments(db.File)
+	if err != nil {
+		return fmt.Errorf("list segments: %w", err)
+	}
+
+	for _, s := range segments {
+		paths := []string{s.path}
+		if s.compressed {
+			// The uncompressed file can still exist, when the process
+			// stopped during compression.
+			paths = append(paths, s.path[:len(s.path)-len(compressedExt)])
+		}
+
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("remove segment: %w", err)
+			}
+		}
+	}
+
+	if err := os.Rename(replacement, db.File); err != nil {
+		return fmt.Errorf("rename replacement: %w", err)
+	}
+
+	return syncDir(filepath.Dir(db.File))
+}
+
+// syncDir calls fsync on a directory, so renames in it are persisted.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// Directories can not be synced on windows.
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncing directory: %w", err)
+	}
+	return nil
+}
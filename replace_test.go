@@ -0,0 +1,140 @@
+package sticky
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestDBFile_replace_with_removes_segments(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	db := FileDB{File: path.Join(tmpdir, "events.log"), MaxSegmentSize: 20, Compress: true, Checksum: true}
+	defer db.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := db.Append([]byte(fmt.Sprintf("event number %d", i))); err != nil {
+			t.Fatalf("append to db: %v", err)
+		}
+	}
+
+	if err := db.ReplaceWith(strings.NewReader("snapshot\nlast")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	segments, err := listSegments(db.File)
+	if err != nil {
+		t.Fatalf("list segments: %v", err)
+	}
+	if len(segments) != 0 {
+		t.Errorf("got %d segments after replace, expected 0", len(segments))
+	}
+
+	if err := db.Append([]byte("new")); err != nil {
+		t.Fatalf("append after replace: %v", err)
+	}
+
+	got, err := readDB(t, &db)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if expect := "snapshot\nlast\nnew\n"; got != expect {
+		t.Errorf("got `%s`, expected `%s`", got, expect)
+	}
+
+	// Wait for the background compression.
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	size, err := db.Size()
+	if err != nil {
+		t.Fatalf("size: %v", err)
+	}
+
+	infos, err := db.Segments()
+	if err != nil {
+		t.Fatalf("segments: %v", err)
+	}
+
+	var expectSize int64
+	for _, info := range infos {
+		expectSize += info.Size
+	}
+
+	if size != expectSize {
+		t.Errorf("got size %d, expected %d", size, expectSize)
+	}
+}
+
+func TestDBFile_unfinished_replace_is_finished_on_read(t *testing.T) {
+	tmpdir := t.TempDir()
+	file := path.Join(tmpdir, "events.log")
+
+	// Simulate a crash after the replacement was written but before the
+	// segments where removed.
+	files := map[string]string{
+		"events-000001.log":  "old1\n",
+		"events.log":         "old2\n",
+		"events.log.replace": "new\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(path.Join(tmpdir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	db := FileDB{File: file}
+	defer db.Close()
+
+	got, err := readDB(t, &db)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if got != "new\n" {
+		t.Errorf("got `%s`, expected `new\n`", got)
+	}
+
+	if _, err := os.Stat(file + replaceExt); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("replacement file still exists: %v", err)
+	}
+}
+
+func TestMemoryDB_replace_with(t *testing.T) {
+	db := NewMemoryDB("one\ntwo")
+
+	if err := db.ReplaceWith(strings.NewReader("three\n")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	records := db.Records()
+	if len(records) != 1 || string(records[0]) != "three" {
+		t.Errorf("got records %q, expected [three]", records)
+	}
+
+	size, err := db.Size()
+	if err != nil {
+		t.Fatalf("size: %v", err)
+	}
+	if size != 6 {
+		t.Errorf("got size %d, expected 6", size)
+	}
+}
+
+func TestReplaceDB_not_supported(t *testing.T) {
+	// The anonymous struct hides all methods except Reader and Append.
+	db := struct{ database }{NewMemoryDB()}
+
+	if err := replaceDB(db, strings.NewReader("")); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error `%v`, expected ErrNotSupported", err)
+	}
+
+	if _, err := dbSize(db); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error `%v`, expected ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,62 @@
+package sticky
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReadEnvelopes calls fn for each record of r. r has the format of the reader
+// of a database. Empty lines are skipped. If fn returns an error, the reading
+// stops and the error is returned.
+//
+// Records can have at most 1 MiB. See WithMaxEventSize.
+func ReadEnvelopes(r io.Reader, fn func(Envelope) error) error {
+	return scanRecords(context.Background(), r, defaultMaxEventSize, func(line []byte) error {
+		e, err := DecodeEnvelope(line)
+		if err != nil {
+			return err
+		}
+		return fn(e)
+	})
+}
+
+// Replay builds a projection from a database. The events of r are decoded
+// like on load and given to apply with the time of the event. It starts with
+// empty.
+//
+// Use it, to build read models with another shape then the model of the
+// Sticky. Snapshot records are ignored. If the database was compacted, the
+// events before the compaction are not in the database and an error wrapping
+// ErrNotSupported is returned.
+func Replay[Model, P any](r io.Reader, getEvent func(string) Event[Model], empty P, apply func(p P, event Event[Model], t time.Time) P) (P, error) {
+	l := loader[Model]{
+		getEvent:     getEvent,
+		maxEventSize: defaultMaxEventSize,
+	}
+
+	p := empty
+	var records int
+	err := ReadEnvelopes(r, func(e Envelope) error {
+		records++
+		if e.Type == snapshotType {
+			if records == 1 {
+				return fmt.Errorf("database is compacted: %w", ErrNotSupported)
+			}
+			return nil
+		}
+
+		event, eventTime, err := l.decodeEvent(e)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", records, err)
+		}
+
+		p = apply(p, event, eventTime)
+		return nil
+	})
+	if err != nil {
+		return empty, fmt.Errorf("replaying database: %w", err)
+	}
+	return p, nil
+}
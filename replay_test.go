@@ -0,0 +1,40 @@
+package sticky
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	log := `{"time":"2024-01-01 10:00:00","type":"add","payload":{"value":1}}` + "\n\n" +
+		`{"time":"2024-01-02 10:00:00","type":"add","payload":{"value":2}}` + "\n" +
+		`{"time":"2024-01-02 11:00:00","type":"add","payload":{"value":3}}` + "\n"
+
+	perDay, err := Replay(strings.NewReader(log), getTestEvent, map[string]int{}, func(p map[string]int, event Event[testModel], t time.Time) map[string]int {
+		p[t.Format("2006-01-02")] += event.(*addEvent).Value
+		return p
+	})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	if len(perDay) != 2 || perDay["2024-01-01"] != 1 || perDay["2024-01-02"] != 5 {
+		t.Errorf("got %v, expected 1 and 5 per day", perDay)
+	}
+}
+
+func TestReplay_errors(t *testing.T) {
+	apply := func(p int, _ Event[testModel], _ time.Time) int { return p + 1 }
+
+	_, err := Replay(strings.NewReader(`{"time":"2024-01-01 10:00:00","type":"unknown","payload":{}}`), getTestEvent, 0, apply)
+	if !errors.Is(err, errUnknownEvent) {
+		t.Errorf("got error `%v`, expected unknown event", err)
+	}
+
+	_, err = Replay(strings.NewReader(`{"time":"2024-01-01 10:00:00","type":"$snapshot","payload":"e30="}`), getTestEvent, 0, apply)
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error `%v`, expected ErrNotSupported", err)
+	}
+}
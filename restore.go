@@ -0,0 +1,184 @@
+package sticky
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BackupError is returned from Restore and VerifyBackup, when a line of the
+// backup is invalid.
+type BackupError struct {
+	// Line is the line number starting at 1.
+	Line int
+	Err  error
+}
+
+func (err BackupError) Error() string {
+	return fmt.Sprintf("backup line %d: %v", err.Line, err.Err)
+}
+
+func (err BackupError) Unwrap() error {
+	return err.Err
+}
+
+// RestoreOption is an option for Restore, VerifyLog and VerifyBackup.
+type RestoreOption func(*restoreConfig)
+
+type restoreConfig struct {
+	maxEventSize int
+	timeLayout   string
+
+	// encrypted decrypts the records, if it is not nil.
+	encrypted *EncryptedDB
+}
+
+// RestoreMaxEventSize sets the maximum size of a record like
+// WithMaxEventSize. Use the same size as for the Sticky, that wrote the
+// backup.
+func RestoreMaxEventSize(n int) RestoreOption {
+	return func(c *restoreConfig) {
+		c.maxEventSize = n
+	}
+}
+
+// RestoreTimeFormat accepts times with the layout like WithTimeFormat. Use
+// the same layout as for the Sticky, that wrote the backup.
+func RestoreTimeFormat(layout string) RestoreOption {
+	return func(c *restoreConfig) {
+		c.timeLayout = layout
+	}
+}
+
+func newRestoreConfig(options []RestoreOption) restoreConfig {
+	c := restoreConfig{maxEventSize: defaultMaxEventSize}
+	for _, o := range options {
+		o(&c)
+	}
+	return c
+}
+
+// Restore writes the records of a backup into an empty database. See
+// Sticky.Backup.
+//
+// Each line of the backup is checked, before anything is written: It has to
+// be a valid record with a time in the default format, RFC 3339 or the
+// layout of RestoreTimeFormat, its checksum has to match, if it has one, and
+// its sequence number has to follow the one of the record before, if both
+// have one. The first invalid line is returned as BackupError.
+//
+// The records are written without their checksums. A FileDB with Checksum
+// adds new ones. The records are held in memory until they are written. If
+// the database is not empty, ErrNotEmpty is returned.
+//
+// If db is an EncryptedDB, encrypted records of the backup are decrypted
+// with its keys before they are checked. All records are written encrypted
+// with the current key.
+func Restore(db database, backup io.Reader, options ...RestoreOption) error {
+	empty, err := isEmpty(db)
+	if err != nil {
+		return err
+	}
+
+	if !empty {
+		return ErrNotEmpty
+	}
+
+	config := newRestoreConfig(options)
+	config.encrypted, _ = db.(*EncryptedDB)
+
+	var records [][]byte
+	err = verifyBackup(backup, config, func(line int, e Envelope, record []byte) error {
+		records = append(records, bytes.Clone(record))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := appendRecords(db, records); err != nil {
+		return fmt.Errorf("writing records: %w", err)
+	}
+	return nil
+}
+
+// VerifyLog checks the records of r like Restore, without writing them. r
+// can be a backup or the file of a FileDB.
+func VerifyLog(r io.Reader, options ...RestoreOption) error {
+	return verifyBackup(r, newRestoreConfig(options), func(int, Envelope, []byte) error { return nil })
+}
+
+// VerifyBackup is like VerifyLog. It also checks, that getEvent knows the
+// name of each event.
+func VerifyBackup[Model any](r io.Reader, getEvent func(name string) Event[Model], options ...RestoreOption) error {
+	return verifyBackup(r, newRestoreConfig(options), func(line int, e Envelope, _ []byte) error {
+		if e.Type == snapshotType {
+			return nil
+		}
+
+		if getEvent(e.Type) == nil {
+			return BackupError{Line: line, Err: fmt.Errorf("unknown event `%s`", e.Type)}
+		}
+		return nil
+	})
+}
+
+// verifyBackup checks each line of r and calls fn with the record without
+// its checksum. Empty lines are skipped.
+func verifyBackup(r io.Reader, config restoreConfig, fn func(line int, e Envelope, record []byte) error) error {
+	encrypted := config.encrypted
+	maxSize := config.maxEventSize
+	if encrypted != nil {
+		maxSize = encrypted.storedSize(maxSize)
+	}
+	maxSize += checksumLen
+	scanner := newRecordScanner(r, maxSize, nil)
+
+	var lineNo int
+	var seq uint64
+	for scanner.Scan() {
+		lineNo++
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		record, ok := splitChecksum(line)
+		if !ok {
+			return BackupError{Line: lineNo, Err: errors.New("invalid checksum")}
+		}
+
+		if encrypted != nil && bytes.HasPrefix(record, []byte(encryptedPrefix)) {
+			plain, err := encrypted.decrypt(record)
+			if err != nil {
+				return BackupError{Line: lineNo, Err: err}
+			}
+			record = plain
+		}
+
+		e, err := DecodeEnvelope(record)
+		if err != nil {
+			return BackupError{Line: lineNo, Err: err}
+		}
+
+		if _, err := e.parseTime(config.timeLayout); err != nil {
+			return BackupError{Line: lineNo, Err: err}
+		}
+
+		if e.Seq != 0 && seq != 0 && e.Seq != seq+1 {
+			return BackupError{Line: lineNo, Err: fmt.Errorf("sequence number %d after %d", e.Seq, seq)}
+		}
+		seq = e.Seq
+
+		if err := fn(lineNo, e, record); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return BackupError{Line: lineNo + 1, Err: scanError(err, maxSize)}
+	}
+	return nil
+}
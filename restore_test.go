@@ -0,0 +1,162 @@
+package sticky
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestRestore(t *testing.T) {
+	tmpdir := t.TempDir()
+	db := &FileDB{File: path.Join(tmpdir, "events.log"), Checksum: true}
+	defer db.Close()
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	var backup bytes.Buffer
+	if err := s.Backup(context.Background(), &backup); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+
+	if err := VerifyBackup(bytes.NewReader(backup.Bytes()), getTestEvent); err != nil {
+		t.Fatalf("verify backup: %v", err)
+	}
+
+	restoredDB := NewMemoryDB()
+	if err := Restore(restoredDB, &backup); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	for _, record := range restoredDB.Records() {
+		if bytes.Contains(record, []byte(checksumSep)) {
+			t.Errorf("restored record `%s` contains its checksum", record)
+		}
+	}
+
+	restored, err := New(restoredDB, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("loading restored database: %v", err)
+	}
+
+	m, done := restored.ForReading()
+	done()
+	if m.Sum != 6 {
+		t.Errorf("got sum %d, expected 6", m.Sum)
+	}
+}
+
+func TestRestore_invalid(t *testing.T) {
+	valid := `{"time":"2024-01-01 00:00:00","type":"add","seq":1,"payload":{"value":1}}`
+	withChecksum := string(appendChecksum([]byte(valid), []byte(valid)))
+
+	for _, tt := range []struct {
+		name   string
+		backup string
+		line   int
+	}{
+		{"json", valid + "\n{\n", 2},
+		{"time", valid + "\n" + `{"time":"yesterday","type":"add","seq":2,"payload":{}}`, 2},
+		{"checksum", withChecksum + "\n\n" + strings.Replace(withChecksum, `"seq":1`, `"seq":2`, 1), 3},
+		{"seq", valid + "\n" + strings.Replace(valid, `"seq":1`, `"seq":3`, 1), 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryDB()
+			err := Restore(db, strings.NewReader(tt.backup))
+
+			var backupErr BackupError
+			if !errors.As(err, &backupErr) {
+				t.Fatalf("got error `%v`, expected a BackupError", err)
+			}
+
+			if backupErr.Line != tt.line {
+				t.Errorf("got line %d, expected %d", backupErr.Line, tt.line)
+			}
+
+			if len(db.Records()) != 0 {
+				t.Errorf("database is not empty after a failed restore")
+			}
+		})
+	}
+}
+
+func TestRestore_not_empty(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)
+
+	if err := Restore(db, strings.NewReader("")); !errors.Is(err, ErrNotEmpty) {
+		t.Errorf("got error `%v`, expected ErrNotEmpty", err)
+	}
+}
+
+func TestVerifyBackup_unknown_event(t *testing.T) {
+	backup := `{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}` + "\n" +
+		`{"time":"2024-01-01 00:00:00","type":"sub","payload":{"value":1}}` + "\n"
+
+	var backupErr BackupError
+	if err := VerifyBackup(strings.NewReader(backup), getTestEvent); !errors.As(err, &backupErr) || backupErr.Line != 2 {
+		t.Errorf("got error `%v`, expected a BackupError in line 2", err)
+	}
+}
+
+func TestVerifyLog(t *testing.T) {
+	valid := `{"time":"2024-01-01 00:00:00","type":"add","seq":1,"payload":{"value":1}}`
+
+	if err := VerifyLog(strings.NewReader(valid + "\n" + strings.Replace(valid, `"seq":1`, `"seq":2`, 1))); err != nil {
+		t.Errorf("verify valid log: %v", err)
+	}
+
+	var backupErr BackupError
+	if err := VerifyLog(strings.NewReader(valid + "\n" + valid)); !errors.As(err, &backupErr) || backupErr.Line != 2 {
+		t.Errorf("got error `%v`, expected a BackupError in line 2", err)
+	}
+}
+
+func TestRestore_options(t *testing.T) {
+	const layout = "02.01.2006 15:04"
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getTestEvent, WithTimeFormat[testModel](layout), WithMaxEventSize[testModel](2*defaultMaxEventSize))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// The payload is bigger then the default maximum.
+	big := fmt.Sprintf(`{"time":"2024-01-01 00:00:00","type":"add","seq":2,"payload":{"value":1,"pad":"%s"}}`, strings.Repeat("x", defaultMaxEventSize))
+	if err := db.Append([]byte(big)); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	var backup bytes.Buffer
+	for _, record := range db.Records() {
+		backup.Write(record)
+		backup.WriteByte('\n')
+	}
+
+	if err := VerifyLog(bytes.NewReader(backup.Bytes())); err == nil {
+		t.Errorf("verify log without options returned no error")
+	}
+
+	options := []RestoreOption{RestoreTimeFormat(layout), RestoreMaxEventSize(2 * defaultMaxEventSize)}
+	if err := VerifyBackup(bytes.NewReader(backup.Bytes()), getTestEvent, options...); err != nil {
+		t.Errorf("verify backup: %v", err)
+	}
+
+	if err := Restore(NewMemoryDB(), &backup, options...); err != nil {
+		t.Errorf("restore: %v", err)
+	}
+}
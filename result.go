@@ -0,0 +1,42 @@
+package sticky
+
+import "context"
+
+// WriteResult is like Sticky.Write, but f can return a value, that is
+// returned to the caller. For example the id of a new object.
+//
+// If f returns an error, nothing is written and the error is returned. If the
+// event can not be written, the error of the write is returned.
+func WriteResult[Model, R any](s *Sticky[Model], f func(Model) (Event[Model], R, error)) (R, error) {
+	var zero R
+	if s.closed.Load() {
+		return zero, ErrClosed
+	}
+
+	var result R
+	err := s.withWriteLock(context.Background(), func() error {
+		event, r, err := f(s.model)
+		if err != nil {
+			return err
+		}
+
+		if err := s.writeChain(s.write, writeOptions{})(event); err != nil {
+			return err
+		}
+		result = r
+		return nil
+	})
+	if err != nil {
+		return zero, err
+	}
+	return result, nil
+}
+
+// ReadResult is like Sticky.Read, but f can return a value, that is returned
+// to the caller.
+func ReadResult[Model, R any](s *Sticky[Model], f func(Model) (R, error)) (R, error) {
+	m, done := s.ForReading()
+	defer done()
+
+	return f(m)
+}
@@ -0,0 +1,44 @@
+package sticky
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWriteResult(t *testing.T) {
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	sum, err := WriteResult(s, func(m testModel) (Event[testModel], int, error) {
+		return addEvent{Value: 2}, m.Sum + 2, nil
+	})
+	if err != nil {
+		t.Fatalf("write result: %v", err)
+	}
+
+	if sum != 2 {
+		t.Errorf("got result %d, expected 2", sum)
+	}
+
+	abort := errors.New("abort")
+	_, err = WriteResult(s, func(m testModel) (Event[testModel], int, error) {
+		return addEvent{Value: 1}, 0, abort
+	})
+	if !errors.Is(err, abort) {
+		t.Errorf("got error `%v`, expected `%v`", err, abort)
+	}
+
+	if len(db.Records()) != 1 {
+		t.Errorf("got %d records, expected 1", len(db.Records()))
+	}
+
+	got, err := ReadResult(s, func(m testModel) (int, error) {
+		return m.Sum, nil
+	})
+	if err != nil || got != 2 {
+		t.Errorf("read result returned %d, %v, expected 2", got, err)
+	}
+}
@@ -0,0 +1,147 @@
+package sticky
+
+import (
+	"context"
+	"sync"
+)
+
+// rwLock is a reader/writer lock like sync.RWMutex, but waiting for the lock
+// can be canceled with a context.
+//
+// Like sync.RWMutex, a waiting writer blocks new readers, so writers do not
+// starve. A reader must not take the lock again while holding it.
+type rwLock struct {
+	mu             sync.Mutex
+	readers        int
+	writer         bool
+	writersWaiting int
+
+	// changed is closed, when the lock is released. It is nil, if nobody
+	// waits.
+	changed chan struct{}
+}
+
+// Lock locks for writing.
+func (l *rwLock) Lock() {
+	l.acquire(context.Background(), true)
+}
+
+// RLock locks for reading.
+func (l *rwLock) RLock() {
+	l.acquire(context.Background(), false)
+}
+
+// LockCtx locks for writing. It returns the error of the context, if it is
+// done before the lock is taken.
+func (l *rwLock) LockCtx(ctx context.Context) error {
+	return l.acquire(ctx, true)
+}
+
+// RLockCtx locks for reading. See LockCtx.
+func (l *rwLock) RLockCtx(ctx context.Context) error {
+	return l.acquire(ctx, false)
+}
+
+// TryLock locks for writing, if the lock is free. It never waits.
+func (l *rwLock) TryLock() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.writer || l.readers > 0 {
+		return false
+	}
+	l.writer = true
+	return true
+}
+
+// Unlock releases the write lock.
+func (l *rwLock) Unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.writer {
+		panic("sticky: unlock of unlocked rwLock")
+	}
+	l.writer = false
+	l.notify()
+}
+
+// RUnlock releases a read lock.
+func (l *rwLock) RUnlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.readers == 0 {
+		panic("sticky: runlock of unlocked rwLock")
+	}
+	l.readers--
+	if l.readers == 0 {
+		l.notify()
+	}
+}
+
+func (l *rwLock) acquire(ctx context.Context, write bool) error {
+	l.mu.Lock()
+	if write {
+		l.writersWaiting++
+	}
+
+	for {
+		if l.tryAcquire(write) {
+			l.mu.Unlock()
+			return nil
+		}
+
+		if l.changed == nil {
+			l.changed = make(chan struct{})
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+			l.mu.Lock()
+
+		case <-ctx.Done():
+			l.mu.Lock()
+			if write {
+				l.writersWaiting--
+				// Readers could wait for this writer.
+				l.notify()
+			}
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+// tryAcquire takes the lock, if it is free. For a writer, writersWaiting has
+// to include the writer.
+//
+// Has to be called with l.mu.
+func (l *rwLock) tryAcquire(write bool) bool {
+	if write {
+		if l.writer || l.readers > 0 {
+			return false
+		}
+		l.writersWaiting--
+		l.writer = true
+		return true
+	}
+
+	if l.writer || l.writersWaiting > 0 {
+		return false
+	}
+	l.readers++
+	return true
+}
+
+// notify wakes up all waiting goroutines.
+//
+// Has to be called with l.mu.
+func (l *rwLock) notify() {
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
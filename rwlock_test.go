@@ -0,0 +1,207 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWriteCtx_canceled_while_waiting(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, _, done := s.ForWriting()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = s.WriteCtx(ctx, func(testModel) Event[testModel] { return addEvent{Value: 1} })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("write returned `%v`, expected context.DeadlineExceeded", err)
+	}
+
+	if err := s.ReadCtx(ctx, func(testModel) error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("read returned `%v`, expected context.DeadlineExceeded", err)
+	}
+
+	done()
+
+	if err := s.WriteCtx(context.Background(), func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Errorf("write after unlock returned: %v", err)
+	}
+}
+
+func TestRWLock_canceled_writer_releases_readers(t *testing.T) {
+	var l rwLock
+	l.RLock()
+
+	// The waiting writer blocks new readers.
+	ctx, cancel := context.WithCancel(context.Background())
+	writerDone := make(chan error)
+	go func() {
+		writerDone <- l.LockCtx(ctx)
+	}()
+
+	for {
+		l.mu.Lock()
+		waiting := l.writersWaiting
+		l.mu.Unlock()
+		if waiting == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	readerDone := make(chan struct{})
+	go func() {
+		l.RLock()
+		close(readerDone)
+	}()
+
+	cancel()
+	if err := <-writerDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("writer returned `%v`, expected context.Canceled", err)
+	}
+
+	select {
+	case <-readerDone:
+	case <-time.After(time.Second):
+		t.Fatalf("reader is still blocked after the writer was canceled")
+	}
+}
+
+func TestTryWrite(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, _, done := s.ForWriting()
+	ok, err := s.TryWrite(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+	done()
+
+	if ok || err != nil {
+		t.Errorf("try write while locked returned %t, %v, expected false", ok, err)
+	}
+
+	ok, err = s.TryWrite(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+	if !ok || err != nil {
+		t.Errorf("try write returned %t, %v, expected true", ok, err)
+	}
+}
+
+func TestTryWrite_stress(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	const workers = 8
+	const rounds = 200
+
+	var tried atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Microsecond)
+			defer cancel()
+
+			for j := 0; j < rounds; j++ {
+				switch (i + j) % 4 {
+				case 0:
+					ok, err := s.TryWrite(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+					if err != nil {
+						t.Errorf("try write: %v", err)
+					}
+					if ok {
+						tried.Add(1)
+					}
+				case 1:
+					if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+						t.Errorf("write: %v", err)
+					}
+					tried.Add(1)
+				case 2:
+					s.ReadCtx(ctx, func(testModel) error { return nil })
+				case 3:
+					s.Read(func(testModel) error { return nil })
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The lock has to be free after all workers are done.
+	_, _, done, ok := s.TryForWriting()
+	if !ok {
+		t.Fatalf("lock is still held")
+	}
+	done()
+
+	model, done := s.ForReading()
+	done()
+
+	if int64(model.Sum) != tried.Load() {
+		t.Errorf("got sum %d, expected %d", model.Sum, tried.Load())
+	}
+}
+
+func TestWithWriteTimeout(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithWriteTimeout[testModel](10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, readDone := s.ForReading()
+
+	err = s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+	var timeoutErr ErrLockTimeout
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("write returned `%v`, expected ErrLockTimeout", err)
+	}
+
+	if timeoutErr.Waited < 10*time.Millisecond {
+		t.Errorf("got waited `%s`, expected at least 10ms", timeoutErr.Waited)
+	}
+
+	_, write, done := s.ForWriting()
+	if err := write(addEvent{Value: 1}); !errors.As(err, &timeoutErr) {
+		t.Errorf("write from ForWriting returned `%v`, expected ErrLockTimeout", err)
+	}
+	done()
+
+	readDone()
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Errorf("write after unlock returned: %v", err)
+	}
+}
+
+func TestWithLockWait(t *testing.T) {
+	var waits []time.Duration
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithLockWait[testModel](func(wait time.Duration) {
+		waits = append(waits, wait)
+	}))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, readDone := s.ForReading()
+	time.AfterFunc(10*time.Millisecond, readDone)
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if len(waits) != 1 || waits[0] < 10*time.Millisecond {
+		t.Errorf("got waits %v, expected one wait of at least 10ms", waits)
+	}
+}
@@ -0,0 +1,287 @@
+// Package s3db implements a sticky database that stores the events in an
+// object storage like S3.
+//
+// Appended events are buffered in memory and written as new segment objects.
+// A manifest object records the order of the segments.
+package s3db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrNotFound has to be returned by Client.Get, when an object does not
+// exist.
+var ErrNotFound = errors.New("object not found")
+
+// Client is the part of an object storage, that is needed by the DB.
+type Client interface {
+	// Get returns the content of an object. It has to return an error
+	// wrapping ErrNotFound, if the object does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Put creates or replaces an object.
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+const (
+	defaultFlushEvents   = 100
+	defaultFlushInterval = 5 * time.Second
+	manifestName         = "manifest.json"
+)
+
+// Option is a option for s3db.Open()
+type Option func(db *DB)
+
+// WithFlushEvents writes a new segment after n buffered events. Default is
+// 100.
+func WithFlushEvents(n int) Option {
+	return func(db *DB) {
+		db.flushEvents = n
+	}
+}
+
+// WithFlushInterval writes a new segment at the latest after the duration.
+// Default is 5 seconds.
+func WithFlushInterval(d time.Duration) Option {
+	return func(db *DB) {
+		db.flushInterval = d
+	}
+}
+
+type manifest struct {
+	Segments []string `json:"segments"`
+}
+
+// DB is a event database based on an object storage.
+type DB struct {
+	client        Client
+	prefix        string
+	flushEvents   int
+	flushInterval time.Duration
+
+	mu         sync.Mutex
+	manifest   manifest
+	nextNumber int
+	buf        bytes.Buffer
+	buffered   int
+	flushTimer *time.Timer
+	flushErr   error
+}
+
+// Open reads the manifest below the prefix.
+//
+// The prefix is put in front of all object keys. For example "myapp/".
+func Open(ctx context.Context, client Client, prefix string, options ...Option) (*DB, error) {
+	db := DB{
+		client:        client,
+		prefix:        prefix,
+		flushEvents:   defaultFlushEvents,
+		flushInterval: defaultFlushInterval,
+		nextNumber:    1,
+	}
+
+	for _, o := range options {
+		o(&db)
+	}
+
+	r, err := client.Get(ctx, prefix+manifestName)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return &db, nil
+		}
+		return nil, fmt.Errorf("get manifest: %w", err)
+	}
+	defer r.Close()
+
+	if err := json.NewDecoder(r).Decode(&db.manifest); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+
+	db.nextNumber = len(db.manifest.Segments) + 1
+	return &db, nil
+}
+
+// Reader returns all events from all segments and the unflushed buffer.
+func (db *DB) Reader() (io.ReadCloser, error) {
+	db.mu.Lock()
+	segments := append([]string(nil), db.manifest.Segments...)
+	buffered := bytes.Clone(db.buf.Bytes())
+	db.mu.Unlock()
+
+	return &segmentReader{
+		ctx:      context.Background(),
+		client:   db.client,
+		prefix:   db.prefix,
+		segments: segments,
+		buffered: buffered,
+	}, nil
+}
+
+// Append adds the event to the buffer. The buffer is flushed, when it
+// contains enough events.
+//
+// If a background flush failed, the error is returned.
+func (db *DB) Append(bs []byte) error {
+	return db.AppendBatch([][]byte{bs})
+}
+
+// AppendBatch adds many events to the buffer. They are always written to the
+// same segment.
+func (db *DB) AppendBatch(records [][]byte) error {
+	for _, bs := range records {
+		if bytes.Contains(bs, []byte("\n")) {
+			return errors.New("event contains a newline")
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.flushErr; err != nil {
+		db.flushErr = nil
+		return fmt.Errorf("previous flush: %w", err)
+	}
+
+	for _, bs := range records {
+		db.buf.Write(bs)
+		db.buf.WriteByte('\n')
+		db.buffered++
+	}
+
+	if db.buffered >= db.flushEvents {
+		return db.flush(context.Background())
+	}
+
+	if db.flushInterval > 0 && db.flushTimer == nil {
+		db.flushTimer = time.AfterFunc(db.flushInterval, db.timedFlush)
+	}
+	return nil
+}
+
+// Flush writes the buffered events as new segment.
+func (db *DB) Flush(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.flush(ctx)
+}
+
+// Sync writes the buffered events as new segment. It is the same as Flush
+// with a background context.
+func (db *DB) Sync() error {
+	return db.Flush(context.Background())
+}
+
+// flush writes the buffer as a new segment and updates the manifest.
+//
+// Has to be called with the lock. On error, the events stay in the buffer.
+func (db *DB) flush(ctx context.Context) error {
+	if db.flushTimer != nil {
+		db.flushTimer.Stop()
+		db.flushTimer = nil
+	}
+
+	if db.buffered == 0 {
+		return nil
+	}
+
+	name := fmt.Sprintf("segment-%06d.log", db.nextNumber)
+	if err := db.client.Put(ctx, db.prefix+name, db.buf.Bytes()); err != nil {
+		return fmt.Errorf("put segment %s: %w", name, err)
+	}
+
+	newManifest := manifest{Segments: append(append([]string(nil), db.manifest.Segments...), name)}
+	encoded, err := json.Marshal(newManifest)
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	if err := db.client.Put(ctx, db.prefix+manifestName, encoded); err != nil {
+		return fmt.Errorf("put manifest: %w", err)
+	}
+
+	db.manifest = newManifest
+	db.nextNumber++
+	db.buf.Reset()
+	db.buffered = 0
+	return nil
+}
+
+func (db *DB) timedFlush() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.flushTimer = nil
+	if err := db.flush(context.Background()); err != nil {
+		db.flushErr = err
+	}
+}
+
+// Close flushes the buffered events.
+//
+// Call Close on graceful shutdown. Otherwise buffered events get lost.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.flushErr = nil
+	return db.flush(context.Background())
+}
+
+// segmentReader concatenates the segments and the buffered events.
+type segmentReader struct {
+	ctx      context.Context
+	client   Client
+	prefix   string
+	segments []string
+	buffered []byte
+
+	current io.ReadCloser
+}
+
+func (r *segmentReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.segments) == 0 {
+				if len(r.buffered) == 0 {
+					return 0, io.EOF
+				}
+				n := copy(p, r.buffered)
+				r.buffered = r.buffered[n:]
+				return n, nil
+			}
+
+			segment, err := r.client.Get(r.ctx, r.prefix+r.segments[0])
+			if err != nil {
+				return 0, fmt.Errorf("get segment %s: %w", r.segments[0], err)
+			}
+			r.current = segment
+			r.segments = r.segments[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *segmentReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package s3db_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky/s3db"
+)
+
+type memoryClient struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryClient() *memoryClient {
+	return &memoryClient{objects: make(map[string][]byte)}
+}
+
+func (c *memoryClient) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("key %s: %w", key, s3db.ErrNotFound)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (c *memoryClient) Put(ctx context.Context, key string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.objects[key] = bytes.Clone(data)
+	return nil
+}
+
+func (c *memoryClient) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.objects[key]
+	return ok
+}
+
+func readAll(t *testing.T, db *s3db.DB) string {
+	t.Helper()
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+	return string(got)
+}
+
+func TestS3DB_flush_after_n_events(t *testing.T) {
+	ctx := context.Background()
+	client := newMemoryClient()
+
+	db, err := s3db.Open(ctx, client, "app/", s3db.WithFlushEvents(2), s3db.WithFlushInterval(0))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	for _, event := range []string{"one", "two", "three"} {
+		if err := db.Append([]byte(event)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	if !client.has("app/segment-000001.log") {
+		t.Errorf("first segment was not written")
+	}
+
+	if client.has("app/segment-000002.log") {
+		t.Errorf("second segment was written before it was full")
+	}
+
+	if got := readAll(t, db); got != "one\ntwo\nthree\n" {
+		t.Errorf("got `%s`, expected buffered and flushed events", got)
+	}
+}
+
+func TestS3DB_close_flushes_and_reopen_resumes_numbering(t *testing.T) {
+	ctx := context.Background()
+	client := newMemoryClient()
+
+	db, err := s3db.Open(ctx, client, "", s3db.WithFlushEvents(100))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	if err := db.Append([]byte("one")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	db, err = s3db.Open(ctx, client, "", s3db.WithFlushEvents(100))
+	if err != nil {
+		t.Fatalf("reopen db: %v", err)
+	}
+
+	if err := db.Append([]byte("two")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if !client.has("segment-000002.log") {
+		t.Errorf("second segment was not written after reopen")
+	}
+
+	db, err = s3db.Open(ctx, client, "")
+	if err != nil {
+		t.Fatalf("reopen db: %v", err)
+	}
+
+	if got := readAll(t, db); got != "one\ntwo\n" {
+		t.Errorf("got `%s`, expected `one\ntwo\n`", got)
+	}
+}
+
+func TestS3DB_flush_after_interval(t *testing.T) {
+	ctx := context.Background()
+	client := newMemoryClient()
+
+	db, err := s3db.Open(ctx, client, "", s3db.WithFlushInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Append([]byte("one")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !client.has("segment-000001.log") {
+		if time.Now().After(deadline) {
+			t.Fatalf("segment was not flushed after interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,494 @@
+package sticky
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const compressedExt = ".gz"
+
+// SegmentError is returned from the reader of a FileDB, when a segment could
+// not be read.
+type SegmentError struct {
+	Path string
+	Err  error
+}
+
+func (err SegmentError) Error() string {
+	return fmt.Sprintf("segment %s: %v", err.Path, err.Err)
+}
+
+func (err SegmentError) Unwrap() error {
+	return err.Err
+}
+
+// SegmentInfo describes one segment of a FileDB.
+type SegmentInfo struct {
+	// Number is the number of the segment. The active file has number 0.
+	Number int
+	Path   string
+
+	// Size is the size of the file on disk.
+	Size       int64
+	Compressed bool
+
+	// FirstEvent and LastEvent are the times of the first and last event in
+	// the segment. They are zero, if the segment is empty.
+	FirstEvent time.Time
+	LastEvent  time.Time
+}
+
+// Segments returns information about all segments. The active file is the
+// last entry.
+//
+// The times of the events are parsed like on load. After the FileDB was given
+// to New, the layout of WithTimeFormat is also accepted.
+//
+// Segments reads all files. This can take some time on big databases.
+func (db *FileDB) Segments() ([]SegmentInfo, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.finishReplace(); err != nil {
+		return nil, err
+	}
+
+	segments, err := listSegments(db.File)
+	if err != nil {
+		return nil, fmt.Errorf("list segments: %w", err)
+	}
+
+	if _, err := os.Stat(db.File); err == nil {
+		segments = append(segments, segment{path: db.File})
+	}
+
+	infos := make([]SegmentInfo, 0, len(segments))
+	for _, s := range segments {
+		info, err := s.info(db.timeLayout)
+		if err != nil {
+			return nil, SegmentError{Path: s.path, Err: err}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// info reads the segment. layout is the layout of the times.
+func (s segment) info(layout string) (SegmentInfo, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return SegmentInfo{}, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return SegmentInfo{}, err
+	}
+
+	info := SegmentInfo{
+		Number:     s.number,
+		Path:       s.path,
+		Size:       stat.Size(),
+		Compressed: s.compressed,
+	}
+
+	var r io.Reader = f
+	if s.compressed {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return SegmentInfo{}, err
+		}
+		r = zr
+	}
+
+	var first, last []byte
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if first == nil {
+				first = bytes.Clone(line)
+			}
+			last = line
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return SegmentInfo{}, err
+		}
+	}
+
+	info.FirstEvent = recordTime(first, layout)
+	info.LastEvent = recordTime(last, layout)
+	return info, nil
+}
+
+// recordTime returns the time of a record or the zero time, if it can not be
+// parsed with the layout or the default layouts.
+func recordTime(record []byte, layout string) time.Time {
+	record, _ = splitChecksum(record)
+
+	envelope, err := DecodeEnvelope(record)
+	if err != nil {
+		return time.Time{}
+	}
+
+	t, err := envelope.parseTime(layout)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+type segment struct {
+	number     int
+	path       string
+	compressed bool
+}
+
+// segmentName returns the path of the segment with the given number.
+func segmentName(file string, number int) string {
+	ext := filepath.Ext(file)
+	return fmt.Sprintf("%s-%06d%s", strings.TrimSuffix(file, ext), number, ext)
+}
+
+// listSegments returns all completed segments of file ordered by number.
+//
+// If a segment exists compressed and uncompressed, the compressed one is
+// used. This happens, when the process stopped before the uncompressed file
+// was removed.
+func listSegments(file string) ([]segment, error) {
+	dir := filepath.Dir(file)
+	ext := filepath.Ext(file)
+	prefix := strings.TrimSuffix(filepath.Base(file), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading directory: %w", err)
+	}
+
+	byNumber := make(map[int]segment)
+	for _, entry := range entries {
+		name := entry.Name()
+		compressed := strings.HasSuffix(name, ext+compressedExt)
+		rest := strings.TrimSuffix(name, compressedExt)
+
+		if !strings.HasPrefix(rest, prefix) || !strings.HasSuffix(rest, ext) {
+			continue
+		}
+
+		digits := strings.TrimSuffix(strings.TrimPrefix(rest, prefix), ext)
+		number, err := strconv.Atoi(digits)
+		if err != nil || len(digits) < 6 {
+			continue
+		}
+
+		if existing, ok := byNumber[number]; ok && existing.compressed {
+			continue
+		}
+
+		byNumber[number] = segment{
+			number:     number,
+			path:       filepath.Join(dir, name),
+			compressed: compressed,
+		}
+	}
+
+	segments := make([]segment, 0, len(byNumber))
+	for _, s := range byNumber {
+		segments = append(segments, s)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].number < segments[j].number })
+	return segments, nil
+}
+
+// compressSegment writes a gzip version of the segment and removes the
+// uncompressed file.
+func (db *FileDB) compressSegment(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+	defer in.Close()
+
+	tmpPath := path + compressedExt + ".tmp"
+	out, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("create compressed segment: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return fmt.Errorf("compressing segment %s: %w", path, err)
+	}
+
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return fmt.Errorf("compressing segment %s: %w", path, err)
+	}
+
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return fmt.Errorf("syncing compressed segment: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("closing compressed segment: %w", err)
+	}
+
+	// The lock makes sure, that no reader is listing the segments right now.
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := os.Rename(tmpPath, path+compressedExt); err != nil {
+		return fmt.Errorf("rename compressed segment: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove uncompressed segment: %w", err)
+	}
+	return nil
+}
+
+// segmentReader reads a segment and decompresses it, if necessary.
+//
+// All errors are returned as SegmentError.
+type segmentReader struct {
+	segment segment
+	r       io.Reader
+	zr      *gzip.Reader
+}
+
+func newSegmentReader(s segment, f *os.File) *segmentReader {
+	return &segmentReader{segment: s, r: f}
+}
+
+func (r *segmentReader) Read(p []byte) (int, error) {
+	if r.segment.compressed && r.zr == nil {
+		zr, err := gzip.NewReader(r.r)
+		if err != nil {
+			return 0, SegmentError{Path: r.segment.path, Err: err}
+		}
+		r.zr = zr
+	}
+
+	var n int
+	var err error
+	if r.zr != nil {
+		n, err = r.zr.Read(p)
+	} else {
+		n, err = r.r.Read(p)
+	}
+
+	if err != nil && err != io.EOF {
+		return n, SegmentError{Path: r.segment.path, Err: err}
+	}
+	return n, err
+}
+
+// openFiles opens all segments and the active file.
+//
+// Has to be called with the lock. It makes sure, that no segment is rotated or
+// compressed, while the files are opened. After they are open, they can be
+// renamed or removed.
+func (db *FileDB) openFiles() (*fileReader, error) {
+	fr := fileReader{db: db}
+
+	segments, err := listSegments(db.File)
+	if err != nil {
+		return nil, fmt.Errorf("list segments: %w", err)
+	}
+
+	for _, segment := range segments {
+		f, err := os.Open(segment.path)
+		if errors.Is(err, os.ErrNotExist) && !segment.compressed {
+			// Another process could have compressed the segment after it was
+			// listed.
+			segment.path += compressedExt
+			segment.compressed = true
+			f, err = os.Open(segment.path)
+		}
+		if err != nil {
+			fr.Close()
+			return nil, fmt.Errorf("open segment: %w", err)
+		}
+		fr.files = append(fr.files, f)
+		fr.segments = append(fr.segments, newSegmentReader(segment, f))
+		fr.lastSegment = segment.number
+	}
+
+	f, err := os.Open(db.File)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fr.Close()
+			return nil, fmt.Errorf("open database file: %w", err)
+		}
+	} else {
+		fr.files = append(fr.files, f)
+		fr.active = f
+	}
+
+	return &fr, nil
+}
+
+// fileReader reads all segments and the active file of a FileDB.
+//
+// io.EOF is not final. When the reader is read again, it returns the events,
+// that where appended in the meantime. If the active file was rotated, all
+// files are opened again and the already read bytes are skipped.
+type fileReader struct {
+	db       *FileDB
+	segments []*segmentReader
+	files    []*os.File
+	active   *os.File
+
+	// offset is the number of bytes, that where returned.
+	offset int64
+
+	// lastSegment is the number of the last segment, that was opened.
+	lastSegment int
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	for len(r.segments) > 0 {
+		n, err := r.segments[0].Read(p)
+		r.offset += int64(n)
+		if err == io.EOF {
+			r.segments = r.segments[1:]
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+
+	if r.active == nil {
+		// There was no active file, when the reader was created. Since then,
+		// it could have been created and rotated.
+		changed, err := r.changed()
+		if err != nil {
+			return 0, err
+		}
+
+		if !changed {
+			return 0, io.EOF
+		}
+
+		if err := r.reopen(); err != nil {
+			return 0, err
+		}
+		return r.Read(p)
+	}
+
+	n, err := r.active.Read(p)
+	r.offset += int64(n)
+	if err != io.EOF {
+		return n, err
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+
+	rotated, err := r.rotated()
+	if err != nil {
+		return 0, err
+	}
+
+	if !rotated {
+		return 0, io.EOF
+	}
+
+	if err := r.reopen(); err != nil {
+		return 0, err
+	}
+	return r.Read(p)
+}
+
+// rotated returns true, if the active file is not the file at the path of the
+// database anymore.
+func (r *fileReader) rotated() (bool, error) {
+	activeInfo, err := r.active.Stat()
+	if err != nil {
+		return false, fmt.Errorf("checking active file: %w", err)
+	}
+
+	pathInfo, err := os.Stat(r.db.File)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, fmt.Errorf("checking database file: %w", err)
+	}
+
+	return !os.SameFile(activeInfo, pathInfo), nil
+}
+
+// changed returns true, if there is an active file or a new segment. It is
+// used, when there was no active file, when the reader was created. After a
+// rotation, the active file is only created by the next append.
+func (r *fileReader) changed() (bool, error) {
+	if _, err := os.Stat(r.db.File); err == nil {
+		return true, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("checking database file: %w", err)
+	}
+
+	segments, err := listSegments(r.db.File)
+	if err != nil {
+		return false, fmt.Errorf("list segments: %w", err)
+	}
+	return len(segments) > 0 && segments[len(segments)-1].number > r.lastSegment, nil
+}
+
+// reopen opens all files again and skips the bytes, that where already read.
+func (r *fileReader) reopen() error {
+	r.db.mu.Lock()
+	fr, err := r.db.openFiles()
+	r.db.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.CopyN(io.Discard, fr, r.offset); err != nil {
+		fr.Close()
+		if err == io.EOF {
+			return fmt.Errorf("database is smaller then the %d bytes already read", r.offset)
+		}
+		return fmt.Errorf("skipping already read bytes: %w", err)
+	}
+
+	r.Close()
+	*r = *fr
+	return nil
+}
+
+func (r *fileReader) Close() error {
+	var firstErr error
+	for _, f := range r.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	r.files = nil
+	return firstErr
+}
@@ -0,0 +1,265 @@
+package sticky
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func readDB(t *testing.T, db *FileDB) (string, error) {
+	t.Helper()
+
+	dbReader, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer dbReader.Close()
+
+	got, err := io.ReadAll(dbReader)
+	return string(got), err
+}
+
+func TestDBFile_rotate_segments(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	db := FileDB{File: path.Join(tmpdir, "events.log"), MaxSegmentSize: 20}
+	defer db.Close()
+
+	var expect strings.Builder
+	for i := 0; i < 10; i++ {
+		event := fmt.Sprintf("event number %d", i)
+		expect.WriteString(event + "\n")
+		if err := db.Append([]byte(event)); err != nil {
+			t.Fatalf("append to db: %v", err)
+		}
+	}
+
+	for _, name := range []string{"events-000001.log", "events-000002.log"} {
+		if _, err := os.Stat(path.Join(tmpdir, name)); err != nil {
+			t.Errorf("segment %s does not exist: %v", name, err)
+		}
+	}
+
+	got, err := readDB(t, &db)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if got != expect.String() {
+		t.Errorf("got `%s`, expected `%s`", got, expect.String())
+	}
+}
+
+func TestDBFile_compress_segments(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	db := FileDB{File: path.Join(tmpdir, "events.log"), MaxSegmentSize: 20, Compress: true}
+
+	var expect strings.Builder
+	for i := 0; i < 10; i++ {
+		event := fmt.Sprintf("event number %d", i)
+		expect.WriteString(event + "\n")
+		if err := db.Append([]byte(event)); err != nil {
+			t.Fatalf("append to db: %v", err)
+		}
+	}
+
+	// Close waits for the background compression.
+	if err := db.Close(); err != nil {
+		t.Fatalf("closing db: %v", err)
+	}
+
+	if _, err := os.Stat(path.Join(tmpdir, "events-000001.log.gz")); err != nil {
+		t.Errorf("compressed segment does not exist: %v", err)
+	}
+
+	if _, err := os.Stat(path.Join(tmpdir, "events-000001.log")); !os.IsNotExist(err) {
+		t.Errorf("uncompressed segment was not removed")
+	}
+
+	got, err := readDB(t, &db)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if got != expect.String() {
+		t.Errorf("got `%s`, expected `%s`", got, expect.String())
+	}
+}
+
+func TestDBFile_corrupted_compressed_segment(t *testing.T) {
+	tmpdir := t.TempDir()
+	segmentPath := path.Join(tmpdir, "events-000001.log.gz")
+
+	if err := os.WriteFile(segmentPath, []byte("not gzip"), 0666); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	db := FileDB{File: path.Join(tmpdir, "events.log")}
+
+	_, err := readDB(t, &db)
+
+	var errSegment SegmentError
+	if !errors.As(err, &errSegment) {
+		t.Fatalf("got error `%v`, expected a SegmentError", err)
+	}
+
+	if errSegment.Path != segmentPath {
+		t.Errorf("error has path %s, expected %s", errSegment.Path, segmentPath)
+	}
+}
+
+func TestDBFile_segments_info(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	db := FileDB{File: path.Join(tmpdir, "events.log"), MaxSegmentSize: 100, Compress: true}
+
+	for i := 0; i < 5; i++ {
+		event := fmt.Sprintf(`{"time":"2024-01-0%d 12:00:00","type":"event","payload":{}}`, i+1)
+		if err := db.Append([]byte(event)); err != nil {
+			t.Fatalf("append to db: %v", err)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("closing db: %v", err)
+	}
+
+	infos, err := db.Segments()
+	if err != nil {
+		t.Fatalf("getting segments: %v", err)
+	}
+
+	if len(infos) != 3 {
+		t.Fatalf("got %d segments, expected 3: %v", len(infos), infos)
+	}
+
+	first := infos[0]
+	if first.Number != 1 || !first.Compressed {
+		t.Errorf("first segment is %v, expected compressed segment 1", first)
+	}
+
+	if got := first.FirstEvent.Format(timeFormat); got != "2024-01-01 12:00:00" {
+		t.Errorf("first event of first segment is %s", got)
+	}
+
+	if got := first.LastEvent.Format(timeFormat); got != "2024-01-02 12:00:00" {
+		t.Errorf("last event of first segment is %s", got)
+	}
+
+	active := infos[2]
+	if active.Number != 0 || active.Path != db.File {
+		t.Errorf("last segment is %v, expected the active file", active)
+	}
+
+	if got := active.LastEvent.Format(timeFormat); got != "2024-01-05 12:00:00" {
+		t.Errorf("last event of active file is %s", got)
+	}
+}
+
+func TestDBFile_rotation_with_concurrent_appends(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	db := FileDB{File: path.Join(tmpdir, "events.log"), MaxSegmentSize: 50}
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := db.Append([]byte(fmt.Sprintf("event %d-%d", i, j))); err != nil {
+					t.Errorf("append to db: %v", err)
+				}
+			}
+		}(i)
+	}
+
+	// Read while the appends happen.
+	for i := 0; i < 10; i++ {
+		if _, err := readDB(t, &db); err != nil {
+			t.Errorf("reading db: %v", err)
+		}
+	}
+	wg.Wait()
+
+	got, err := readDB(t, &db)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	if len(lines) != 200 {
+		t.Errorf("got %d events, expected 200", len(lines))
+	}
+
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		if seen[line] {
+			t.Errorf("event %s was read twice", line)
+		}
+		seen[line] = true
+	}
+}
+
+func TestDBFile_reader_after_rotation_without_active_file(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	// Each append rotates the file, so there is no active file afterwards.
+	db := FileDB{File: path.Join(tmpdir, "events.log"), MaxSegmentSize: 1}
+	defer db.Close()
+
+	if err := db.Append([]byte("first")); err != nil {
+		t.Fatalf("append to db: %v", err)
+	}
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "first\n" {
+		t.Fatalf("got `%s` and error %v, expected the first event", got, err)
+	}
+
+	if err := db.Append([]byte("second")); err != nil {
+		t.Fatalf("append to db: %v", err)
+	}
+
+	got, err = io.ReadAll(r)
+	if err != nil || string(got) != "second\n" {
+		t.Errorf("got `%s` and error %v, expected the event of the new segment", got, err)
+	}
+}
+
+func TestDBFile_segments_info_time_format(t *testing.T) {
+	const layout = "02.01.2006 15:04"
+	db := &FileDB{File: path.Join(t.TempDir(), "events.log")}
+	defer db.Close()
+
+	s, err := New(db, testModel{}, getTestEvent, WithTimeFormat[testModel](layout))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	infos, err := db.Segments()
+	if err != nil {
+		t.Fatalf("getting segments: %v", err)
+	}
+
+	if len(infos) != 1 || infos[0].FirstEvent.IsZero() {
+		t.Errorf("got segments %v, expected the time of the event with the custom layout", infos)
+	}
+}
@@ -0,0 +1,504 @@
+package sticky
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// encryptedFormat is the record format of records with encrypted fields.
+// Older versions of sticky would load the encrypted values as plain values.
+// With the higher format, they refuse to load the records.
+const encryptedFormat = 2
+
+// PersonalData can be implemented by an event, whose payload contains
+// personal data. With WithShredding, the fields are encrypted with a key of
+// the subject. After Sticky.Forget, the fields can not be decrypted anymore.
+type PersonalData interface {
+	// Subject returns the id of the person, the data belongs to. An empty
+	// string means, that the event has no personal data.
+	Subject() string
+
+	// PersonalFields returns the JSON names of the top level fields of the
+	// payload, that are encrypted.
+	PersonalFields() []string
+}
+
+// KeyStore stores the keys of the subjects for WithShredding.
+//
+// The keys are the only way to read the encrypted fields. Make backups of
+// the key store, but keep in mind, that a backup of a deleted key makes the
+// data readable again.
+type KeyStore interface {
+	// Key returns the key of the subject. It returns nil, if the subject has
+	// no key.
+	Key(subject string) ([]byte, error)
+
+	// CreateKey returns the key of the subject. If there is none, a new one
+	// is created.
+	CreateKey(subject string) ([]byte, error)
+
+	// DeleteKey removes the key of the subject.
+	DeleteKey(subject string) error
+}
+
+// newKey returns a random key for AES-256.
+func newKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("creating key: %w", err)
+	}
+	return key, nil
+}
+
+// MemoryKeyStore holds the keys in memory. Use it for tests. The zero value
+// is an empty store. It is safe for concurrent use.
+type MemoryKeyStore struct {
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+// Key returns the key of the subject.
+func (ks *MemoryKeyStore) Key(subject string) ([]byte, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	return ks.keys[subject], nil
+}
+
+// CreateKey returns the key of the subject and creates it, if necessary.
+func (ks *MemoryKeyStore) CreateKey(subject string) ([]byte, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if key, ok := ks.keys[subject]; ok {
+		return key, nil
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if ks.keys == nil {
+		ks.keys = make(map[string][]byte)
+	}
+	ks.keys[subject] = key
+	return key, nil
+}
+
+// DeleteKey removes the key of the subject.
+func (ks *MemoryKeyStore) DeleteKey(subject string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	delete(ks.keys, subject)
+	return nil
+}
+
+// FileKeyStore stores each key in its own file in the directory Dir. The
+// name of the file is a hash of the subject.
+//
+// DeleteKey removes the file. Depending on the file system, the content can
+// still be on the disk.
+type FileKeyStore struct {
+	Dir string
+
+	// mu makes sure, that a key is only created once.
+	mu sync.Mutex
+}
+
+const keyFileExt = ".key"
+
+func (ks *FileKeyStore) path(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return filepath.Join(ks.Dir, hex.EncodeToString(sum[:])+keyFileExt)
+}
+
+// Key returns the key of the subject.
+func (ks *FileKeyStore) Key(subject string) ([]byte, error) {
+	key, err := os.ReadFile(ks.path(subject))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading key: %w", err)
+	}
+	return key, nil
+}
+
+// CreateKey returns the key of the subject and creates it, if necessary. The
+// file is written atomically.
+func (ks *FileKeyStore) CreateKey(subject string) ([]byte, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, err := ks.Key(subject)
+	if err != nil || key != nil {
+		return key, err
+	}
+
+	if key, err = newKey(); err != nil {
+		return nil, err
+	}
+
+	path := ks.path(subject)
+	tmp := path + ".tmp"
+	if err := writeFileSync(tmp, key); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("rename key file: %w", err)
+	}
+
+	if err := syncDir(ks.Dir); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeleteKey removes the file of the key.
+func (ks *FileKeyStore) DeleteKey(subject string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if err := os.Remove(ks.path(subject)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing key: %w", err)
+	}
+	return syncDir(ks.Dir)
+}
+
+// shredder encrypts and decrypts the personal fields of events. It caches
+// the ciphers of the keys.
+type shredder struct {
+	store KeyStore
+
+	mu      sync.Mutex
+	ciphers map[string]subjectCipher
+}
+
+// subjectCipher is the cipher of the key of a subject and the id of the key.
+type subjectCipher struct {
+	id   string
+	aead cipher.AEAD
+}
+
+func newShredder(store KeyStore) *shredder {
+	return &shredder{store: store, ciphers: make(map[string]subjectCipher)}
+}
+
+// cipher returns the cipher of the subject. With create, a key is created,
+// if the subject has none. Otherwise ok is false.
+func (sh *shredder) cipher(subject string, create bool) (c subjectCipher, ok bool, err error) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if c, ok := sh.ciphers[subject]; ok {
+		return c, true, nil
+	}
+
+	var key []byte
+	if create {
+		key, err = sh.store.CreateKey(subject)
+	} else {
+		key, err = sh.store.Key(subject)
+	}
+	if err != nil {
+		return subjectCipher{}, false, fmt.Errorf("key of subject: %w", err)
+	}
+
+	if key == nil {
+		return subjectCipher{}, false, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return subjectCipher{}, false, fmt.Errorf("key of subject: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return subjectCipher{}, false, fmt.Errorf("key of subject: %w", err)
+	}
+
+	c = subjectCipher{id: keyID(key), aead: aead}
+	sh.ciphers[subject] = c
+	return c, true, nil
+}
+
+// forget deletes the key of the subject.
+func (sh *shredder) forget(subject string) error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	delete(sh.ciphers, subject)
+	return sh.store.DeleteKey(subject)
+}
+
+// additionalData binds an encrypted value to its subject and field.
+func additionalData(subject, field string) []byte {
+	return []byte(subject + "\x00" + field)
+}
+
+// encrypt replaces the personal fields of the payload with encrypted
+// strings. It returns the names of the encrypted fields. Fields, that are
+// not in the payload, are ignored.
+func (sh *shredder) encrypt(payload []byte, subject string, fields []string) ([]byte, []string, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &values); err != nil {
+		return nil, nil, fmt.Errorf("payload with personal data has to be a JSON object: %w", err)
+	}
+
+	c, _, err := sh.cipher(subject, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var encrypted []string
+	for _, field := range fields {
+		value, ok := values[field]
+		if !ok {
+			continue
+		}
+
+		nonce := make([]byte, c.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, nil, fmt.Errorf("creating nonce: %w", err)
+		}
+
+		sealed := c.aead.Seal(nonce, nonce, value, additionalData(subject, field))
+		encoded, err := json.Marshal(c.id + ":" + base64.RawStdEncoding.EncodeToString(sealed))
+		if err != nil {
+			return nil, nil, fmt.Errorf("encoding field %s: %w", field, err)
+		}
+		values[field] = encoded
+		encrypted = append(encrypted, field)
+	}
+
+	if len(encrypted) == 0 {
+		return payload, nil, nil
+	}
+
+	payload, err = json.Marshal(values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding payload: %w", err)
+	}
+	return payload, encrypted, nil
+}
+
+// decrypt replaces the encrypted fields of the envelope with the plain
+// values. If the key of the subject was deleted, the fields are removed, so
+// the event gets zero values.
+func (sh *shredder) decrypt(e Envelope) (json.RawMessage, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(e.Payload, &values); err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+
+	c, ok, err := sh.cipher(e.Subject, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range e.Encrypted {
+		value, exists := values[field]
+		if !exists {
+			continue
+		}
+
+		plain, forgotten, err := decryptValue(c, ok, value, additionalData(e.Subject, field))
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field, err)
+		}
+
+		if forgotten {
+			delete(values, field)
+			continue
+		}
+		values[field] = plain
+	}
+
+	return json.Marshal(values)
+}
+
+// decryptValue decrypts one encrypted value. forgotten is true, if there is
+// no key or the value was encrypted with a deleted key.
+func decryptValue(c subjectCipher, ok bool, value, additional []byte) (plain []byte, forgotten bool, err error) {
+	var encoded string
+	if err := json.Unmarshal(value, &encoded); err != nil {
+		return nil, false, fmt.Errorf("encrypted value is not a string: %w", err)
+	}
+
+	id, data, found := strings.Cut(encoded, ":")
+	if !found {
+		return nil, false, errors.New("encrypted value has no key id")
+	}
+
+	if !ok || id != c.id {
+		return nil, true, nil
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("decoding value: %w", err)
+	}
+
+	if len(sealed) < c.aead.NonceSize() {
+		return nil, false, errors.New("encrypted value is too short")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err = c.aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, false, fmt.Errorf("decrypting value: %w", err)
+	}
+	return plain, false, nil
+}
+
+// Forget deletes the key of the subject. Afterwards, the personal fields of
+// its events can not be decrypted anymore. When the database is loaded, the
+// fields get their zero values. See PersonalData.
+//
+// Afterwards, the model is replayed from the database, so it does not contain
+// the data anymore. The snapshot records in the database are replaced with
+// snapshots of the replayed model. With a SnapshotStore, a snapshot of the
+// replayed model is saved. A SnapshotStore has to remove the older
+// snapshots, like FileSnapshotStore does. Projections and handlers still have
+// the data.
+//
+// The database has to support ReplaceWith, if it contains snapshot records.
+// Otherwise, an error wrapping ErrNotSupported is returned and the key is
+// deleted, but the snapshots still contain the data.
+//
+// Without WithShredding, an error wrapping ErrNotSupported is returned.
+func (s *Sticky[Model]) Forget(subject string) error {
+	if s.loader.shredder == nil {
+		return fmt.Errorf("forget without WithShredding: %w", ErrNotSupported)
+	}
+
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	if err := s.flushAsync(); err != nil {
+		return err
+	}
+	if err := s.commitGroup(); err != nil {
+		return err
+	}
+
+	if err := s.loader.shredder.forget(subject); err != nil {
+		return fmt.Errorf("forget subject: %w", err)
+	}
+
+	if err := s.replaySnapshots(); err != nil {
+		return fmt.Errorf("removing data of subject from the snapshots: %w", err)
+	}
+	return nil
+}
+
+// replaySnapshots replays the database into a new model and replaces the
+// model of the Sticky with it. Each snapshot record in the database is
+// replaced with a snapshot of the replayed model at its position.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) replaySnapshots() error {
+	r, err := s.db.Reader()
+	if err != nil {
+		return StorageError{Op: "open database", Err: err}
+	}
+	defer r.Close()
+
+	l := s.loader.replayLoader()
+	model := s.newModel()
+
+	var content bytes.Buffer
+	var records uint64
+	var rewritten bool
+	err = scanRecords(context.Background(), r, l.maxEventSize, func(line []byte) error {
+		records++
+
+		envelope, err := DecodeEnvelope(line)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", records, err)
+		}
+
+		if envelope.Type == snapshotType && records > 1 {
+			data, err := marshalSnapshot(model)
+			if err != nil {
+				return err
+			}
+
+			if envelope.Payload, err = json.Marshal(data); err != nil {
+				return fmt.Errorf("encoding snapshot: %w", err)
+			}
+
+			if line, err = EncodeEnvelope(envelope); err != nil {
+				return fmt.Errorf("encoding snapshot: %w", err)
+			}
+			rewritten = true
+		} else {
+			if model, err = l.apply(model, envelope); err != nil {
+				if envelope.Type != snapshotType && l.skipUnknown(envelope, err) {
+					err = nil
+				} else {
+					return fmt.Errorf("record %d: %w", records, err)
+				}
+			}
+		}
+
+		content.Write(line)
+		content.WriteByte('\n')
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.Close()
+
+	s.model = model
+	s.publish()
+
+	if rewritten {
+		if err := replaceDB(s.db, &content); err != nil {
+			return StorageError{Op: "replacing database", Err: err}
+		}
+	}
+
+	if s.snapshotStore != nil {
+		data, err := marshalSnapshot(model)
+		if err != nil {
+			return err
+		}
+
+		if err := s.snapshotStore.Save(s.records, data); err != nil {
+			return StorageError{Op: "saving snapshot", Err: err}
+		}
+	}
+	return nil
+}
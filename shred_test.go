@@ -0,0 +1,351 @@
+package sticky
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type userModel struct {
+	Emails map[string]string
+	Plans  map[string]string
+}
+
+type signupEvent struct {
+	User  string `json:"user"`
+	Email string `json:"email"`
+	Plan  string `json:"plan"`
+}
+
+func (e signupEvent) Name() string {
+	return "signup"
+}
+
+func (e signupEvent) Subject() string {
+	return e.User
+}
+
+func (e signupEvent) PersonalFields() []string {
+	return []string{"email"}
+}
+
+func (e signupEvent) Validate(userModel) error {
+	return nil
+}
+
+func (e signupEvent) Execute(m userModel, _ time.Time) userModel {
+	if m.Emails == nil {
+		m.Emails = make(map[string]string)
+		m.Plans = make(map[string]string)
+	}
+	m.Emails[e.User] = e.Email
+	m.Plans[e.User] = e.Plan
+	return m
+}
+
+// snapshotUserModel is a userModel, that can be written as snapshot.
+type snapshotUserModel struct {
+	userModel
+}
+
+func (m snapshotUserModel) MarshalSnapshot() ([]byte, error) {
+	return json.Marshal(m.userModel)
+}
+
+func (m snapshotUserModel) UnmarshalSnapshot(data []byte) (snapshotUserModel, error) {
+	var model snapshotUserModel
+	err := json.Unmarshal(data, &model.userModel)
+	return model, err
+}
+
+type snapshotSignupEvent struct {
+	signupEvent
+}
+
+func (e snapshotSignupEvent) Validate(snapshotUserModel) error {
+	return nil
+}
+
+func (e snapshotSignupEvent) Execute(m snapshotUserModel, t time.Time) snapshotUserModel {
+	return snapshotUserModel{e.signupEvent.Execute(m.userModel, t)}
+}
+
+func getSnapshotSignupEvent(name string) Event[snapshotUserModel] {
+	if name == "signup" {
+		return &snapshotSignupEvent{}
+	}
+	return nil
+}
+
+func getSignupEvent(name string) Event[userModel] {
+	if name == "signup" {
+		return &signupEvent{}
+	}
+	return nil
+}
+
+func loadUsers(t *testing.T, db database, store KeyStore) userModel {
+	t.Helper()
+
+	s, err := New(db, userModel{}, getSignupEvent, WithShredding[userModel](store))
+	if err != nil {
+		t.Fatalf("loading sticky: %v", err)
+	}
+
+	m, done := s.ForReading()
+	done()
+	return m
+}
+
+func TestShredding(t *testing.T) {
+	db := NewMemoryDB()
+	var store MemoryKeyStore
+
+	s, err := New(db, userModel{}, getSignupEvent, WithShredding[userModel](&store))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for _, event := range []signupEvent{
+		{User: "max", Email: "max@example.com", Plan: "free"},
+		{User: "eva", Email: "eva@example.com", Plan: "pro"},
+	} {
+		if err := s.Write(func(userModel) Event[userModel] { return event }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	for _, record := range db.Records() {
+		if bytes.Contains(record, []byte("@example.com")) {
+			t.Errorf("record `%s` contains the plain email", record)
+		}
+
+		e, err := DecodeEnvelope(record)
+		if err != nil {
+			t.Fatalf("decoding record: %v", err)
+		}
+
+		if e.Format != encryptedFormat || e.Subject == "" || len(e.Encrypted) != 1 {
+			t.Errorf("got envelope %+v, expected the encrypted format with subject", e)
+		}
+	}
+
+	m := loadUsers(t, db, &store)
+	if m.Emails["max"] != "max@example.com" || m.Emails["eva"] != "eva@example.com" {
+		t.Errorf("got emails %v after reload, expected the decrypted emails", m.Emails)
+	}
+
+	if err := s.Forget("max"); err != nil {
+		t.Fatalf("forget: %v", err)
+	}
+
+	m = loadUsers(t, db, &store)
+	if email, ok := m.Emails["max"]; !ok || email != "" {
+		t.Errorf("got email `%s` of the forgotten subject, expected the zero value", email)
+	}
+
+	if m.Plans["max"] != "free" || m.Emails["eva"] != "eva@example.com" {
+		t.Errorf("got %v, expected the other fields and subjects to be unchanged", m)
+	}
+}
+
+func TestShredding_new_key_after_forget(t *testing.T) {
+	db := NewMemoryDB()
+	var store MemoryKeyStore
+
+	s, err := New(db, userModel{}, getSignupEvent, WithShredding[userModel](&store))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	write := func(email string) {
+		t.Helper()
+		if err := s.Write(func(userModel) Event[userModel] { return signupEvent{User: "max", Email: email} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	write("old@example.com")
+	if err := s.Forget("max"); err != nil {
+		t.Fatalf("forget: %v", err)
+	}
+	write("new@example.com")
+
+	// Load only the first record. Its key was deleted, so the new key of the
+	// subject must not be used for it.
+	first := NewMemoryDB(string(db.Records()[0]))
+	if m := loadUsers(t, first, &store); m.Emails["max"] != "" {
+		t.Errorf("got email `%s` of the forgotten key", m.Emails["max"])
+	}
+
+	if m := loadUsers(t, db, &store); m.Emails["max"] != "new@example.com" {
+		t.Errorf("got email `%s`, expected the new email", m.Emails["max"])
+	}
+}
+
+func TestForget_removes_data_from_snapshots(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		options  func(dir string) []Option[snapshotUserModel]
+		snapshot bool
+	}{
+		{"snapshot record", func(string) []Option[snapshotUserModel] { return nil }, true},
+		{"snapshot store", func(dir string) []Option[snapshotUserModel] {
+			return []Option[snapshotUserModel]{WithSnapshotStore[snapshotUserModel](FileSnapshotStore{Dir: dir})}
+		}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := &FileKeyStore{Dir: t.TempDir()}
+			file := filepath.Join(dir, "events.log")
+			options := append(tt.options(dir), WithShredding[snapshotUserModel](store))
+
+			s, err := New(&FileDB{File: file}, snapshotUserModel{}, getSnapshotSignupEvent, options...)
+			if err != nil {
+				t.Fatalf("creating sticky: %v", err)
+			}
+
+			for _, event := range []signupEvent{
+				{User: "max", Email: "max@example.com", Plan: "free"},
+				{User: "eva", Email: "eva@example.com", Plan: "pro"},
+			} {
+				if err := s.Write(func(snapshotUserModel) Event[snapshotUserModel] { return snapshotSignupEvent{event} }); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+			}
+
+			if err := s.Snapshot(); err != nil {
+				t.Fatalf("snapshot: %v", err)
+			}
+
+			if err := s.Forget("max"); err != nil {
+				t.Fatalf("forget: %v", err)
+			}
+
+			model, done := s.ForReading()
+			email := model.Emails["max"]
+			done()
+			if email != "" {
+				t.Errorf("got email `%s` of the forgotten subject in the model", email)
+			}
+
+			if err := s.Snapshot(); err != nil {
+				t.Fatalf("snapshot after forget: %v", err)
+			}
+
+			if err := s.Compact(context.Background()); !errors.Is(err, ErrNotSupported) {
+				t.Errorf("got error `%v` from Compact, expected ErrNotSupported", err)
+			}
+			s.Close()
+
+			files, err := filepath.Glob(filepath.Join(dir, "*"))
+			if err != nil {
+				t.Fatalf("listing files: %v", err)
+			}
+
+			for _, f := range files {
+				content := readSnapshotContent(t, f)
+				if bytes.Contains(content, []byte("max@example.com")) {
+					t.Errorf("file %s contains the email of the forgotten subject", filepath.Base(f))
+				}
+
+				if tt.snapshot && f == file && !bytes.Contains(content, []byte("eva@example.com")) {
+					t.Errorf("file %s does not contain the snapshot with the other subject", filepath.Base(f))
+				}
+			}
+
+			reloaded, err := New(&FileDB{File: file}, snapshotUserModel{}, getSnapshotSignupEvent, options...)
+			if err != nil {
+				t.Fatalf("reloading sticky: %v", err)
+			}
+			defer reloaded.Close()
+
+			model, done = reloaded.ForReading()
+			defer done()
+			if model.Emails["max"] != "" || model.Plans["max"] != "free" || model.Emails["eva"] != "eva@example.com" {
+				t.Errorf("got %v after reload, expected only the email of max to be removed", model.userModel)
+			}
+		})
+	}
+}
+
+// readSnapshotContent returns the content of the file with the decoded data of
+// the snapshot records.
+func readSnapshotContent(t *testing.T, file string) []byte {
+	t.Helper()
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		e, err := DecodeEnvelope(line)
+		if err != nil || e.Type != snapshotType {
+			continue
+		}
+
+		var data []byte
+		if err := json.Unmarshal(e.Payload, &data); err != nil {
+			t.Fatalf("decoding snapshot: %v", err)
+		}
+		content = append(content, data...)
+	}
+	return content
+}
+
+func TestShredding_load_without_option(t *testing.T) {
+	db := NewMemoryDB()
+
+	s, err := New(db, userModel{}, getSignupEvent, WithShredding[userModel](&MemoryKeyStore{}))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(userModel) Event[userModel] { return signupEvent{User: "max", Email: "max@example.com"} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := New(db, userModel{}, getSignupEvent); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error `%v`, expected ErrNotSupported", err)
+	}
+
+	if err := (&Sticky[userModel]{}).Forget("max"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error `%v` from Forget without WithShredding, expected ErrNotSupported", err)
+	}
+}
+
+func TestFileKeyStore(t *testing.T) {
+	store := &FileKeyStore{Dir: t.TempDir()}
+
+	if key, err := store.Key("max"); err != nil || key != nil {
+		t.Fatalf("got key %v and error %v, expected no key", key, err)
+	}
+
+	key, err := store.CreateKey("max")
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	again, err := store.CreateKey("max")
+	if err != nil {
+		t.Fatalf("create key again: %v", err)
+	}
+
+	if !bytes.Equal(key, again) {
+		t.Errorf("CreateKey returned a new key for an existing subject")
+	}
+
+	if err := store.DeleteKey("max"); err != nil {
+		t.Fatalf("delete key: %v", err)
+	}
+
+	if key, err := store.Key("max"); err != nil || key != nil {
+		t.Errorf("got key %v and error %v after delete, expected no key", key, err)
+	}
+}
@@ -0,0 +1,279 @@
+package sticky
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// snapshotType is the type of the envelope of a snapshot record. Events can
+// not use this name.
+const snapshotType = "$snapshot"
+
+// Snapshotter can be implemented by a model, to make it possible to write
+// snapshots.
+//
+// When the model implements Snapshotter, the model is loaded from the most
+// recent snapshot in the database and only the events after it are replayed.
+// UnmarshalSnapshot is called on the empty model.
+type Snapshotter[Model any] interface {
+	MarshalSnapshot() ([]byte, error)
+	UnmarshalSnapshot([]byte) (Model, error)
+}
+
+// Cloner can be implemented by a model, to copy it. With automatic
+// snapshots, the snapshot of a model that implements Cloner is serialized
+// without holding the lock. See WithAutoSnapshot.
+//
+// With WithCloneOnRead, the readers get a copy of the model. The copy has to
+// be deep, so it does not share maps or slices with the model.
+//
+// Each load or replay of the database starts from a copy of the empty model,
+// if it implements Cloner. A write executes its events on a copy, so the
+// model is not changed, if the write fails.
+type Cloner[Model any] interface {
+	Clone() Model
+}
+
+// Snapshot writes the current model as snapshot record to the database or
+// to the SnapshotStore, if one is set with WithSnapshotStore.
+//
+// The model has to implement Snapshotter. Otherwise, an error wrapping
+// ErrNotSupported is returned. A snapshot record has to be smaller then the
+// maximum event size. See WithMaxEventSize.
+func (s *Sticky[Model]) Snapshot() error {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.snapshot()
+}
+
+// snapshot writes a snapshot of the current model.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) snapshot() error {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	data, err := marshalSnapshot(s.model)
+	if err != nil {
+		return err
+	}
+
+	return s.writeSnapshot(data)
+}
+
+// writeSnapshot saves the snapshot in the SnapshotStore or appends it as
+// record. Afterwards, the counters for automatic snapshots are reset.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) writeSnapshot(data []byte) (err error) {
+	_, endTrace := s.startTrace(context.Background(), "snapshot")
+	defer func() { endTrace(TraceResult{Err: err}) }()
+
+	if s.snapshotStore != nil {
+		if err := s.snapshotStore.Save(s.records, data); err != nil {
+			return StorageError{Op: "saving snapshot", Err: err}
+		}
+	} else {
+		if err := s.flushAsync(); err != nil {
+			return err
+		}
+
+		record, err := s.snapshotRecord(data, s.version, s.seq+1)
+		if err != nil {
+			return err
+		}
+
+		if err := s.db.Append(record); err != nil {
+			return StorageError{Op: "writing snapshot to db", Err: err}
+		}
+		s.records++
+		s.seq++
+	}
+
+	s.eventsSinceSnapshot = 0
+	s.lastSnapshot = s.now()
+	s.stats.lastSnapshot = s.lastSnapshot
+
+	if s.logger != nil {
+		s.logger.Info("snapshot written", "version", s.version)
+	}
+	return nil
+}
+
+// marshalSnapshot returns the snapshot of the model.
+func marshalSnapshot[Model any](model Model) ([]byte, error) {
+	snapshotter, ok := any(model).(Snapshotter[Model])
+	if !ok {
+		return nil, fmt.Errorf("model %T does not implement Snapshotter: %w", model, ErrNotSupported)
+	}
+
+	data, err := snapshotter.MarshalSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("marshal snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// snapshotRecord encodes a snapshot as record. The version is the version of
+// the model of the snapshot and seq the sequence number of the record.
+func (s *Sticky[Model]) snapshotRecord(data []byte, version, seq uint64) ([]byte, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	bs, err := EncodeEnvelope(Envelope{
+		Time:    formatTime(s.now(), s.loader.timeLayout),
+		Type:    snapshotType,
+		Version: version,
+		Seq:     seq,
+		Payload: payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	if len(bs) > s.loader.maxEventSize {
+		return nil, EventTooLargeError{Name: snapshotType, Size: len(bs), Max: s.loader.maxEventSize}
+	}
+	return bs, nil
+}
+
+// autoSnapshot writes a snapshot, if one of the thresholds of
+// WithAutoSnapshot is crossed.
+//
+// If the model implements Cloner, a copy of the model is serialized in the
+// background. The snapshot is only written, if no events where written in the
+// meantime. Otherwise, the next write tries again.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) autoSnapshot() {
+	// The snapshot must not contain the events of a group, before it is
+	// synced.
+	if s.eventsSinceSnapshot == 0 || s.snapshotting || s.group != nil {
+		return
+	}
+
+	everyReached := s.autoSnapshotEvery > 0 && s.eventsSinceSnapshot >= s.autoSnapshotEvery
+	intervalReached := s.autoSnapshotInterval > 0 && s.now().Sub(s.lastSnapshot) >= s.autoSnapshotInterval
+	if !everyReached && !intervalReached {
+		return
+	}
+
+	cloner, ok := any(s.model).(Cloner[Model])
+	if !ok {
+		if err := s.snapshot(); err != nil {
+			s.snapshotError(err)
+		}
+		return
+	}
+
+	s.snapshotting = true
+	clone := cloner.Clone()
+	written := s.eventsWritten
+
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+
+		data, err := marshalSnapshot(clone)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.snapshotting = false
+
+		if err != nil {
+			s.snapshotError(err)
+			return
+		}
+
+		// After Close, the database is still open until all background
+		// goroutines are finished.
+		if s.eventsWritten != written {
+			return
+		}
+
+		if err := s.writeSnapshot(data); err != nil {
+			s.snapshotError(err)
+		}
+	}()
+}
+
+func (s *Sticky[Model]) snapshotError(err error) {
+	if s.logger != nil {
+		s.logger.Error("automatic snapshot failed", "error", err)
+	}
+
+	if s.onSnapshotError != nil {
+		s.onSnapshotError(fmt.Errorf("automatic snapshot: %w", err))
+	}
+}
+
+// applySnapshot returns the model from a snapshot record.
+func (l *loader[Model]) applySnapshot(model Model, e Envelope) (Model, error) {
+	var data []byte
+	if err := json.Unmarshal(e.Payload, &data); err != nil {
+		return model, fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	return l.unmarshalSnapshot(model, data)
+}
+
+// unmarshalSnapshot returns the model from the snapshot data.
+func (l *loader[Model]) unmarshalSnapshot(model Model, data []byte) (Model, error) {
+	snapshotter, ok := any(model).(Snapshotter[Model])
+	if !ok {
+		return model, fmt.Errorf("found a snapshot, but model %T does not implement Snapshotter", model)
+	}
+
+	model, err := snapshotter.UnmarshalSnapshot(data)
+	if err != nil {
+		return model, fmt.Errorf("unmarshal snapshot: %w", err)
+	}
+	return model, nil
+}
+
+// findLastSnapshot returns the index of the last snapshot record. Empty lines
+// are not counted. It returns -1, if there is no snapshot.
+//
+// Read errors are ignored. They are reported, when the events are loaded.
+func findLastSnapshot(r io.Reader, maxEventSize int) int {
+	marker := []byte(`"type":"` + snapshotType + `"`)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxEventSize+2)
+
+	last := -1
+	index := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		// The check for the marker is much faster then decoding each line.
+		// The marker could also be part of a payload, so the line has to be
+		// decoded to be sure.
+		if bytes.Contains(line, marker) {
+			if e, err := DecodeEnvelope(line); err == nil && e.Type == snapshotType {
+				last = index
+			}
+		}
+		index++
+	}
+	return last
+}
@@ -0,0 +1,336 @@
+package sticky
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// snapshotModel counts the replayed events. The counter is not part of the
+// snapshot.
+type snapshotModel struct {
+	Sum      int
+	Replayed int
+}
+
+func (m snapshotModel) MarshalSnapshot() ([]byte, error) {
+	return []byte(strconv.Itoa(m.Sum)), nil
+}
+
+func (m snapshotModel) UnmarshalSnapshot(data []byte) (snapshotModel, error) {
+	sum, err := strconv.Atoi(string(data))
+	if err != nil {
+		return snapshotModel{}, err
+	}
+	return snapshotModel{Sum: sum}, nil
+}
+
+type snapshotAddEvent struct {
+	Value int `json:"value"`
+}
+
+func (e snapshotAddEvent) Name() string                 { return "add" }
+func (e snapshotAddEvent) Validate(snapshotModel) error { return nil }
+func (e snapshotAddEvent) Execute(m snapshotModel, _ time.Time) snapshotModel {
+	m.Sum += e.Value
+	m.Replayed++
+	return m
+}
+
+func getSnapshotTestEvent(name string) Event[snapshotModel] {
+	if name == "add" {
+		return &snapshotAddEvent{}
+	}
+	return nil
+}
+
+func TestSnapshot_load_starts_at_last_snapshot(t *testing.T) {
+	db := NewMemoryDB()
+
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	write := func(value int) {
+		t.Helper()
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: value} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	write(1)
+	write(2)
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("first snapshot: %v", err)
+	}
+	write(3)
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("second snapshot: %v", err)
+	}
+	write(4)
+	write(5)
+
+	records := db.Records()
+	if len(records) != 7 {
+		t.Fatalf("got %d records, expected 7", len(records))
+	}
+
+	reloaded, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	model, done := reloaded.ForReading()
+	done()
+
+	if model.Sum != 15 {
+		t.Errorf("got sum %d, expected 15", model.Sum)
+	}
+
+	if model.Replayed != 2 {
+		t.Errorf("replayed %d events, expected 2", model.Replayed)
+	}
+}
+
+func TestSnapshot_model_without_snapshotter(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Snapshot(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("got error `%v`, expected ErrNotSupported", err)
+	}
+
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"$snapshot","payload":"MQ=="}`)
+	if _, err := New(db, testModel{}, getTestEvent); err == nil || !strings.Contains(err.Error(), "Snapshotter") {
+		t.Errorf("got error `%v`, expected error about Snapshotter", err)
+	}
+}
+
+func TestSnapshot_marker_in_payload_is_no_snapshot(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1,"other":{"type":"$snapshot"}}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+	)
+
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 3 {
+		t.Errorf("got sum %d, expected 3", model.Sum)
+	}
+}
+
+func TestWithAutoSnapshot_every_n_events(t *testing.T) {
+	db := NewMemoryDB()
+
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent, WithAutoSnapshot[snapshotModel](2, 0))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 5; i++ {
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	var types []string
+	for _, record := range db.Records() {
+		e, err := DecodeEnvelope(record)
+		if err != nil {
+			t.Fatalf("decoding record: %v", err)
+		}
+		types = append(types, e.Type)
+	}
+
+	expect := "add,add,$snapshot,add,add,$snapshot,add"
+	if got := strings.Join(types, ","); got != expect {
+		t.Errorf("got records %s, expected %s", got, expect)
+	}
+
+	if got := s.Stats().EventsSinceSnapshot; got != 1 {
+		t.Errorf("got %d events since snapshot, expected 1", got)
+	}
+
+	reloaded, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	if got := reloaded.Stats().EventsSinceSnapshot; got != 1 {
+		t.Errorf("reloaded sticky has %d events since snapshot, expected 1", got)
+	}
+}
+
+func TestWithAutoSnapshot_max_interval(t *testing.T) {
+	db := NewMemoryDB()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := New(
+		db,
+		snapshotModel{},
+		getSnapshotTestEvent,
+		WithAutoSnapshot[snapshotModel](0, time.Hour),
+		WithNow[snapshotModel](func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	write := func() {
+		t.Helper()
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	write()
+	if got := len(db.Records()); got != 1 {
+		t.Fatalf("got %d records before the interval, expected 1", got)
+	}
+
+	now = now.Add(time.Hour)
+	write()
+	if got := len(db.Records()); got != 3 {
+		t.Fatalf("got %d records after the interval, expected 3", got)
+	}
+}
+
+// cloneModel is a Snapshotter, that implements Cloner.
+type cloneModel struct {
+	Sum int
+}
+
+func (m cloneModel) Clone() cloneModel {
+	return m
+}
+
+func (m cloneModel) MarshalSnapshot() ([]byte, error) {
+	return []byte(strconv.Itoa(m.Sum)), nil
+}
+
+func (m cloneModel) UnmarshalSnapshot(data []byte) (cloneModel, error) {
+	sum, err := strconv.Atoi(string(data))
+	return cloneModel{Sum: sum}, err
+}
+
+type cloneAddEvent struct {
+	Value int `json:"value"`
+}
+
+func (e cloneAddEvent) Name() string              { return "add" }
+func (e cloneAddEvent) Validate(cloneModel) error { return nil }
+func (e cloneAddEvent) Execute(m cloneModel, _ time.Time) cloneModel {
+	m.Sum += e.Value
+	return m
+}
+
+func TestWithAutoSnapshot_clone_in_background(t *testing.T) {
+	db := NewMemoryDB()
+	getEvent := func(string) Event[cloneModel] { return &cloneAddEvent{} }
+
+	s, err := New(db, cloneModel{}, getEvent, WithAutoSnapshot[cloneModel](2, 0))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 3} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	// Close waits for the background snapshot.
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	records := db.Records()
+	if len(records) != 3 {
+		t.Fatalf("got %d records, expected 3", len(records))
+	}
+
+	reloaded, err := New(db, cloneModel{}, getEvent)
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	model, done := reloaded.ForReading()
+	done()
+
+	if model.Sum != 6 {
+		t.Errorf("got sum %d, expected 6", model.Sum)
+	}
+
+	if got := reloaded.Stats().EventsSinceSnapshot; got != 0 {
+		t.Errorf("got %d events since snapshot, expected 0", got)
+	}
+}
+
+// mapModel is a Cloner with a map, that is changed in place by setEvent.
+type mapModel struct {
+	Values map[string]int
+}
+
+func (m mapModel) Clone() mapModel {
+	values := make(map[string]int, len(m.Values))
+	for k, v := range m.Values {
+		values[k] = v
+	}
+	return mapModel{Values: values}
+}
+
+type setEvent struct {
+	Key   string `json:"key"`
+	Value int    `json:"value"`
+}
+
+func (e setEvent) Name() string            { return "set" }
+func (e setEvent) Validate(mapModel) error { return nil }
+func (e setEvent) Execute(m mapModel, _ time.Time) mapModel {
+	if m.Values == nil {
+		m.Values = make(map[string]int)
+	}
+	m.Values[e.Key] = e.Value
+	return m
+}
+
+func TestWithCloneOnRead(t *testing.T) {
+	getEvent := func(string) Event[mapModel] { return &setEvent{} }
+	s, err := New(NewMemoryDB(), mapModel{Values: map[string]int{}}, getEvent, WithCloneOnRead[mapModel]())
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(mapModel) Event[mapModel] { return setEvent{Key: "a", Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if err := s.Write(func(mapModel) Event[mapModel] { return setEvent{Key: "a", Value: 2} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := model.Values["a"]; got != 1 {
+		t.Errorf("got `%d`, expected the value at reading time 1", got)
+	}
+}
+
+func TestWithCloneOnRead_no_cloner(t *testing.T) {
+	_, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithCloneOnRead[testModel]())
+	if err == nil || !strings.Contains(err.Error(), "Cloner") {
+		t.Errorf("got error `%v`, expected an error about Cloner", err)
+	}
+}
@@ -0,0 +1,157 @@
+package sticky
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SnapshotStore stores snapshots outside of the database. See
+// WithSnapshotStore.
+//
+// The version of a snapshot is the number of records in the database, that
+// are contained in the snapshot.
+type SnapshotStore interface {
+	// Save stores a snapshot.
+	Save(version uint64, data []byte) error
+
+	// Latest returns the snapshot with the highest version. It returns nil
+	// data, if there is no snapshot.
+	Latest() (version uint64, data []byte, err error)
+}
+
+// readerAfter is a database, that can skip records efficiently.
+type readerAfter interface {
+	// ReaderAfter returns a reader, that skips the first n records.
+	ReaderAfter(n uint64) (io.ReadCloser, error)
+}
+
+// FileSnapshotStore stores each snapshot in a file called
+// "snapshot-<version>.bin" in the directory Dir.
+//
+// Use the directory of the database file. Each database needs its own
+// directory. After a snapshot is saved, the older snapshots are removed.
+type FileSnapshotStore struct {
+	Dir string
+}
+
+const (
+	snapshotFilePrefix = "snapshot-"
+	snapshotFileExt    = ".bin"
+)
+
+// Save writes the snapshot to a temporary file and renames it, so there is
+// never an incomplete snapshot.
+func (fs FileSnapshotStore) Save(version uint64, data []byte) error {
+	versions, err := fs.versions()
+	if err != nil {
+		return err
+	}
+
+	path := fs.path(version)
+	tmp := path + ".tmp"
+	if err := writeFileSync(tmp, data); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+
+	if err := syncDir(fs.Dir); err != nil {
+		return err
+	}
+
+	for _, v := range versions {
+		if v == version {
+			continue
+		}
+
+		if err := os.Remove(fs.path(v)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove old snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// Latest reads the snapshot with the highest version.
+func (fs FileSnapshotStore) Latest() (uint64, []byte, error) {
+	versions, err := fs.versions()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if len(versions) == 0 {
+		return 0, nil, nil
+	}
+
+	latest := versions[0]
+	for _, v := range versions[1:] {
+		latest = max(latest, v)
+	}
+
+	data, err := os.ReadFile(fs.path(latest))
+	if err != nil {
+		return 0, nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	return latest, data, nil
+}
+
+func (fs FileSnapshotStore) path(version uint64) string {
+	return filepath.Join(fs.Dir, snapshotFilePrefix+strconv.FormatUint(version, 10)+snapshotFileExt)
+}
+
+// versions returns the versions of all snapshot files.
+func (fs FileSnapshotStore) versions() ([]uint64, error) {
+	entries, err := os.ReadDir(fs.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading snapshot directory: %w", err)
+	}
+
+	var versions []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, snapshotFilePrefix) || !strings.HasSuffix(name, snapshotFileExt) {
+			continue
+		}
+
+		digits := strings.TrimSuffix(strings.TrimPrefix(name, snapshotFilePrefix), snapshotFileExt)
+		version, err := strconv.ParseUint(digits, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// writeFileSync writes data to a new file and syncs it.
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing file: %w", err)
+	}
+	return nil
+}
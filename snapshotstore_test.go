@@ -0,0 +1,111 @@
+package sticky
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestFileSnapshotStore_save_and_latest(t *testing.T) {
+	store := FileSnapshotStore{Dir: t.TempDir()}
+
+	version, data, err := store.Latest()
+	if err != nil {
+		t.Fatalf("latest on empty store: %v", err)
+	}
+	if data != nil || version != 0 {
+		t.Errorf("got version %d with data `%s` from empty store, expected nothing", version, data)
+	}
+
+	if err := store.Save(3, []byte("three")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := store.Save(10, []byte("ten")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	version, data, err = store.Latest()
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if version != 10 || string(data) != "ten" {
+		t.Errorf("got version %d with data `%s`, expected version 10 with `ten`", version, data)
+	}
+
+	entries, err := os.ReadDir(store.Dir)
+	if err != nil {
+		t.Fatalf("reading dir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "snapshot-10.bin" {
+		t.Errorf("got files %v, expected only snapshot-10.bin", entries)
+	}
+}
+
+func TestWithSnapshotStore(t *testing.T) {
+	dir := t.TempDir()
+	db := FileDB{File: path.Join(dir, "events.log")}
+	defer db.Close()
+	store := FileSnapshotStore{Dir: dir}
+
+	s, err := New(&db, snapshotModel{}, getSnapshotTestEvent, WithSnapshotStore[snapshotModel](store))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	write := func(value int) {
+		t.Helper()
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: value} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	write(1)
+	write(2)
+	write(3)
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	write(4)
+	write(5)
+
+	content, err := readDB(t, &db)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+	if strings.Contains(content, snapshotType) {
+		t.Errorf("database contains a snapshot record: %s", content)
+	}
+
+	version, _, err := store.Latest()
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if version != 3 {
+		t.Errorf("got snapshot version %d, expected 3", version)
+	}
+
+	reloaded, err := New(&db, snapshotModel{}, getSnapshotTestEvent, WithSnapshotStore[snapshotModel](store))
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	model, done := reloaded.ForReading()
+	done()
+
+	if model.Sum != 15 || model.Replayed != 2 {
+		t.Errorf("got model %+v, expected sum 15 with two replayed events", model)
+	}
+}
+
+func TestWithSnapshotStore_snapshot_newer_then_database(t *testing.T) {
+	store := FileSnapshotStore{Dir: t.TempDir()}
+	if err := store.Save(5, []byte("10")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	_, err := New(NewMemoryDB(), snapshotModel{}, getSnapshotTestEvent, WithSnapshotStore[snapshotModel](store))
+	if err == nil {
+		t.Fatalf("New returned no error for a snapshot newer then the database")
+	}
+}
@@ -0,0 +1,232 @@
+// Package sqlitedb implements a sticky database that stores the events in a
+// sqlite table.
+package sqlitedb
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/ostcar/sticky/internal/records"
+	_ "modernc.org/sqlite" // sqlite driver
+)
+
+const defaultTable = "events"
+
+var validTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Option is a option for sqlitedb.Open()
+type Option func(db *DB)
+
+// WithTable uses a custom table name. Default is "events".
+func WithTable(name string) Option {
+	return func(db *DB) {
+		db.table = name
+	}
+}
+
+// DB is a event database based on a sqlite table.
+type DB struct {
+	conn  *sql.DB
+	table string
+}
+
+// Open opens the sqlite database in the given file.
+//
+// The database uses WAL mode. The table is created, if it does not exist.
+func Open(file string, options ...Option) (*DB, error) {
+	db := DB{
+		table: defaultTable,
+	}
+
+	for _, o := range options {
+		o(&db)
+	}
+
+	if !validTable.MatchString(db.table) {
+		return nil, fmt.Errorf("invalid table name `%s`", db.table)
+	}
+
+	// Sqlite only supports one writer at a time. With the busy timeout, a
+	// second connection waits instead of failing with SQLITE_BUSY.
+	conn, err := sql.Open("sqlite", file+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+
+	if _, err := conn.Exec(`PRAGMA journal_mode=WAL`); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("enable wal mode: %w", err)
+	}
+
+	create := fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (seq INTEGER PRIMARY KEY AUTOINCREMENT, event BLOB NOT NULL)`,
+		db.table,
+	)
+	if _, err := conn.Exec(create); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("create table %s: %w", db.table, err)
+	}
+
+	db.conn = conn
+	return &db, nil
+}
+
+// Reader returns all events in sequence order separated by newlines.
+func (db *DB) Reader() (io.ReadCloser, error) {
+	rows, err := db.conn.Query(fmt.Sprintf(`SELECT event FROM %s ORDER BY seq`, db.table))
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	return &rowReader{rows: rows}, nil
+}
+
+// ReaderAfter is like Reader, but skips the first n events.
+func (db *DB) ReaderAfter(n uint64) (io.ReadCloser, error) {
+	rows, err := db.conn.Query(fmt.Sprintf(`SELECT event FROM %s ORDER BY seq LIMIT -1 OFFSET ?`, db.table), n)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+	return &rowReader{rows: rows}, nil
+}
+
+// Append inserts an event as new row.
+func (db *DB) Append(bs []byte) error {
+	if bytes.Contains(bs, []byte("\n")) {
+		return errors.New("event contains a newline")
+	}
+
+	if _, err := db.conn.Exec(fmt.Sprintf(`INSERT INTO %s (event) VALUES (?)`, db.table), bs); err != nil {
+		return fmt.Errorf("insert event: %w", err)
+	}
+	return nil
+}
+
+// AppendBatch inserts many events in one transaction.
+func (db *DB) AppendBatch(records [][]byte) error {
+	for _, bs := range records {
+		if bytes.Contains(bs, []byte("\n")) {
+			return errors.New("event contains a newline")
+		}
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s (event) VALUES (?)`, db.table))
+	if err != nil {
+		return fmt.Errorf("prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, bs := range records {
+		if _, err := stmt.Exec(bs); err != nil {
+			return fmt.Errorf("insert event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit events: %w", err)
+	}
+	return nil
+}
+
+// Size returns the size of all events including the newlines.
+func (db *DB) Size() (int64, error) {
+	var size int64
+	query := fmt.Sprintf(`SELECT COALESCE(SUM(LENGTH(event) + 1), 0) FROM %s`, db.table)
+	if err := db.conn.QueryRow(query).Scan(&size); err != nil {
+		return 0, fmt.Errorf("query size: %w", err)
+	}
+	return size, nil
+}
+
+// ReplaceWith deletes all events and inserts the lines from r in one
+// transaction.
+func (db *DB) ReplaceWith(r io.Reader) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s`, db.table)); err != nil {
+		return fmt.Errorf("delete events: %w", err)
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s (event) VALUES (?)`, db.table))
+	if err != nil {
+		return fmt.Errorf("prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	br := bufio.NewReader(r)
+	for {
+		record, err := records.Next(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading new content: %w", err)
+		}
+
+		if _, err := stmt.Exec(record); err != nil {
+			return fmt.Errorf("insert event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit events: %w", err)
+	}
+	return nil
+}
+
+// Close closes the sqlite database.
+func (db *DB) Close() error {
+	return db.conn.Close()
+}
+
+// rowReader streams the rows of a query as newline separated bytes.
+type rowReader struct {
+	rows *sql.Rows
+	buf  []byte
+	err  error
+}
+
+func (r *rowReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		if !r.rows.Next() {
+			r.err = io.EOF
+			if err := r.rows.Err(); err != nil {
+				r.err = fmt.Errorf("reading events: %w", err)
+			}
+			continue
+		}
+
+		var event []byte
+		if err := r.rows.Scan(&event); err != nil {
+			r.err = fmt.Errorf("scanning event: %w", err)
+			continue
+		}
+		r.buf = append(event, '\n')
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *rowReader) Close() error {
+	return r.rows.Close()
+}
@@ -0,0 +1,192 @@
+package sqlitedb_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ostcar/sticky/sqlitedb"
+)
+
+func TestSqliteDB_read_empty_db(t *testing.T) {
+	db, err := sqlitedb.Open(path.Join(t.TempDir(), "events.sqlite"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	if scanner.Scan() {
+		t.Errorf("got `%s` from empty db. Expected nothing", scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Errorf("reading db: %v", err)
+	}
+}
+
+func TestSqliteDB_round_trip(t *testing.T) {
+	const count = 3000
+	file := path.Join(t.TempDir(), "events.sqlite")
+
+	db, err := sqlitedb.Open(file, sqlitedb.WithTable("my_events"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	for i := 0; i < count; i++ {
+		if err := db.Append([]byte(fmt.Sprintf(`{"number":%d}`, i))); err != nil {
+			t.Fatalf("append event %d: %v", i, err)
+		}
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("closing db: %v", err)
+	}
+
+	db, err = sqlitedb.Open(file, sqlitedb.WithTable("my_events"))
+	if err != nil {
+		t.Fatalf("reopen db: %v", err)
+	}
+	defer db.Close()
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		expect := fmt.Sprintf(`{"number":%d}`, i)
+		if scanner.Text() != expect {
+			t.Fatalf("line %d is `%s`, expected `%s`", i, scanner.Text(), expect)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if i != count {
+		t.Errorf("got %d events, expected %d", i, count)
+	}
+}
+
+func TestSqliteDB_invalid_table_name(t *testing.T) {
+	if _, err := sqlitedb.Open(path.Join(t.TempDir(), "events.sqlite"), sqlitedb.WithTable("bad; name")); err == nil {
+		t.Errorf("open with invalid table name did not return an error")
+	}
+}
+
+func TestSqliteDB_append_new_line_creates_an_error(t *testing.T) {
+	db, err := sqlitedb.Open(path.Join(t.TempDir(), "events.sqlite"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Append([]byte("some string\nwith newline")); err == nil {
+		t.Errorf("append an newline did not return an error")
+	}
+}
+
+func TestSqliteDB_append_while_reading(t *testing.T) {
+	db, err := sqlitedb.Open(path.Join(t.TempDir(), "events.sqlite"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Append([]byte("first")); err != nil {
+		t.Fatalf("append event: %v", err)
+	}
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	if err := db.Append([]byte("second")); err != nil {
+		t.Fatalf("append event while reading: %v", err)
+	}
+}
+
+func TestSqliteDB_replace_with(t *testing.T) {
+	db, err := sqlitedb.Open(path.Join(t.TempDir(), "events.sqlite"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.AppendBatch([][]byte{[]byte("one"), []byte("two")}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if err := db.ReplaceWith(strings.NewReader("three\n")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	if err := db.Append([]byte("four")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	r, err := db.Reader()
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if expect := "three\nfour\n"; string(got) != expect {
+		t.Errorf("got `%s`, expected `%s`", got, expect)
+	}
+
+	size, err := db.Size()
+	if err != nil {
+		t.Fatalf("size: %v", err)
+	}
+	if size != int64(len(got)) {
+		t.Errorf("got size %d, expected %d", size, len(got))
+	}
+}
+
+func TestSqliteDB_reader_after(t *testing.T) {
+	db, err := sqlitedb.Open(path.Join(t.TempDir(), "events.sqlite"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.AppendBatch([][]byte{[]byte("one"), []byte("two"), []byte("three")}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	r, err := db.ReaderAfter(2)
+	if err != nil {
+		t.Fatalf("getting db reader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading db: %v", err)
+	}
+
+	if string(got) != "three\n" {
+		t.Errorf("got `%s`, expected `three\n`", got)
+	}
+}
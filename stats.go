@@ -0,0 +1,137 @@
+package sticky
+
+import (
+	"maps"
+	"time"
+)
+
+// Stats contains runtime information about a Sticky.
+type Stats struct {
+	// Version is the version of the model.
+	Version uint64
+
+	// EventsSinceSnapshot is the number of events after the last snapshot.
+	// Without snapshots, it is the number of all events.
+	EventsSinceSnapshot int
+
+	// LastSeq is the sequence number of the last record in the database.
+	LastSeq uint64
+
+	// EventsWritten is the number of events, that where written since New
+	// or the last call of ResetStats. EventTypesWritten are the numbers per
+	// event name.
+	EventsWritten     uint64
+	EventTypesWritten map[string]uint64
+
+	// LastWrite is the time of the last successful write. It is zero, if
+	// nothing was written since New.
+	LastWrite time.Time
+
+	// LastSnapshot is the time of the last snapshot. It is zero, if there is
+	// no snapshot.
+	LastSnapshot time.Time
+
+	// Size is the size of the database in bytes. It is -1, if the database
+	// can not report its size.
+	Size int64
+}
+
+// writeStats are the counters of Stats.
+type writeStats struct {
+	events       uint64
+	eventTypes   map[string]uint64
+	lastWrite    time.Time
+	lastSnapshot time.Time
+}
+
+// written counts a written event.
+func (ws *writeStats) written(name string, t time.Time) {
+	ws.events++
+	if ws.eventTypes == nil {
+		ws.eventTypes = make(map[string]uint64)
+	}
+	ws.eventTypes[name]++
+	ws.lastWrite = t
+}
+
+// Stats returns runtime information.
+func (s *Sticky[Model]) Stats() Stats {
+	s.mu.RLock()
+	stats := Stats{
+		Version:             s.version,
+		EventsSinceSnapshot: s.eventsSinceSnapshot,
+		LastSeq:             s.seq,
+		EventsWritten:       s.stats.events,
+		EventTypesWritten:   maps.Clone(s.stats.eventTypes),
+		LastWrite:           s.stats.lastWrite,
+		LastSnapshot:        s.stats.lastSnapshot,
+	}
+	s.mu.RUnlock()
+
+	// The size is read without the lock, so a slow database does not block
+	// the writers.
+	size, err := dbSize(s.db)
+	if err != nil {
+		size = -1
+	}
+	stats.Size = size
+
+	return stats
+}
+
+// ResetStats sets EventsWritten and EventTypesWritten of Stats to zero.
+func (s *Sticky[Model]) ResetStats() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.events = 0
+	s.stats.eventTypes = nil
+}
+
+// LoadStats contains information about the load of the model in New.
+type LoadStats struct {
+	// Events is the number of loaded events. Events after the last snapshot
+	// are not counted.
+	Events int
+
+	// EventTypes is the number of loaded events per type of the envelope.
+	// It also counts events, that where skipped. See WithUnknownEvents.
+	EventTypes map[string]int
+
+	// Duration is the time, that New needed.
+	Duration time.Duration
+
+	// BytesRead is the number of bytes, that where read from the database.
+	BytesRead int64
+
+	// FirstEvent and LastEvent are the times of the first and last loaded
+	// event.
+	FirstEvent time.Time
+	LastEvent  time.Time
+}
+
+// count counts a loaded event. t is zero, if the time of the event is
+// unknown.
+func (ls *LoadStats) count(eventType string, t time.Time) {
+	ls.Events++
+	if ls.EventTypes == nil {
+		ls.EventTypes = make(map[string]int)
+	}
+	ls.EventTypes[eventType]++
+
+	if t.IsZero() {
+		return
+	}
+
+	if ls.FirstEvent.IsZero() {
+		ls.FirstEvent = t
+	}
+	ls.LastEvent = t
+}
+
+// LoadStats returns information about the load of the model in New.
+func (s *Sticky[Model]) LoadStats() LoadStats {
+	stats := s.loadStats
+	stats.EventTypes = maps.Clone(stats.EventTypes)
+	return stats
+}
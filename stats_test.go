@@ -0,0 +1,125 @@
+package sticky
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithNow[testModel](func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if stats := s.Stats(); stats.EventsWritten != 0 || !stats.LastWrite.IsZero() || !stats.LastSnapshot.IsZero() {
+		t.Errorf("got %+v, expected no writes and no snapshot", stats)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	stats := s.Stats()
+	if stats.Version != 3 || stats.EventsWritten != 3 || stats.EventTypesWritten["add"] != 3 {
+		t.Errorf("got %+v, expected 3 written add events", stats)
+	}
+
+	if !stats.LastWrite.Equal(now) {
+		t.Errorf("got last write `%v`, expected `%v`", stats.LastWrite, now)
+	}
+
+	if stats.Size <= 0 {
+		t.Errorf("got size %d, expected the size of the database", stats.Size)
+	}
+
+	s.ResetStats()
+
+	stats = s.Stats()
+	if stats.EventsWritten != 0 || len(stats.EventTypesWritten) != 0 || stats.Version != 3 {
+		t.Errorf("got %+v after reset, expected no written events at version 3", stats)
+	}
+}
+
+func TestLoadStats(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-02 00:00:00","type":"removed","payload":{}}`,
+		`{"time":"2024-01-03 00:00:00","type":"add","payload":{"value":2}}`,
+	)
+
+	s, err := New(db, testModel{}, getTestEvent, WithUnknownEvents[testModel](UnknownEventsSkip))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	stats := s.LoadStats()
+	if stats.Events != 3 {
+		t.Errorf("got %d events, expected 3", stats.Events)
+	}
+
+	if stats.EventTypes["add"] != 2 || stats.EventTypes["removed"] != 1 {
+		t.Errorf("got event types %v, expected 2 add and 1 removed", stats.EventTypes)
+	}
+
+	if stats.BytesRead == 0 {
+		t.Errorf("got 0 bytes read")
+	}
+
+	expectFirst := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expectLast := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	if !stats.FirstEvent.Equal(expectFirst) || !stats.LastEvent.Equal(expectLast) {
+		t.Errorf("got first event `%v` and last event `%v`, expected `%v` and `%v`", stats.FirstEvent, stats.LastEvent, expectFirst, expectLast)
+	}
+
+	if stats.Duration <= 0 {
+		t.Errorf("got duration %v, expected a positive duration", stats.Duration)
+	}
+}
+
+// slowSizeDB blocks in Size, until release is closed.
+type slowSizeDB struct {
+	*MemoryDB
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (db *slowSizeDB) Size() (int64, error) {
+	close(db.entered)
+	<-db.release
+	return db.MemoryDB.Size()
+}
+
+func TestStats_size_does_not_block_writes(t *testing.T) {
+	db := &slowSizeDB{MemoryDB: NewMemoryDB(), entered: make(chan struct{}), release: make(chan struct{})}
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	statsDone := make(chan Stats)
+	go func() { statsDone <- s.Stats() }()
+	<-db.entered
+
+	writeDone := make(chan error)
+	go func() {
+		writeDone <- s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+	}()
+
+	select {
+	case err := <-writeDone:
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	case <-time.After(time.Second):
+		close(db.release)
+		t.Fatalf("write is blocked by Stats")
+	}
+
+	close(db.release)
+	if stats := <-statsDone; stats.Version != 0 {
+		t.Errorf("got version %d, expected 0 from before the write", stats.Version)
+	}
+}
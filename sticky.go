@@ -1,13 +1,17 @@
 package sticky
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ostcar/topic"
@@ -20,90 +24,615 @@ type Event[Model any] interface {
 	Name() string
 }
 
+// ExecuterWithError can be implemented by an event, whose execution can fail.
+// ExecuteErr is called instead of Execute.
+//
+// When it returns an error on write, the event is not written and the model
+// is not changed. If ExecuteErr changes the model in place, for example a
+// map, it has to undo the change before it returns the error.
+type ExecuterWithError[Model any] interface {
+	ExecuteErr(Model, time.Time) (Model, error)
+}
+
+// withoutNil returns the events, that are not nil.
+func withoutNil[Model any](events []Event[Model]) []Event[Model] {
+	filtered := events[:0:0]
+	for _, event := range events {
+		if event != nil {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// monotonicTime returns the time of a new event. If the clock went back, the
+// time of the last event plus the precision of the envelope is used, so the
+// events in the database are ordered by time.
+//
+// If the clock went back more then maxClockSkew, ErrClockSkew is returned.
+func (s *Sticky[Model]) monotonicTime(t, last time.Time) (time.Time, error) {
+	if !t.Before(last) {
+		return t, nil
+	}
+
+	if s.maxClockSkew > 0 && last.Sub(t) > s.maxClockSkew {
+		return t, ErrClockSkew{Last: last, Now: t}
+	}
+
+	clamped := last.Add(s.timePrecision)
+	if s.onClockSkew != nil {
+		s.onClockSkew(t, clamped)
+	}
+	return clamped, nil
+}
+
+// executeEvent executes the event on the model. If the event implements
+// ExecuterWithError, its error is returned as ExecutionError with the name of
+// the event.
+func executeEvent[Model any](event Event[Model], name string, model Model, t time.Time) (Model, error) {
+	executer, ok := event.(ExecuterWithError[Model])
+	if !ok {
+		return event.Execute(model, t), nil
+	}
+
+	newModel, err := executer.ExecuteErr(model, t)
+	if err != nil {
+		return model, ExecutionError{EventName: name, err: err}
+	}
+	return newModel, nil
+}
+
+// database is the storage of the events. Append must not keep the slice after
+// it returned.
 type database interface {
 	Reader() (io.ReadCloser, error)
 	Append([]byte) error
 }
 
+// batchAppender is a database, that can append many events at once. Either
+// all events are written or none.
+type batchAppender interface {
+	AppendBatch([][]byte) error
+}
+
+// appendRecords appends the records with one call, if the database supports
+// it.
+func appendRecords(db database, records [][]byte) error {
+	switch {
+	case len(records) == 0:
+		return nil
+
+	case len(records) == 1:
+		return db.Append(records[0])
+	}
+
+	if batcher, ok := db.(batchAppender); ok {
+		return batcher.AppendBatch(records)
+	}
+
+	for _, record := range records {
+		if err := db.Append(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tailTruncater is a database, that can remove the last bytes.
+type tailTruncater interface {
+	TruncateTail(n int64) error
+}
+
+// sizer is a database, that can report the size of the stored events in
+// bytes.
+type sizer interface {
+	Size() (int64, error)
+}
+
+// replacer is a database, that can replace all stored events at once. Either
+// the old or the new events are stored, never a mix.
+//
+// The database gets one record per line. ReplaceWith is called with the write
+// lock of the Sticky.
+type replacer interface {
+	ReplaceWith(r io.Reader) error
+}
+
+// dbSize returns the size of the database. It returns ErrNotSupported, if the
+// database can not report its size.
+func dbSize(db database) (int64, error) {
+	s, ok := db.(sizer)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return s.Size()
+}
+
+// replaceDB replaces the content of the database. It returns
+// ErrNotSupported, if the database can not replace its content atomically.
+func replaceDB(db database, r io.Reader) error {
+	rp, ok := db.(replacer)
+	if !ok {
+		return ErrNotSupported
+	}
+	return rp.ReplaceWith(r)
+}
+
 // Sticky is some sort of db that persists a model on disk in a event storage
 // way.
 type Sticky[Model any] struct {
-	mu    sync.RWMutex
-	model Model
+	mu         rwLock
+	model      Model
+	emptyModel Model
 
 	now   func() time.Time
 	db    database
-	topic *topic.Topic[string]
+	topic *topic.Topic[*Notification[Model]]
+
+	// notificationHistory is set by WithNotificationHistory. publishTimes are
+	// the times before each message of the topic, that is not pruned.
+	notificationHistory int
+	publishTimes        []time.Time
+
+	loader        loader[Model]
+	registry      *Registry[Model]
+	onTailDropped func(dropped []byte, truncated bool)
+	readOnly      bool
+	onFollowError func(error)
+
+	// oneTimePerBatch uses the same time for all events of a batch. See
+	// WithBatchTime.
+	oneTimePerBatch bool
+
+	// name is set by WithName. logger is set by WithLogger and has the name
+	// as attribute.
+	name   string
+	logger *slog.Logger
+
+	// loadStats are the numbers of the load in New.
+	loadStats LoadStats
+
+	// strictEvents and strictRoundTrip are set by WithStrictEvents and
+	// WithRoundTripCheck. strictChecked are the event types, that passed
+	// the checks.
+	strictEvents    bool
+	strictRoundTrip bool
+	strictChecked   map[reflect.Type]bool
+
+	// allowUnregistered is set by WithAllowUnregistered. registered are the
+	// names, that getEvent knows.
+	allowUnregistered bool
+	registered        map[string]bool
+
+	// onLoadProgress is set by WithLoadProgress.
+	onLoadProgress func(eventsLoaded int, bytesRead int64)
+
+	// beforeWriteHooks are set by WithBeforeWrite.
+	beforeWriteHooks []func(ctx context.Context, event Event[Model]) (Event[Model], error)
+
+	// afterWriteHooks are set by WithAfterWrite. With afterWriteUnlocked,
+	// they are called after the lock is released. Until then, they are in
+	// pendingAfterWrite.
+	afterWriteHooks    []func(events []AppliedEvent[Model], model Model)
+	afterWriteUnlocked bool
+	pendingAfterWrite  []func()
+
+	// writeMiddlewares are set by WithWriteMiddleware.
+	writeMiddlewares []func(next WriteFunc[Model]) WriteFunc[Model]
+
+	// writeTimeout is set by WithWriteTimeout.
+	writeTimeout time.Duration
+
+	// onLockWait is set by WithLockWait.
+	onLockWait func(wait time.Duration)
+
+	// trace is set by WithTrace.
+	trace func(ctx context.Context, operation string) (context.Context, func(TraceResult))
+
+	// groupWindow and groupMaxBatch are set by WithGroupCommit. group is the
+	// open group, joined is the group of the last write.
+	groupWindow   time.Duration
+	groupMaxBatch int
+	group         *commitGroup[Model]
+	joined        *commitGroup[Model]
+
+	// asyncQueue is the queue of WriteAsync. It is created with the first
+	// call. asyncPending counts the jobs, that are not appended. asyncErr is
+	// set by the background goroutine, if an append fails.
+	asyncQueue     chan asyncJob
+	asyncQueueSize int
+	asyncPending   sync.WaitGroup
+	asyncErr       error
+
+	// failed is the error of a failed sync. Afterwards, it is not known,
+	// which records are on stable storage, so all writes return it.
+	failed error
+
+	// The buffers of write. They are used with the write lock. The database
+	// must not keep the records after Append returned.
+	payloadBuf     bytes.Buffer
+	payloadEncoder *json.Encoder
+	recordBuf      []byte
+	recordEnds     []int
+	times          []time.Time
+
+	// writeQueue is set by WithWriteQueue. The write goroutine reads the jobs
+	// from it.
+	writeQueue chan writeJob[Model]
+
+	// cloneOnRead is set by WithCloneOnRead.
+	cloneOnRead bool
+
+	// copyOnWrite is set by WithCopyOnWrite. Then published holds the model
+	// for the readers.
+	copyOnWrite bool
+	published   atomic.Pointer[published[Model]]
+
+	// leakThreshold and onLeak are set by WithLockLeakDetection.
+	leakThreshold time.Duration
+	onLeak        func(stack []byte, heldFor time.Duration)
+
+	// validateBeforeBatch validates all events of a batch against the model
+	// before the batch. See WithBatchValidationBeforeExecute.
+	validateBeforeBatch bool
+
+	// sharedModel is true, if a copy of the model shares maps, slices or
+	// pointers with it.
+	sharedModel bool
+
+	snapshotStore        SnapshotStore
+	autoSnapshotEvery    int
+	autoSnapshotInterval time.Duration
+	onSnapshotError      func(error)
+
+	compactSize       int64
+	compactInterval   time.Duration
+	onCompactionError func(error)
+
+	// version is the number of events, that are applied to the model.
+	version uint64
+
+	// records is the number of records in the database. It is the version
+	// of a snapshot in the SnapshotStore.
+	records uint64
+
+	// seq is the sequence number of the last record.
+	seq uint64
+
+	// lastTime is the time of the last event. timePrecision is the smallest
+	// difference of two times in the envelope. maxClockSkew and onClockSkew
+	// are set by the options.
+	lastTime      time.Time
+	timePrecision time.Duration
+	maxClockSkew  time.Duration
+	onClockSkew   func(original, clamped time.Time)
+
+	// eventsWritten counts all written events. eventsSinceSnapshot and
+	// lastSnapshot are the state for automatic snapshots. They are only
+	// changed with the write lock.
+	eventsWritten       uint64
+	eventsSinceSnapshot int
+	lastSnapshot        time.Time
+	snapshotting        bool
+
+	// stats are the counters for Stats. They are changed with the write
+	// lock.
+	stats writeStats
+
+	// background counts the running background goroutines.
+	background sync.WaitGroup
+
+	// closed is set while holding the write lock. closeCtx is canceled after
+	// it was set.
+	closed      atomic.Bool
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
 }
 
 // New initializes a new Sticky instance.
+//
+// getEvent is called for each loaded record. It has to return a new pointer
+// to the event each time. If it returns a shared instance, each record
+// overwrites the payload of the previous one. Use a Registry to avoid this.
 func New[Model any](db database, emptyModel Model, getEvent func(name string) Event[Model], os ...Option[Model]) (*Sticky[Model], error) {
-	dbReader, err := db.Reader()
-	if err != nil {
-		return nil, fmt.Errorf("open database: %w", err)
+	start := time.Now()
+	s := newSticky(db, getEvent, os...)
+	s.emptyModel = emptyModel
+	s.initLogger()
+
+	if err := s.checkEvents(); err != nil {
+		return nil, err
 	}
-	defer dbReader.Close()
 
-	model, err := loadModel(dbReader, getEvent, emptyModel)
-	if err != nil {
-		return nil, fmt.Errorf("loading database: %w", err)
+	if err := s.checkCloner(); err != nil {
+		return nil, err
 	}
 
-	s := Sticky[Model]{
-		model: model,
-		now:   time.Now,
-		db:    db,
-		topic: topic.New[string](),
+	_, endTrace := s.startTrace(context.Background(), "load")
+	model, err := s.loadModel(s.newModel())
+	if err != nil {
+		endTrace(TraceResult{Err: err})
+		return nil, err
+	}
+	s.model = model
+	s.records = s.loader.records
+	s.version = s.loader.version
+	s.publish()
+	s.seq = s.loader.seq
+	s.lastTime = s.loader.lastTime
+	s.loadStats = s.loader.stats
+	s.loadStats.Duration = time.Since(start)
+	endTrace(TraceResult{Count: s.loadStats.Events})
+	if s.logger != nil {
+		s.logger.Info("model loaded", "events", s.loadStats.Events, "version", s.version, "bytes", s.loadStats.BytesRead, "duration", s.loadStats.Duration)
+	}
+	s.eventsSinceSnapshot = s.loader.eventsSinceSnapshot
+	s.lastSnapshot = s.loader.lastSnapshot
+	s.stats.lastSnapshot = s.loader.lastSnapshot
+	if s.lastSnapshot.IsZero() {
+		s.lastSnapshot = s.now()
 	}
 
-	for _, o := range os {
-		o(&s)
+	if s.loader.droppedTail != nil {
+		if err := s.truncateTail(); err != nil {
+			return nil, fmt.Errorf("truncating database: %w", err)
+		}
 	}
-	return &s, nil
-}
 
-func loadModel[Model any](r io.Reader, getEvent func(name string) Event[Model], model Model) (Model, error) {
-	var zero Model
+	if s.groupWindow > 0 {
+		if _, ok := db.(syncer); !ok {
+			return nil, fmt.Errorf("group commit: database can not sync: %w", ErrNotSupported)
+		}
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := bytes.TrimSpace(scanner.Bytes())
-		if len(line) == 0 {
-			continue
+		// The model before the group is restored, if the sync fails. So the
+		// events must not change it.
+		if !s.copyOnWrite {
+			return nil, errors.New("WithGroupCommit needs WithCopyOnWrite")
 		}
+	}
+
+	if s.writeQueue != nil {
+		s.background.Add(1)
+		go s.runWriteQueue()
+	}
 
-		var typer struct {
-			Type    string          `json:"type"`
-			Time    string          `json:"time"`
-			Payload json.RawMessage `json:"payload"`
+	if s.compactSize > 0 && !s.readOnly {
+		if _, ok := db.(sizer); !ok {
+			return nil, fmt.Errorf("background compaction: database can not report its size: %w", ErrNotSupported)
 		}
-		if err := json.Unmarshal(line, &typer); err != nil {
-			return zero, fmt.Errorf("decoding event: %w", err)
+
+		s.background.Add(1)
+		go s.compactInBackground()
+	}
+
+	return s, nil
+}
+
+// loadModel loads the model from the database.
+//
+// With projections or handlers, that are called on load, all records are
+// replayed. With a SnapshotStore, the
+// latest snapshot is loaded and only the records after it are replayed.
+// Otherwise, if the model implements Snapshotter, the
+// database is read twice. The first time to find the last snapshot record and
+// the second time to load the snapshot and the events after it.
+func (s *Sticky[Model]) loadModel(emptyModel Model) (Model, error) {
+	model := emptyModel
+	var skip uint64
+
+	switch {
+	case len(s.loader.projections) > 0 || s.loader.handlersOnLoad():
+		// The projections and handlers need all events.
+
+	case s.snapshotStore != nil:
+		version, data, err := s.snapshotStore.Latest()
+		if err != nil {
+			return emptyModel, StorageError{Op: "reading latest snapshot", Err: err}
 		}
 
-		event := getEvent(typer.Type)
-		if event == nil {
-			return zero, fmt.Errorf("unknown event `%s`, payload `%s`", typer.Type, typer.Payload)
+		if data != nil {
+			if model, err = s.loader.unmarshalSnapshot(emptyModel, data); err != nil {
+				return emptyModel, err
+			}
+			skip = version
+			s.loader.version = version
 		}
 
-		if err := json.Unmarshal(typer.Payload, &event); err != nil {
-			return zero, fmt.Errorf("loading event `%s`: %w", typer.Type, err)
+	default:
+		if _, ok := any(emptyModel).(Snapshotter[Model]); !ok {
+			break
 		}
 
-		eventTime, err := time.Parse(timeFormat, typer.Time)
+		dbReader, err := s.db.Reader()
 		if err != nil {
-			return zero, fmt.Errorf("event `%s` has invalid time %s: %w", typer.Type, typer.Time, err)
+			return emptyModel, StorageError{Op: "open database", Err: err}
+		}
+
+		if last := findLastSnapshot(dbReader, s.loader.maxEventSize); last > 0 {
+			skip = uint64(last)
 		}
+		dbReader.Close()
+	}
 
-		model = event.Execute(model, eventTime)
+	minRecords := skip
+	var dbReader io.ReadCloser
+	var err error
+	if ra, ok := s.db.(readerAfter); ok && skip > 0 {
+		dbReader, err = ra.ReaderAfter(skip)
+		s.loader.records = skip
+		s.loader.seq = skip
+		skip = 0
+	} else {
+		dbReader, err = s.db.Reader()
 	}
-	if err := scanner.Err(); err != nil {
-		return zero, fmt.Errorf("scanning events: %w", err)
+	if err != nil {
+		return emptyModel, StorageError{Op: "open database", Err: err}
 	}
+	defer dbReader.Close()
 
+	if s.logger != nil {
+		s.logger.Info("loading model", "skip", skip)
+	}
+
+	counter := &countingReader{r: dbReader}
+	if s.onLoadProgress != nil {
+		s.loader.progress = newLoadProgress(counter, s.onLoadProgress)
+	}
+
+	model, err = s.loader.load(counter, model, skip)
+	if err != nil {
+		return emptyModel, fmt.Errorf("loading database: %w", err)
+	}
+	s.loader.stats.BytesRead = counter.n.Load()
+
+	if s.loader.progress != nil {
+		s.loader.progress.report()
+		s.loader.progress = nil
+	}
+
+	if s.loader.records < minRecords {
+		return emptyModel, fmt.Errorf("snapshot has version %d, but the database has only %d records", minRecords, s.loader.records)
+	}
 	return model, nil
 }
 
+// checkEvents checks the registry, the projections and the aliases.
+func (s *Sticky[Model]) checkEvents() error {
+	if s.loader.getEvent == nil {
+		return errors.New("getEvent is nil, use WithRegistry or give a function")
+	}
+
+	if s.registry != nil {
+		if err := s.registry.Check(); err != nil {
+			return fmt.Errorf("checking registry: %w", err)
+		}
+	}
+
+	if err := s.loader.checkProjections(); err != nil {
+		return err
+	}
+
+	return s.loader.checkAliases()
+}
+
+// checkCloner checks, that the model implements Cloner, if WithCloneOnRead
+// or WithCopyOnWrite is used.
+func (s *Sticky[Model]) checkCloner() error {
+	if _, ok := any(s.emptyModel).(Cloner[Model]); ok {
+		return nil
+	}
+
+	if s.cloneOnRead {
+		return fmt.Errorf("WithCloneOnRead: model %T does not implement Cloner", s.emptyModel)
+	}
+
+	if s.copyOnWrite {
+		return fmt.Errorf("WithCopyOnWrite: model %T does not implement Cloner", s.emptyModel)
+	}
+	return nil
+}
+
+// newModel returns a copy of the empty model, if it implements Cloner. Each
+// load starts from a new model, so events, that change a map of the model in
+// place, do not change the empty model or the model of the Sticky.
+func (s *Sticky[Model]) newModel() Model {
+	if cloner, ok := any(s.emptyModel).(Cloner[Model]); ok {
+		return cloner.Clone()
+	}
+	return s.emptyModel
+}
+
+// clone returns a copy of the model. Has to be called with the lock.
+func (s *Sticky[Model]) clone() Model {
+	return any(s.model).(Cloner[Model]).Clone()
+}
+
+// hasReferences returns true, if a value of the type shares memory with its
+// copies.
+func hasReferences(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Pointer, reflect.Interface, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return true
+	case reflect.Array:
+		return hasReferences(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasReferences(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// eventName returns the name of the event. With a registry, the name can be
+// derived from the type.
+func (s *Sticky[Model]) eventName(event Event[Model]) string {
+	if s.registry != nil {
+		return s.registry.NameOf(event)
+	}
+	return event.Name()
+}
+
+// newSticky creates a Sticky with the options applied but without loading
+// the model.
+func newSticky[Model any](db database, getEvent func(name string) Event[Model], os ...Option[Model]) *Sticky[Model] {
+	s := Sticky[Model]{
+		now:            time.Now,
+		db:             db,
+		topic:          topic.New[*Notification[Model]](),
+		asyncQueueSize: defaultAsyncQueueSize,
+
+		notificationHistory: defaultNotificationHistory,
+		loader: loader[Model]{
+			getEvent:        getEvent,
+			maxEventSize:    defaultMaxEventSize,
+			maxPayloadDepth: defaultMaxPayloadDepth,
+		},
+	}
+	s.closeCtx, s.closeCancel = context.WithCancel(context.Background())
+	s.payloadEncoder = json.NewEncoder(&s.payloadBuf)
+
+	for _, o := range os {
+		o(&s)
+	}
+
+	s.timePrecision = timePrecision(s.loader.timeLayout)
+	s.sharedModel = hasReferences(reflect.TypeOf((*Model)(nil)).Elem())
+
+	if fileDB, ok := db.(*FileDB); ok {
+		fileDB.ReadOnly = fileDB.ReadOnly || s.readOnly
+		fileDB.timeLayout = s.loader.timeLayout
+	}
+
+	return &s
+}
+
+// NewReadOnly initializes a Sticky instance, that can not write events.
+//
+// The write functions return ErrReadOnly. A FileDB is switched to read only
+// mode, so it also works on a read only filesystem.
+func NewReadOnly[Model any](db database, emptyModel Model, getEvent func(name string) Event[Model], os ...Option[Model]) (*Sticky[Model], error) {
+	return New(db, emptyModel, getEvent, append(os, WithReadOnly[Model]())...)
+}
+
+// truncateTail removes the dropped tail from the database, if the database
+// supports it, and calls the callback.
+func (s *Sticky[Model]) truncateTail() error {
+	truncated := false
+	if truncater, ok := s.db.(tailTruncater); ok && !s.readOnly {
+		if err := truncater.TruncateTail(s.loader.droppedBytes); err != nil {
+			return StorageError{Op: "truncating tail", Err: err}
+		}
+		truncated = true
+	}
+
+	if s.onTailDropped != nil {
+		s.onTailDropped(s.loader.droppedTail, truncated)
+	}
+	return nil
+}
+
 // ForReading returns the model for reading.
 //
 // Call the done function, when reading is finished.
@@ -113,8 +642,46 @@ func loadModel[Model any](r io.Reader, getEvent func(name string) Event[Model],
 //
 // m...
 func (s *Sticky[Model]) ForReading() (Model, func()) {
+	if s.copyOnWrite {
+		return s.published.Load().model, func() {}
+	}
+
+	s.mu.RLock()
+	if s.cloneOnRead {
+		defer s.mu.RUnlock()
+		return s.clone(), func() {}
+	}
+	return s.model, s.watchLock(s.mu.RUnlock)
+}
+
+// ForReadingVersioned is like ForReading, but also returns the version of the
+// model.
+//
+// The version is the number of events, that where applied to the model. It
+// increases by one for each event.
+func (s *Sticky[Model]) ForReadingVersioned() (Model, uint64, func()) {
+	if s.copyOnWrite {
+		p := s.published.Load()
+		return p.model, p.version, func() {}
+	}
+
+	s.mu.RLock()
+	if s.cloneOnRead {
+		defer s.mu.RUnlock()
+		return s.clone(), s.version, func() {}
+	}
+	return s.model, s.version, s.watchLock(s.mu.RUnlock)
+}
+
+// Version returns the number of events, that where applied to the model.
+//
+// Each event is written with its version, so it survives restarts,
+// snapshots and compaction.
+func (s *Sticky[Model]) Version() uint64 {
 	s.mu.RLock()
-	return s.model, func() { s.mu.RUnlock() }
+	defer s.mu.RUnlock()
+
+	return s.version
 }
 
 // ForWriting returns the model for writing.
@@ -128,46 +695,486 @@ func (s *Sticky[Model]) ForReading() (Model, func()) {
 //
 // event := ...
 // write(event)
+//
+// After Close, the write function returns ErrClosed.
 func (s *Sticky[Model]) ForWriting() (Model, func(...Event[Model]) error, func()) {
-	s.mu.Lock()
+	if s.closed.Load() {
+		// Do not wait for the lock.
+		m, done := s.ForReading()
+		defer done()
+		return m, func(...Event[Model]) error { return ErrClosed }, func() {}
+	}
+
+	if err := s.lockWrite(context.Background()); err != nil {
+		return s.emptyModel, func(...Event[Model]) error { return err }, func() {}
+	}
+
+	return s.model,
+		func(events ...Event[Model]) error {
+			return s.writeChain(s.writeNow, writeOptions{})(events...)
+		},
+		s.watchLock(s.unlockWrite)
+}
+
+// ForWritingCtx is like ForWriting, but returns the error of the context, if
+// it is done before the lock is taken.
+func (s *Sticky[Model]) ForWritingCtx(ctx context.Context) (Model, func(...Event[Model]) error, func(), error) {
+	if s.closed.Load() {
+		return s.emptyModel, nil, nil, ErrClosed
+	}
+
+	if err := s.lockWrite(ctx); err != nil {
+		return s.emptyModel, nil, nil, err
+	}
+
+	return s.model,
+		func(events ...Event[Model]) error {
+			return s.writeChain(s.writeNow, writeOptions{ctx: ctx})(events...)
+		},
+		s.watchLock(s.unlockWrite),
+		nil
+}
+
+// TryForWriting is like ForWriting, but does not wait for the lock. If the
+// lock is held by someone else, the last return value is false and the
+// functions are nil.
+func (s *Sticky[Model]) TryForWriting() (Model, func(...Event[Model]) error, func(), bool) {
+	if s.closed.Load() {
+		return s.emptyModel, func(...Event[Model]) error { return ErrClosed }, func() {}, true
+	}
+
+	if !s.mu.TryLock() {
+		return s.emptyModel, nil, nil, false
+	}
+
 	return s.model,
 		func(events ...Event[Model]) error {
-			for _, event := range events {
-				if err := event.Validate(s.model); err != nil {
-					return ValidationError{err}
-				}
+			return s.writeChain(s.writeNow, writeOptions{})(events...)
+		},
+		s.watchLock(s.unlockWrite),
+		true
+}
+
+// lockWrite takes the write lock. With WithWriteTimeout, it returns
+// ErrLockTimeout, if the lock could not be taken in time.
+func (s *Sticky[Model]) lockWrite(ctx context.Context) error {
+	var start time.Time
+	if s.writeTimeout > 0 || s.onLockWait != nil {
+		start = time.Now()
+	}
+
+	if s.writeTimeout <= 0 {
+		if err := s.mu.LockCtx(ctx); err != nil {
+			return err
+		}
+		s.lockWaited(start)
+		return nil
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, s.writeTimeout)
+	defer cancel()
+
+	if err := s.mu.LockCtx(timeoutCtx); err != nil {
+		if ctx.Err() == nil {
+			return ErrLockTimeout{Waited: time.Since(start)}
+		}
+		return err
+	}
+	s.lockWaited(start)
+	return nil
+}
+
+// lockWaited calls the function of WithLockWait with the time since start.
+func (s *Sticky[Model]) lockWaited(start time.Time) {
+	if s.onLockWait != nil {
+		s.onLockWait(time.Since(start))
+	}
+}
+
+// writeOptions change the behavior of write.
+type writeOptions struct {
+	// durable syncs the database after the events where appended and before
+	// they are executed.
+	durable bool
+
+	// meta is written to the envelope of each event.
+	meta map[string]string
+
+	// correlation and causation are written to the envelope of each event.
+	correlation string
+	causation   string
+
+	// ctx is given to the functions of WithBeforeWrite. Nil means
+	// context.Background.
+	ctx context.Context
+
+	// async appends the events in the background and sends the result to
+	// the channel. See WriteAsync.
+	async chan<- error
+}
+
+// WriteOption is an option for WriteWith.
+type WriteOption func(*writeOptions)
+
+// WithCorrelation sets the correlation id of the events. Use the same id for
+// all events, that belong to one action of a user.
+func WithCorrelation(id string) WriteOption {
+	return func(o *writeOptions) {
+		o.correlation = id
+	}
+}
+
+// WithCausation sets the causation id of the events. It is the id of the
+// event or command, that triggered the events.
+func WithCausation(id string) WriteOption {
+	return func(o *writeOptions) {
+		o.causation = id
+	}
+}
+
+// write validates, persists and executes the events.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) write(events []Event[Model], opts writeOptions) (err error) {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	if s.failed != nil {
+		return s.failed
+	}
+
+	// A nil event means, that there is nothing to write.
+	events = withoutNil(events)
+	if len(events) == 0 {
+		return nil
+	}
+
+	if s.trace != nil {
+		var endTrace func(TraceResult)
+		opts.ctx, endTrace = s.startTrace(opts.ctx, "write")
+		defer func() {
+			endTrace(TraceResult{Events: s.eventNames(events), Count: len(events), Err: err})
+		}()
+	}
+
+	events, err = s.beforeWrite(opts.ctx, events)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	// The events are executed on a working copy of the model, so a failed
+	// write does not change it. A model with maps, slices or pointers can
+	// only be copied, if it implements Cloner. Otherwise all events are
+	// validated against the model before any of them is executed.
+	model := s.model
+	cloner, isCloner := any(s.model).(Cloner[Model])
+	if isCloner {
+		model = cloner.Clone()
+	}
+
+	validateFirst := s.validateBeforeBatch || (s.sharedModel && !isCloner && len(events) > 1)
+	if validateFirst {
+		for i, event := range events {
+			if err := event.Validate(s.model); err != nil {
+				return ValidationError{Index: i, EventName: s.eventName(event), err: err}
+			}
+		}
+	}
+
+	// Each event is validated against the model after the events before it.
+	// The events are executed before they are written, so an event, that can
+	// not be executed, is not written.
+	batchTime := eventTime(s.now(), s.loader.timeLayout)
+	lastTime := s.lastTime
+	// The buffers are reused for each write. The records are slices of
+	// recordBuf, that are created after all events are encoded.
+	times := s.times[:0]
+	recordEnds := s.recordEnds[:0]
+	recordBuf := s.recordBuf[:0]
+	// models are the models after each event for the handlers.
+	var models []Model
+	if len(s.loader.handlers) > 0 {
+		models = make([]Model, len(events))
+	}
+	for i, event := range events {
+		if s.eventName(event) == snapshotType {
+			return fmt.Errorf("event name %s is reserved for snapshots", snapshotType)
+		}
+
+		if !s.allowUnregistered {
+			if err := s.checkRegistered(event); err != nil {
+				return err
 			}
+		}
 
-			for _, event := range events {
-				now := s.now().UTC()
-				rawEvent := struct {
-					Time    string       `json:"time"`
-					Type    string       `json:"type"`
-					Payload Event[Model] `json:"payload"`
-				}{
-					now.Format(timeFormat),
-					event.Name(),
-					event,
-				}
-
-				bs, err := json.Marshal(rawEvent)
-				if err != nil {
-					return fmt.Errorf("encoding event: %w", err)
-				}
-
-				if err := s.db.Append(bs); err != nil {
-					return fmt.Errorf("writing event to db: `%s`: %w", bs, err)
-				}
-
-				s.model = event.Execute(s.model, s.now())
-				s.topic.Publish(event.Name())
+		if !validateFirst {
+			if err := event.Validate(model); err != nil {
+				return ValidationError{Index: i, EventName: s.eventName(event), err: err}
 			}
+		}
 
-			return nil
-		},
-		func() {
-			s.mu.Unlock()
+		// The event is executed with the same time, that is written to the
+		// database, so the model is the same after a reload.
+		now := batchTime
+		if !s.oneTimePerBatch && i > 0 {
+			now = eventTime(s.now(), s.loader.timeLayout)
+		}
+
+		now, err := s.monotonicTime(now, lastTime)
+		if err != nil {
+			return err
+		}
+		lastTime = now
+		times = append(times, now)
+
+		if model, err = executeEvent(event, s.eventName(event), model, now); err != nil {
+			if execErr, ok := err.(ExecutionError); ok {
+				execErr.Index = i
+				execErr.EventName = s.eventName(event)
+				err = execErr
+			}
+			return err
+		}
+		if models != nil {
+			models[i] = model
+		}
+
+		s.payloadBuf.Reset()
+		if err := s.payloadEncoder.Encode(event); err != nil {
+			return fmt.Errorf("encoding event: %w", err)
+		}
+
+		// Encode adds a newline.
+		payload := bytes.TrimSuffix(s.payloadBuf.Bytes(), []byte("\n"))
+
+		if s.strictEvents {
+			if err := s.checkStrict(event, payload); err != nil {
+				return err
+			}
+		}
+
+		var subject string
+		var encrypted []string
+		if personal, ok := event.(PersonalData); ok && s.loader.shredder != nil && personal.Subject() != "" {
+			payload, encrypted, err = s.loader.shredder.encrypt(payload, personal.Subject(), personal.PersonalFields())
+			if err != nil {
+				return fmt.Errorf("encrypting event: %w", err)
+			}
+			if len(encrypted) > 0 {
+				subject = personal.Subject()
+			}
+		}
+
+		start := len(recordBuf)
+		recordBuf, err = appendRecord(recordBuf, &Envelope{
+			Type:        s.eventName(event),
+			Schema:      schemaVersion(event),
+			Version:     s.version + uint64(i) + 1,
+			Seq:         s.seq + uint64(i) + 1,
+			Meta:        opts.meta,
+			Correlation: opts.correlation,
+			Causation:   opts.causation,
+			Subject:     subject,
+			Encrypted:   encrypted,
+			Payload:     payload,
+		}, now, s.loader.timeLayout)
+		if err != nil {
+			return fmt.Errorf("encoding event: %w", err)
+		}
+
+		if size := len(recordBuf) - start; size > s.loader.maxEventSize {
+			return EventTooLargeError{Name: s.eventName(event), Size: size, Max: s.loader.maxEventSize}
+		}
+		recordEnds = append(recordEnds, len(recordBuf))
+	}
+	s.times, s.recordEnds, s.recordBuf = times, recordEnds, recordBuf
+
+	records := make([][]byte, len(recordEnds))
+	start := 0
+	for i, end := range recordEnds {
+		records[i] = recordBuf[start:end:end]
+		start = end
+	}
+
+	// The end of the database before a new group. The records of the group
+	// are removed, if its sync fails.
+	var mark *tailMark
+	if s.groupWindow > 0 && opts.async == nil {
+		mark = s.markGroupTail()
+	}
+
+	after := s.afterWrite(events, times, model, opts)
+	if opts.async != nil {
+		// The queue keeps the records, so they can not use the buffer.
+		for i := range records {
+			records[i] = bytes.Clone(records[i])
+		}
+		s.enqueueAsync(records, opts.async, after)
+	} else {
+		// The records of WriteAsync have to be appended first.
+		if err := s.flushAsync(); err != nil {
+			return err
+		}
+
+		if err := appendRecords(s.db, records); err != nil {
+			if s.logger != nil {
+				s.logger.Error("writing events failed", "events", len(records), "error", err)
+			}
+			return StorageError{Op: "writing events to db", Err: err}
+		}
+	}
+
+	// With group commit, the database is synced for many writes together.
+	var group *commitGroup[Model]
+	if s.groupWindow > 0 && opts.async == nil {
+		group = s.joinGroup(len(events), mark)
+	}
+
+	// If the sync fails, the records are in the database anyway. So they are
+	// applied like other written events, so the next load gets the same
+	// model.
+	var syncErr error
+	if opts.durable && group == nil {
+		if syncErr = s.syncDB(); syncErr != nil {
+			s.failed = syncErr
+		}
+	}
+
+	s.model = model
+	s.lastTime = lastTime
+	notifications := make([]*Notification[Model], len(events))
+	for i, event := range events {
+		s.stats.written(s.eventName(event), times[i])
+		notifications[i] = &Notification[Model]{
+			Name:  s.eventName(event),
+			Event: event,
+			Time:  times[i],
+			Seq:   s.seq + uint64(i) + 1,
+			Meta:  opts.meta,
+
+			Correlation: opts.correlation,
+			Causation:   opts.causation,
+		}
+	}
+
+	s.version += uint64(len(events))
+	s.records += uint64(len(events))
+	s.seq += uint64(len(events))
+	s.eventsWritten += uint64(len(events))
+	s.publish()
+	s.eventsSinceSnapshot += len(events)
+
+	// The batch is published as one message after it is applied, so
+	// listeners do not see a model in the middle of the batch. With group
+	// commit, the projections, handlers and listeners get the events after
+	// the sync.
+	if group != nil {
+		// The times are reused by the next write.
+		groupTimes := slices.Clone(times)
+		group.effects = append(group.effects, func() { s.writtenEffects(events, groupTimes, models) })
+		group.notifications = append(group.notifications, notifications...)
+	} else {
+		s.writtenEffects(events, times, models)
+		s.publishNotifications(notifications)
+	}
+
+	// With WriteAsync, the background goroutine calls the hooks after the
+	// append.
+	if opts.async == nil && syncErr == nil {
+		s.runAfterWrite(after, group)
+	}
+
+	if group != nil && s.groupMaxBatch > 0 && group.events >= s.groupMaxBatch {
+		s.commitGroup()
+	}
+
+	if s.logger != nil {
+		s.logger.Debug("events written", "events", len(events), "version", s.version, "published", group == nil)
+	}
+
+	if syncErr != nil {
+		return syncErr
+	}
+
+	s.autoSnapshot()
+	return nil
+}
+
+// writtenEffects applies the projections and calls the handlers of written
+// events. models are the models after each event or nil, if there are no
+// handlers.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) writtenEffects(events []Event[Model], times []time.Time, models []Model) {
+	for i, event := range events {
+		s.loader.applyProjections(s.eventName(event), event, times[i])
+		if models != nil {
+			s.loader.callHandlers(s.eventName(event), event, times[i], models[i], false)
+		}
+	}
+}
+
+// WriteDurable is like Write, but the database is synced, before the event is
+// executed. When WriteDurable returns without an error, the event is on stable
+// storage.
+//
+// For databases without a Sync method, it behaves like Write. If the sync
+// fails, the event is in the database, but maybe not on stable storage. It is
+// executed like with Write and the error is returned. Afterwards, all writes
+// return the error. Create a new Sticky to load the database again.
+func (s *Sticky[Model]) WriteDurable(f func(Model) Event[Model]) error {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	return s.withWriteLock(context.Background(), func() error {
+		event := f(s.model)
+		return s.writeChain(s.write, writeOptions{durable: true})(event)
+	})
+}
+
+// Sync makes sure, that all written events are on stable storage.
+//
+// Sync calls the Sync method of the database. For databases without a Sync
+// method, it does nothing.
+func (s *Sticky[Model]) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flushAsync(); err != nil {
+		return err
+	}
+
+	if s.group != nil {
+		return s.commitGroup()
+	}
+
+	return s.syncDB()
+}
+
+// syncer is a database, that can flush its buffers to stable storage.
+type syncer interface {
+	Sync() error
+}
+
+func (s *Sticky[Model]) syncDB() error {
+	if db, ok := s.db.(syncer); ok {
+		if err := db.Sync(); err != nil {
+			if s.logger != nil {
+				s.logger.Error("syncing database failed", "error", err)
+			}
+			return StorageError{Op: "syncing database", Err: err}
 		}
+	}
+	return nil
 }
 
 // Read calls a function that has access to an instance of the model for
@@ -180,35 +1187,242 @@ func (s *Sticky[Model]) Read(f func(Model) error) error {
 }
 
 // Write calls a function that has access to an instance of the model for
-// writing. It has to return an event.
+// writing. It has to return an event. If it returns nil, nothing is written.
 //
 // Write can return a ValidationError or ExecutionError when the event can not
 // be processed.
 func (s *Sticky[Model]) Write(f func(Model) Event[Model]) error {
-	m, write, done := s.ForWriting()
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	return s.withWriteLock(context.Background(), func() error {
+		event := f(s.model)
+		return s.writeChain(s.write, writeOptions{})(event)
+	})
+}
+
+// ReadCtx is like Read, but returns the error of the context, if it is done
+// before the lock is taken.
+func (s *Sticky[Model]) ReadCtx(ctx context.Context, f func(Model) error) error {
+	if s.copyOnWrite {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return f(s.published.Load().model)
+	}
+
+	if err := s.mu.RLockCtx(ctx); err != nil {
+		return err
+	}
+
+	if s.cloneOnRead {
+		m := s.clone()
+		s.mu.RUnlock()
+		return f(m)
+	}
+	defer s.mu.RUnlock()
+
+	return f(s.model)
+}
+
+// WriteCtx is like Write, but returns the error of the context, if it is done
+// before the lock is taken.
+func (s *Sticky[Model]) WriteCtx(ctx context.Context, f func(Model) Event[Model]) error {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	return s.withWriteLock(ctx, func() error {
+		event := f(s.model)
+		return s.writeChain(s.write, writeOptions{ctx: ctx})(event)
+	})
+}
+
+// TryWrite is like Write, but does not wait for the lock. It returns false,
+// if the lock is held by someone else. Then f is not called.
+func (s *Sticky[Model]) TryWrite(f func(Model) Event[Model]) (bool, error) {
+	m, write, done, ok := s.TryForWriting()
+	if !ok {
+		return false, nil
+	}
 	defer done()
-	event := f(m)
-	return write(event)
+
+	return true, write(f(m))
 }
 
+// WriteMany is like Write, but f can return many events. They are written as
+// one batch. If f returns no events, nothing is written. If f returns an
+// error, nothing is written and the error is returned.
+func (s *Sticky[Model]) WriteMany(f func(Model) ([]Event[Model], error)) error {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	return s.withWriteLock(context.Background(), func() error {
+		events, err := f(s.model)
+		if err != nil {
+			return err
+		}
+		return s.writeChain(s.write, writeOptions{})(events...)
+	})
+}
+
+// WriteMeta is like Write, but stores the metadata with the event. Use it for
+// information, that is not part of the payload. For example the id of the user,
+// that triggered the event.
+//
+// The metadata is ignored, when the model is loaded. Use WithLoadHook to get
+// it.
+func (s *Sticky[Model]) WriteMeta(meta map[string]string, f func(Model) Event[Model]) error {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	return s.withWriteLock(context.Background(), func() error {
+		event := f(s.model)
+		return s.writeChain(s.write, writeOptions{meta: meta})(event)
+	})
+}
+
+// WriteWith is like Write, but with options for the envelope of the event.
+// See WithCorrelation and WithCausation.
+func (s *Sticky[Model]) WriteWith(f func(Model) Event[Model], options ...WriteOption) error {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	var opts writeOptions
+	for _, o := range options {
+		o(&opts)
+	}
+
+	return s.withWriteLock(context.Background(), func() error {
+		event := f(s.model)
+		return s.writeChain(s.write, opts)(event)
+	})
+}
+
+// WriteIfVersion is like Write, but only writes the event, if the model still
+// has the expected version. Otherwise, ErrVersionMismatch is returned and
+// nothing is written.
+//
+// Use it, to compute events from a model read with ForReadingVersioned without
+// holding the write lock.
+func (s *Sticky[Model]) WriteIfVersion(expected uint64, f func(Model) Event[Model]) error {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	return s.withWriteLock(context.Background(), func() error {
+		if s.version != expected {
+			return ErrVersionMismatch{Expected: expected, Actual: s.version}
+		}
+
+		event := f(s.model)
+		return s.writeChain(s.write, writeOptions{})(event)
+	})
+}
+
+// Listen returns an iterator over the names of written events. Use Subscribe
+// to get the events.
+//
+// A value contains the names of all events of one write in the order of the
+// events.
+//
+// The iterator stops, when the context is done or the Sticky is closed. It
+// also stops on other errors without returning them. Use Subscribe to get
+// the errors.
 func (s *Sticky[Model]) Listen(ctx context.Context) func(yield func(val []string) bool) {
-	tid := s.topic.LastID()
-	return func(yield func(val []string) bool) {
-		for {
-			newTID, eventNames, err := s.topic.Receive(ctx, tid)
-			if err != nil {
-				return
-			}
-			tid = newTID
-			if !yield(eventNames) {
-				return
-			}
+	return notificationNames(s.Subscribe(ctx))
+}
+
+// ListenFor is like Listen, but only returns the names of the events with one
+// of the names. See SubscribeFor.
+func (s *Sticky[Model]) ListenFor(ctx context.Context, names ...string) func(yield func(val []string) bool) {
+	return notificationNames(s.SubscribeFor(ctx, names...))
+}
+
+// Close stops the Sticky instance.
+//
+// It waits for running writes. Afterwards, all writes return ErrClosed and all
+// Listen iterators stop. If the database implements io.Closer, it is closed.
+//
+// Reading the model is still possible after Close.
+func (s *Sticky[Model]) Close() error {
+	s.mu.Lock()
+	alreadyClosed := s.closed.Swap(true)
+	asyncErr := s.stopAsync()
+	s.commitGroup()
+	s.mu.Unlock()
+
+	if alreadyClosed {
+		return nil
+	}
+
+	s.closeCancel()
+	s.background.Wait()
+
+	if closer, ok := s.db.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return StorageError{Op: "closing database", Err: err}
 		}
 	}
+	return asyncErr
+}
+
+// ErrClosed is returned from the write functions after Close was called.
+var ErrClosed = errors.New("sticky is closed")
+
+// ErrMissedEvents is returned by a subscription, when the events after its
+// last value are not available anymore.
+var ErrMissedEvents = errors.New("events of the subscription are not available anymore")
+
+// ErrReadOnly is returned from the write functions of a read only Sticky.
+var ErrReadOnly = errors.New("sticky is read only")
+
+// ErrNotSupported is returned, when the database does not support an
+// operation. For example, when it can not replace its content atomically.
+var ErrNotSupported = errors.New("operation is not supported by the database")
+
+// ErrNotEmpty is returned from Import, when the database already has
+// records.
+var ErrNotEmpty = errors.New("database is not empty")
+
+// StorageError happens, when the database or the snapshot store returns an
+// error. Op describes the operation. Use errors.Is to check the error of the
+// database, for example syscall.ENOSPC.
+//
+// It is returned by New, when the database or a snapshot can not be read, by
+// the write functions, when the events can not be written or synced, by
+// Snapshot, Compact and Close and by the functions, that read the database
+// like SubscribeFrom, Events, ModelAt and Backup.
+type StorageError struct {
+	Op  string
+	Err error
+}
+
+func (err StorageError) Error() string {
+	return fmt.Sprintf("%s: %v", err.Op, err.Err)
+}
+
+func (err StorageError) Unwrap() error {
+	return err.Err
+}
+
+// IsStorageError returns true, if err is or wraps a StorageError.
+func IsStorageError(err error) bool {
+	var storageErr StorageError
+	return errors.As(err, &storageErr)
 }
 
 // ValidationError happens, when the event can not be validated.
 type ValidationError struct {
+	// Index is the position of the event in the batch and EventName its
+	// name.
+	Index     int
+	EventName string
+
 	err error
 }
 
@@ -220,6 +1434,81 @@ func (err ValidationError) Unwrap() error {
 	return err.err
 }
 
+// ErrClockSkew happens, when the clock went back more then the value of
+// WithMaxClockSkew since the last event.
+type ErrClockSkew struct {
+	Last time.Time
+	Now  time.Time
+}
+
+func (err ErrClockSkew) Error() string {
+	return fmt.Sprintf("clock went back %s: last event at %s, now is %s", err.Last.Sub(err.Now), FormatTime(err.Last), FormatTime(err.Now))
+}
+
+// ErrUnregisteredEvent happens, when an event is written, that getEvent, or
+// the registry, does not return for its name. The event could not be loaded.
+// See WithAllowUnregistered.
+type ErrUnregisteredEvent struct {
+	Name string
+	Type string
+}
+
+func (err ErrUnregisteredEvent) Error() string {
+	return fmt.Sprintf("event `%s` of type %s is not registered", err.Name, err.Type)
+}
+
+// ErrLockTimeout happens, when the write lock could not be taken in the time
+// of WithWriteTimeout.
+type ErrLockTimeout struct {
+	Waited time.Duration
+}
+
+func (err ErrLockTimeout) Error() string {
+	return fmt.Sprintf("waited %s for the write lock", err.Waited)
+}
+
+// ExecutionError happens, when an event, that implements ExecuterWithError,
+// can not be executed.
+type ExecutionError struct {
+	// Index is the position of the event in the batch and EventName its
+	// name. Index is only set, when the event was written.
+	Index     int
+	EventName string
+
+	err error
+}
+
+func (err ExecutionError) Error() string {
+	return fmt.Sprintf("Execution Error: %v", err.err)
+}
+
+func (err ExecutionError) Unwrap() error {
+	return err.err
+}
+
 func (err ValidationError) String() string {
 	return err.err.Error()
 }
+
+// ErrVersionMismatch is returned by WriteIfVersion, when the model has another
+// version then expected.
+type ErrVersionMismatch struct {
+	Expected uint64
+	Actual   uint64
+}
+
+func (err ErrVersionMismatch) Error() string {
+	return fmt.Sprintf("expected version %d, but model has version %d", err.Expected, err.Actual)
+}
+
+// EventTooLargeError happens, when an encoded event is bigger then the
+// configured maximum. See WithMaxEventSize.
+type EventTooLargeError struct {
+	Name string
+	Size int
+	Max  int
+}
+
+func (err EventTooLargeError) Error() string {
+	return fmt.Sprintf("event `%s` has %d bytes, maximum is %d", err.Name, err.Size, err.Max)
+}
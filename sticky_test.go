@@ -0,0 +1,1263 @@
+package sticky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type testModel struct {
+	Sum int
+}
+
+type addEvent struct {
+	Value int `json:"value"`
+}
+
+func (e addEvent) Name() string {
+	return "add"
+}
+
+func (e addEvent) Validate(m testModel) error {
+	if e.Value < 0 {
+		return errors.New("value must not be negative")
+	}
+	return nil
+}
+
+func (e addEvent) Execute(m testModel, _ time.Time) testModel {
+	m.Sum += e.Value
+	return m
+}
+
+func getTestEvent(name string) Event[testModel] {
+	switch name {
+	case "add":
+		return &addEvent{}
+	default:
+		return nil
+	}
+}
+
+func TestNew_broken_last_line_is_an_error(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}`, `{"time":"2024-01-0`)
+
+	if _, err := New(db, testModel{}, getTestEvent); err == nil {
+		t.Errorf("loading broken db did not return an error")
+	}
+}
+
+func TestNew_truncated_tail_recovery(t *testing.T) {
+	tmpdir := t.TempDir()
+	file := path.Join(tmpdir, "file.db")
+
+	content := `{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}` + "\n" + `{"time":"2024-01-0`
+	if err := os.WriteFile(file, []byte(content), 0666); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	db := FileDB{File: file}
+	defer db.Close()
+
+	var gotDropped string
+	var gotTruncated bool
+	s, err := New(&db, testModel{}, getTestEvent, WithTruncatedTailRecovery[testModel](func(dropped []byte, truncated bool) {
+		gotDropped = string(dropped)
+		gotTruncated = truncated
+	}))
+	if err != nil {
+		t.Fatalf("loading db: %v", err)
+	}
+
+	if gotDropped != `{"time":"2024-01-0` || !gotTruncated {
+		t.Errorf("callback got (%q, %t), expected the broken line to be dropped and truncated", gotDropped, gotTruncated)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 2} }); err != nil {
+		t.Fatalf("writing event: %v", err)
+	}
+
+	s, err = New(&db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("loading db again: %v", err)
+	}
+
+	s.Read(func(m testModel) error {
+		if m.Sum != 3 {
+			t.Errorf("got sum %d, expected 3", m.Sum)
+		}
+		return nil
+	})
+}
+
+func TestNew_truncated_tail_recovery_broken_middle_line(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-0`,
+		`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}`,
+	)
+
+	if _, err := New(db, testModel{}, getTestEvent, WithTruncatedTailRecovery[testModel](nil)); err == nil {
+		t.Errorf("loading db with broken middle line did not return an error")
+	}
+}
+
+func TestWrite_event_too_large(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithMaxEventSize[testModel](20))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	err = s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+
+	var errTooLarge EventTooLargeError
+	if !errors.As(err, &errTooLarge) {
+		t.Fatalf("got error `%v`, expected EventTooLargeError", err)
+	}
+
+	if errTooLarge.Name != "add" || errTooLarge.Max != 20 {
+		t.Errorf("got %v, expected name add and max 20", errTooLarge)
+	}
+}
+
+func TestNew_event_bigger_then_64kb(t *testing.T) {
+	bigPayload := `{"value":1,"padding":"` + strings.Repeat("x", 100_000) + `"}`
+	db := NewMemoryDB(`{"time":"2024-01-01 12:00:00","type":"add","payload":` + bigPayload + `}`)
+
+	if _, err := New(db, testModel{}, getTestEvent); err != nil {
+		t.Errorf("loading db with big event: %v", err)
+	}
+
+	if _, err := New(db, testModel{}, getTestEvent, WithMaxEventSize[testModel](1000)); err == nil {
+		t.Errorf("loading db with event bigger then max size did not return an error")
+	}
+}
+
+func TestNewReadOnly_rejects_writes(t *testing.T) {
+	db := FileDB{File: path.Join(t.TempDir(), "file.db")}
+	if err := db.Append([]byte(`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}`)); err != nil {
+		t.Fatalf("append to db: %v", err)
+	}
+	db.Close()
+
+	s, err := NewReadOnly(&db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Write returned `%v`, expected ErrReadOnly", err)
+	}
+
+	_, write, done := s.ForWriting()
+	err = write(addEvent{Value: 1})
+	done()
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("write function returned `%v`, expected ErrReadOnly", err)
+	}
+
+	if err := db.Append([]byte("event")); err == nil {
+		t.Errorf("FileDB was not switched to read only")
+	}
+
+	s.Read(func(m testModel) error {
+		if m.Sum != 1 {
+			t.Errorf("got sum %d, expected 1", m.Sum)
+		}
+		return nil
+	})
+}
+
+// batchCountingDB counts the calls to AppendBatch.
+type batchCountingDB struct {
+	*MemoryDB
+	batches int
+}
+
+func (db *batchCountingDB) AppendBatch(records [][]byte) error {
+	db.batches++
+	return db.MemoryDB.AppendBatch(records)
+}
+
+// brokenEvent can not be encoded to json.
+type brokenEvent struct {
+	Func func()
+}
+
+func (brokenEvent) Name() string                               { return "broken" }
+func (brokenEvent) Validate(testModel) error                   { return nil }
+func (brokenEvent) Execute(m testModel, _ time.Time) testModel { return m }
+
+func TestWrite_batch_uses_append_batch(t *testing.T) {
+	db := &batchCountingDB{MemoryDB: NewMemoryDB()}
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, write, done := s.ForWriting()
+	err = write(addEvent{Value: 1}, addEvent{Value: 2}, addEvent{Value: 3})
+	done()
+	if err != nil {
+		t.Fatalf("writing events: %v", err)
+	}
+
+	if db.batches != 1 {
+		t.Errorf("AppendBatch was called %d times, expected 1", db.batches)
+	}
+
+	if got := len(db.Records()); got != 3 {
+		t.Errorf("db has %d records, expected 3", got)
+	}
+}
+
+func TestWrite_batch_with_failing_event_writes_nothing(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		events []Event[testModel]
+	}{
+		{"validation", []Event[testModel]{addEvent{Value: 1}, addEvent{Value: -1}}},
+		{"encoding", []Event[testModel]{addEvent{Value: 1}, brokenEvent{}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryDB()
+			s, err := New(db, testModel{}, getTestEvent)
+			if err != nil {
+				t.Fatalf("creating sticky: %v", err)
+			}
+
+			_, write, done := s.ForWriting()
+			err = write(tt.events...)
+			done()
+			if err == nil {
+				t.Fatalf("writing events did not return an error")
+			}
+
+			if got := len(db.Records()); got != 0 {
+				t.Errorf("db has %d records, expected 0", got)
+			}
+
+			s.Read(func(m testModel) error {
+				if m.Sum != 0 {
+					t.Errorf("model was changed to sum %d", m.Sum)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	file := path.Join(t.TempDir(), "file.db")
+	db := FileDB{File: file, Locking: true}
+
+	s, err := New(&db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	listenDone := make(chan struct{})
+	go func() {
+		s.Listen(context.Background())(func([]string) bool { return true })
+		close(listenDone)
+	}()
+
+	_, write, done := s.ForWriting()
+
+	closeDone := make(chan error)
+	go func() {
+		closeDone <- s.Close()
+	}()
+
+	// The running write has to finish.
+	if err := write(addEvent{Value: 1}); err != nil {
+		t.Errorf("write while closing: %v", err)
+	}
+	done()
+
+	if err := <-closeDone; err != nil {
+		t.Fatalf("closing sticky: %v", err)
+	}
+
+	select {
+	case <-listenDone:
+	case <-time.After(time.Second):
+		t.Errorf("Listen did not stop after Close")
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after close returned `%v`, expected ErrClosed", err)
+	}
+
+	_, write, done = s.ForWriting()
+	err = write(addEvent{Value: 1})
+	done()
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("write function after close returned `%v`, expected ErrClosed", err)
+	}
+
+	// The lock of the FileDB was released.
+	other := FileDB{File: file, Locking: true}
+	if _, err := New(&other, testModel{}, getTestEvent); err != nil {
+		t.Errorf("opening db after close: %v", err)
+	}
+	other.Close()
+
+	if err := s.Close(); err != nil {
+		t.Errorf("second close returned: %v", err)
+	}
+}
+
+// syncCountingDB counts the calls to Sync.
+type syncCountingDB struct {
+	*MemoryDB
+	syncs int
+}
+
+func (db *syncCountingDB) Sync() error {
+	db.syncs++
+	return nil
+}
+
+func TestWriteDurable_syncs_database(t *testing.T) {
+	db := &syncCountingDB{MemoryDB: NewMemoryDB()}
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("writing event: %v", err)
+	}
+
+	if db.syncs != 0 {
+		t.Errorf("Write called Sync")
+	}
+
+	if err := s.WriteDurable(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("writing durable event: %v", err)
+	}
+
+	if db.syncs != 1 {
+		t.Errorf("WriteDurable called Sync %d times, expected 1", db.syncs)
+	}
+
+	if err := s.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	if db.syncs != 2 {
+		t.Errorf("Sync was called %d times, expected 2", db.syncs)
+	}
+}
+
+func TestWriteDurable_failed_sync(t *testing.T) {
+	db := &groupSyncDB{MemoryDB: NewMemoryDB(), err: errors.New("disk full")}
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.WriteDurable(func(testModel) Event[testModel] { return addEvent{Value: 1} }); !errors.Is(err, db.err) {
+		t.Fatalf("write durable returned `%v`, expected the sync error", err)
+	}
+
+	model, version, done := s.ForReadingVersioned()
+	done()
+	if model.Sum != 1 || version != 1 {
+		t.Errorf("got sum %d at version %d, expected the event in the database to be applied", model.Sum, version)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); !errors.Is(err, db.err) {
+		t.Errorf("write after the failed sync returned `%v`, expected the sync error", err)
+	}
+
+	reloaded, err := New(db.MemoryDB, testModel{}, getTestEvent, WithStrictSeq[testModel]())
+	if err != nil {
+		t.Fatalf("reloading database: %v", err)
+	}
+
+	model, done = reloaded.ForReading()
+	done()
+	if model.Sum != 1 {
+		t.Errorf("got sum %d after reload, expected 1", model.Sum)
+	}
+}
+func TestSync_without_sync_support_does_nothing(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Sync(); err != nil {
+		t.Errorf("sync returned: %v", err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	db := NewMemoryDB()
+
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: i} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if got := s.Version(); got != 3 {
+		t.Errorf("got version %d, expected 3", got)
+	}
+
+	if record := string(db.Records()[2]); !strings.Contains(record, `"version":3`) {
+		t.Errorf("record `%s` does not contain its version", record)
+	}
+
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: 4} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := s.Compact(context.Background(), CompactKeep(2)); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	reloaded, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	model, version, done := reloaded.ForReadingVersioned()
+	done()
+
+	if version != 4 {
+		t.Errorf("got version %d after reload, expected 4", version)
+	}
+
+	if model.Sum != 10 {
+		t.Errorf("got sum %d, expected 10", model.Sum)
+	}
+}
+
+func TestWriteIfVersion(t *testing.T) {
+	db := NewMemoryDB()
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, version, done := s.ForReadingVersioned()
+	done()
+
+	if err := s.WriteIfVersion(version, func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write with current version: %v", err)
+	}
+
+	err = s.WriteIfVersion(version, func(testModel) Event[testModel] { return addEvent{Value: 2} })
+
+	var mismatch ErrVersionMismatch
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("got error `%v`, expected ErrVersionMismatch", err)
+	}
+
+	if mismatch.Expected != 0 || mismatch.Actual != 1 {
+		t.Errorf("got mismatch %+v, expected 0 and 1", mismatch)
+	}
+
+	if got := len(db.Records()); got != 1 {
+		t.Errorf("got %d records, expected 1", got)
+	}
+}
+
+func TestWriteMeta(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	meta := map[string]string{"user": "42"}
+	if err := s.WriteMeta(meta, func(testModel) Event[testModel] { return addEvent{Value: 2} }); err != nil {
+		t.Fatalf("write meta: %v", err)
+	}
+
+	if record := string(db.Records()[1]); !strings.Contains(record, `"meta":{"user":"42"}`) {
+		t.Errorf("record `%s` does not contain the metadata", record)
+	}
+
+	var loaded []map[string]string
+	hook := func(_ Event[testModel], meta map[string]string) {
+		loaded = append(loaded, meta)
+	}
+
+	reloaded, err := New(db, testModel{}, getTestEvent, WithLoadHook(hook))
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	model, done := reloaded.ForReading()
+	done()
+
+	if model.Sum != 3 {
+		t.Errorf("got sum %d, expected 3", model.Sum)
+	}
+
+	if len(loaded) != 2 || loaded[0] != nil || loaded[1]["user"] != "42" {
+		t.Errorf("load hook got %v, expected [nil map[user:42]]", loaded)
+	}
+}
+
+func TestNew_record_with_newer_format(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"v":3,"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+	)
+
+	_, err := New(db, testModel{}, getTestEvent, WithTruncatedTailRecovery[testModel](nil))
+	if err == nil || !strings.Contains(err.Error(), "newer sticky, record version 3") {
+		t.Errorf("got error `%v`, expected error about newer record version", err)
+	}
+}
+
+func TestRecordFormatOf(t *testing.T) {
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	for _, tt := range []struct {
+		record string
+		expect int
+	}{
+		{string(db.Records()[0]), RecordFormat},
+		{`{"time":"2024-01-01 00:00:00","type":"add","payload":{}}`, 1},
+		{`{"v":7,"type":"add"}`, 7},
+	} {
+		got, err := RecordFormatOf([]byte(tt.record))
+		if err != nil {
+			t.Fatalf("RecordFormatOf(`%s`): %v", tt.record, err)
+		}
+
+		if got != tt.expect {
+			t.Errorf("RecordFormatOf(`%s`) = %d, expected %d", tt.record, got, tt.expect)
+		}
+	}
+}
+
+func TestSeq(t *testing.T) {
+	// A legacy record without a sequence number.
+	db := NewMemoryDB(`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}`)
+
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: 2} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	for i, record := range db.Records()[1:] {
+		expect := fmt.Sprintf(`"seq":%d`, i+2)
+		if !strings.Contains(string(record), expect) {
+			t.Errorf("record `%s` does not contain `%s`", record, expect)
+		}
+	}
+
+	if err := s.Compact(context.Background(), CompactKeep(1)); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	reloaded, err := New(db, snapshotModel{}, getSnapshotTestEvent, WithStrictSeq[snapshotModel]())
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	if got := reloaded.Stats().LastSeq; got != 3 {
+		t.Errorf("got last seq %d, expected 3", got)
+	}
+}
+
+func TestSeq_out_of_order(t *testing.T) {
+	record := func(seq int) string {
+		return fmt.Sprintf(`{"time":"2024-01-01 12:00:00","type":"add","seq":%d,"payload":{"value":1}}`, seq)
+	}
+
+	for _, tt := range []struct {
+		name        string
+		records     []string
+		strict      bool
+		expectError bool
+	}{
+		{"in order", []string{record(5), record(6)}, true, false},
+		{"gap", []string{record(1), record(3)}, false, false},
+		{"gap strict", []string{record(1), record(3)}, true, true},
+		{"duplicate", []string{record(1), record(1)}, false, false},
+		{"duplicate strict", []string{record(1), record(1)}, true, true},
+		{"smaller", []string{record(2), record(1)}, false, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []Option[testModel]
+			if tt.strict {
+				options = append(options, WithStrictSeq[testModel]())
+			}
+
+			_, err := New(NewMemoryDB(tt.records...), testModel{}, getTestEvent, options...)
+			if tt.expectError != (err != nil) {
+				t.Errorf("got error `%v`, expected error: %t", err, tt.expectError)
+			}
+
+			var warnings []error
+			options = append(options, WithSeqWarning[testModel](func(err error) {
+				warnings = append(warnings, err)
+			}))
+
+			if _, err := New(NewMemoryDB(tt.records...), testModel{}, getTestEvent, options...); err != nil {
+				t.Fatalf("loading with warnings: %v", err)
+			}
+
+			if tt.expectError != (len(warnings) > 0) {
+				t.Errorf("got warnings %v, expected warning: %t", warnings, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestWriteWith_correlation(t *testing.T) {
+	db := NewMemoryDB()
+
+	var applied []AppliedEvent[testModel]
+	s, err := New(db, testModel{}, getTestEvent, WithAfterWrite[testModel](func(events []AppliedEvent[testModel], _ testModel) {
+		applied = append(applied, events...)
+	}))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	live := s.Subscribe(context.Background())
+
+	event := func(testModel) Event[testModel] { return addEvent{Value: 1} }
+	if err := s.WriteWith(event, WithCorrelation("action-1"), WithCausation("command-1")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if len(applied) != 1 || applied[0].Correlation != "action-1" || applied[0].Causation != "command-1" {
+		t.Errorf("got applied events %+v, expected the correlation and causation", applied)
+	}
+
+	for name, subscription := range map[string]func(yield func([]Notification[testModel], error) bool){
+		"live":     live,
+		"catch up": s.SubscribeFrom(context.Background(), 1),
+	} {
+		subscription(func(notifications []Notification[testModel], err error) bool {
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			if n := notifications[0]; n.Correlation != "action-1" || n.Causation != "command-1" {
+				t.Errorf("%s: got correlation `%s` and causation `%s`, expected `action-1` and `command-1`", name, n.Correlation, n.Causation)
+			}
+			return false
+		})
+	}
+
+	envelope, err := DecodeEnvelope(db.Records()[0])
+	if err != nil {
+		t.Fatalf("decoding record: %v", err)
+	}
+
+	if envelope.Correlation != "action-1" || envelope.Causation != "command-1" {
+		t.Errorf("got correlation `%s` and causation `%s`, expected `action-1` and `command-1`", envelope.Correlation, envelope.Causation)
+	}
+}
+
+func TestWithEventAlias(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"oldAdd","payload":{"value":1}}`)
+
+	s, err := New(db, testModel{}, getTestEvent, WithEventAlias[testModel]("oldAdd", "add"))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 2} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 3 {
+		t.Errorf("got sum %d, expected 3", model.Sum)
+	}
+
+	if record := string(db.Records()[1]); !strings.Contains(record, `"type":"add"`) {
+		t.Errorf("record `%s` does not use the current name", record)
+	}
+}
+
+func TestWithEventAlias_unknown_event(t *testing.T) {
+	_, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithEventAlias[testModel]("oldAdd", "missing"))
+	if err == nil {
+		t.Fatalf("New with an alias to an unknown event did not return an error")
+	}
+
+	if !strings.Contains(err.Error(), "missing") {
+		t.Errorf("got error `%v`, expected it to name the unknown event", err)
+	}
+}
+
+// withdrawEvent fails, when the sum would get negative.
+type withdrawEvent struct {
+	Value int `json:"value"`
+}
+
+func (e withdrawEvent) Name() string {
+	return "withdraw"
+}
+
+func (e withdrawEvent) Validate(testModel) error {
+	return nil
+}
+
+func (e withdrawEvent) Execute(m testModel, _ time.Time) testModel {
+	m.Sum -= e.Value
+	return m
+}
+
+func (e withdrawEvent) ExecuteErr(m testModel, t time.Time) (testModel, error) {
+	if m.Sum < e.Value {
+		return m, errors.New("sum to small")
+	}
+	return e.Execute(m, t), nil
+}
+
+func getWithdrawTestEvent(name string) Event[testModel] {
+	if name == "withdraw" {
+		return &withdrawEvent{}
+	}
+	return getTestEvent(name)
+}
+
+func TestWrite_execution_error(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)
+
+	s, err := New(db, testModel{}, getWithdrawTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	err = s.Write(func(testModel) Event[testModel] { return withdrawEvent{Value: 2} })
+
+	var executionErr ExecutionError
+	if !errors.As(err, &executionErr) {
+		t.Fatalf("got error `%v`, expected an ExecutionError", err)
+	}
+
+	if executionErr.EventName != "withdraw" {
+		t.Errorf("got event name `%s`, expected withdraw", executionErr.EventName)
+	}
+
+	if len(db.Records()) != 1 {
+		t.Errorf("got %d records, expected the event not to be written", len(db.Records()))
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 1 {
+		t.Errorf("got sum %d, expected 1", model.Sum)
+	}
+}
+
+func TestNew_execution_error(t *testing.T) {
+	newDB := func() *MemoryDB {
+		return NewMemoryDB(
+			`{"time":"2024-01-01 00:00:00","type":"withdraw","payload":{"value":2}}`,
+			`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		)
+	}
+
+	var executionErr ExecutionError
+	if _, err := New(newDB(), testModel{}, getWithdrawTestEvent); !errors.As(err, &executionErr) {
+		t.Errorf("got error `%v`, expected an ExecutionError", err)
+	}
+
+	if executionErr.EventName != "withdraw" {
+		t.Errorf("got event name `%s` on load, expected withdraw", executionErr.EventName)
+	}
+
+	var skipped []Event[testModel]
+	onError := func(event Event[testModel], _ error) {
+		skipped = append(skipped, event)
+	}
+
+	s, err := New(newDB(), testModel{}, getWithdrawTestEvent, WithExecutionErrorSkip(onError))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	model, version, done := s.ForReadingVersioned()
+	done()
+
+	if model.Sum != 1 || version != 2 || len(skipped) != 1 {
+		t.Errorf("got sum %d, version %d and %d skipped events, expected 1, 2 and 1", model.Sum, version, len(skipped))
+	}
+}
+
+// initEvent can only be applied on an empty model.
+type initEvent struct {
+	Value int `json:"value"`
+}
+
+func (e initEvent) Name() string {
+	return "init"
+}
+
+func (e initEvent) Validate(m testModel) error {
+	if m.Sum != 0 {
+		return errors.New("model is already initialized")
+	}
+	return nil
+}
+
+func (e initEvent) Execute(m testModel, _ time.Time) testModel {
+	m.Sum = e.Value
+	return m
+}
+
+func getInitTestEvent(name string) Event[testModel] {
+	if name == "init" {
+		return &initEvent{}
+	}
+	return getTestEvent(name)
+}
+
+func TestWrite_batch_validates_against_intermediate_model(t *testing.T) {
+	events := []Event[testModel]{initEvent{Value: 1}, initEvent{Value: 2}}
+
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getInitTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, write, done := s.ForWriting()
+	err = write(events...)
+	done()
+
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got error `%v`, expected a ValidationError", err)
+	}
+
+	if validationErr.Index != 1 || validationErr.EventName != "init" {
+		t.Errorf("got failing event %d `%s`, expected 1 `init`", validationErr.Index, validationErr.EventName)
+	}
+
+	if len(db.Records()) != 0 {
+		t.Errorf("db has %d records, expected 0", len(db.Records()))
+	}
+
+	legacy, err := New(NewMemoryDB(), testModel{}, getInitTestEvent, WithBatchValidationBeforeExecute[testModel]())
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	_, write, done = legacy.ForWriting()
+	err = write(events...)
+	done()
+	if err != nil {
+		t.Fatalf("writing with validation before execute: %v", err)
+	}
+
+	model, done := legacy.ForReading()
+	done()
+
+	if model.Sum != 2 {
+		t.Errorf("got sum %d, expected 2", model.Sum)
+	}
+}
+
+// timeModel stores the times of its events.
+type timeModel struct {
+	Times []time.Time
+}
+
+type timeEvent struct{}
+
+func (e timeEvent) Name() string {
+	return "time"
+}
+
+func (e timeEvent) Validate(timeModel) error {
+	return nil
+}
+
+func (e timeEvent) Execute(m timeModel, t time.Time) timeModel {
+	m.Times = append(m.Times, t)
+	return m
+}
+
+func getTimeTestEvent(name string) Event[timeModel] {
+	if name == "time" {
+		return &timeEvent{}
+	}
+	return nil
+}
+
+func TestWrite_replay_has_same_times(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		options    []Option[timeModel]
+		expectSame bool
+	}{
+		{"per event", nil, false},
+		{"per batch", []Option[timeModel]{WithBatchTime[timeModel]()}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			current := time.Date(2024, 1, 1, 12, 0, 0, 500, time.Local)
+			now := func() time.Time {
+				current = current.Add(1500 * time.Millisecond)
+				return current
+			}
+
+			db := NewMemoryDB()
+			s, err := New(db, timeModel{}, getTimeTestEvent, append(tt.options, WithNow[timeModel](now))...)
+			if err != nil {
+				t.Fatalf("creating sticky: %v", err)
+			}
+
+			_, write, done := s.ForWriting()
+			err = write(timeEvent{}, timeEvent{})
+			done()
+			if err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			reloaded, err := New(db, timeModel{}, getTimeTestEvent)
+			if err != nil {
+				t.Fatalf("reloading sticky: %v", err)
+			}
+
+			live, done := s.ForReading()
+			done()
+			replayed, done := reloaded.ForReading()
+			done()
+
+			liveJSON, _ := json.Marshal(live)
+			replayedJSON, _ := json.Marshal(replayed)
+			if string(liveJSON) != string(replayedJSON) {
+				t.Errorf("live model `%s` is different then replayed model `%s`", liveJSON, replayedJSON)
+			}
+
+			if same := live.Times[0].Equal(live.Times[1]); same != tt.expectSame {
+				t.Errorf("events have the same time: %t, expected %t", same, tt.expectSame)
+			}
+		})
+	}
+}
+
+func TestWrite_clock_goes_back(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 12:00:00","type":"add","payload":{"value":1}}`)
+
+	current := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+	now := func() time.Time { return current }
+
+	var clamped []time.Time
+	hook := func(_, c time.Time) {
+		clamped = append(clamped, c)
+	}
+
+	s, err := New(db, testModel{}, getTestEvent, WithNow[testModel](now), WithClockSkewHook[testModel](hook))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if record := string(db.Records()[1]); !strings.Contains(record, `"time":"2024-01-01 12:00:01"`) {
+		t.Errorf("record `%s` does not have the clamped time", record)
+	}
+
+	if len(clamped) != 1 {
+		t.Errorf("hook was called %d times, expected 1", len(clamped))
+	}
+
+	strict, err := New(db, testModel{}, getTestEvent, WithNow[testModel](now), WithMaxClockSkew[testModel](time.Minute))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	var skewErr ErrClockSkew
+	if err := strict.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); !errors.As(err, &skewErr) {
+		t.Errorf("got error `%v`, expected ErrClockSkew", err)
+	}
+}
+
+func TestWriteMany(t *testing.T) {
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	err = s.WriteMany(func(testModel) ([]Event[testModel], error) {
+		return []Event[testModel]{addEvent{Value: 1}, addEvent{Value: 2}}, nil
+	})
+	if err != nil {
+		t.Fatalf("write many: %v", err)
+	}
+
+	if err := s.WriteMany(func(testModel) ([]Event[testModel], error) { return nil, nil }); err != nil {
+		t.Errorf("write many without events returned: %v", err)
+	}
+
+	abort := errors.New("abort")
+	err = s.WriteMany(func(testModel) ([]Event[testModel], error) {
+		return []Event[testModel]{addEvent{Value: 1}}, abort
+	})
+	if !errors.Is(err, abort) {
+		t.Errorf("got error `%v`, expected `%v`", err, abort)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return nil }); err != nil {
+		t.Errorf("write of nil event returned: %v", err)
+	}
+
+	if len(db.Records()) != 2 {
+		t.Errorf("got %d records, expected 2", len(db.Records()))
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 3 {
+		t.Errorf("got sum %d, expected 3", model.Sum)
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		b.Fatalf("creating sticky: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: i} }); err != nil {
+			b.Fatalf("write: %v", err)
+		}
+	}
+}
+
+// failingDB returns err from Append and readErr from Reader.
+type failingDB struct {
+	*MemoryDB
+	err     error
+	readErr error
+}
+
+func (db *failingDB) Append(bs []byte) error {
+	if db.err != nil {
+		return db.err
+	}
+	return db.MemoryDB.Append(bs)
+}
+
+func (db *failingDB) AppendBatch(records [][]byte) error {
+	if db.err != nil {
+		return db.err
+	}
+	return db.MemoryDB.AppendBatch(records)
+}
+
+func (db *failingDB) Reader() (io.ReadCloser, error) {
+	if db.readErr != nil {
+		return nil, db.readErr
+	}
+	return db.MemoryDB.Reader()
+}
+
+func TestStorageError(t *testing.T) {
+	db := &failingDB{MemoryDB: NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)}
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+
+	db.err = syscall.ENOSPC
+	err = s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} })
+
+	var storageErr StorageError
+	if !errors.As(err, &storageErr) || storageErr.Op != "writing events to db" {
+		t.Fatalf("got error `%v`, expected a StorageError of the write", err)
+	}
+
+	if !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("got error `%v`, expected it to wrap ENOSPC", err)
+	}
+
+	err = s.Write(func(testModel) Event[testModel] { return addEvent{Value: -1} })
+	if err == nil || IsStorageError(err) {
+		t.Errorf("got error `%v` for an invalid event, expected a validation error", err)
+	}
+
+	db.readErr = syscall.EIO
+	if _, err := New(db, testModel{}, getTestEvent); !IsStorageError(err) || !errors.Is(err, syscall.EIO) {
+		t.Errorf("got error `%v` from New, expected a StorageError wrapping EIO", err)
+	}
+}
+
+func TestWrite_unregistered_event(t *testing.T) {
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+
+	err = s.Write(func(testModel) Event[testModel] { return initEvent{Value: 1} })
+
+	var unregistered ErrUnregisteredEvent
+	if !errors.As(err, &unregistered) {
+		t.Fatalf("got error `%v`, expected ErrUnregisteredEvent", err)
+	}
+
+	if unregistered.Name != "init" || unregistered.Type != "sticky.initEvent" {
+		t.Errorf("got %+v, expected event init of type sticky.initEvent", unregistered)
+	}
+
+	if len(db.Records()) != 0 {
+		t.Errorf("got %d records, expected the event not to be written", len(db.Records()))
+	}
+
+	allowed, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithAllowUnregistered[testModel]())
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer allowed.Close()
+
+	if err := allowed.Write(func(testModel) Event[testModel] { return initEvent{Value: 1} }); err != nil {
+		t.Errorf("write with WithAllowUnregistered: %v", err)
+	}
+}
+
+// keysModel has a map and is no Cloner.
+type keysModel struct {
+	Keys map[string]bool
+}
+
+// cloneKeysModel is keysModel as Cloner.
+type cloneKeysModel struct {
+	keysModel
+}
+
+func (m cloneKeysModel) Clone() cloneKeysModel {
+	keys := make(map[string]bool, len(m.Keys))
+	for k := range m.Keys {
+		keys[k] = true
+	}
+	return cloneKeysModel{keysModel{Keys: keys}}
+}
+
+// addKeyEvent adds a key, that must not exist.
+type addKeyEvent[Model interface{ keys() map[string]bool }] struct {
+	Key string `json:"key"`
+}
+
+func (m keysModel) keys() map[string]bool { return m.Keys }
+
+func (e addKeyEvent[Model]) Name() string { return "add_key" }
+
+func (e addKeyEvent[Model]) Validate(m Model) error {
+	if m.keys()[e.Key] {
+		return fmt.Errorf("key %s exists", e.Key)
+	}
+	return nil
+}
+
+func (e addKeyEvent[Model]) Execute(m Model, _ time.Time) Model {
+	m.keys()[e.Key] = true
+	return m
+}
+
+func TestWrite_failed_batch_does_not_change_model(t *testing.T) {
+	t.Run("cloner", func(t *testing.T) {
+		getEvent := func(string) Event[cloneKeysModel] { return &addKeyEvent[cloneKeysModel]{} }
+		s, err := New(NewMemoryDB(), cloneKeysModel{keysModel{Keys: map[string]bool{}}}, getEvent)
+		if err != nil {
+			t.Fatalf("creating sticky: %v", err)
+		}
+
+		err = s.WriteMany(func(cloneKeysModel) ([]Event[cloneKeysModel], error) {
+			return []Event[cloneKeysModel]{addKeyEvent[cloneKeysModel]{Key: "a"}, addKeyEvent[cloneKeysModel]{Key: "a"}}, nil
+		})
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Index != 1 {
+			t.Fatalf("got error `%v`, expected a ValidationError for the second event", err)
+		}
+
+		model, done := s.ForReading()
+		defer done()
+		if len(model.Keys) != 0 {
+			t.Errorf("got keys %v after the failed write, expected none", model.Keys)
+		}
+	})
+
+	t.Run("no cloner", func(t *testing.T) {
+		getEvent := func(string) Event[keysModel] { return &addKeyEvent[keysModel]{} }
+		s, err := New(NewMemoryDB(), keysModel{Keys: map[string]bool{}}, getEvent)
+		if err != nil {
+			t.Fatalf("creating sticky: %v", err)
+		}
+
+		if err := s.Write(func(keysModel) Event[keysModel] { return addKeyEvent[keysModel]{Key: "a"} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		// Without a copy, the events are validated against the model before
+		// the batch.
+		err = s.WriteMany(func(keysModel) ([]Event[keysModel], error) {
+			return []Event[keysModel]{addKeyEvent[keysModel]{Key: "b"}, addKeyEvent[keysModel]{Key: "a"}}, nil
+		})
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Index != 1 {
+			t.Fatalf("got error `%v`, expected a ValidationError for the second event", err)
+		}
+
+		model, done := s.ForReading()
+		defer done()
+		if len(model.Keys) != 1 || !model.Keys["a"] {
+			t.Errorf("got keys %v after the failed write, expected only a", model.Keys)
+		}
+	})
+}
@@ -0,0 +1,20 @@
+module github.com/ostcar/sticky/stickygrpc
+
+go 1.21.1
+
+require (
+	github.com/ostcar/sticky v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.62.1
+	google.golang.org/protobuf v1.33.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/ostcar/topic v0.4.1 // indirect
+	golang.org/x/net v0.22.0 // indirect
+	golang.org/x/sys v0.19.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240311132316-a219d84964c2 // indirect
+)
+
+replace github.com/ostcar/sticky => ..
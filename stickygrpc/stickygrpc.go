@@ -0,0 +1,209 @@
+// Package stickygrpc contains a gRPC service for sticky.
+//
+// The service is defined in stickypb/sticky.proto. To regenerate the code,
+// run `go generate` with protoc-gen-go and protoc-gen-go-grpc in the PATH.
+package stickygrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative stickypb/sticky.proto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickygrpc/stickypb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Option is a option for stickygrpc.NewServer()
+type Option[Model any] func(s *Server[Model])
+
+// WithAppend enables AppendEvents. getEvent returns a new event for a name,
+// like the argument of sticky.New. Registry.Get can be used.
+//
+// The events are written like all other events, so they are validated and
+// the middlewares and hooks are called.
+func WithAppend[Model any](getEvent func(name string) sticky.Event[Model]) Option[Model] {
+	return func(s *Server[Model]) {
+		s.getEvent = getEvent
+	}
+}
+
+// Server implements stickypb.StickyServer.
+type Server[Model any] struct {
+	stickypb.UnimplementedStickyServer
+
+	sticky   *sticky.Sticky[Model]
+	getEvent func(name string) sticky.Event[Model]
+}
+
+// NewServer returns a server for the Sticky. Register it with
+// stickypb.RegisterStickyServer.
+//
+// Without WithAppend, AppendEvents returns the code Unimplemented.
+func NewServer[Model any](s *sticky.Sticky[Model], options ...Option[Model]) *Server[Model] {
+	server := &Server[Model]{sticky: s}
+	for _, o := range options {
+		o(server)
+	}
+	return server
+}
+
+// StreamEvents sends the events of each write until the client cancels the
+// stream or the Sticky is closed.
+func (s *Server[Model]) StreamEvents(req *stickypb.StreamEventsRequest, stream stickypb.Sticky_StreamEventsServer) error {
+	ctx := stream.Context()
+
+	var subscription func(yield func([]sticky.Notification[Model], error) bool)
+	if req.GetFromSeq() > 0 {
+		subscription = s.sticky.SubscribeFrom(ctx, req.GetFromSeq())
+	} else {
+		subscription = s.sticky.Subscribe(ctx)
+	}
+
+	names := make(map[string]bool, len(req.GetNames()))
+	for _, name := range req.GetNames() {
+		names[name] = true
+	}
+
+	var streamErr error
+	subscription(func(notifications []sticky.Notification[Model], err error) bool {
+		if err != nil {
+			streamErr = subscriptionError(err)
+			return false
+		}
+
+		resp := &stickypb.StreamEventsResponse{}
+		for _, n := range notifications {
+			if len(names) > 0 && !names[n.Name] {
+				continue
+			}
+
+			envelope, err := encodeEnvelope(n)
+			if err != nil {
+				streamErr = status.Error(codes.Internal, err.Error())
+				return false
+			}
+			resp.Events = append(resp.Events, envelope)
+		}
+
+		if len(resp.Events) == 0 {
+			return true
+		}
+
+		if err := stream.Send(resp); err != nil {
+			streamErr = err
+			return false
+		}
+		return true
+	})
+
+	if streamErr != nil {
+		return streamErr
+	}
+	return ctx.Err()
+}
+
+// GetStats returns the stats of the Sticky.
+func (s *Server[Model]) GetStats(ctx context.Context, req *stickypb.GetStatsRequest) (*stickypb.GetStatsResponse, error) {
+	stats := s.sticky.Stats()
+	return &stickypb.GetStatsResponse{
+		Version:             stats.Version,
+		LastSeq:             stats.LastSeq,
+		EventsSinceSnapshot: int64(stats.EventsSinceSnapshot),
+		EventsWritten:       stats.EventsWritten,
+		Size:                stats.Size,
+	}, nil
+}
+
+// AppendEvents decodes the events and writes them in one batch.
+func (s *Server[Model]) AppendEvents(ctx context.Context, req *stickypb.AppendEventsRequest) (*stickypb.AppendEventsResponse, error) {
+	if s.getEvent == nil {
+		return nil, status.Error(codes.Unimplemented, "appending events is not enabled")
+	}
+
+	if len(req.GetEvents()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no events")
+	}
+
+	events := make([]sticky.Event[Model], len(req.GetEvents()))
+	for i, e := range req.GetEvents() {
+		event := s.getEvent(e.GetType())
+		if event == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "event %d: unknown event type `%s`", i, e.GetType())
+		}
+
+		if err := json.Unmarshal(e.GetPayload(), event); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "event %d: decoding payload: %v", i, err)
+		}
+		events[i] = event
+	}
+
+	_, write, done, err := s.sticky.ForWritingCtx(ctx)
+	if err != nil {
+		return nil, writeError(err)
+	}
+
+	err = write(events...)
+	done()
+	if err != nil {
+		return nil, writeError(err)
+	}
+
+	// Other writes could have happened after done.
+	return &stickypb.AppendEventsResponse{Version: s.sticky.Version()}, nil
+}
+
+// encodeEnvelope returns the envelope of a notification.
+func encodeEnvelope[Model any](n sticky.Notification[Model]) (*stickypb.Envelope, error) {
+	payload, err := json.Marshal(n.Event)
+	if err != nil {
+		return nil, fmt.Errorf("encoding event `%s`: %w", n.Name, err)
+	}
+
+	return &stickypb.Envelope{
+		Type:    n.Name,
+		Seq:     n.Seq,
+		Time:    n.Time.Format(time.RFC3339Nano),
+		Payload: payload,
+		Meta:    n.Meta,
+	}, nil
+}
+
+// subscriptionError returns the status of an error of a subscription.
+func subscriptionError(err error) error {
+	switch {
+	case errors.Is(err, sticky.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, sticky.ErrMissedEvents):
+		return status.Error(codes.DataLoss, err.Error())
+	case errors.Is(err, sticky.ErrNotSupported):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// writeError returns the status of an error of a write.
+func writeError(err error) error {
+	var validationErr sticky.ValidationError
+	var timeoutErr sticky.ErrLockTimeout
+	switch {
+	case errors.As(err, &validationErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, sticky.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, sticky.ErrReadOnly):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &timeoutErr):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
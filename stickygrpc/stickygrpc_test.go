@@ -0,0 +1,219 @@
+package stickygrpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickygrpc"
+	"github.com/ostcar/sticky/stickygrpc/stickypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type model struct {
+	Sum int
+}
+
+type addEvent struct {
+	Value int `json:"value"`
+}
+
+func (e addEvent) Name() string {
+	return "add"
+}
+
+func (e addEvent) Validate(m model) error {
+	if e.Value < 0 {
+		return errors.New("value has to be positive")
+	}
+	return nil
+}
+
+func (e addEvent) Execute(m model, _ time.Time) model {
+	m.Sum += e.Value
+	return m
+}
+
+func newRegistry(t *testing.T) *sticky.Registry[model] {
+	t.Helper()
+
+	var registry sticky.Registry[model]
+	if err := registry.Register("add", func() sticky.Event[model] { return &addEvent{} }); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	return &registry
+}
+
+// newClient starts a server on a bufconn listener and returns a client for
+// it.
+func newClient(t *testing.T, server *stickygrpc.Server[model]) stickypb.StickyClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	stickypb.RegisterStickyServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return stickypb.NewStickyClient(conn)
+}
+
+func TestStreamEvents(t *testing.T) {
+	registry := newRegistry(t)
+	db := sticky.NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+	)
+	s, err := sticky.New(db, model{}, registry.Get)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	client := newClient(t, stickygrpc.NewServer(s))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamEvents(ctx, &stickypb.StreamEventsRequest{FromSeq: 2})
+	if err != nil {
+		t.Fatalf("stream events: %v", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("receive catch up: %v", err)
+	}
+
+	if len(resp.Events) != 1 || resp.Events[0].Seq != 2 || string(resp.Events[0].Payload) != `{"value":2}` {
+		t.Fatalf("got %v, expected the event with seq 2", resp.Events)
+	}
+
+	if err := s.Write(func(model) sticky.Event[model] { return addEvent{Value: 3} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	resp, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("receive live: %v", err)
+	}
+
+	event := resp.Events[0]
+	if event.Type != "add" || event.Seq != 3 || string(event.Payload) != `{"value":3}` {
+		t.Errorf("got %v, expected add with seq 3", event)
+	}
+
+	if _, err := time.Parse(time.RFC3339Nano, event.Time); err != nil {
+		t.Errorf("time `%s` is not RFC 3339: %v", event.Time, err)
+	}
+
+	s.Close()
+
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("got error `%v` after close, expected Unavailable", err)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	registry := newRegistry(t)
+	s, err := sticky.New(sticky.NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`), model{}, registry.Get)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	client := newClient(t, stickygrpc.NewServer(s))
+
+	stats, err := client.GetStats(context.Background(), &stickypb.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("get stats: %v", err)
+	}
+
+	if stats.Version != 1 || stats.LastSeq != 1 {
+		t.Errorf("got version %d and last seq %d, expected 1 and 1", stats.Version, stats.LastSeq)
+	}
+}
+
+func TestAppendEvents(t *testing.T) {
+	registry := newRegistry(t)
+	s, err := sticky.New(sticky.NewMemoryDB(), model{}, registry.Get)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	client := newClient(t, stickygrpc.NewServer(s, stickygrpc.WithAppend(registry.Get)))
+
+	resp, err := client.AppendEvents(context.Background(), &stickypb.AppendEventsRequest{
+		Events: []*stickypb.AppendEvent{
+			{Type: "add", Payload: []byte(`{"value":1}`)},
+			{Type: "add", Payload: []byte(`{"value":2}`)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("append events: %v", err)
+	}
+
+	if resp.Version != 2 {
+		t.Errorf("got version %d, expected 2", resp.Version)
+	}
+
+	m, done := s.ForReading()
+	done()
+	if m.Sum != 3 {
+		t.Errorf("got sum %d, expected 3", m.Sum)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		event *stickypb.AppendEvent
+	}{
+		{"invalid", &stickypb.AppendEvent{Type: "add", Payload: []byte(`{"value":-1}`)}},
+		{"unknown type", &stickypb.AppendEvent{Type: "sub", Payload: []byte(`{"value":1}`)}},
+		{"invalid payload", &stickypb.AppendEvent{Type: "add", Payload: []byte(`{`)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.AppendEvents(context.Background(), &stickypb.AppendEventsRequest{Events: []*stickypb.AppendEvent{tt.event}})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("got error `%v`, expected InvalidArgument", err)
+			}
+		})
+	}
+
+	if v := s.Version(); v != 2 {
+		t.Errorf("got version %d after the invalid events, expected 2", v)
+	}
+}
+
+func TestAppendEvents_disabled(t *testing.T) {
+	registry := newRegistry(t)
+	s, err := sticky.New(sticky.NewMemoryDB(), model{}, registry.Get)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	client := newClient(t, stickygrpc.NewServer(s))
+
+	_, err = client.AppendEvents(context.Background(), &stickypb.AppendEventsRequest{
+		Events: []*stickypb.AppendEvent{{Type: "add", Payload: []byte(`{"value":1}`)}},
+	})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("got error `%v`, expected Unimplemented", err)
+	}
+}
@@ -0,0 +1,221 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: sticky.proto
+
+package stickypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Sticky_StreamEvents_FullMethodName = "/sticky.v1.Sticky/StreamEvents"
+	Sticky_GetStats_FullMethodName     = "/sticky.v1.Sticky/GetStats"
+	Sticky_AppendEvents_FullMethodName = "/sticky.v1.Sticky/AppendEvents"
+)
+
+// StickyClient is the client API for Sticky service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StickyClient interface {
+	// StreamEvents sends the events of each write. With from_seq, the events of
+	// the database starting with this sequence number are sent first.
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (Sticky_StreamEventsClient, error)
+	// GetStats returns runtime information.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	// AppendEvents writes events. The events are validated like all other
+	// writes. It is only available, if the server allows it.
+	AppendEvents(ctx context.Context, in *AppendEventsRequest, opts ...grpc.CallOption) (*AppendEventsResponse, error)
+}
+
+type stickyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStickyClient(cc grpc.ClientConnInterface) StickyClient {
+	return &stickyClient{cc}
+}
+
+func (c *stickyClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (Sticky_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Sticky_ServiceDesc.Streams[0], Sticky_StreamEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &stickyStreamEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Sticky_StreamEventsClient interface {
+	Recv() (*StreamEventsResponse, error)
+	grpc.ClientStream
+}
+
+type stickyStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *stickyStreamEventsClient) Recv() (*StreamEventsResponse, error) {
+	m := new(StreamEventsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *stickyClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, Sticky_GetStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stickyClient) AppendEvents(ctx context.Context, in *AppendEventsRequest, opts ...grpc.CallOption) (*AppendEventsResponse, error) {
+	out := new(AppendEventsResponse)
+	err := c.cc.Invoke(ctx, Sticky_AppendEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StickyServer is the server API for Sticky service.
+// All implementations must embed UnimplementedStickyServer
+// for forward compatibility
+type StickyServer interface {
+	// StreamEvents sends the events of each write. With from_seq, the events of
+	// the database starting with this sequence number are sent first.
+	StreamEvents(*StreamEventsRequest, Sticky_StreamEventsServer) error
+	// GetStats returns runtime information.
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	// AppendEvents writes events. The events are validated like all other
+	// writes. It is only available, if the server allows it.
+	AppendEvents(context.Context, *AppendEventsRequest) (*AppendEventsResponse, error)
+	mustEmbedUnimplementedStickyServer()
+}
+
+// UnimplementedStickyServer must be embedded to have forward compatible implementations.
+type UnimplementedStickyServer struct {
+}
+
+func (UnimplementedStickyServer) StreamEvents(*StreamEventsRequest, Sticky_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedStickyServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedStickyServer) AppendEvents(context.Context, *AppendEventsRequest) (*AppendEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AppendEvents not implemented")
+}
+func (UnimplementedStickyServer) mustEmbedUnimplementedStickyServer() {}
+
+// UnsafeStickyServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StickyServer will
+// result in compilation errors.
+type UnsafeStickyServer interface {
+	mustEmbedUnimplementedStickyServer()
+}
+
+func RegisterStickyServer(s grpc.ServiceRegistrar, srv StickyServer) {
+	s.RegisterService(&Sticky_ServiceDesc, srv)
+}
+
+func _Sticky_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StickyServer).StreamEvents(m, &stickyStreamEventsServer{stream})
+}
+
+type Sticky_StreamEventsServer interface {
+	Send(*StreamEventsResponse) error
+	grpc.ServerStream
+}
+
+type stickyStreamEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *stickyStreamEventsServer) Send(m *StreamEventsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Sticky_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StickyServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sticky_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StickyServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sticky_AppendEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StickyServer).AppendEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sticky_AppendEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StickyServer).AppendEvents(ctx, req.(*AppendEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Sticky_ServiceDesc is the grpc.ServiceDesc for Sticky service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Sticky_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sticky.v1.Sticky",
+	HandlerType: (*StickyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStats",
+			Handler:    _Sticky_GetStats_Handler,
+		},
+		{
+			MethodName: "AppendEvents",
+			Handler:    _Sticky_AppendEvents_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _Sticky_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sticky.proto",
+}
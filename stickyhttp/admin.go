@@ -0,0 +1,237 @@
+package stickyhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ostcar/sticky"
+)
+
+// adminHandler is the handler of AdminHandler.
+type adminHandler[Model any] struct {
+	sticky *sticky.Sticky[Model]
+}
+
+// AdminHandler returns a handler for operators. It has the endpoints:
+//
+//	GET  /stats     the stats of the Sticky. See Sticky.Stats.
+//	GET  /version   the version of the model.
+//	GET  /events    the events of the database as JSON lines.
+//	POST /snapshot  writes a snapshot. See Sticky.Snapshot.
+//	POST /compact   compacts the database. See Sticky.Compact.
+//
+// /events accepts the query parameters from and to, that are the first and
+// last sequence number, type, that can be given more then once, and limit.
+// See Sticky.Events. The write lock is not held, while the events are sent.
+//
+// /compact accepts the query parameters keep, see sticky.CompactKeep, and
+// before as RFC 3339 time, see sticky.CompactBefore.
+//
+// The handler has no authentication. Wrap it with the authentication of the
+// application. Use http.StripPrefix, to mount it under a path.
+func AdminHandler[Model any](s *sticky.Sticky[Model]) http.Handler {
+	return &adminHandler[Model]{sticky: s}
+}
+
+func (h *adminHandler[Model]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var method string
+	var handle func(http.ResponseWriter, *http.Request)
+	switch r.URL.Path {
+	case "/stats":
+		method, handle = http.MethodGet, h.stats
+	case "/version":
+		method, handle = http.MethodGet, h.version
+	case "/events":
+		method, handle = http.MethodGet, h.events
+	case "/snapshot":
+		method, handle = http.MethodPost, h.snapshot
+	case "/compact":
+		method, handle = http.MethodPost, h.compact
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	handle(w, r)
+}
+
+func (h *adminHandler[Model]) stats(w http.ResponseWriter, r *http.Request) {
+	stats := h.sticky.Stats()
+	writeJSON(w, struct {
+		Version             uint64            `json:"version"`
+		LastSeq             uint64            `json:"last_seq"`
+		EventsSinceSnapshot int               `json:"events_since_snapshot"`
+		EventsWritten       uint64            `json:"events_written"`
+		EventTypesWritten   map[string]uint64 `json:"event_types_written"`
+		LastWrite           *time.Time        `json:"last_write,omitempty"`
+		LastSnapshot        *time.Time        `json:"last_snapshot,omitempty"`
+		Size                int64             `json:"size"`
+	}{
+		Version:             stats.Version,
+		LastSeq:             stats.LastSeq,
+		EventsSinceSnapshot: stats.EventsSinceSnapshot,
+		EventsWritten:       stats.EventsWritten,
+		EventTypesWritten:   stats.EventTypesWritten,
+		LastWrite:           optionalTime(stats.LastWrite),
+		LastSnapshot:        optionalTime(stats.LastSnapshot),
+		Size:                stats.Size,
+	})
+}
+
+func (h *adminHandler[Model]) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, struct {
+		Version uint64 `json:"version"`
+	}{h.sticky.Version()})
+}
+
+func (h *adminHandler[Model]) events(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, err := queryUint(query.Get("from"))
+	if err != nil {
+		http.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+
+	to, err := queryUint(query.Get("to"))
+	if err != nil {
+		http.Error(w, "invalid to", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := queryUint(query.Get("limit"))
+	if err != nil {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	filters := []sticky.EventFilter{sticky.FilterSeq(from, to)}
+	if types := query["type"]; len(types) > 0 {
+		filters = append(filters, sticky.FilterNames(types...))
+	}
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	var sent uint64
+	var started bool
+
+	h.sticky.Events(r.Context(), filters...)(func(envelope sticky.Envelope, err error) bool {
+		if err != nil {
+			if !started {
+				http.Error(w, err.Error(), errorStatus(err))
+			}
+			// After the first record, the status can not be changed. The
+			// client sees a truncated response.
+			return false
+		}
+
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			started = true
+		}
+
+		if err := encoder.Encode(envelope); err != nil {
+			return false
+		}
+
+		sent++
+		if flusher != nil && sent%100 == 0 {
+			flusher.Flush()
+		}
+		return limit == 0 || sent < limit
+	})
+
+	if !started {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *adminHandler[Model]) snapshot(w http.ResponseWriter, r *http.Request) {
+	if err := h.sticky.Snapshot(); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	h.version(w, r)
+}
+
+func (h *adminHandler[Model]) compact(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var options []sticky.CompactOption
+
+	if v := query.Get("keep"); v != "" {
+		keep, err := strconv.Atoi(v)
+		if err != nil || keep < 0 {
+			http.Error(w, "invalid keep", http.StatusBadRequest)
+			return
+		}
+		options = append(options, sticky.CompactKeep(keep))
+	}
+
+	if v := query.Get("before"); v != "" {
+		before, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+		options = append(options, sticky.CompactBefore(before))
+	}
+
+	if err := h.sticky.Compact(r.Context(), options...); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	h.version(w, r)
+}
+
+// errorStatus returns the http status for an error of the Sticky.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, sticky.ErrNotSupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, sticky.ErrClosed):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, sticky.ErrReadOnly):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v as JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("encoding response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// queryUint parses the value of a query parameter. It returns 0, if the
+// value is empty.
+func queryUint(v string) (uint64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(v, 10, 64)
+}
+
+// optionalTime returns nil for the zero time, so it is omitted.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,98 @@
+package stickyhttp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickyhttp"
+)
+
+func TestAdminHandler(t *testing.T) {
+	s := newSticky(t)
+	if err := s.Write(func(model) sticky.Event[model] { return addEvent{Value: 3} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	handler := stickyhttp.AdminHandler(s)
+
+	t.Run("version", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+		if got := strings.TrimSpace(rec.Body.String()); got != `{"version":3}` {
+			t.Errorf("got `%s`, expected version 3", got)
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+		var stats struct {
+			LastSeq       uint64 `json:"last_seq"`
+			EventsWritten uint64 `json:"events_written"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+			t.Fatalf("decoding `%s`: %v", rec.Body, err)
+		}
+
+		if stats.LastSeq != 3 || stats.EventsWritten != 1 {
+			t.Errorf("got %+v, expected last seq 3 and 1 written event", stats)
+		}
+	})
+
+	t.Run("events", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?from=2&type=add&limit=1", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+		}
+
+		var envelopes []sticky.Envelope
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var envelope sticky.Envelope
+			if err := json.Unmarshal(scanner.Bytes(), &envelope); err != nil {
+				t.Fatalf("decoding `%s`: %v", scanner.Text(), err)
+			}
+			envelopes = append(envelopes, envelope)
+		}
+
+		if len(envelopes) != 1 || envelopes[0].Seq != 2 || string(envelopes[0].Payload) != `{"value":2}` {
+			t.Errorf("got %v, expected the record with seq 2", envelopes)
+		}
+	})
+
+	t.Run("events to", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events?to=2", nil))
+
+		if lines := strings.Count(rec.Body.String(), "\n"); lines != 2 {
+			t.Errorf("got %d records, expected 2", lines)
+		}
+	})
+
+	t.Run("snapshot not supported", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/snapshot", nil))
+
+		if rec.Code != http.StatusNotImplemented {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusNotImplemented)
+		}
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/compact", nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("got status %d, expected %d", rec.Code, http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,12 @@
+module github.com/ostcar/sticky/stickyhttp
+
+go 1.21.1
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/ostcar/sticky v0.0.0-00010101000000-000000000000
+)
+
+require github.com/ostcar/topic v0.4.1 // indirect
+
+replace github.com/ostcar/sticky => ..
@@ -0,0 +1,269 @@
+// Package stickyhttp contains http handlers for sticky.
+package stickyhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ostcar/sticky"
+)
+
+const (
+	defaultHeartbeat = 30 * time.Second
+	defaultSendQueue = 16
+)
+
+// Option is a option for stickyhttp.NewSSEHandler()
+type Option func(c *config)
+
+type config struct {
+	payloads       bool
+	heartbeat      time.Duration
+	maxConnections int
+	sendQueue      int
+}
+
+// WithPayloads adds the payload of each event to the messages.
+func WithPayloads() Option {
+	return func(c *config) {
+		c.payloads = true
+	}
+}
+
+// WithHeartbeat sends a comment after each d without events, so proxies do
+// not close the connection. The WebSocket handler sends a ping each d.
+// Default is 30 seconds. 0 disables the heartbeat.
+func WithHeartbeat(d time.Duration) Option {
+	return func(c *config) {
+		c.heartbeat = d
+	}
+}
+
+// WithSendQueue sets the number of messages, the WebSocket handler buffers
+// for a client. If a client is to slow and the buffer is full, the connection
+// is closed. Default is 16.
+func WithSendQueue(n int) Option {
+	return func(c *config) {
+		c.sendQueue = n
+	}
+}
+
+// WithMaxConnections limits the number of open connections. Further
+// requests get the status 503. Default is no limit.
+func WithMaxConnections(n int) Option {
+	return func(c *config) {
+		c.maxConnections = n
+	}
+}
+
+// connectionLimit has a value for each open connection. It is nil, if there
+// is no limit.
+type connectionLimit chan struct{}
+
+func newConnectionLimit(max int) connectionLimit {
+	if max <= 0 {
+		return nil
+	}
+	return make(connectionLimit, max)
+}
+
+// acquire returns false, if there are to many connections. Otherwise release
+// has to be called, when the connection is closed.
+func (l connectionLimit) acquire() bool {
+	if l == nil {
+		return true
+	}
+
+	select {
+	case l <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l connectionLimit) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+// newConfig returns the config with the options.
+func newConfig(options []Option) config {
+	cfg := config{
+		heartbeat: defaultHeartbeat,
+		sendQueue: defaultSendQueue,
+	}
+	for _, o := range options {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// sseHandler is the handler of NewSSEHandler.
+type sseHandler[Model any] struct {
+	sticky      *sticky.Sticky[Model]
+	cfg         config
+	connections connectionLimit
+}
+
+// NewSSEHandler returns a handler, that streams the written events as
+// Server-Sent Events.
+//
+// Each message contains the events of one write. The id of the message is
+// the sequence number of the last event. The data is a JSON object like
+//
+//	{"events":[{"name":"add","seq":3,"time":"2024-01-01T12:00:00Z"}]}
+//
+// With WithPayloads, each event also has the field "payload".
+//
+// If the request has the header Last-Event-ID, the events after it are sent
+// first. See Sticky.SubscribeFrom. The stream ends, when the request is
+// canceled or the Sticky is closed. On other errors, a message with the
+// event "error" is sent before the stream ends.
+func NewSSEHandler[Model any](s *sticky.Sticky[Model], options ...Option) http.Handler {
+	cfg := newConfig(options)
+	return &sseHandler[Model]{
+		sticky:      s,
+		cfg:         cfg,
+		connections: newConnectionLimit(cfg.maxConnections),
+	}
+}
+
+func (h *sseHandler[Model]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	if !h.connections.acquire() {
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.connections.release()
+
+	var subscription func(yield func([]sticky.Notification[Model], error) bool)
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		seq, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		subscription = h.sticky.SubscribeFrom(r.Context(), seq+1)
+	} else {
+		subscription = h.sticky.Subscribe(r.Context())
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	batches := receive(r.Context(), subscription)
+
+	var heartbeat <-chan time.Time
+	if h.cfg.heartbeat > 0 {
+		ticker := time.NewTicker(h.cfg.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case b, ok := <-batches:
+			if !ok {
+				return
+			}
+
+			if b.err != nil {
+				// The message can not contain newlines.
+				data, _ := json.Marshal(b.err.Error())
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+				flusher.Flush()
+				return
+			}
+
+			data, err := encodeEvents(b.notifications, h.cfg.payloads)
+			if err != nil {
+				return
+			}
+
+			last := b.notifications[len(b.notifications)-1].Seq
+			if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", last, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// encodeEvents returns the JSON object of the notifications.
+func encodeEvents[Model any](notifications []sticky.Notification[Model], payloads bool) ([]byte, error) {
+	type event struct {
+		Name    string          `json:"name"`
+		Seq     uint64          `json:"seq"`
+		Time    time.Time       `json:"time"`
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}
+
+	events := make([]event, len(notifications))
+	for i, n := range notifications {
+		events[i] = event{Name: n.Name, Seq: n.Seq, Time: n.Time}
+		if payloads {
+			payload, err := json.Marshal(n.Event)
+			if err != nil {
+				return nil, fmt.Errorf("encoding event: %w", err)
+			}
+			events[i].Payload = payload
+		}
+	}
+
+	data, err := json.Marshal(struct {
+		Events []event `json:"events"`
+	}{events})
+	if err != nil {
+		return nil, fmt.Errorf("encoding message: %w", err)
+	}
+	return data, nil
+}
+
+// batch is a value of a subscription.
+type batch[Model any] struct {
+	notifications []sticky.Notification[Model]
+	err           error
+}
+
+// receive runs the subscription in a goroutine, so the handler can send
+// heartbeats while it waits for events. The channel is closed, when the
+// subscription ends.
+func receive[Model any](ctx context.Context, subscription func(yield func([]sticky.Notification[Model], error) bool)) <-chan batch[Model] {
+	batches := make(chan batch[Model])
+	go func() {
+		defer close(batches)
+
+		subscription(func(notifications []sticky.Notification[Model], err error) bool {
+			select {
+			case batches <- batch[Model]{notifications: notifications, err: err}:
+				return err == nil
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return batches
+}
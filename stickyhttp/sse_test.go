@@ -0,0 +1,149 @@
+package stickyhttp_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickyhttp"
+)
+
+type model struct {
+	Sum int
+}
+
+type addEvent struct {
+	Value int `json:"value"`
+}
+
+func (e addEvent) Name() string {
+	return "add"
+}
+
+func (e addEvent) Validate(m model) error {
+	return nil
+}
+
+func (e addEvent) Execute(m model, _ time.Time) model {
+	m.Sum += e.Value
+	return m
+}
+
+func getEvent(name string) sticky.Event[model] {
+	if name == "add" {
+		return &addEvent{}
+	}
+	return nil
+}
+
+func newSticky(t *testing.T) *sticky.Sticky[model] {
+	t.Helper()
+
+	db := sticky.NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+	)
+
+	s, err := sticky.New(db, model{}, getEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	return s
+}
+
+// readMessage returns the lines of the next message, that is not a comment.
+func readMessage(t *testing.T, scanner *bufio.Scanner) []string {
+	t.Helper()
+
+	var lines []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(lines) > 0 {
+				return lines
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+	t.Fatalf("stream ended: %v", scanner.Err())
+	return nil
+}
+
+func TestSSEHandler_last_event_id(t *testing.T) {
+	s := newSticky(t)
+	server := httptest.NewServer(stickyhttp.NewSSEHandler(s, stickyhttp.WithPayloads(), stickyhttp.WithHeartbeat(time.Millisecond)))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+	req.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("got content type %s, expected text/event-stream", ct)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	message := readMessage(t, scanner)
+	if len(message) != 2 || message[0] != "id: 2" || !strings.Contains(message[1], `"seq":2`) || !strings.Contains(message[1], `"payload":{"value":2}`) {
+		t.Errorf("got message %v, expected the second event", message)
+	}
+
+	if err := s.Write(func(model) sticky.Event[model] { return addEvent{Value: 3} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	message = readMessage(t, scanner)
+	if len(message) != 2 || message[0] != "id: 3" {
+		t.Errorf("got message %v, expected the written event", message)
+	}
+}
+
+func TestSSEHandler_max_connections(t *testing.T) {
+	s := newSticky(t)
+	server := httptest.NewServer(stickyhttp.NewSSEHandler(s, stickyhttp.WithMaxConnections(1)))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+
+	first, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	defer first.Body.Close()
+
+	second, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("second request: %v", err)
+	}
+	second.Body.Close()
+
+	if second.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, expected %d", second.StatusCode, http.StatusServiceUnavailable)
+	}
+}
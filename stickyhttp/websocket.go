@@ -0,0 +1,238 @@
+package stickyhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ostcar/sticky"
+)
+
+const (
+	// subscribeTimeout is the time, the client has to send the subscribe
+	// message.
+	subscribeTimeout = 10 * time.Second
+
+	// writeTimeout is the time for writing one frame.
+	writeTimeout = 10 * time.Second
+)
+
+// subscribeMessage is the first message of the client.
+type subscribeMessage struct {
+	Events []string `json:"events"`
+	From   uint64   `json:"from"`
+}
+
+// frame is a message for the client. If close is not nil, it is sent as
+// close message and the connection is closed.
+type frame struct {
+	data  []byte
+	close []byte
+}
+
+// wsHandler is the handler of NewWebSocketHandler.
+type wsHandler[Model any] struct {
+	sticky      *sticky.Sticky[Model]
+	cfg         config
+	connections connectionLimit
+	upgrader    websocket.Upgrader
+}
+
+// NewWebSocketHandler returns a handler, that sends the written events over a
+// WebSocket.
+//
+// After the connection is opened, the client has to send a subscribe message
+// like
+//
+//	{"events":["add"],"from":3}
+//
+// events are the names of the events, the client gets. Without names, it gets
+// all events. If from is set, the events starting with this sequence number
+// are sent first. See Sticky.SubscribeFrom.
+//
+// Each text message contains the events of one write like the data of
+// NewSSEHandler. If the client does not read fast enough, the connection is
+// closed with the code 1008. See WithSendQueue. When the Sticky is closed, the
+// connection is closed with the code 1001.
+//
+// The origin of the request is checked like with websocket.Upgrader.
+func NewWebSocketHandler[Model any](s *sticky.Sticky[Model], options ...Option) http.Handler {
+	cfg := newConfig(options)
+	return &wsHandler[Model]{
+		sticky:      s,
+		cfg:         cfg,
+		connections: newConnectionLimit(cfg.maxConnections),
+	}
+}
+
+func (h *wsHandler[Model]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.connections.acquire() {
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.connections.release()
+
+	// Upgrade writes the error to the client.
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var sub subscribeMessage
+	conn.SetReadDeadline(time.Now().Add(subscribeTimeout))
+	if err := conn.ReadJSON(&sub); err != nil {
+		writeClose(conn, closeMessage(websocket.ClosePolicyViolation, "invalid subscribe message"))
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// The client does not send more messages. Reading is necessary for the
+	// control messages and to notice, when the client closes the
+	// connection.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	queue := make(chan frame, h.cfg.sendQueue)
+	overflow := make(chan struct{})
+	go h.subscribe(ctx, sub, queue, overflow)
+
+	var ping <-chan time.Time
+	if h.cfg.heartbeat > 0 {
+		ticker := time.NewTicker(h.cfg.heartbeat)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-overflow:
+			writeClose(conn, closeMessage(websocket.ClosePolicyViolation, "client is to slow"))
+			return
+
+		case <-ping:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+
+		case f := <-queue:
+			if f.close != nil {
+				writeClose(conn, f.close)
+				return
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, f.data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe sends the events of the subscription to the queue. If the queue
+// is full, overflow is closed. When the subscription ends, a close frame is
+// sent.
+func (h *wsHandler[Model]) subscribe(ctx context.Context, sub subscribeMessage, queue chan<- frame, overflow chan<- struct{}) {
+	wanted := make(map[string]bool, len(sub.Events))
+	for _, name := range sub.Events {
+		wanted[name] = true
+	}
+
+	var subscription func(yield func([]sticky.Notification[Model], error) bool)
+	switch {
+	case sub.From > 0:
+		subscription = h.sticky.SubscribeFrom(ctx, sub.From)
+	case len(sub.Events) > 0:
+		subscription = h.sticky.SubscribeFor(ctx, sub.Events...)
+	default:
+		subscription = h.sticky.Subscribe(ctx)
+	}
+
+	var closing []byte
+	subscription(func(notifications []sticky.Notification[Model], err error) bool {
+		if err != nil {
+			closing = subscriptionClose(err)
+			return false
+		}
+
+		if len(wanted) > 0 {
+			notifications = filter(notifications, wanted)
+			if len(notifications) == 0 {
+				return true
+			}
+		}
+
+		data, err := encodeEvents(notifications, h.cfg.payloads)
+		if err != nil {
+			closing = closeMessage(websocket.CloseInternalServerErr, err.Error())
+			return false
+		}
+
+		select {
+		case queue <- frame{data: data}:
+			return true
+		default:
+			close(overflow)
+			return false
+		}
+	})
+
+	if closing == nil {
+		// The request is done or the queue is full.
+		return
+	}
+
+	select {
+	case queue <- frame{close: closing}:
+	case <-ctx.Done():
+	}
+}
+
+// subscriptionClose returns the close message for the error of a
+// subscription.
+func subscriptionClose(err error) []byte {
+	if errors.Is(err, sticky.ErrClosed) {
+		return closeMessage(websocket.CloseGoingAway, err.Error())
+	}
+	return closeMessage(websocket.CloseInternalServerErr, err.Error())
+}
+
+// filter returns the notifications with one of the names.
+func filter[Model any](notifications []sticky.Notification[Model], names map[string]bool) []sticky.Notification[Model] {
+	var filtered []sticky.Notification[Model]
+	for _, n := range notifications {
+		if names[n.Name] {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+// writeClose sends the close message to the client.
+func writeClose(conn *websocket.Conn, message []byte) {
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeTimeout))
+}
+
+// closeMessage returns a close message. The text is shortened to the maximum
+// size of a control message.
+func closeMessage(code int, text string) []byte {
+	const maxText = 123
+	if len(text) > maxText {
+		text = text[:maxText]
+	}
+	return websocket.FormatCloseMessage(code, text)
+}
@@ -0,0 +1,113 @@
+package stickyhttp_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickyhttp"
+)
+
+// wsClient is an example client for NewWebSocketHandler.
+type wsClient struct {
+	conn *websocket.Conn
+}
+
+// dial opens the connection and sends the subscribe message.
+func dial(t *testing.T, url string, events []string, from uint64) *wsClient {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	subscribe := struct {
+		Events []string `json:"events"`
+		From   uint64   `json:"from,omitempty"`
+	}{events, from}
+
+	if err := conn.WriteJSON(subscribe); err != nil {
+		t.Fatalf("sending subscribe message: %v", err)
+	}
+	return &wsClient{conn: conn}
+}
+
+// next returns the sequence numbers of the next message.
+func (c *wsClient) next() ([]uint64, error) {
+	var message struct {
+		Events []struct {
+			Name string `json:"name"`
+			Seq  uint64 `json:"seq"`
+		} `json:"events"`
+	}
+
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, err
+	}
+
+	seqs := make([]uint64, len(message.Events))
+	for i, e := range message.Events {
+		seqs[i] = e.Seq
+	}
+	return seqs, nil
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	s := newSticky(t)
+	server := httptest.NewServer(stickyhttp.NewWebSocketHandler(s))
+	defer server.Close()
+
+	client := dial(t, server.URL, []string{"add"}, 2)
+
+	seqs, err := client.next()
+	if err != nil {
+		t.Fatalf("reading first message: %v", err)
+	}
+	if len(seqs) != 1 || seqs[0] != 2 {
+		t.Errorf("got seqs %v, expected [2]", seqs)
+	}
+
+	if err := s.Write(func(model) sticky.Event[model] { return addEvent{Value: 3} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	seqs, err = client.next()
+	if err != nil {
+		t.Fatalf("reading second message: %v", err)
+	}
+	if len(seqs) != 1 || seqs[0] != 3 {
+		t.Errorf("got seqs %v, expected [3]", seqs)
+	}
+}
+
+func TestWebSocketHandler_sticky_closed(t *testing.T) {
+	s := newSticky(t)
+	server := httptest.NewServer(stickyhttp.NewWebSocketHandler(s))
+	defer server.Close()
+
+	client := dial(t, server.URL, nil, 1)
+	if _, err := client.next(); err != nil {
+		t.Fatalf("reading first message: %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("closing sticky: %v", err)
+	}
+
+	_, err := client.next()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Errorf("got error `%v`, expected close code %d", err, websocket.CloseGoingAway)
+	}
+}
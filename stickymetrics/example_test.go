@@ -0,0 +1,27 @@
+package stickymetrics_test
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickymetrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func Example() {
+	collector := stickymetrics.New[model]("accounts")
+
+	s, err := sticky.New(sticky.NewMemoryDB(), model{}, getEvent, collector.Options()...)
+	if err != nil {
+		log.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+	collector.Watch(s)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,165 @@
+// Package stickymetrics exports metrics of a sticky instance to prometheus.
+//
+// The Collector gets the write metrics with the options of Options and the
+// other metrics with Sticky.Stats.
+package stickymetrics
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ostcar/sticky"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// durationBuckets are the buckets for the write latency and the lock wait.
+// Writes to a local file take a few microseconds.
+var durationBuckets = prometheus.ExponentialBuckets(0.00001, 4, 10)
+
+// Collector is a prometheus.Collector for a sticky instance.
+//
+// The event type of the metrics is the value of the Name method of the
+// event.
+type Collector[Model any] struct {
+	mu     sync.RWMutex
+	sticky *sticky.Sticky[Model]
+
+	writes       *prometheus.CounterVec
+	failures     *prometheus.CounterVec
+	writeLatency prometheus.Histogram
+	lockWait     prometheus.Histogram
+
+	version       *prometheus.Desc
+	size          *prometheus.Desc
+	sinceSnapshot *prometheus.Desc
+}
+
+// New creates a collector. name is added to all metrics as label `sticky`, so
+// more then one sticky instance can be registered.
+//
+// The options of Options have to be used with sticky.New, and the sticky
+// instance has to be set with Watch.
+func New[Model any](name string) *Collector[Model] {
+	labels := prometheus.Labels{"sticky": name}
+
+	return &Collector[Model]{
+		writes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "sticky_events_written_total",
+			Help:        "Number of written events.",
+			ConstLabels: labels,
+		}, []string{"event"}),
+
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "sticky_write_failures_total",
+			Help:        "Number of events, that failed validation or execution.",
+			ConstLabels: labels,
+		}, []string{"event", "reason"}),
+
+		writeLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "sticky_write_duration_seconds",
+			Help:        "Duration of writes without waiting for the lock.",
+			ConstLabels: labels,
+			Buckets:     durationBuckets,
+		}),
+
+		lockWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "sticky_lock_wait_seconds",
+			Help:        "Time, writes waited for the write lock.",
+			ConstLabels: labels,
+			Buckets:     durationBuckets,
+		}),
+
+		version:       prometheus.NewDesc("sticky_version", "Version of the model.", nil, labels),
+		size:          prometheus.NewDesc("sticky_log_size_bytes", "Size of the database.", nil, labels),
+		sinceSnapshot: prometheus.NewDesc("sticky_events_since_snapshot", "Number of events after the last snapshot.", nil, labels),
+	}
+}
+
+// Options returns the options for sticky.New, that collect the write
+// metrics.
+func (c *Collector[Model]) Options() []sticky.Option[Model] {
+	return []sticky.Option[Model]{
+		sticky.WithWriteMiddleware(c.middleware),
+		sticky.WithAfterWrite(c.afterWrite),
+		sticky.WithLockWait[Model](c.observeLockWait),
+	}
+}
+
+// Watch sets the sticky instance for the metrics from Sticky.Stats. Without
+// it, only the write metrics are collected.
+func (c *Collector[Model]) Watch(s *sticky.Sticky[Model]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sticky = s
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector[Model]) Describe(ch chan<- *prometheus.Desc) {
+	c.writes.Describe(ch)
+	c.failures.Describe(ch)
+	c.writeLatency.Describe(ch)
+	c.lockWait.Describe(ch)
+	ch <- c.version
+	ch <- c.size
+	ch <- c.sinceSnapshot
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector[Model]) Collect(ch chan<- prometheus.Metric) {
+	c.writes.Collect(ch)
+	c.failures.Collect(ch)
+	c.writeLatency.Collect(ch)
+	c.lockWait.Collect(ch)
+
+	c.mu.RLock()
+	s := c.sticky
+	c.mu.RUnlock()
+
+	if s == nil {
+		return
+	}
+
+	stats := s.Stats()
+	ch <- prometheus.MustNewConstMetric(c.version, prometheus.GaugeValue, float64(stats.Version))
+	ch <- prometheus.MustNewConstMetric(c.sinceSnapshot, prometheus.GaugeValue, float64(stats.EventsSinceSnapshot))
+	if stats.Size >= 0 {
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+	}
+}
+
+// middleware measures the duration of each write and counts the failed
+// events.
+func (c *Collector[Model]) middleware(next sticky.WriteFunc[Model]) sticky.WriteFunc[Model] {
+	return func(events ...sticky.Event[Model]) error {
+		start := time.Now()
+		err := next(events...)
+		c.writeLatency.Observe(time.Since(start).Seconds())
+
+		var validationErr sticky.ValidationError
+		var executionErr sticky.ExecutionError
+		switch {
+		case errors.As(err, &validationErr):
+			c.failures.WithLabelValues(validationErr.EventName, "validation").Inc()
+		case errors.As(err, &executionErr):
+			c.failures.WithLabelValues(executionErr.EventName, "execution").Inc()
+		}
+		return err
+	}
+}
+
+// afterWrite counts the written events.
+//
+// The events are counted after the write and not in the middleware, since
+// WithBeforeWrite can change them.
+func (c *Collector[Model]) afterWrite(events []sticky.AppliedEvent[Model], _ Model) {
+	for _, event := range events {
+		c.writes.WithLabelValues(event.Event.Name()).Inc()
+	}
+}
+
+// observeLockWait is called by sticky with the time a write waited for the
+// lock.
+func (c *Collector[Model]) observeLockWait(wait time.Duration) {
+	c.lockWait.Observe(wait.Seconds())
+}
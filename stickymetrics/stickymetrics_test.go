@@ -0,0 +1,101 @@
+package stickymetrics_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickymetrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type model struct {
+	Sum int
+}
+
+type addEvent struct {
+	Value int `json:"value"`
+}
+
+func (e addEvent) Name() string {
+	return "add"
+}
+
+func (e addEvent) Validate(m model) error {
+	if e.Value < 0 {
+		return errors.New("value must not be negative")
+	}
+	return nil
+}
+
+func (e addEvent) Execute(m model, _ time.Time) model {
+	m.Sum += e.Value
+	return m
+}
+
+func getEvent(name string) sticky.Event[model] {
+	if name == "add" {
+		return &addEvent{}
+	}
+	return nil
+}
+
+func TestCollector(t *testing.T) {
+	collector := stickymetrics.New[model]("test")
+
+	s, err := sticky.New(sticky.NewMemoryDB(), model{}, getEvent, collector.Options()...)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	collector.Watch(s)
+
+	for _, value := range []int{1, 2, -1} {
+		_ = s.Write(func(model) sticky.Event[model] { return addEvent{Value: value} })
+	}
+
+	size := s.Stats().Size
+	expected := `
+# HELP sticky_events_since_snapshot Number of events after the last snapshot.
+# TYPE sticky_events_since_snapshot gauge
+sticky_events_since_snapshot{sticky="test"} 2
+# HELP sticky_events_written_total Number of written events.
+# TYPE sticky_events_written_total counter
+sticky_events_written_total{event="add",sticky="test"} 2
+# HELP sticky_log_size_bytes Size of the database.
+# TYPE sticky_log_size_bytes gauge
+sticky_log_size_bytes{sticky="test"} ` + strconv.FormatInt(size, 10) + `
+# HELP sticky_version Version of the model.
+# TYPE sticky_version gauge
+sticky_version{sticky="test"} 2
+# HELP sticky_write_failures_total Number of events, that failed validation or execution.
+# TYPE sticky_write_failures_total counter
+sticky_write_failures_total{event="add",reason="validation",sticky="test"} 1
+`
+
+	if err := testutil.CollectAndCompare(
+		collector,
+		strings.NewReader(expected),
+		"sticky_events_since_snapshot",
+		"sticky_events_written_total",
+		"sticky_log_size_bytes",
+		"sticky_version",
+		"sticky_write_failures_total",
+	); err != nil {
+		t.Error(err)
+	}
+
+	if got := testutil.CollectAndCount(collector, "sticky_write_duration_seconds", "sticky_lock_wait_seconds"); got != 2 {
+		t.Errorf("got %d histograms, expected 2", got)
+	}
+}
+
+func TestCollector_without_watch(t *testing.T) {
+	collector := stickymetrics.New[model]("test")
+
+	if got := testutil.CollectAndCount(collector, "sticky_version"); got != 0 {
+		t.Errorf("got %d version metrics, expected none without a sticky instance", got)
+	}
+}
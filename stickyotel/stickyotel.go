@@ -0,0 +1,91 @@
+// Package stickyotel creates OpenTelemetry spans for the load, the writes,
+// the snapshots and the compactions of sticky.
+//
+// Use WriteCtx or ForWritingCtx, so the spans of the writes are children of
+// the span of the caller.
+package stickyotel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ostcar/sticky"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the tracer, that creates the spans.
+const tracerName = "github.com/ostcar/sticky/stickyotel"
+
+// Option is a option for stickyotel.WithTracing()
+type Option func(c *config)
+
+type config struct {
+	provider trace.TracerProvider
+}
+
+// WithTracerProvider uses a special tracer provider. Default is the global
+// provider from otel.GetTracerProvider().
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithTracing returns the option for sticky.New, that creates the spans.
+//
+// The spans are called `sticky.load`, `sticky.write`, `sticky.snapshot` and
+// `sticky.compact`. The spans of load and write have the number of events as
+// attribute `sticky.events`. The spans of write also have the names of the
+// events as `sticky.event_names` and the result of the validation as
+// `sticky.validation`.
+func WithTracing[Model any](options ...Option) sticky.Option[Model] {
+	c := config{
+		provider: otel.GetTracerProvider(),
+	}
+	for _, o := range options {
+		o(&c)
+	}
+
+	tracer := c.provider.Tracer(tracerName)
+
+	return sticky.WithTrace[Model](func(ctx context.Context, operation string) (context.Context, func(sticky.TraceResult)) {
+		ctx, span := tracer.Start(ctx, "sticky."+operation)
+		return ctx, func(result sticky.TraceResult) {
+			end(span, operation, result)
+		}
+	})
+}
+
+// end sets the attributes of the result and ends the span.
+func end(span trace.Span, operation string, result sticky.TraceResult) {
+	defer span.End()
+
+	switch operation {
+	case "load":
+		span.SetAttributes(attribute.Int("sticky.events", result.Count))
+
+	case "write":
+		span.SetAttributes(
+			attribute.Int("sticky.events", result.Count),
+			attribute.StringSlice("sticky.event_names", result.Events),
+			attribute.String("sticky.validation", validation(result.Err)),
+		)
+	}
+
+	if result.Err != nil {
+		span.RecordError(result.Err)
+		span.SetStatus(codes.Error, result.Err.Error())
+	}
+}
+
+// validation returns the result of the validation of a write.
+func validation(err error) string {
+	var validationErr sticky.ValidationError
+	if errors.As(err, &validationErr) {
+		return "failed"
+	}
+	return "ok"
+}
@@ -0,0 +1,108 @@
+package stickyotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickyotel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type model struct {
+	Sum int
+}
+
+type addEvent struct {
+	Value int `json:"value"`
+}
+
+func (e addEvent) Name() string {
+	return "add"
+}
+
+func (e addEvent) Validate(m model) error {
+	if e.Value < 0 {
+		return errors.New("value must not be negative")
+	}
+	return nil
+}
+
+func (e addEvent) Execute(m model, _ time.Time) model {
+	m.Sum += e.Value
+	return m
+}
+
+func getEvent(name string) sticky.Event[model] {
+	if name == "add" {
+		return &addEvent{}
+	}
+	return nil
+}
+
+func TestWithTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	db := sticky.NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)
+	s, err := sticky.New(db, model{}, getEvent, stickyotel.WithTracing[model](stickyotel.WithTracerProvider(provider)))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "handler")
+	if err := s.WriteCtx(ctx, func(model) sticky.Event[model] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	parent.End()
+
+	if err := s.Write(func(model) sticky.Event[model] { return addEvent{Value: -1} }); err == nil {
+		t.Fatalf("invalid write did not return an error")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans, expected 4", len(spans))
+	}
+
+	load := spans[0]
+	if load.Name() != "sticky.load" || !hasAttribute(load.Attributes(), attribute.Int("sticky.events", 1)) {
+		t.Errorf("got span %s with %v, expected sticky.load with one event", load.Name(), load.Attributes())
+	}
+
+	write := spans[1]
+	if write.Name() != "sticky.write" {
+		t.Fatalf("got span %s, expected sticky.write", write.Name())
+	}
+
+	if write.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("write span is not a child of the span from the context")
+	}
+
+	if !hasAttribute(write.Attributes(), attribute.StringSlice("sticky.event_names", []string{"add"})) {
+		t.Errorf("got attributes %v, expected the event names", write.Attributes())
+	}
+
+	failed := spans[3]
+	if !hasAttribute(failed.Attributes(), attribute.String("sticky.validation", "failed")) {
+		t.Errorf("got attributes %v, expected a failed validation", failed.Attributes())
+	}
+
+	if failed.Status().Code != codes.Error {
+		t.Errorf("got status %v, expected an error", failed.Status())
+	}
+}
+
+func hasAttribute(attributes []attribute.KeyValue, expected attribute.KeyValue) bool {
+	for _, attr := range attributes {
+		if attr.Key == expected.Key && attr.Value.Emit() == expected.Value.Emit() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package stickytest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickytest"
+)
+
+type model struct {
+	Times []time.Time
+}
+
+type tickEvent struct{}
+
+func (e tickEvent) Name() string {
+	return "tick"
+}
+
+func (e tickEvent) Validate(model) error {
+	return nil
+}
+
+func (e tickEvent) Execute(m model, t time.Time) model {
+	m.Times = append(m.Times, t)
+	return m
+}
+
+func getEvent(name string) sticky.Event[model] {
+	if name == "tick" {
+		return &tickEvent{}
+	}
+	return nil
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := stickytest.NewClock(start)
+
+	s, err := sticky.New(sticky.NewMemoryDB(), model{}, getEvent, sticky.WithClock[model](clock))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Write(func(model) sticky.Event[model] { return tickEvent{} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		clock.Advance(time.Hour)
+	}
+
+	m, done := s.ForReading()
+	done()
+
+	if len(m.Times) != 2 || !m.Times[0].Equal(start) || !m.Times[1].Equal(start.Add(time.Hour)) {
+		t.Errorf("got times %v, expected %s and one hour later", m.Times, start)
+	}
+
+	clock.Set(start)
+	if got := clock.Now(); !got.Equal(start) {
+		t.Errorf("got %s after set, expected %s", got, start)
+	}
+}
@@ -0,0 +1,143 @@
+package stickytest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ostcar/sticky"
+)
+
+// The flag -update writes the golden files instead of comparing them. It is
+// only registered, if no other package defined it before.
+func init() {
+	if flag.Lookup("update") == nil {
+		flag.Bool("update", false, "update the golden files of stickytest.Golden")
+	}
+}
+
+// update returns the value of the flag -update.
+func update() bool {
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	v, _ := getter.Get().(bool)
+	return v
+}
+
+// encryptedValue matches a field, that was encrypted by sticky.WithShredding.
+var encryptedValue = regexp.MustCompile(`"[0-9a-f]{8}:[A-Za-z0-9+/]+"`)
+
+// Golden compares the log of the harness with the golden file at path. On a
+// difference, the test fails with a diff of the lines. Run the test with
+// -update to write the golden file.
+//
+// With the clock of the harness, the log is deterministic. Only encrypted
+// fields, that use a random nonce, are replaced with "<encrypted>".
+func Golden[Model any](t testing.TB, h *Harness[Model], path string) {
+	t.Helper()
+
+	got, err := goldenLog(h.DB.Records())
+	if err != nil {
+		t.Fatalf("normalizing log: %v", err)
+	}
+
+	if update() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("creating directory of golden file: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("golden file %s does not exist, run the test with -update to create it", path)
+		}
+		t.Fatalf("reading golden file: %v", err)
+	}
+
+	if !bytes.Equal(got, expected) {
+		t.Errorf("log differs from golden file %s (-golden +got):\n%s", path, diffLines(string(expected), string(got)))
+	}
+}
+
+// goldenLog returns the records as lines.
+func goldenLog(records [][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, record := range records {
+		record = bytes.TrimSpace(record)
+		if len(record) == 0 {
+			continue
+		}
+
+		e, err := sticky.DecodeEnvelope(record)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+
+		if len(e.Encrypted) > 0 {
+			record = encryptedValue.ReplaceAll(record, []byte(`"<encrypted>"`))
+		}
+
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// diffLines returns the lines, that are only in one of the texts. Lines only
+// in a start with "-", lines only in b with "+".
+func diffLines(a, b string) string {
+	as := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	bs := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of as[i:]
+	// and bs[j:].
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			if as[i] == bs[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(as) || j < len(bs) {
+		switch {
+		case i < len(as) && j < len(bs) && as[i] == bs[j]:
+			fmt.Fprintf(&sb, "  %s\n", as[i])
+			i++
+			j++
+		case j < len(bs) && (i == len(as) || lcs[i][j+1] >= lcs[i+1][j]):
+			fmt.Fprintf(&sb, "+ %s\n", bs[j])
+			j++
+		default:
+			fmt.Fprintf(&sb, "- %s\n", as[i])
+			i++
+		}
+	}
+	return sb.String()
+}
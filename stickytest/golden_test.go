@@ -0,0 +1,46 @@
+package stickytest_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky/stickytest"
+)
+
+func TestGolden(t *testing.T) {
+	h := stickytest.New(t, counter{}, getCounterEvent)
+
+	h.Given(addEvent{Amount: 1})
+	h.AdvanceClock(time.Minute)
+	h.Given(addEvent{Amount: 2})
+
+	stickytest.Golden(t, h, filepath.Join("testdata", "counter.jsonl"))
+}
+
+func TestGolden_diff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.jsonl")
+	golden := `{"time":"2024-01-01 00:00:00","type":"add","seq":1,"payload":{"amount":1}}` + "\n" +
+		`{"time":"2024-01-01 00:00:00","type":"add","seq":2,"payload":{"amount":5}}` + "\n"
+	if err := os.WriteFile(path, []byte(golden), 0o644); err != nil {
+		t.Fatalf("writing golden file: %v", err)
+	}
+
+	r := &recorder{TB: t}
+	h := stickytest.New[counter](r, counter{}, getCounterEvent)
+	h.Given(addEvent{Amount: 1}, addEvent{Amount: 2})
+
+	stickytest.Golden[counter](r, h, path)
+
+	if len(r.errors) != 1 {
+		t.Fatalf("got errors %q, expected one", r.errors)
+	}
+
+	for _, expect := range []string{"- " + `{"time":"2024-01-01 00:00:00","type":"add","seq":2,"payload":{"amount":5}}`, "+ ", `"amount":2`} {
+		if !strings.Contains(r.errors[0], expect) {
+			t.Errorf("got diff `%s`, expected it to contain `%s`", r.errors[0], expect)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package stickytest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky"
+)
+
+// Start is the time of the clock of a new Harness.
+var Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Harness is a Sticky instance for tests. It uses a MemoryDB and a Clock, that
+// starts at Start.
+//
+// The methods fail the test on errors.
+type Harness[Model any] struct {
+	*sticky.Sticky[Model]
+
+	DB    *sticky.MemoryDB
+	Clock *Clock
+
+	t        testing.TB
+	newModel func() Model
+	getEvent func(name string) sticky.Event[Model]
+	options  []sticky.Option[Model]
+}
+
+// New returns a Harness for the model. The options are used for the Sticky
+// instance and also, when the log is loaded again by ReplayCheck.
+//
+// The Sticky instance is closed, when the test ends.
+//
+// ReplayCheck starts each replay from empty. If the model contains maps or
+// slices, it has to implement sticky.Cloner or the Harness has to be created
+// with NewFunc. Otherwise the replay changes the maps of the live model and
+// ReplayCheck can not find a difference.
+func New[Model any](t testing.TB, empty Model, getEvent func(name string) sticky.Event[Model], options ...sticky.Option[Model]) *Harness[Model] {
+	t.Helper()
+
+	return NewFunc(t, func() Model { return empty }, getEvent, options...)
+}
+
+// NewFunc is like New, but gets the empty model from newModel. It is called
+// for the Sticky instance and for each replay of ReplayCheck.
+func NewFunc[Model any](t testing.TB, newModel func() Model, getEvent func(name string) sticky.Event[Model], options ...sticky.Option[Model]) *Harness[Model] {
+	t.Helper()
+
+	h := &Harness[Model]{
+		DB:       sticky.NewMemoryDB(),
+		Clock:    NewClock(Start),
+		t:        t,
+		newModel: newModel,
+		getEvent: getEvent,
+	}
+	h.options = append([]sticky.Option[Model]{sticky.WithClock[Model](h.Clock)}, options...)
+
+	s, err := sticky.New(h.DB, newModel(), getEvent, h.options...)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	h.Sticky = s
+	return h
+}
+
+// AdvanceClock moves the clock forward by d.
+func (h *Harness[Model]) AdvanceClock(d time.Duration) time.Time {
+	return h.Clock.Advance(d)
+}
+
+// Model returns the current model.
+func (h *Harness[Model]) Model() Model {
+	model, done := h.ForReading()
+	defer done()
+	return model
+}
+
+// EventNames returns the names of all events in the log. Snapshots are not
+// returned.
+func (h *Harness[Model]) EventNames() []string {
+	h.t.Helper()
+
+	r, err := h.DB.Reader()
+	if err != nil {
+		h.t.Fatalf("reading database: %v", err)
+	}
+	defer r.Close()
+
+	names := []string{}
+	sticky.ReadEvents(context.Background(), r)(func(e sticky.Envelope, err error) bool {
+		if err != nil {
+			h.t.Fatalf("reading events: %v", err)
+		}
+		names = append(names, e.Type)
+		return true
+	})
+	return names
+}
+
+// AssertEvents fails the test, if the log does not contain exactly the events
+// with the names, in this order.
+func (h *Harness[Model]) AssertEvents(names ...string) {
+	h.t.Helper()
+
+	if got := h.EventNames(); !slices.Equal(got, names) {
+		h.t.Errorf("got events %v, expected %v", got, names)
+	}
+}
+
+// AssertModel fails the test, if check returns false for the current model.
+func (h *Harness[Model]) AssertModel(check func(Model) bool) {
+	h.t.Helper()
+
+	if model := h.Model(); !check(model) {
+		h.t.Errorf("model %+v does not match", model)
+	}
+}
+
+// ReplayCheck loads the log into a new model and fails the test, if it is not
+// the same as the current model. The models are compared with
+// reflect.DeepEqual. Use ReplayCheckFunc for models with unexported state.
+func (h *Harness[Model]) ReplayCheck() {
+	h.t.Helper()
+
+	h.ReplayCheckFunc(func(a, b Model) bool { return reflect.DeepEqual(a, b) })
+}
+
+// ReplayCheckFunc is like ReplayCheck but compares the models with equal.
+func (h *Harness[Model]) ReplayCheckFunc(equal func(live, replayed Model) bool) {
+	h.t.Helper()
+
+	records := h.DB.Records()
+	lines := make([]string, len(records))
+	for i, record := range records {
+		lines[i] = string(record)
+	}
+
+	replayed, err := sticky.NewReadOnly(sticky.NewMemoryDB(lines...), h.newModel(), h.getEvent, h.options...)
+	if err != nil {
+		h.t.Fatalf("loading the log: %v", err)
+	}
+	defer replayed.Close()
+
+	model, done := replayed.ForReading()
+	done()
+
+	if live := h.Model(); !equal(live, model) {
+		h.t.Errorf("replayed model %+v differs from live model %+v", model, live)
+	}
+}
+
+// Scenario is a test in the form given, when, then. Create it with
+// Harness.Given.
+type Scenario[Model any] struct {
+	h       *Harness[Model]
+	written int
+	err     error
+}
+
+// Given writes the events. They are the state before the scenario.
+func (h *Harness[Model]) Given(events ...sticky.Event[Model]) *Scenario[Model] {
+	h.t.Helper()
+
+	if len(events) > 0 {
+		err := h.WriteMany(func(Model) ([]sticky.Event[Model], error) {
+			return events, nil
+		})
+		if err != nil {
+			h.t.Fatalf("writing the given events: %v", err)
+		}
+	}
+
+	return &Scenario[Model]{h: h, written: len(h.EventNames())}
+}
+
+// When writes the event returned by f like Sticky.Write. It can be called
+// more then once. After an error, the next calls do nothing.
+//
+// An error is not a failure. Check it with ThenError.
+func (sc *Scenario[Model]) When(f func(Model) sticky.Event[Model]) *Scenario[Model] {
+	if sc.err == nil {
+		sc.err = sc.h.Write(f)
+	}
+	return sc
+}
+
+// Then fails the test, if the write function returned an error or if other
+// events then names where written by it.
+func (sc *Scenario[Model]) Then(names ...string) *Scenario[Model] {
+	sc.h.t.Helper()
+
+	if sc.err != nil {
+		sc.h.t.Errorf("when returned: %v", sc.err)
+		return sc
+	}
+
+	got := sc.h.EventNames()[sc.written:]
+	if !slices.Equal(got, names) {
+		sc.h.t.Errorf("got events %v, expected %v", got, names)
+	}
+	return sc
+}
+
+// ThenError fails the test, if the write function did not return an error
+// that matches target with errors.Is. A nil target expects any error.
+func (sc *Scenario[Model]) ThenError(target error) *Scenario[Model] {
+	sc.h.t.Helper()
+
+	switch {
+	case sc.err == nil:
+		sc.h.t.Errorf("when returned no error")
+	case target != nil && !errors.Is(sc.err, target):
+		sc.h.t.Errorf("got error `%v`, expected `%v`", sc.err, target)
+	}
+	return sc
+}
+
+// ThenModel fails the test, if check returns false for the current model.
+func (sc *Scenario[Model]) ThenModel(check func(Model) bool) *Scenario[Model] {
+	sc.h.t.Helper()
+
+	sc.h.AssertModel(check)
+	return sc
+}
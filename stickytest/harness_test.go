@@ -0,0 +1,149 @@
+package stickytest_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ostcar/sticky"
+	"github.com/ostcar/sticky/stickytest"
+)
+
+type counter struct {
+	Sum  int
+	Last time.Time
+}
+
+var errNotPositive = errors.New("amount has to be positive")
+
+type addEvent struct {
+	Amount int `json:"amount"`
+}
+
+func (e addEvent) Name() string {
+	return "add"
+}
+
+func (e addEvent) Validate(counter) error {
+	if e.Amount <= 0 {
+		return errNotPositive
+	}
+	return nil
+}
+
+func (e addEvent) Execute(m counter, t time.Time) counter {
+	m.Sum += e.Amount
+	m.Last = t
+	return m
+}
+
+func getCounterEvent(name string) sticky.Event[counter] {
+	if name == "add" {
+		return &addEvent{}
+	}
+	return nil
+}
+
+func add(amount int) func(counter) sticky.Event[counter] {
+	return func(counter) sticky.Event[counter] { return addEvent{Amount: amount} }
+}
+
+func TestHarness(t *testing.T) {
+	h := stickytest.New(t, counter{}, getCounterEvent)
+
+	if err := h.Write(add(1)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	later := h.AdvanceClock(time.Hour)
+	if err := h.Write(add(2)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	h.AssertEvents("add", "add")
+	h.AssertModel(func(m counter) bool { return m.Sum == 3 && m.Last.Equal(later) })
+	h.ReplayCheck()
+}
+
+func TestScenario(t *testing.T) {
+	h := stickytest.New(t, counter{}, getCounterEvent)
+
+	h.Given(addEvent{Amount: 1}, addEvent{Amount: 2}).
+		When(add(3)).
+		Then("add").
+		ThenModel(func(m counter) bool { return m.Sum == 6 })
+
+	h.Given().
+		When(add(-1)).
+		ThenError(errNotPositive)
+
+	h.AssertEvents("add", "add", "add")
+	h.ReplayCheck()
+}
+
+// recorder is a testing.TB, that records errors.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestHarness_failures(t *testing.T) {
+	r := &recorder{TB: t}
+	h := stickytest.New[counter](r, counter{}, getCounterEvent)
+
+	h.Given(addEvent{Amount: 1}).When(add(2)).Then("add", "add")
+	h.AssertModel(func(m counter) bool { return m.Sum == 1 })
+	h.Given().When(add(1)).ThenError(nil)
+	h.ReplayCheckFunc(func(live, replayed counter) bool { return false })
+
+	if len(r.errors) != 4 {
+		t.Errorf("got errors %q, expected 4", r.errors)
+	}
+}
+
+type keys struct {
+	Values map[string]int
+}
+
+// setEvent sets the key to the next value of a counter, that is not part of
+// the model. So replaying it gives a different model.
+type setEvent struct {
+	Key string `json:"key"`
+}
+
+var setCalls int
+
+func (e setEvent) Name() string {
+	return "set"
+}
+
+func (e setEvent) Validate(keys) error {
+	return nil
+}
+
+func (e setEvent) Execute(m keys, _ time.Time) keys {
+	setCalls++
+	m.Values[e.Key] = setCalls
+	return m
+}
+
+func TestHarness_replay_check_non_deterministic(t *testing.T) {
+	getEvent := func(string) sticky.Event[keys] { return &setEvent{} }
+	newKeys := func() keys { return keys{Values: make(map[string]int)} }
+
+	r := &recorder{TB: t}
+	h := stickytest.NewFunc[keys](r, newKeys, getEvent)
+
+	if err := h.Write(func(keys) sticky.Event[keys] { return setEvent{Key: "a"} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	h.ReplayCheck()
+
+	if len(r.errors) != 1 {
+		t.Errorf("got errors %q, expected the replayed model to differ", r.errors)
+	}
+}
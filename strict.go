@@ -0,0 +1,97 @@
+package sticky
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// EventDefinitionError happens with WithStrictEvents, when a written event
+// could not be loaded as the same event.
+type EventDefinitionError struct {
+	Name string
+	Err  error
+}
+
+func (err EventDefinitionError) Error() string {
+	return fmt.Sprintf("event `%s` can not be loaded: %v", err.Name, err.Err)
+}
+
+func (err EventDefinitionError) Unwrap() error {
+	return err.Err
+}
+
+// checkRegistered returns ErrUnregisteredEvent, if getEvent does not know
+// the name of the event. Each name is only checked once.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) checkRegistered(event Event[Model]) error {
+	name := s.eventName(event)
+	if s.registered[name] {
+		return nil
+	}
+
+	if s.loader.getEvent(name) == nil {
+		return ErrUnregisteredEvent{Name: name, Type: fmt.Sprintf("%T", event)}
+	}
+
+	if s.registered == nil {
+		s.registered = make(map[string]bool)
+	}
+	s.registered[name] = true
+	return nil
+}
+
+// checkStrict checks, that the event can be loaded from its name and
+// payload. Each type is only checked once.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) checkStrict(event Event[Model], payload []byte) error {
+	eventType := reflect.TypeOf(event)
+	if s.strictChecked[eventType] {
+		return nil
+	}
+
+	name := s.eventName(event)
+	loaded := s.loader.getEvent(name)
+	if loaded == nil {
+		return EventDefinitionError{Name: name, Err: errors.New("getEvent returns nil for the name")}
+	}
+
+	if got, expected := indirectType(reflect.TypeOf(loaded)), indirectType(eventType); got != expected {
+		return EventDefinitionError{Name: name, Err: fmt.Errorf("getEvent returns %s for the name, written event is %s", got, expected)}
+	}
+
+	if got := s.eventName(loaded); got != name {
+		return EventDefinitionError{Name: name, Err: fmt.Errorf("getEvent returns an event with the name `%s`", got)}
+	}
+
+	if s.strictRoundTrip {
+		if err := json.Unmarshal(payload, &loaded); err != nil {
+			return EventDefinitionError{Name: name, Err: fmt.Errorf("decoding payload `%s`: %w", payload, err)}
+		}
+
+		if loaded == nil {
+			return EventDefinitionError{Name: name, Err: errors.New("payload is null")}
+		}
+
+		if !reflect.DeepEqual(reflect.Indirect(reflect.ValueOf(loaded)).Interface(), reflect.Indirect(reflect.ValueOf(event)).Interface()) {
+			return EventDefinitionError{Name: name, Err: fmt.Errorf("payload `%s` decodes to %+v, written event is %+v, check for unexported fields", payload, loaded, event)}
+		}
+	}
+
+	if s.strictChecked == nil {
+		s.strictChecked = make(map[reflect.Type]bool)
+	}
+	s.strictChecked[eventType] = true
+	return nil
+}
+
+// indirectType returns the type, that t points to.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,106 @@
+package sticky
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// userCreated has a typo in its name.
+type userCreated struct {
+	User string `json:"user"`
+}
+
+func (userCreated) Name() string                               { return "userCreatd" }
+func (userCreated) Validate(testModel) error                   { return nil }
+func (userCreated) Execute(m testModel, _ time.Time) testModel { return m }
+
+// secretEvent has a field, that is not written.
+type secretEvent struct {
+	Value  int `json:"value"`
+	secret string
+}
+
+func (secretEvent) Name() string                               { return "secret" }
+func (secretEvent) Validate(testModel) error                   { return nil }
+func (secretEvent) Execute(m testModel, _ time.Time) testModel { return m }
+
+func TestWithStrictEvents(t *testing.T) {
+	var calls int
+	getEvent := func(name string) Event[testModel] {
+		calls++
+		switch name {
+		case "userCreated":
+			return &userCreated{}
+		case "secret":
+			// Wrong type for the name.
+			return &addEvent{}
+		}
+		return getTestEvent(name)
+	}
+
+	// Unregistered names are allowed, so the strict check sees them.
+	s, err := New(NewMemoryDB(), testModel{}, getEvent, WithStrictEvents[testModel](), WithAllowUnregistered[testModel]())
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+
+	for _, tt := range []struct {
+		event     Event[testModel]
+		expectErr string
+	}{
+		{userCreated{User: "max"}, "getEvent returns nil"},
+		{secretEvent{Value: 1}, "getEvent returns sticky.addEvent for the name"},
+	} {
+		err := s.Write(func(testModel) Event[testModel] { return tt.event })
+
+		var defErr EventDefinitionError
+		if !errors.As(err, &defErr) || !strings.Contains(err.Error(), tt.expectErr) {
+			t.Errorf("got error `%v`, expected an EventDefinitionError with `%s`", err, tt.expectErr)
+		}
+	}
+
+	calls = 0
+	for i := 0; i < 3; i++ {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("getEvent was called %d times, expected only once for the type", calls)
+	}
+}
+
+func TestWithRoundTripCheck(t *testing.T) {
+	getEvent := func(name string) Event[testModel] {
+		if name == "secret" {
+			return &secretEvent{}
+		}
+		return getTestEvent(name)
+	}
+
+	db := NewMemoryDB()
+	s, err := New(db, testModel{}, getEvent, WithRoundTripCheck[testModel]())
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	err = s.Write(func(testModel) Event[testModel] { return secretEvent{Value: 1, secret: "x"} })
+
+	var defErr EventDefinitionError
+	if !errors.As(err, &defErr) || defErr.Name != "secret" || !strings.Contains(err.Error(), "unexported fields") {
+		t.Errorf("got error `%v`, expected an EventDefinitionError of the secret event", err)
+	}
+
+	if len(db.Records()) != 1 {
+		t.Errorf("got %d records, expected the secret event not to be written", len(db.Records()))
+	}
+}
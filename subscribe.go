@@ -0,0 +1,160 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ostcar/topic"
+)
+
+// defaultNotificationHistory is the number of writes, that are held in the
+// topic. See WithNotificationHistory.
+const defaultNotificationHistory = 1024
+
+// Notification is a written event. See Subscribe.
+type Notification[Model any] struct {
+	// Name is the name of the event.
+	Name string
+
+	// Event is the written event. It must not be changed.
+	Event Event[Model]
+
+	// Time and Seq are the time and the sequence number of the envelope.
+	Time time.Time
+	Seq  uint64
+
+	// Meta is the metadata of the envelope. It is nil for events without
+	// metadata and must not be changed.
+	Meta map[string]string
+
+	// Correlation and Causation are the ids of the envelope. See
+	// WithCorrelation and WithCausation.
+	Correlation string
+	Causation   string
+}
+
+// Subscribe returns an iterator over the written events. Each value contains
+// the events, that where written since the last value.
+//
+// The iterator stops, when the context is done. If it stops for another
+// reason, the error is returned with the last value. It is ErrClosed, when the
+// Sticky is closed, and wraps ErrMissedEvents, when events are not in the
+// topic anymore.
+func (s *Sticky[Model]) Subscribe(ctx context.Context) func(yield func(val []Notification[Model], err error) bool) {
+	return s.subscribe(ctx, s.topic.LastID(), nil)
+}
+
+// SubscribeFor is like Subscribe, but only returns the events with one of the
+// names. If none of the events since the last value has one of the names, the
+// iterator waits for the next events.
+//
+// The names do not have to be known events.
+func (s *Sticky[Model]) SubscribeFor(ctx context.Context, names ...string) func(yield func(val []Notification[Model], err error) bool) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	return s.subscribe(ctx, s.topic.LastID(), func(n *Notification[Model]) bool {
+		return wanted[n.Name]
+	})
+}
+
+// subscribe returns an iterator over the notifications of the topic after
+// tid. If filter is not nil, only the notifications are returned, where it
+// returns true.
+func (s *Sticky[Model]) subscribe(ctx context.Context, tid uint64, filter func(*Notification[Model]) bool) func(yield func(val []Notification[Model], err error) bool) {
+	return func(yield func(val []Notification[Model], err error) bool) {
+		receiveCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(s.closeCtx, cancel)
+		defer stop()
+
+		for {
+			newTID, received, err := s.topic.Receive(receiveCtx, tid)
+			if err != nil {
+				if err := s.subscriptionErr(ctx, err); err != nil {
+					yield(nil, err)
+				}
+				return
+			}
+			tid = newTID
+
+			// The topic has pointers, since its values have to be comparable.
+			notifications := make([]Notification[Model], 0, len(received))
+			for _, n := range received {
+				if filter == nil || filter(n) {
+					notifications = append(notifications, *n)
+				}
+			}
+
+			if len(notifications) == 0 {
+				continue
+			}
+
+			if !yield(notifications, nil) {
+				return
+			}
+		}
+	}
+}
+
+// publishNotifications publishes the notifications of one write and prunes the
+// topic to the last writes.
+//
+// Has to be called with the write lock.
+func (s *Sticky[Model]) publishNotifications(notifications []*Notification[Model]) {
+	// The time is taken before Publish, so the message at this time is not
+	// pruned, but all messages before it.
+	s.publishTimes = append(s.publishTimes, time.Now())
+	s.topic.Publish(notifications...)
+
+	if s.notificationHistory <= 0 {
+		s.publishTimes = nil
+		return
+	}
+
+	if drop := len(s.publishTimes) - s.notificationHistory; drop > 0 {
+		s.topic.Prune(s.publishTimes[drop])
+		s.publishTimes = append(s.publishTimes[:0], s.publishTimes[drop:]...)
+	}
+}
+
+// subscriptionErr returns the error of a subscription, that stopped with err.
+// It returns nil, if the context of the subscription is done.
+func (s *Sticky[Model]) subscriptionErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if s.closeCtx.Err() != nil {
+		return ErrClosed
+	}
+
+	var unknownID topic.UnknownIDError
+	if errors.As(err, &unknownID) {
+		return fmt.Errorf("%w: %v", ErrMissedEvents, err)
+	}
+	return err
+}
+
+// notificationNames returns an iterator over the names of the notifications of
+// subscription. A value contains the name of each notification, also when an
+// event name is in more then one. The error of the subscription is ignored.
+func notificationNames[Model any](subscription func(yield func(val []Notification[Model], err error) bool)) func(yield func(val []string) bool) {
+	return func(yield func(val []string) bool) {
+		subscription(func(notifications []Notification[Model], err error) bool {
+			if err != nil {
+				return false
+			}
+
+			names := make([]string, len(notifications))
+			for i, n := range notifications {
+				names[i] = n.Name
+			}
+			return yield(names)
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithNow[testModel](func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	subscription := s.Subscribe(ctx)
+
+	if err := s.WriteMeta(map[string]string{"user": "1"}, func(testModel) Event[testModel] { return addEvent{Value: 5} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var got []Notification[testModel]
+	subscription(func(notifications []Notification[testModel], err error) bool {
+		if err != nil {
+			t.Errorf("subscription: %v", err)
+		}
+		got = notifications
+		return false
+	})
+
+	if len(got) != 1 {
+		t.Fatalf("got %d notifications, expected 1", len(got))
+	}
+
+	n := got[0]
+	if n.Name != "add" || n.Seq != 1 || !n.Time.Equal(now) || n.Meta["user"] != "1" {
+		t.Errorf("got notification %+v, expected the add event with seq 1, the time and the meta", n)
+	}
+
+	if event, ok := n.Event.(addEvent); !ok || event.Value != 5 {
+		t.Errorf("got event %v, expected the written event", n.Event)
+	}
+}
+
+func TestListenFor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := New(NewMemoryDB(), testModel{}, getWithdrawTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	listener := s.ListenFor(ctx, "withdraw", "not-registered")
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 2} }); err != nil {
+		t.Fatalf("write add: %v", err)
+	}
+
+	if err := s.WriteMany(func(testModel) ([]Event[testModel], error) {
+		return []Event[testModel]{withdrawEvent{Value: 1}, addEvent{Value: 1}}, nil
+	}); err != nil {
+		t.Fatalf("write withdraw: %v", err)
+	}
+
+	var got [][]string
+	listener(func(names []string) bool {
+		got = append(got, names)
+		return false
+	})
+
+	if len(got) != 1 || len(got[0]) != 1 || got[0][0] != "withdraw" {
+		t.Errorf("got %v, expected only [withdraw]", got)
+	}
+}
+
+func TestSubscribeFrom(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":3}}`,
+	)
+
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	received := make(chan []uint64)
+	go func() {
+		var seqs []uint64
+		s.SubscribeFrom(ctx, 2)(func(notifications []Notification[testModel], err error) bool {
+			if err != nil {
+				t.Errorf("subscription: %v", err)
+				return false
+			}
+
+			for _, n := range notifications {
+				seqs = append(seqs, n.Seq)
+			}
+			return len(seqs) < 4
+		})
+		received <- seqs
+	}()
+
+	for i := 0; i < 2; i++ {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	select {
+	case seqs := <-received:
+		if len(seqs) != 4 || seqs[0] != 2 || seqs[1] != 3 || seqs[2] != 4 || seqs[3] != 5 {
+			t.Errorf("got seqs %v, expected [2 3 4 5]", seqs)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("subscription did not get the events")
+	}
+}
+
+func TestSubscribeFrom_compacted(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+	)
+
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Compact(context.Background()); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	var gotErr error
+	s.SubscribeFrom(context.Background(), 1)(func(_ []Notification[snapshotModel], err error) bool {
+		gotErr = err
+		return false
+	})
+
+	if !errors.Is(gotErr, ErrNotSupported) {
+		t.Errorf("got error `%v`, expected ErrNotSupported", gotErr)
+	}
+}
+
+func TestWriteMany_publishes_one_message(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getWithdrawTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listener := s.Listen(ctx)
+
+	before := s.topic.LastID()
+	if err := s.WriteMany(func(testModel) ([]Event[testModel], error) {
+		return []Event[testModel]{addEvent{Value: 2}, withdrawEvent{Value: 1}, addEvent{Value: 3}}, nil
+	}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := s.topic.LastID() - before; got != 1 {
+		t.Errorf("write published %d messages, expected 1", got)
+	}
+
+	var got [][]string
+	listener(func(names []string) bool {
+		got = append(got, names)
+		return false
+	})
+
+	if len(got) != 1 || !slices.Equal(got[0], []string{"add", "withdraw", "add"}) {
+		t.Errorf("listen got %v, expected [[add withdraw add]]", got)
+	}
+}
+
+func TestSubscribe_errors(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s.Subscribe(ctx)(func(_ []Notification[testModel], err error) bool {
+		t.Errorf("got value with error `%v` after cancel, expected a clean stop", err)
+		return false
+	})
+
+	subscription := s.Subscribe(context.Background())
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	var gotErr error
+	subscription(func(_ []Notification[testModel], err error) bool {
+		gotErr = err
+		return false
+	})
+
+	if !errors.Is(gotErr, ErrClosed) {
+		t.Errorf("got error `%v`, expected ErrClosed", gotErr)
+	}
+}
+
+func TestSubscribe_missed_events(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	write := func() {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	write()
+	subscription := s.Subscribe(context.Background())
+	write()
+	write()
+	s.topic.Prune(time.Now())
+
+	var gotErr error
+	subscription(func(_ []Notification[testModel], err error) bool {
+		gotErr = err
+		return false
+	})
+
+	if !errors.Is(gotErr, ErrMissedEvents) {
+		t.Errorf("got error `%v`, expected ErrMissedEvents", gotErr)
+	}
+}
+
+func TestWithNotificationHistory(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent, WithNotificationHistory[testModel](10))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	write := func() {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	write()
+	subscription := s.Subscribe(context.Background())
+	for i := 0; i < 1000; i++ {
+		write()
+	}
+
+	_, notifications, err := s.topic.Receive(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+
+	if len(notifications) > 10 {
+		t.Errorf("topic holds %d notifications, expected at most 10", len(notifications))
+	}
+
+	if len(s.publishTimes) > 10 {
+		t.Errorf("got %d publish times, expected at most 10", len(s.publishTimes))
+	}
+
+	if got := notifications[len(notifications)-1].Seq; got != 1001 {
+		t.Errorf("last notification has seq %d, expected 1001", got)
+	}
+
+	var gotErr error
+	subscription(func(_ []Notification[testModel], err error) bool {
+		gotErr = err
+		return false
+	})
+
+	if !errors.Is(gotErr, ErrMissedEvents) {
+		t.Errorf("got error `%v`, expected ErrMissedEvents", gotErr)
+	}
+}
@@ -0,0 +1,85 @@
+package sticky
+
+import "context"
+
+// ChanOption is a option for SubscribeChan.
+type ChanOption func(*chanConfig)
+
+type chanConfig struct {
+	dropOldest bool
+}
+
+// ChanDropOldest drops the oldest value of the channel, when it is full, and
+// sends the new value. The consumer gets the newest events but can miss
+// older ones. The buffer is at least 1.
+func ChanDropOldest() ChanOption {
+	return func(c *chanConfig) {
+		c.dropOldest = true
+	}
+}
+
+// SubscribeChan returns a channel with the values of Subscribe. The channel is
+// closed, after the context is done, the returned function is called, the
+// Sticky is closed or the subscription has an error. Use Subscribe to get the
+// error.
+//
+// Writers never wait for the consumer. When the buffer is full, the
+// subscription waits until the consumer reads the channel. The events written
+// meanwhile are sent together as the next value, so no event is lost. Use
+// ChanDropOldest to drop old values instead.
+func (s *Sticky[Model]) SubscribeChan(ctx context.Context, buffer int, options ...ChanOption) (<-chan []Notification[Model], func()) {
+	var cfg chanConfig
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	if cfg.dropOldest {
+		// Without a buffer, there is nothing to drop.
+		buffer = max(buffer, 1)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ch := make(chan []Notification[Model], buffer)
+	subscription := s.Subscribe(ctx)
+
+	go func() {
+		defer close(ch)
+
+		subscription(func(notifications []Notification[Model], err error) bool {
+			if err != nil {
+				return false
+			}
+
+			if cfg.dropOldest {
+				sendDropOldest(ch, notifications)
+				return true
+			}
+
+			select {
+			case ch <- notifications:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return ch, cancel
+}
+
+// sendDropOldest sends the value to the channel. If the channel is full, the
+// oldest value is dropped. The caller has to be the only sender.
+func sendDropOldest[T any](ch chan T, value T) {
+	for {
+		select {
+		case ch <- value:
+			return
+		default:
+		}
+
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
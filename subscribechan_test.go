@@ -0,0 +1,83 @@
+package sticky
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubscribeChan(t *testing.T) {
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	ch, cancel := s.SubscribeChan(context.Background(), 1)
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	select {
+	case notifications := <-ch:
+		if len(notifications) != 1 || notifications[0].Name != "add" {
+			t.Errorf("got %v, expected one add event", notifications)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("did not receive the event")
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("got a value after cancel, expected the channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("channel was not closed after cancel")
+	}
+}
+
+func TestSubscribeChan_drop_oldest_does_not_block_writers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := New(NewMemoryDB(), testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	// The channel is not read, until all events are written.
+	ch, _ := s.SubscribeChan(ctx, 1, ChanDropOldest())
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := 0; i < 1000; i++ {
+			if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+				t.Errorf("write: %v", err)
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("writers are blocked by the consumer")
+	}
+
+	// The last value contains the last event.
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case notifications := <-ch:
+			if notifications[len(notifications)-1].Seq == 1000 {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("did not receive the last event")
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package sticky
+
+import "context"
+
+// TraceResult is the result of an operation for WithTrace.
+type TraceResult struct {
+	// Events are the names of the written events. It is only set for writes.
+	Events []string
+
+	// Count is the number of loaded or written events.
+	Count int
+
+	// Err is the error of the operation.
+	Err error
+}
+
+// startTrace calls the function of WithTrace. Without WithTrace, it returns
+// ctx and a function, that does nothing.
+func (s *Sticky[Model]) startTrace(ctx context.Context, operation string) (context.Context, func(TraceResult)) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if s.trace == nil {
+		return ctx, func(TraceResult) {}
+	}
+	return s.trace(ctx, operation)
+}
+
+// eventNames returns the names of the events.
+func (s *Sticky[Model]) eventNames(events []Event[Model]) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = s.eventName(event)
+	}
+	return names
+}
@@ -0,0 +1,44 @@
+package sticky
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestWithTrace(t *testing.T) {
+	var operations []string
+	var results []TraceResult
+	trace := func(ctx context.Context, operation string) (context.Context, func(TraceResult)) {
+		operations = append(operations, operation)
+		return ctx, func(result TraceResult) {
+			results = append(results, result)
+		}
+	}
+
+	s, err := New(NewMemoryDB(), snapshotModel{}, getSnapshotTestEvent, WithTrace[snapshotModel](trace))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(snapshotModel) Event[snapshotModel] { return snapshotAddEvent{Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	if err := s.Compact(context.Background()); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	expect := []string{"load", "write", "snapshot", "compact"}
+	if !slices.Equal(operations, expect) || len(results) != len(expect) {
+		t.Fatalf("got operations %v with %d results, expected %v", operations, len(results), expect)
+	}
+
+	if write := results[1]; write.Count != 1 || !slices.Equal(write.Events, []string{"add"}) {
+		t.Errorf("got write result %+v, expected one add event", write)
+	}
+}
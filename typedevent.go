@@ -0,0 +1,77 @@
+package sticky
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventType defines an event by its payload and functions. The payload is
+// written to the database without a wrapper.
+//
+//	var addEvent = sticky.NewEvent("add", nil, func(m Model, p addPayload, _ time.Time) Model {
+//		m.Sum += p.Value
+//		return m
+//	})
+//
+//	addEvent.Register(&registry)
+//	s.Write(func(Model) sticky.Event[Model] { return addEvent.New(addPayload{Value: 1}) })
+type EventType[Model, Payload any] struct {
+	name     string
+	validate func(Model, Payload) error
+	execute  func(Model, Payload, time.Time) Model
+}
+
+// NewEvent creates an EventType. validate can be nil.
+func NewEvent[Model, Payload any](name string, validate func(Model, Payload) error, execute func(Model, Payload, time.Time) Model) *EventType[Model, Payload] {
+	return &EventType[Model, Payload]{
+		name:     name,
+		validate: validate,
+		execute:  execute,
+	}
+}
+
+// Name returns the name of the event.
+func (t *EventType[Model, Payload]) Name() string {
+	return t.name
+}
+
+// New returns an event with the payload.
+func (t *EventType[Model, Payload]) New(payload Payload) Event[Model] {
+	return &typedEvent[Model, Payload]{t: t, payload: payload}
+}
+
+// Register adds the event to the registry.
+func (t *EventType[Model, Payload]) Register(r *Registry[Model]) error {
+	return r.Register(t.name, func() Event[Model] {
+		return &typedEvent[Model, Payload]{t: t}
+	})
+}
+
+// typedEvent is an event created by an EventType.
+type typedEvent[Model, Payload any] struct {
+	t       *EventType[Model, Payload]
+	payload Payload
+}
+
+func (e *typedEvent[Model, Payload]) Name() string {
+	return e.t.name
+}
+
+func (e *typedEvent[Model, Payload]) Validate(m Model) error {
+	if e.t.validate == nil {
+		return nil
+	}
+	return e.t.validate(m, e.payload)
+}
+
+func (e *typedEvent[Model, Payload]) Execute(m Model, t time.Time) Model {
+	return e.t.execute(m, e.payload, t)
+}
+
+func (e *typedEvent[Model, Payload]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.payload)
+}
+
+func (e *typedEvent[Model, Payload]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &e.payload)
+}
@@ -0,0 +1,68 @@
+package sticky
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type typedAddPayload struct {
+	Value int `json:"value"`
+}
+
+func TestNewEvent(t *testing.T) {
+	add := NewEvent(
+		"add",
+		func(_ testModel, p typedAddPayload) error {
+			if p.Value < 0 {
+				return errors.New("value must not be negative")
+			}
+			return nil
+		},
+		func(m testModel, p typedAddPayload, _ time.Time) testModel {
+			m.Sum += p.Value
+			return m
+		},
+	)
+
+	var registry Registry[testModel]
+	if err := add.Register(&registry); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)
+	s, err := New(db, testModel{}, registry.Get)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return add.New(typedAddPayload{Value: 2}) }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var validationErr ValidationError
+	if err := s.Write(func(testModel) Event[testModel] { return add.New(typedAddPayload{Value: -1}) }); !errors.As(err, &validationErr) {
+		t.Errorf("write of invalid event returned `%v`, expected a ValidationError", err)
+	}
+
+	envelope, err := DecodeEnvelope(db.Records()[1])
+	if err != nil {
+		t.Fatalf("decoding record: %v", err)
+	}
+
+	if got := string(envelope.Payload); got != `{"value":2}` {
+		t.Errorf("got payload `%s`, expected `{\"value\":2}`", got)
+	}
+
+	reloaded, err := New(db, testModel{}, registry.Get)
+	if err != nil {
+		t.Fatalf("reloading sticky: %v", err)
+	}
+
+	model, done := reloaded.ForReading()
+	done()
+
+	if model.Sum != 3 {
+		t.Errorf("got sum %d, expected 3", model.Sum)
+	}
+}
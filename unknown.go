@@ -0,0 +1,64 @@
+package sticky
+
+import (
+	"errors"
+	"fmt"
+)
+
+// UnknownEvents decides, what happens on load with records of events, that
+// getEvent does not know. See WithUnknownEvents.
+type UnknownEvents int
+
+const (
+	// UnknownEventsFail returns an error. This is the default.
+	UnknownEventsFail UnknownEvents = iota
+
+	// UnknownEventsSkip ignores the records.
+	UnknownEventsSkip
+
+	// UnknownEventsPreserve ignores the records, but keeps them in memory.
+	// They can be returned with Sticky.UnknownEvents.
+	UnknownEventsPreserve
+)
+
+// errUnknownEvent is returned by decodeEvent, when getEvent does not know the
+// event.
+var errUnknownEvent = errors.New("unknown event")
+
+// skipUnknown returns true, if the error is an unknown event, that should be
+// ignored. In this case, the envelope is preserved, if configured.
+func (l *loader[Model]) skipUnknown(e Envelope, err error) bool {
+	if l.unknownEvents == UnknownEventsFail || !errors.Is(err, errUnknownEvent) {
+		return false
+	}
+
+	l.unknownCount++
+	if l.logger != nil {
+		l.logger.Warn("unknown event skipped", "event", e.Type, "records", l.records)
+	}
+
+	if l.unknownEvents == UnknownEventsPreserve {
+		l.unknown = append(l.unknown, e)
+	}
+	return true
+}
+
+// UnknownEvents returns the records of unknown events, that where ignored on
+// load. It is only filled with UnknownEventsPreserve.
+func (s *Sticky[Model]) UnknownEvents() []Envelope {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Envelope(nil), s.loader.unknown...)
+}
+
+// checkUnknownEvents returns an error, if unknown events where ignored. They
+// would be lost by a compaction.
+//
+// Has to be called with the lock.
+func (s *Sticky[Model]) checkUnknownEvents() error {
+	if s.loader.unknownCount > 0 {
+		return fmt.Errorf("database contains %d unknown events: %w", s.loader.unknownCount, ErrNotSupported)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithUnknownEvents(t *testing.T) {
+	newDB := func() *MemoryDB {
+		return NewMemoryDB(
+			`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`,
+			`{"time":"2024-01-01 00:00:00","type":"newEvent","payload":{"value":5}}`,
+			`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":2}}`,
+		)
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		if _, err := New(newDB(), testModel{}, getTestEvent); !errors.Is(err, errUnknownEvent) {
+			t.Errorf("got error `%v`, expected an unknown event", err)
+		}
+	})
+
+	for _, tt := range []struct {
+		name           string
+		mode           UnknownEvents
+		expectPreserve int
+	}{
+		{"skip", UnknownEventsSkip, 0},
+		{"preserve", UnknownEventsPreserve, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(newDB(), testModel{}, getTestEvent, WithUnknownEvents[testModel](tt.mode))
+			if err != nil {
+				t.Fatalf("creating sticky: %v", err)
+			}
+
+			model, version, done := s.ForReadingVersioned()
+			done()
+
+			if model.Sum != 3 || version != 3 {
+				t.Errorf("got sum %d with version %d, expected sum 3 with version 3", model.Sum, version)
+			}
+
+			unknown := s.UnknownEvents()
+			if len(unknown) != tt.expectPreserve {
+				t.Fatalf("got %d unknown events, expected %d", len(unknown), tt.expectPreserve)
+			}
+
+			if len(unknown) > 0 && unknown[0].Type != "newEvent" {
+				t.Errorf("got unknown event `%s`, expected `newEvent`", unknown[0].Type)
+			}
+		})
+	}
+}
+
+func TestWithUnknownEvents_alias(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"oldAdd","payload":{"value":1}}`)
+
+	s, err := New(db, testModel{}, getTestEvent, WithEventAlias[testModel]("oldAdd", "add"), WithUnknownEvents[testModel](UnknownEventsPreserve))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 1 || len(s.UnknownEvents()) != 0 {
+		t.Errorf("got sum %d with %d unknown events, expected the aliased event to be applied", model.Sum, len(s.UnknownEvents()))
+	}
+}
+
+func TestWithUnknownEvents_no_compaction(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"newEvent","payload":{"value":5}}`)
+
+	s, err := New(db, snapshotModel{}, getSnapshotTestEvent, WithUnknownEvents[snapshotModel](UnknownEventsSkip))
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Compact(context.Background()); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("compact returned `%v`, expected ErrNotSupported", err)
+	}
+}
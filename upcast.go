@@ -0,0 +1,69 @@
+package sticky
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SchemaVersioner can be implemented by an event, whose payload changed. The
+// schema version is written into the envelope of the event. Events without
+// SchemaVersion have version 0.
+//
+// Use WithUpcaster to transform payloads of older versions, before they are
+// decoded.
+type SchemaVersioner interface {
+	SchemaVersion() int
+}
+
+// UpcastError happens, when the payload of an event can not be transformed
+// to the current schema version.
+type UpcastError struct {
+	Name    string
+	Version int
+	Current int
+	Err     error
+}
+
+func (err UpcastError) Error() string {
+	return fmt.Sprintf("event `%s` has schema version %d, current is %d: %v", err.Name, err.Version, err.Current, err.Err)
+}
+
+func (err UpcastError) Unwrap() error {
+	return err.Err
+}
+
+// upcastKey identifies the upcaster of an event from one schema version.
+type upcastKey struct {
+	name string
+	from int
+}
+
+// schemaVersion returns the schema version of an event.
+func schemaVersion(event any) int {
+	if versioner, ok := event.(SchemaVersioner); ok {
+		return versioner.SchemaVersion()
+	}
+	return 0
+}
+
+// upcast transforms the payload from the version to the current version.
+func (l *loader[Model]) upcast(name string, payload json.RawMessage, version, current int) (json.RawMessage, error) {
+	if version > current {
+		return nil, UpcastError{Name: name, Version: version, Current: current, Err: errors.New("schema version is newer then the event")}
+	}
+
+	for v := version; v < current; v++ {
+		upcaster, ok := l.upcasters[upcastKey{name: name, from: v}]
+		if !ok {
+			return nil, UpcastError{Name: name, Version: version, Current: current, Err: fmt.Errorf("no upcaster from version %d", v)}
+		}
+
+		var err error
+		payload, err = upcaster(payload)
+		if err != nil {
+			return nil, UpcastError{Name: name, Version: version, Current: current, Err: fmt.Errorf("upcasting from version %d: %w", v, err)}
+		}
+	}
+	return payload, nil
+}
@@ -0,0 +1,107 @@
+package sticky
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// depositEvent is at schema version 2. Version 0 had the field `value`,
+// version 1 the field `val`.
+type depositEvent struct {
+	Amount int `json:"amount"`
+}
+
+func (e depositEvent) Name() string {
+	return "deposit"
+}
+
+func (e depositEvent) SchemaVersion() int {
+	return 2
+}
+
+func (e depositEvent) Validate(m testModel) error {
+	return nil
+}
+
+func (e depositEvent) Execute(m testModel, _ time.Time) testModel {
+	m.Sum += e.Amount
+	return m
+}
+
+func getDepositEvent(name string) Event[testModel] {
+	if name == "deposit" {
+		return &depositEvent{}
+	}
+	return nil
+}
+
+// renameField returns an upcaster, that renames a field of the payload.
+func renameField(from, to string) func(json.RawMessage) (json.RawMessage, error) {
+	return func(payload json.RawMessage) (json.RawMessage, error) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return nil, err
+		}
+		fields[to] = fields[from]
+		delete(fields, from)
+		return json.Marshal(fields)
+	}
+}
+
+func TestWithUpcaster(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"deposit","payload":{"value":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"deposit","schema":1,"payload":{"val":2}}`,
+	)
+
+	s, err := New(
+		db,
+		testModel{},
+		getDepositEvent,
+		WithUpcaster[testModel]("deposit", 0, renameField("value", "val")),
+		WithUpcaster[testModel]("deposit", 1, renameField("val", "amount")),
+	)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+
+	if err := s.Write(func(testModel) Event[testModel] { return depositEvent{Amount: 3} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	model, done := s.ForReading()
+	done()
+
+	if model.Sum != 6 {
+		t.Errorf("got sum %d, expected 6", model.Sum)
+	}
+
+	if record := string(db.Records()[2]); !strings.Contains(record, `"schema":2`) {
+		t.Errorf("record `%s` does not contain the schema version", record)
+	}
+}
+
+func TestWithUpcaster_missing(t *testing.T) {
+	db := NewMemoryDB(
+		`{"time":"2024-01-01 00:00:00","type":"deposit","schema":2,"payload":{"amount":1}}`,
+		`{"time":"2024-01-01 00:00:00","type":"deposit","payload":{"value":1}}`,
+	)
+
+	_, err := New(db, testModel{}, getDepositEvent, WithUpcaster[testModel]("deposit", 0, renameField("value", "val")))
+
+	var upcastErr UpcastError
+	if !errors.As(err, &upcastErr) {
+		t.Fatalf("got error `%v`, expected an UpcastError", err)
+	}
+
+	if upcastErr.Name != "deposit" || upcastErr.Version != 0 || upcastErr.Current != 2 {
+		t.Errorf("got %+v, expected deposit from version 0 to 2", upcastErr)
+	}
+
+	if !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("got error `%v`, expected it to name the record", err)
+	}
+}
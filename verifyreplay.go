@@ -0,0 +1,115 @@
+package sticky
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplayMismatchError is returned by VerifyReplay, when the replayed model
+// differs from the live model.
+type ReplayMismatchError struct {
+	Version uint64
+}
+
+func (err ReplayMismatchError) Error() string {
+	return fmt.Sprintf("replayed model differs from the live model at version %d", err.Version)
+}
+
+// VerifyReplay loads the database into a new model and compares it with the
+// live model. It returns a ReplayMismatchError, if equal returns false. Use it
+// to find Execute methods, that are not deterministic, or events, that are
+// not in the database.
+//
+// The version is pinned with a short write lock and the database is replayed
+// up to it without a lock. The events, that are written in the meantime, are
+// applied from the notifications. Only equal is called with the read lock, so
+// it must not call methods of the Sticky.
+//
+// If the database was compacted, the replay starts at the snapshot.
+func (s *Sticky[Model]) VerifyReplay(ctx context.Context, equal func(live, replayed Model) bool) error {
+	r, l, tid, seq, err := s.catchUpReader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	l.stop = func(Envelope) (bool, error) {
+		return false, ctx.Err()
+	}
+
+	replayed := s.newModel()
+	if l.maxRecords > 0 {
+		if replayed, err = l.load(r, replayed, 0); err != nil {
+			return fmt.Errorf("replaying database: %w", err)
+		}
+	}
+	r.Close()
+
+	// Most of the events, that where written during the replay, are applied
+	// without the lock.
+	if replayed, tid, seq, err = s.applyWritten(ctx, l, replayed, tid, seq); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if replayed, _, seq, err = s.applyWritten(ctx, l, replayed, tid, seq); err != nil {
+		return err
+	}
+
+	// The events of an open commit group are applied to the model but not
+	// published yet.
+	if s.group != nil {
+		if replayed, seq, err = applyNotifications(l, replayed, s.group.notifications, seq); err != nil {
+			return err
+		}
+	}
+
+	if l.version != s.version {
+		return fmt.Errorf("replayed version %d, live version is %d", l.version, s.version)
+	}
+
+	if !equal(s.model, replayed) {
+		return ReplayMismatchError{Version: s.version}
+	}
+	return nil
+}
+
+// applyWritten executes the notifications after tid on the model and returns
+// it with the id of the last notification and the last sequence number.
+func (s *Sticky[Model]) applyWritten(ctx context.Context, l *loader[Model], model Model, tid, seq uint64) (Model, uint64, uint64, error) {
+	if s.topic.LastID() <= tid {
+		return model, tid, seq, nil
+	}
+
+	newTID, notifications, err := s.topic.Receive(ctx, tid)
+	if err != nil {
+		if err := s.subscriptionErr(ctx, err); err != nil {
+			return model, tid, seq, err
+		}
+		return model, tid, seq, ctx.Err()
+	}
+
+	model, seq, err = applyNotifications(l, model, notifications, seq)
+	return model, newTID, seq, err
+}
+
+// applyNotifications executes the events of the notifications after the
+// sequence number seq on the model. Events of a commit group can be in the
+// database, before they are published.
+func applyNotifications[Model any](l *loader[Model], model Model, notifications []*Notification[Model], seq uint64) (Model, uint64, error) {
+	for _, n := range notifications {
+		if n.Seq <= seq {
+			continue
+		}
+
+		var err error
+		if model, err = l.execute(n.Event, n.Name, model, n.Time); err != nil {
+			return model, seq, fmt.Errorf("applying written event %d: %w", n.Seq, err)
+		}
+		l.version++
+		seq = n.Seq
+	}
+	return model, seq, nil
+}
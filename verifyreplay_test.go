@@ -0,0 +1,206 @@
+package sticky
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// counterEvent adds a global counter to the model, so it is different on
+// each execution.
+type counterEvent struct{}
+
+var counterValue int
+
+func (counterEvent) Name() string             { return "counter" }
+func (counterEvent) Validate(testModel) error { return nil }
+func (counterEvent) Execute(m testModel, _ time.Time) testModel {
+	counterValue++
+	m.Sum += counterValue
+	return m
+}
+
+func equalTestModel(live, replayed testModel) bool {
+	return live == replayed
+}
+
+func TestVerifyReplay(t *testing.T) {
+	db := NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 2} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := s.VerifyReplay(context.Background(), equalTestModel); err != nil {
+		t.Errorf("VerifyReplay: %v", err)
+	}
+}
+
+func TestVerifyReplay_mismatch(t *testing.T) {
+	getEvent := func(name string) Event[testModel] {
+		if name == "counter" {
+			return &counterEvent{}
+		}
+		return getTestEvent(name)
+	}
+
+	s, err := New(NewMemoryDB(), testModel{}, getEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+
+	for _, event := range []Event[testModel]{addEvent{Value: 1}, counterEvent{}} {
+		if err := s.Write(func(testModel) Event[testModel] { return event }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	err = s.VerifyReplay(context.Background(), equalTestModel)
+
+	var mismatch ReplayMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("got error `%v`, expected a ReplayMismatchError", err)
+	}
+
+	if mismatch.Version != 2 {
+		t.Errorf("got version %d, expected 2", mismatch.Version)
+	}
+}
+
+// pausingDB blocks the first read of the next reader, until release is
+// closed.
+type pausingDB struct {
+	*MemoryDB
+	opened  chan struct{}
+	release chan struct{}
+}
+
+func (db *pausingDB) Reader() (io.ReadCloser, error) {
+	r, err := db.MemoryDB.Reader()
+	if err != nil || db.opened == nil {
+		return r, err
+	}
+
+	pr := &pausingReader{ReadCloser: r, opened: db.opened, release: db.release}
+	db.opened = nil
+	return pr, nil
+}
+
+type pausingReader struct {
+	io.ReadCloser
+	opened  chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (r *pausingReader) Read(p []byte) (int, error) {
+	r.once.Do(func() {
+		close(r.opened)
+		<-r.release
+	})
+	return r.ReadCloser.Read(p)
+}
+
+func TestVerifyReplay_does_not_block_writers(t *testing.T) {
+	db := &pausingDB{MemoryDB: NewMemoryDB(`{"time":"2024-01-01 00:00:00","type":"add","payload":{"value":1}}`)}
+	s, err := New(db, testModel{}, getTestEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+
+	db.opened = make(chan struct{})
+	db.release = make(chan struct{})
+	opened := db.opened
+
+	done := make(chan error)
+	var replayedSum int
+	go func() {
+		done <- s.VerifyReplay(context.Background(), func(live, replayed testModel) bool {
+			replayedSum = replayed.Sum
+			return live == replayed
+		})
+	}()
+
+	<-opened
+	for i := 0; i < 3; i++ {
+		if err := s.Write(func(testModel) Event[testModel] { return addEvent{Value: 1} }); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	close(db.release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("VerifyReplay: %v", err)
+	}
+
+	if replayedSum != 4 {
+		t.Errorf("got replayed sum %d, expected 4", replayedSum)
+	}
+}
+
+func TestVerifyReplay_open_commit_group(t *testing.T) {
+	db := &groupSyncDB{MemoryDB: NewMemoryDB()}
+	s := newGroupSticky(t, db, time.Hour, 0)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.Write(func(cloneModel) Event[cloneModel] { return cloneAddEvent{Value: 1} })
+	}()
+
+	for s.Version() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	equal := func(live, replayed cloneModel) bool { return live == replayed }
+	if err := s.VerifyReplay(context.Background(), equal); err != nil {
+		t.Errorf("VerifyReplay: %v", err)
+	}
+
+	s.Close()
+	wg.Wait()
+}
+
+func TestVerifyReplay_map_model(t *testing.T) {
+	getEvent := func(string) Event[mapModel] { return &setEvent{} }
+	empty := mapModel{Values: map[string]int{}}
+	s, err := New(NewMemoryDB(), empty, getEvent)
+	if err != nil {
+		t.Fatalf("creating sticky: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Write(func(mapModel) Event[mapModel] { return setEvent{Key: "a", Value: 1} }); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var sameMap bool
+	err = s.VerifyReplay(context.Background(), func(live, replayed mapModel) bool {
+		live.Values["probe"] = 1
+		_, sameMap = replayed.Values["probe"]
+		delete(live.Values, "probe")
+		return live.Values["a"] == replayed.Values["a"]
+	})
+	if err != nil {
+		t.Fatalf("verify replay: %v", err)
+	}
+
+	if sameMap {
+		t.Errorf("replayed model shares the map with the live model")
+	}
+
+	if len(empty.Values) != 0 {
+		t.Errorf("empty model was changed to %v", empty.Values)
+	}
+}